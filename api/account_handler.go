@@ -1,12 +1,15 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/gorilla/mux"
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
 	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
 )
@@ -37,13 +40,14 @@ func (h *AccountHandler) NewAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	request.CustomerID = customerID
+	request.Actor, request.SourceIP, request.RequestID = auditContext(r)
 	if err := request.Validate(); err != nil {
 		logger.Warn("Validation failed for NewAccount", logger.Any("error", err))
 		utils.WriteResponse(w, err.Code, map[string]string{"error": err.AsMessage()})
 		return
 	}
 
-	response, appError := h.service.NewAccount(request)
+	response, appError := h.service.NewAccount(r.Context(), request)
 	if appError != nil {
 		logger.Error("Error creating new account", logger.Any("error", appError))
 		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.AsMessage()})
@@ -72,17 +76,60 @@ func (h *AccountHandler) MakeTransaction(w http.ResponseWriter, r *http.Request)
 
 	request.AccountID = accountID
 	request.CustomerID = customerID
+	request.Actor, request.SourceIP, request.RequestID = auditContext(r)
 	if err := request.Validate(); err != nil {
 		logger.Warn("Validation failed for Transaction", logger.Any("error", err))
 		utils.WriteResponse(w, err.Code, map[string]string{"error": err.AsMessage()})
 		return
 	}
 
-	response, appError := h.service.MakeTransaction(request)
+	response, appError := h.service.MakeTransaction(r.Context(), request)
 	if appError != nil {
 		logger.Error("Error processing transaction", logger.Any("error", appError))
 		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.AsMessage()})
 		return
 	}
 	utils.WriteResponse(w, http.StatusOK, response)
-}
\ No newline at end of file
+}
+
+func (h *AccountHandler) FreezeAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.service.FreezeAccount)
+}
+
+func (h *AccountHandler) CloseAccount(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.service.CloseAccount)
+}
+
+// changeStatus reads the optional reason of a Freeze or Close; the body may
+// be empty.
+func (h *AccountHandler) changeStatus(w http.ResponseWriter, r *http.Request, change func(context.Context, dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError)) {
+	var request dto.AccountStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		logger.Warn("Failed to decode account status request", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+
+	request.AccountID = mux.Vars(r)["account_id"]
+	request.Actor, request.SourceIP, request.RequestID = auditContext(r)
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.AsMessage()})
+		return
+	}
+
+	response, appError := change(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.AsMessage()})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, response)
+}
+
+func (h *AccountHandler) ListAccountEvents(w http.ResponseWriter, r *http.Request) {
+	events, appError := h.service.ListAccountEvents(r.Context(), mux.Vars(r)["account_id"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.AsMessage()})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, events)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type APIKeyHandler struct {
+	service ports.APIKeyService
+}
+
+func NewAPIKeyHandler(service ports.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var request dto.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid API key request payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+	if caller := VerifiedTokenFrom(r.Context()); caller != nil {
+		request.CreatedBy = caller.Username
+	}
+
+	key, appError := h.service.CreateAPIKey(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteResponse(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	keys, appError := h.service.ListAPIKeys(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	if appError := h.service.RevokeAPIKey(r.Context(), mux.Vars(r)["key_id"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+var auditCSVHeader = []string{"id", "occurred_at", "actor", "action", "resource", "outcome", "source_ip", "request_id", "details", "hash"}
+
+type AuditHandler struct {
+	service ports.AuditService
+}
+
+func NewAuditHandler(service ports.AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// ListAuditEvents answers JSON, or CSV when asked with format=csv or an
+// Accept: text/csv header.
+func (h *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := dto.AuditQueryRequest{
+		Actor:    query.Get("actor"),
+		Action:   query.Get("action"),
+		Resource: query.Get("resource"),
+		Outcome:  query.Get("outcome"),
+	}
+	for param, target := range map[string]*int{"limit": &request.Limit, "offset": &request.Offset} {
+		if raw := query.Get(param); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + param})
+				return
+			}
+			*target = n
+		}
+	}
+	for param, target := range map[string]**time.Time{"from": &request.From, "to": &request.To} {
+		if raw := query.Get(param); raw != "" {
+			t, err := time.Parse(time.RFC3339, raw)
+			if err != nil {
+				utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + param + ", expected RFC 3339"})
+				return
+			}
+			*target = &t
+		}
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	events, appError := h.service.ListAuditEvents(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	if query.Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		writeAuditCSV(w, events)
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, events)
+}
+
+func (h *AuditHandler) VerifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	result, appError := h.service.VerifyAuditLog(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, result)
+}
+
+func writeAuditCSV(w http.ResponseWriter, events []dto.AuditEventResponse) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="audit.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	rows := [][]string{auditCSVHeader}
+	for _, e := range events {
+		rows = append(rows, []string{
+			strconv.FormatInt(e.ID, 10),
+			e.OccurredAt.UTC().Format(time.RFC3339Nano),
+			csvSafe(e.Actor),
+			csvSafe(e.Action),
+			csvSafe(e.Resource),
+			e.Outcome,
+			e.SourceIP,
+			csvSafe(e.RequestID),
+			csvSafe(e.Details),
+			e.Hash,
+		})
+	}
+	if err := out.WriteAll(rows); err != nil {
+		logger.Error("Failed to write audit CSV", logger.Any("error", err))
+	}
+}
+
+// csvSafe keeps spreadsheet applications from evaluating a cell written by a
+// caller, such as a username starting with "=".
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -35,8 +35,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
         utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
         return
     }
+    request.SourceIP = utils.ClientIP(r)
+    request.RequestID = RequestIDFrom(r.Context())
 
-    response, appError := h.service.RemoteLogin(request)
+    response, appError := h.service.RemoteLogin(r.Context(), request)
     if appError != nil {
         logger.Warn("Login failed", logger.String("error", appError.Message))
         utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
@@ -58,8 +60,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
         utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
         return
     }
+    request.SourceIP = utils.ClientIP(r)
+    request.RequestID = RequestIDFrom(r.Context())
 
-    response, appError := h.service.Register(request)
+    response, appError := h.service.Register(r.Context(), request)
     if appError != nil {
         logger.Error("Error registering new user",
             logger.String("username", request.Username),
@@ -75,20 +79,36 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
-    if r.Method != http.MethodGet {
+    var token string
+    switch r.Method {
+    case http.MethodPost:
+        var request dto.RefreshRequest
+        if r.ContentLength != 0 {
+            if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+                logger.Warn("Invalid refresh request payload", logger.Any("error", err))
+                utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+                return
+            }
+        }
+        token = utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+        if token == "" {
+            token = request.RefreshToken
+        }
+    case http.MethodGet:
+        token = h.deprecatedQueryToken(w, r)
+    default:
         logger.Warn("Invalid method for refresh", logger.String("method", r.Method))
         utils.WriteResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
         return
     }
 
-    token := r.URL.Query().Get("token")
     if token == "" {
         logger.Warn("Missing token in refresh request")
         utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Missing token"})
         return
     }
 
-    response, appError := h.service.Refresh(token)
+    response, appError := h.service.Refresh(r.Context(), token)
     if appError != nil {
         logger.Error("Error refreshing token", logger.Any("error", appError))
         utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
@@ -98,36 +118,240 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *AuthHandler) Verify(w http.ResponseWriter, r *http.Request) {
-    token := r.URL.Query().Get("token")
-    routeName := r.URL.Query().Get("routeName")
-    vars := utils.ExtractQueryParams(r, []string{"token", "routeName"})
+    var request dto.VerifyRequest
+    switch r.Method {
+    case http.MethodPost:
+        if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+            logger.Warn("Invalid verify request payload", logger.Any("error", err))
+            utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+            return
+        }
+        if headerToken := utils.GetTokenFromHeader(r.Header.Get("Authorization")); headerToken != "" {
+            request.Token = headerToken
+        }
+    case http.MethodGet:
+        request.Token = h.deprecatedQueryToken(w, r)
+        request.RouteName = r.URL.Query().Get("routeName")
+        request.Vars = utils.ExtractQueryParams(r, []string{"token", "routeName"})
+    default:
+        logger.Warn("Invalid method for verify", logger.String("method", r.Method))
+        utils.WriteResponse(w, http.StatusMethodNotAllowed, map[string]string{"error": "Method not allowed"})
+        return
+    }
+
+    if request.Token == "" {
+        logger.Warn("Missing token in verify request")
+        utils.WriteResponse(w, http.StatusUnauthorized, map[string]interface{}{"isAuthorized": false, "error": "Missing token"})
+        return
+    }
 
-    isAuthorized, appError := h.service.RemoteIsAuthorized(token, routeName, vars)
+    verified, appError := h.service.Verify(r.Context(), request.Token, request.RouteName, request.Vars, request.Resource)
     if appError != nil {
         logger.Warn("Authorization failed",
-            logger.String("token", token),
-            logger.String("routeName", routeName),
+            logger.String("routeName", request.RouteName),
             logger.Any("error", appError))
+        utils.WriteResponse(w, appError.Code, map[string]interface{}{"isAuthorized": false, "error": appError.Message})
+        return
+    }
+
+    response := map[string]interface{}{
+        "isAuthorized": true,
+        "username":     verified.Username,
+        "role":         verified.Role,
+        "customer_id":  verified.CustomerID,
+    }
+    if verified.BreakGlassGrantID != "" {
+        response["break_glass_grant_id"] = verified.BreakGlassGrantID
+    }
+    if verified.Actor != "" {
+        response["actor"] = verified.Actor
+    }
+    utils.WriteResponse(w, http.StatusOK, response)
+}
+
+// deprecatedQueryToken reads the legacy ?token= parameter. The GET routes that
+// call it are only registered when AUTH_ALLOW_QUERY_TOKENS is enabled.
+func (h *AuthHandler) deprecatedQueryToken(w http.ResponseWriter, r *http.Request) string {
+    logger.Warn("Token passed in query string, this form is deprecated",
+        logger.String("path", r.URL.Path))
+    w.Header().Set("Deprecation", "true")
+    return r.URL.Query().Get("token")
+}
+
+func (h *AuthHandler) BreakGlass(w http.ResponseWriter, r *http.Request) {
+    var request dto.BreakGlassRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        logger.Warn("Invalid break-glass request payload", logger.Any("error", err))
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+    request.SourceIP = utils.ClientIP(r)
+
+    response, appError := h.service.BreakGlass(r.Context(), request)
+    if appError != nil {
         utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
         return
     }
+    utils.WriteResponse(w, http.StatusCreated, response)
+}
 
-    claims, parseErr := utils.ExtractClaimsFromToken(token, h.service.GetSecretKey)
-    if parseErr != nil {
-        logger.Error("Failed to extract claims from token",
-            logger.String("token", token),
-            logger.Any("error", parseErr))
-        utils.WriteResponse(w, http.StatusInternalServerError, map[string]string{"error": "Error processing token"})
+func (h *AuthHandler) TestPolicy(w http.ResponseWriter, r *http.Request) {
+    callerToken := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+    if callerToken == "" {
+        utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
         return
     }
 
-    response := map[string]interface{}{
-        "isAuthorized": isAuthorized,
-        "role":         claims["role"],
+    var request dto.PolicyTestRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        logger.Warn("Invalid policy test payload", logger.Any("error", err))
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+
+    response, appError := h.service.TestPolicy(r.Context(), callerToken, request)
+    if appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    utils.WriteResponse(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) EnrollMFA(w http.ResponseWriter, r *http.Request) {
+    token := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+    if token == "" {
+        utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
+        return
+    }
+
+    response, appError := h.service.EnrollMFA(r.Context(), token)
+    if appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    w.Header().Set("Cache-Control", "no-store")
+    utils.WriteResponse(w, http.StatusCreated, response)
+}
+
+func (h *AuthHandler) ConfirmMFA(w http.ResponseWriter, r *http.Request) {
+    token := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+    if token == "" {
+        utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
+        return
+    }
+
+    var request dto.MFACodeRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+
+    if appError := h.service.ConfirmMFA(r.Context(), token, request); appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+    var request dto.MFAVerifyRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+
+    request.SourceIP = utils.ClientIP(r)
+    request.RequestID = RequestIDFrom(r.Context())
+
+    response, appError := h.service.VerifyMFA(r.Context(), request)
+    if appError != nil {
+        logger.Warn("MFA verification failed", logger.String("error", appError.Message))
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    utils.WriteResponse(w, http.StatusOK, response)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+    token := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+    if token == "" {
+        utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
+        return
+    }
+
+    var request dto.PasswordChangeRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+    request.SourceIP = utils.ClientIP(r)
+
+    if appError := h.service.ChangePassword(r.Context(), token, request); appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+    var request dto.PasswordForgotRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
     }
-    statusCode := http.StatusOK
-    if !isAuthorized {
-        statusCode = http.StatusForbidden
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
     }
-    utils.WriteResponse(w, statusCode, response)
+
+    request.SourceIP = utils.ClientIP(r)
+
+    if appError := h.service.ForgotPassword(r.Context(), request); appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    utils.WriteResponse(w, http.StatusAccepted, map[string]string{"message": "If the user exists, a reset token has been sent"})
+}
+
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+    var request dto.PasswordResetRequest
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+        return
+    }
+    if err := request.Validate(); err != nil {
+        utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+        return
+    }
+
+    if appError := h.service.ResetPassword(r.Context(), request); appError != nil {
+        utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Cache-Control", "public, max-age=300")
+    utils.WriteResponse(w, http.StatusOK, h.service.GetJWKS())
 }
\ No newline at end of file
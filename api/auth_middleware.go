@@ -1,39 +1,64 @@
 package api
 
 import (
-	"encoding/json"
-	"io"
 	"net/http"
-	"time"
+	"strings"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gorilla/mux"
 	"github.com/titi0001/Microservices-API-in-Go/domain"
 	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
 	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
 )
 
-const (
-	TokenVerificationTimeout = 3 * time.Second
-)
+const APIKeyHeader = "X-API-Key"
+
+// moneyMovingRoutes are refused to impersonation tokens unless listed in
+// IMPERSONATION_ALLOWED_ROUTES.
+var moneyMovingRoutes = []string{"NewAccount", "NewTransaction"}
 
 type AuthMiddleware struct {
-	repo            ports.AuthRepository
-	rolePermissions domain.RolePermissions
+	repo                 ports.AuthRepository
+	verifier             ports.TokenVerifier
+	apiKeys              ports.APIKeyAuthenticator
+	authenticator        ports.CallerAuthenticator
+	resources            *ResourceResolver
+	impersonationBlocked map[string]bool
+	audit                ports.AuditLogger
 }
 
-func NewAuthMiddleware(repo ports.AuthRepository) *AuthMiddleware {
+func NewAuthMiddleware(repo ports.AuthRepository, verifier ports.TokenVerifier, apiKeys ports.APIKeyAuthenticator, authenticator ports.CallerAuthenticator, resources *ResourceResolver, audit ports.AuditLogger) *AuthMiddleware {
 	return &AuthMiddleware{
-		repo:            repo,
-		rolePermissions: *domain.GetRolePermissions(),
+		repo:                 repo,
+		verifier:             verifier,
+		apiKeys:              apiKeys,
+		authenticator:        authenticator,
+		resources:            resources,
+		impersonationBlocked: impersonationBlockedRoutes(),
+		audit:                audit,
+	}
+}
+
+func impersonationBlockedRoutes() map[string]bool {
+	allowed := make(map[string]bool)
+	for _, route := range strings.Split(config.String("IMPERSONATION_ALLOWED_ROUTES", ""), ",") {
+		allowed[strings.TrimSpace(route)] = true
+	}
+	blocked := make(map[string]bool)
+	for _, route := range moneyMovingRoutes {
+		if !allowed[route] {
+			blocked[route] = true
+		}
 	}
+	return blocked
 }
 
 func (a *AuthMiddleware) AuthorizationHandler() func(http.Handler) http.Handler {
 	const (
-		StatusUnauthorized        = http.StatusUnauthorized
-		StatusForbidden           = http.StatusForbidden
-		StatusInternalServerError = http.StatusInternalServerError
+		StatusUnauthorized = http.StatusUnauthorized
 	)
 
 	return func(next http.Handler) http.Handler {
@@ -52,72 +77,127 @@ func (a *AuthMiddleware) AuthorizationHandler() func(http.Handler) http.Handler
 				return
 			}
 
-			authHeader := r.Header.Get("Authorization")
-			logger.Debug("Authorization header", logger.String("header", authHeader))
-			token := utils.GetTokenFromHeader(authHeader)
-			if token == "" {
-				logger.Warn("Missing or invalid auth token", logger.String("header", authHeader))
+			token := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+			apiKey := r.Header.Get(APIKeyHeader)
+			if token == "" && apiKey == "" {
+				logger.Warn("Missing or invalid auth token", logger.String("routeName", currentRouteName))
 				utils.WriteResponse(w, StatusUnauthorized, map[string]string{"error": "Missing token"})
 				return
 			}
 
-			currentRouteVars := mux.Vars(r)
-			verifyURL := utils.BuildVerifyURL(token, currentRouteName, currentRouteVars)
-			logger.Debug("Verification URL", logger.String("url", verifyURL))
-			client := &http.Client{Timeout: TokenVerificationTimeout}
-
-			resp, err := client.Get(verifyURL)
-			if err != nil {
-				logger.Error("Error verifying token", logger.Any("error", err))
-				utils.WriteResponse(w, StatusInternalServerError, map[string]string{"error": "Error verifying token"})
-				return
+			// The caller is authenticated before the resource is resolved, so an
+			// unknown caller cannot make the service read accounts or bodies.
+			var appErr *errs.AppError
+			if token != "" {
+				appErr = a.authenticator.AuthenticateToken(r.Context(), token)
+			} else {
+				appErr = a.authenticator.AuthenticateAPIKey(r.Context(), apiKey)
 			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != http.StatusOK {
-				logger.Warn("Token verification failed", logger.Int("status", resp.StatusCode))
-				utils.WriteResponse(w, StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			if appErr != nil {
+				logger.Warn("Authentication failed",
+					logger.String("routeName", currentRouteName),
+					logger.Int("status", appErr.Code))
+				utils.WriteResponse(w, appErr.Code, map[string]string{"error": appErr.Message})
 				return
 			}
 
-			responseBody, err := io.ReadAll(resp.Body)
-			if err != nil {
-				logger.Error("Error reading response body", logger.Any("error", err))
-				utils.WriteResponse(w, StatusInternalServerError, map[string]string{"error": "Error verifying token"})
+			vars := mux.Vars(r)
+			resource, appErr := a.resources.Resolve(r, vars)
+			if appErr != nil {
+				utils.WriteResponse(w, appErr.Code, map[string]string{"error": appErr.Message})
 				return
 			}
-
-			var verifyResponse map[string]interface{}
-			if err := json.Unmarshal(responseBody, &verifyResponse); err != nil {
-				logger.Error("Error parsing response", logger.Any("error", err))
-				utils.WriteResponse(w, StatusInternalServerError, map[string]string{"error": "Error verifying token"})
-				return
+			var verified *domain.VerifiedToken
+			if token != "" {
+				verified, appErr = a.verifier.Verify(r.Context(), token, currentRouteName, vars, resource)
+			} else {
+				verified, appErr = a.apiKeys.VerifyAPIKey(r.Context(), apiKey, currentRouteName, vars, resource)
 			}
-
-			isAuthorized, ok := verifyResponse["isAuthorized"].(bool)
-			if !ok || !isAuthorized {
-				logger.Warn("Token not authorized", logger.Any("response", verifyResponse))
-				utils.WriteResponse(w, StatusForbidden, map[string]string{"error": "Unauthorized"})
+			if appErr != nil {
+				logger.Warn("Token verification failed",
+					logger.String("routeName", currentRouteName),
+					logger.Int("status", appErr.Code))
+				if appErr.Code == http.StatusForbidden {
+					a.recordDenied(r, deniedActor(token, apiKey), currentRouteName, appErr.Message)
+				}
+				utils.WriteResponse(w, appErr.Code, map[string]string{"error": appErr.Message})
 				return
 			}
 
-			userRole, ok := verifyResponse["role"].(string)
-			if !ok {
-				logger.Error("Role not found in response", logger.Any("response", verifyResponse))
-				utils.WriteResponse(w, StatusInternalServerError, map[string]string{"error": "Invalid token claims"})
-				return
+			if verified.Actor != "" {
+				if a.impersonationBlocked[currentRouteName] {
+					logger.Warn("Route blocked under impersonation",
+						logger.Bool("audit", true),
+						logger.String("actor", verified.Actor),
+						logger.String("username", verified.Username),
+						logger.String("route", currentRouteName))
+					a.recordDenied(r, verified.Actor, currentRouteName, "Not allowed while impersonating "+verified.Username)
+					utils.WriteResponse(w, http.StatusForbidden, map[string]string{"error": "Not allowed while impersonating"})
+					return
+				}
+				logger.Info("Impersonated request",
+					logger.Bool("audit", true),
+					logger.String("actor", verified.Actor),
+					logger.String("username", verified.Username),
+					logger.String("route", currentRouteName),
+					logger.String("method", r.Method),
+					logger.String("path", r.URL.Path))
 			}
 
-			if !a.rolePermissions.IsAuthorizedFor(userRole, currentRouteName) {
-				logger.Warn("Insufficient permissions",
-					logger.String("role", userRole),
-					logger.String("routeName", currentRouteName))
-				utils.WriteResponse(w, StatusForbidden, map[string]string{"error": "Insufficient permissions"})
-				return
+			userRole := verified.Role
+			if verified.BreakGlassGrantID != "" {
+				logger.Warn("Authorized through break-glass grant",
+					logger.Bool("audit", true),
+					logger.String("username", verified.Username),
+					logger.String("grant_id", verified.BreakGlassGrantID),
+					logger.String("route", currentRouteName))
 			}
 
-			logger.Info("Authorization successful", logger.String("role", userRole), logger.String("route", currentRouteName))
-			next.ServeHTTP(w, r)
+			logger.Info("Authorization successful",
+				logger.String("role", userRole),
+				logger.String("route", currentRouteName),
+				logger.String("api_key_id", verified.APIKeyID))
+			next.ServeHTTP(w, withVerifiedToken(r, verified))
 		})
 	}
-}
\ No newline at end of file
+}
+
+// recordDenied writes a refused request to the audit log.
+func (a *AuthMiddleware) recordDenied(r *http.Request, actor, route, reason string) {
+	if a.audit == nil {
+		return
+	}
+	a.audit.Record(r.Context(), domain.AuditEvent{
+		Actor:     actor,
+		Action:    "route." + route,
+		Resource:  r.Method + " " + r.URL.Path,
+		Outcome:   domain.AuditOutcomeDenied,
+		SourceIP:  utils.ClientIP(r),
+		RequestID: RequestIDFrom(r.Context()),
+		Details:   reason,
+	})
+}
+
+// deniedActor names the caller of a request refused with 403. The verifier
+// only answers 403 once the token signature is valid, so its claims can be
+// read without checking it again.
+func deniedActor(token, apiKey string) string {
+	if token == "" {
+		// API keys are bk_<id>_<secret> and act as the user bk_<id>.
+		if parts := strings.SplitN(apiKey, "_", 3); len(parts) == 3 && parts[0]+"_" == domain.APIKeyPrefix {
+			return domain.APIKeyPrefix + parts[1]
+		}
+		return ""
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	if actor, _ := claims["act"].(map[string]interface{}); actor != nil {
+		if sub, _ := actor["sub"].(string); sub != "" {
+			return sub
+		}
+	}
+	username, _ := claims["username"].(string)
+	return username
+}
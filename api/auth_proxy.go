@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// NewAuthServerProxy forwards requests to the auth server. Only the auth
+// server holds the signing keys, so the routes of the main server that issue
+// tokens hand their requests over to it instead of signing themselves.
+func NewAuthServerProxy(authServerURL string) http.Handler {
+	target, err := url.Parse(authServerURL)
+	if err != nil {
+		logger.Fatal("Invalid auth server URL", logger.String("url", authServerURL), logger.Any("error", err))
+	}
+
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		logger.Error("Error forwarding request to the auth server",
+			logger.String("path", r.URL.Path),
+			logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadGateway, map[string]string{"error": "Auth server unavailable"})
+	}
+	return proxy
+}
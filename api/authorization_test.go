@@ -0,0 +1,794 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/policy"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/repository"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+)
+
+// seedAssignments mirrors the role_permissions rows seeded by the migrations.
+var seedAssignments = []domain.RolePermission{
+	{RoleName: "admin", PermissionName: "GetAllCustomers", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetCustomer", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "NewAccount", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "NewTransaction", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetRolePermissions", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListRoles", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CreateRole", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "DeleteRole", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "SetRoleMFA", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListRolePermissions", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "AssignPermission", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "RevokePermission", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListPermissions", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CreatePermission", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "DeletePermission", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListLockouts", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ClearLockout", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListAPIKeys", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CreateAPIKey", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "RevokeAPIKey", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "TestPolicy", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListUsers", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetUser", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "SetUserRole", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "LinkUserCustomer", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "UnlinkUserCustomer", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "SetUserStatus", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ForceLogout", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "Impersonate", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListAuditEvents", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "VerifyAuditLog", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "FreezeAccount", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CloseAccount", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListAccountEvents", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListWebhooks", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CreateWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "DeleteWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListWebhookDeliveries", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "RedeliverWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListCustomerWebhooks", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "CreateCustomerWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "DeleteCustomerWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "ListCustomerWebhookDeliveries", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "RedeliverCustomerWebhook", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetCityReport", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetAccountTypeReport", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetCustomerReport", Scope: domain.ScopeAll},
+	{RoleName: "admin", PermissionName: "GetAccountDailyReport", Scope: domain.ScopeAll},
+	{RoleName: "user", PermissionName: "GetCustomer", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "NewTransaction", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "ListCustomerWebhooks", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "CreateCustomerWebhook", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "DeleteCustomerWebhook", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "ListCustomerWebhookDeliveries", Scope: domain.ScopeOwn},
+	{RoleName: "user", PermissionName: "RedeliverCustomerWebhook", Scope: domain.ScopeOwn},
+}
+
+// publicRoutes are registered without the authorization middleware.
+var publicRoutes = map[string]bool{
+	"AuthLogin":      true,
+	"AuthRegister":   true,
+	"AuthRefresh":    true,
+	"MFAEnroll":      true,
+	"MFAConfirm":     true,
+	"MFAVerify":      true,
+	"PasswordChange": true,
+	"PasswordForgot": true,
+	"PasswordReset":  true,
+}
+
+// expectedAccess lists, for every protected route in setupRoutes, the roles
+// allowed to call it. A route missing from this table fails the test.
+var expectedAccess = map[string]map[string]bool{
+	"AuthVerify":                    {},
+	"GetAllCustomers":               {"admin": true},
+	"GetCustomer":                   {"admin": true, "user": true},
+	"NewAccount":                    {"admin": true},
+	"NewTransaction":                {"admin": true, "user": true},
+	"GetRolePermissions":            {"admin": true},
+	"ListRoles":                     {"admin": true},
+	"CreateRole":                    {"admin": true},
+	"DeleteRole":                    {"admin": true},
+	"SetRoleMFA":                    {"admin": true},
+	"ListRolePermissions":           {"admin": true},
+	"AssignPermission":              {"admin": true},
+	"RevokePermission":              {"admin": true},
+	"ListPermissions":               {"admin": true},
+	"CreatePermission":              {"admin": true},
+	"DeletePermission":              {"admin": true},
+	"ListLockouts":                  {"admin": true},
+	"ClearLockout":                  {"admin": true},
+	"ListAPIKeys":                   {"admin": true},
+	"CreateAPIKey":                  {"admin": true},
+	"RevokeAPIKey":                  {"admin": true},
+	"ListUsers":                     {"admin": true},
+	"GetUser":                       {"admin": true},
+	"SetUserRole":                   {"admin": true},
+	"LinkUserCustomer":              {"admin": true},
+	"UnlinkUserCustomer":            {"admin": true},
+	"SetUserStatus":                 {"admin": true},
+	"ForceLogout":                   {"admin": true},
+	"Impersonate":                   {"admin": true},
+	"ListAuditEvents":               {"admin": true},
+	"VerifyAuditLog":                {"admin": true},
+	"FreezeAccount":                 {"admin": true},
+	"CloseAccount":                  {"admin": true},
+	"ListAccountEvents":             {"admin": true},
+	"ListWebhooks":                  {"admin": true},
+	"CreateWebhook":                 {"admin": true},
+	"DeleteWebhook":                 {"admin": true},
+	"ListWebhookDeliveries":         {"admin": true},
+	"RedeliverWebhook":              {"admin": true},
+	"ListCustomerWebhooks":          {"admin": true, "user": true},
+	"CreateCustomerWebhook":         {"admin": true, "user": true},
+	"DeleteCustomerWebhook":         {"admin": true, "user": true},
+	"ListCustomerWebhookDeliveries": {"admin": true, "user": true},
+	"RedeliverCustomerWebhook":      {"admin": true, "user": true},
+	"GetCityReport":                 {"admin": true},
+	"GetAccountTypeReport":          {"admin": true},
+	"GetCustomerReport":             {"admin": true},
+	"GetAccountDailyReport":         {"admin": true},
+}
+
+var roles = []string{"admin", "user", "auditor"}
+
+var routeVars = map[string]string{
+	"customer_id": "2000",
+	"account_id":  "3000",
+	"role":        "user",
+	"permission":  "GetCustomer",
+	"scope":       "username",
+	"subject":     "2000",
+	"key_id":      "1",
+	"username":    "2000",
+	"webhook_id":  "1",
+	"delivery_id": "1",
+}
+
+type staticPermissions struct {
+	permissions *domain.RolePermissions
+}
+
+func (p staticPermissions) Current() *domain.RolePermissions      { return p.permissions }
+func (p staticPermissions) Reload(context.Context) *errs.AppError { return nil }
+
+type stubBreakGlassRepository struct {
+	grants map[string]domain.BreakGlassGrant
+}
+
+func (r stubBreakGlassRepository) SaveGrant(_ context.Context, g domain.BreakGlassGrant) (*domain.BreakGlassGrant, *errs.AppError) {
+	return &g, nil
+}
+
+func (r stubBreakGlassRepository) FindGrant(_ context.Context, grantID string) (*domain.BreakGlassGrant, *errs.AppError) {
+	grant, ok := r.grants[grantID]
+	if !ok {
+		return nil, errs.NewNotFoundError("Break-glass grant not found")
+	}
+	return &grant, nil
+}
+
+func (r stubBreakGlassRepository) SaveEvent(context.Context, domain.BreakGlassEvent) *errs.AppError {
+	return nil
+}
+
+type stubCustomerService struct{}
+
+func (stubCustomerService) GetCustomer(_ context.Context, id string) (*dto.CustomerResponse, *errs.AppError) {
+	return &dto.CustomerResponse{ID: id}, nil
+}
+
+func (stubCustomerService) GetAllCustomer(context.Context, string) ([]dto.CustomerResponse, *errs.AppError) {
+	return []dto.CustomerResponse{}, nil
+}
+
+type stubAccountService struct{}
+
+func (stubAccountService) NewAccount(context.Context, dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
+	return &dto.NewAccountResponse{}, nil
+}
+
+func (stubAccountService) MakeTransaction(context.Context, dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError) {
+	return &dto.TransactionResponse{}, nil
+}
+
+func (stubAccountService) FreezeAccount(context.Context, dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError) {
+	return &dto.AccountResponse{}, nil
+}
+
+func (stubAccountService) CloseAccount(context.Context, dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError) {
+	return &dto.AccountResponse{}, nil
+}
+
+func (stubAccountService) ListAccountEvents(context.Context, string) ([]dto.AccountEventResponse, *errs.AppError) {
+	return []dto.AccountEventResponse{}, nil
+}
+
+type stubRoleService struct{}
+
+func (stubRoleService) ListRoles(context.Context) ([]dto.RoleResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubRoleService) CreateRole(context.Context, dto.RoleRequest) (*dto.RoleResponse, *errs.AppError) {
+	return &dto.RoleResponse{}, nil
+}
+func (stubRoleService) DeleteRole(context.Context, string) *errs.AppError             { return nil }
+func (stubRoleService) SetRoleMFA(context.Context, dto.RoleMFARequest) *errs.AppError { return nil }
+func (stubRoleService) ListPermissions(context.Context) ([]dto.PermissionResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubRoleService) CreatePermission(context.Context, dto.PermissionRequest) (*dto.PermissionResponse, *errs.AppError) {
+	return &dto.PermissionResponse{}, nil
+}
+func (stubRoleService) DeletePermission(context.Context, string) *errs.AppError { return nil }
+func (stubRoleService) ListRolePermissions(context.Context, string) ([]dto.RolePermissionResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubRoleService) AssignPermission(context.Context, dto.AssignPermissionRequest) (*dto.RolePermissionResponse, *errs.AppError) {
+	return &dto.RolePermissionResponse{}, nil
+}
+func (stubRoleService) RevokePermission(context.Context, string, string) *errs.AppError { return nil }
+
+type stubLockoutService struct{}
+
+func (stubLockoutService) ListLockouts(context.Context) ([]dto.LockoutResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubLockoutService) ClearLockout(context.Context, string, string) *errs.AppError { return nil }
+
+type stubUserService struct{}
+
+func (stubUserService) ListUsers(context.Context, dto.UserSearchRequest) ([]dto.User, *errs.AppError) {
+	return nil, nil
+}
+func (stubUserService) GetUser(_ context.Context, username string) (*dto.User, *errs.AppError) {
+	return &dto.User{Username: username}, nil
+}
+func (stubUserService) SetUserRole(context.Context, dto.UserRoleRequest) *errs.AppError { return nil }
+func (stubUserService) LinkCustomer(context.Context, dto.UserCustomerRequest) *errs.AppError {
+	return nil
+}
+func (stubUserService) UnlinkCustomer(context.Context, string) *errs.AppError { return nil }
+func (stubUserService) SetUserStatus(context.Context, dto.UserStatusRequest) *errs.AppError {
+	return nil
+}
+func (stubUserService) ForceLogout(context.Context, string) (*dto.ForceLogoutResponse, *errs.AppError) {
+	return &dto.ForceLogoutResponse{}, nil
+}
+
+// stubAuthServer stands in for the auth server the token routes are
+// forwarded to.
+var stubAuthServer = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+type stubWebhookService struct{}
+
+func (stubWebhookService) CreateWebhook(context.Context, dto.WebhookRequest) (*dto.WebhookCreatedResponse, *errs.AppError) {
+	return &dto.WebhookCreatedResponse{}, nil
+}
+func (stubWebhookService) ListWebhooks(context.Context, string) ([]dto.WebhookResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubWebhookService) DeleteWebhook(context.Context, string, string) *errs.AppError { return nil }
+func (stubWebhookService) ListDeliveries(context.Context, dto.WebhookDeliveryQueryRequest) ([]dto.WebhookDeliveryResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubWebhookService) Redeliver(context.Context, string, string, string) (*dto.WebhookDeliveryResponse, *errs.AppError) {
+	return &dto.WebhookDeliveryResponse{}, nil
+}
+
+type stubReportService struct{}
+
+func (stubReportService) CityReport(context.Context) ([]dto.CityReportResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubReportService) AccountTypeReport(context.Context, dto.ReportRangeRequest) ([]dto.AccountTypeReportResponse, *errs.AppError) {
+	return nil, nil
+}
+func (stubReportService) CustomerReport(context.Context, string) (*dto.CustomerReportResponse, *errs.AppError) {
+	return &dto.CustomerReportResponse{}, nil
+}
+func (stubReportService) AccountDailyReport(context.Context, dto.ReportRangeRequest) ([]dto.DailyBalanceResponse, *errs.AppError) {
+	return nil, nil
+}
+
+type stubAuditService struct{}
+
+func (stubAuditService) Record(context.Context, domain.AuditEvent) {}
+
+func (stubAuditService) ListAuditEvents(context.Context, dto.AuditQueryRequest) ([]dto.AuditEventResponse, *errs.AppError) {
+	return []dto.AuditEventResponse{}, nil
+}
+
+func (stubAuditService) VerifyAuditLog(context.Context) (*dto.AuditVerifyResponse, *errs.AppError) {
+	return &dto.AuditVerifyResponse{Valid: true}, nil
+}
+
+var (
+	_ ports.RolePermissionsProvider = staticPermissions{}
+	_ ports.BreakGlassRepository    = stubBreakGlassRepository{}
+	_ ports.CustomerService         = stubCustomerService{}
+	_ ports.AccountService          = stubAccountService{}
+	_ ports.RoleService             = stubRoleService{}
+	_ ports.LockoutService          = stubLockoutService{}
+	_ ports.UserService             = stubUserService{}
+	_ ports.AuditLogger             = stubAuditService{}
+	_ ports.AuditService            = stubAuditService{}
+	_ ports.WebhookService          = stubWebhookService{}
+	_ ports.ReportService           = stubReportService{}
+	_ ports.AccountRepository       = stubAccountRepository{}
+)
+
+// stubAccountRepository knows account 3000 of customer 2000 and account 3001
+// of customer 2001.
+type stubAccountRepository struct{}
+
+func (stubAccountRepository) Save(context.Context, domain.Account) (*domain.Account, *errs.AppError) {
+	return nil, nil
+}
+func (stubAccountRepository) SaveTransaction(context.Context, domain.Transaction) (*domain.Transaction, *errs.AppError) {
+	return nil, nil
+}
+func (stubAccountRepository) FindBy(_ context.Context, accountID string) (*domain.Account, *errs.AppError) {
+	owners := map[string]string{"3000": "2000", "3001": "2001"}
+	owner, ok := owners[accountID]
+	if !ok {
+		return nil, errs.NewNotFoundError("Account not found")
+	}
+	return &domain.Account{AccountID: accountID, CustomerID: owner, AccountType: "checking", Amount: 100000}, nil
+}
+func (stubAccountRepository) UpdateStatus(context.Context, string, string) *errs.AppError { return nil }
+
+// memoryAPIKeyRepository keeps API keys in memory for the tests.
+type memoryAPIKeyRepository struct {
+	keys map[string]*domain.APIKey
+}
+
+func (m *memoryAPIKeyRepository) Save(_ context.Context, k domain.APIKey) (*domain.APIKey, *errs.AppError) {
+	k.ID = strconv.Itoa(len(m.keys) + 1)
+	m.keys[k.Prefix] = &k
+	return &k, nil
+}
+func (m *memoryAPIKeyRepository) FindByPrefix(_ context.Context, prefix string) (*domain.APIKey, *errs.AppError) {
+	if k, ok := m.keys[prefix]; ok {
+		return k, nil
+	}
+	return nil, errs.NewNotFoundError("API key not found")
+}
+func (m *memoryAPIKeyRepository) FindAll(context.Context) ([]domain.APIKey, *errs.AppError) {
+	return nil, nil
+}
+func (m *memoryAPIKeyRepository) Revoke(_ context.Context, keyID string, now time.Time) *errs.AppError {
+	for _, k := range m.keys {
+		if k.ID == keyID {
+			k.RevokedOn = &now
+			return nil
+		}
+	}
+	return errs.NewNotFoundError("Active API key not found")
+}
+func (m *memoryAPIKeyRepository) TouchLastUsed(_ context.Context, keyID string, now time.Time) *errs.AppError {
+	for _, k := range m.keys {
+		if k.ID == keyID {
+			k.LastUsedOn = &now
+		}
+	}
+	return nil
+}
+
+type testServer struct {
+	router  *mux.Router
+	keys    *utils.SigningKeySet
+	apiKeys ports.APIKeyService
+}
+
+func newTestServer(t *testing.T, assignments []domain.RolePermission, grants map[string]domain.BreakGlassGrant) testServer {
+	t.Helper()
+
+	keys, err := utils.NewEphemeralSigningKeySet()
+	if err != nil {
+		t.Fatalf("generating signing key: %v", err)
+	}
+
+	permissions := staticPermissions{permissions: domain.NewRolePermissions(assignments)}
+	defaultPolicy, err := policy.Default()
+	if err != nil {
+		t.Fatalf("loading default policy: %v", err)
+	}
+
+	authRepo := repository.NewAuthRepositoryDb(nil)
+	apiKeyRepo := &memoryAPIKeyRepository{keys: make(map[string]*domain.APIKey)}
+	authService := service.NewAuthService(service.AuthServiceDeps{
+		Repo:        authRepo,
+		Permissions: permissions,
+		Policy:      defaultPolicy,
+		BreakGlass:  stubBreakGlassRepository{grants: grants},
+		APIKeys:     apiKeyRepo,
+		Signer:      keys,
+		Keys:        keys,
+	})
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo)
+
+	router := mux.NewRouter()
+	setupRoutes(router, stubCustomerService{}, stubAccountService{}, authService, stubRoleService{}, stubLockoutService{},
+		apiKeyService, stubUserService{}, stubAuthServer, stubAuditService{}, stubWebhookService{},
+		stubReportService{}, NewAuthMiddleware(authRepo, authService, authService, authService, NewResourceResolver(stubAccountRepository{}), stubAuditService{}))
+	return testServer{router: router, keys: keys, apiKeys: apiKeyService}
+}
+
+func (s testServer) token(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token, err := s.keys.Sign(claims)
+	if err != nil {
+		t.Fatalf("signing token: %v", err)
+	}
+	return token
+}
+
+func (s testServer) call(t *testing.T, route *mux.Route, token string, vars map[string]string) int {
+	t.Helper()
+	return s.callWithBody(t, route, token, vars, "{}")
+}
+
+func (s testServer) callWithBody(t *testing.T, route *mux.Route, token string, vars map[string]string, body string) int {
+	t.Helper()
+	return s.callWithHeader(t, route, http.Header{"Authorization": {"Bearer " + token}}, vars, body)
+}
+
+func (s testServer) callWithHeader(t *testing.T, route *mux.Route, header http.Header, vars map[string]string, body string) int {
+	t.Helper()
+
+	pairs := make([]string, 0)
+	for _, name := range mustVarNames(t, route) {
+		pairs = append(pairs, name, vars[name])
+	}
+	u, err := route.URLPath(pairs...)
+	if err != nil {
+		t.Fatalf("building URL for %s: %v", route.GetName(), err)
+	}
+	methods, err := route.GetMethods()
+	if err != nil || len(methods) == 0 {
+		t.Fatalf("route %s has no methods", route.GetName())
+	}
+
+	req := httptest.NewRequest(methods[0], u.String(), strings.NewReader(body))
+	for name, values := range header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func mustVarNames(t *testing.T, route *mux.Route) []string {
+	t.Helper()
+	names, err := route.GetVarNames()
+	if err != nil {
+		t.Fatalf("reading vars of %s: %v", route.GetName(), err)
+	}
+	return names
+}
+
+func protectedRoutes(t *testing.T, router *mux.Router) map[string]*mux.Route {
+	t.Helper()
+	found := make(map[string]*mux.Route)
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		name := route.GetName()
+		if name == "" || publicRoutes[name] {
+			return nil
+		}
+		found[name] = route
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walking routes: %v", err)
+	}
+	return found
+}
+
+func isDenied(code int) bool {
+	return code == http.StatusUnauthorized || code == http.StatusForbidden
+}
+
+func TestEveryRouteHasAnAccessExpectation(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	routes := protectedRoutes(t, server.router)
+
+	for name := range routes {
+		if _, ok := expectedAccess[name]; !ok {
+			t.Errorf("route %s is not covered by expectedAccess", name)
+		}
+	}
+	for name := range expectedAccess {
+		if _, ok := routes[name]; !ok {
+			t.Errorf("expectedAccess lists %s, which setupRoutes does not register", name)
+		}
+	}
+}
+
+func TestRouteAccessByRole(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+
+	for name, route := range protectedRoutes(t, server.router) {
+		for _, role := range roles {
+			t.Run(name+"/"+role, func(t *testing.T) {
+				token := server.token(t, jwt.MapClaims{"username": "tester", "role": role, "customer_id": "2000"})
+				code := server.call(t, route, token, routeVars)
+
+				if expectedAccess[name][role] && isDenied(code) {
+					t.Errorf("expected %s to be allowed for %s, got %d", name, role, code)
+				}
+				if !expectedAccess[name][role] && code != http.StatusForbidden {
+					t.Errorf("expected %s to be forbidden for %s, got %d", name, role, code)
+				}
+			})
+		}
+	}
+}
+
+func TestOwnScopeRejectsOtherCustomers(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	routes := protectedRoutes(t, server.router)
+	token := server.token(t, jwt.MapClaims{"username": "2001", "role": "user", "customer_id": "2001"})
+
+	for _, name := range []string{"GetCustomer", "NewTransaction", "ListCustomerWebhooks", "CreateCustomerWebhook", "RedeliverCustomerWebhook"} {
+		if code := server.call(t, routes[name], token, routeVars); code != http.StatusForbidden {
+			t.Errorf("expected %s on another customer to be forbidden, got %d", name, code)
+		}
+	}
+}
+
+func TestAdminIsDeniedWithoutPermissions(t *testing.T) {
+	server := newTestServer(t, nil, nil)
+	token := server.token(t, jwt.MapClaims{"username": "admin", "role": "admin"})
+
+	for name, route := range protectedRoutes(t, server.router) {
+		if code := server.call(t, route, token, routeVars); code != http.StatusForbidden {
+			t.Errorf("expected %s to fail closed for admin, got %d", name, code)
+		}
+	}
+}
+
+func TestBreakGlassGrant(t *testing.T) {
+	now := time.Now()
+	revokedOn := now.Add(-time.Minute)
+	grants := map[string]domain.BreakGlassGrant{
+		"1": {ID: "1", Username: "admin", ExpiresAt: now.Add(time.Hour)},
+		"2": {ID: "2", Username: "admin", ExpiresAt: now.Add(-time.Minute)},
+		"3": {ID: "3", Username: "admin", ExpiresAt: now.Add(time.Hour), RevokedOn: &revokedOn},
+	}
+	server := newTestServer(t, nil, grants)
+	route := protectedRoutes(t, server.router)["GetAllCustomers"]
+
+	tests := []struct {
+		name     string
+		grantID  string
+		username string
+		allowed  bool
+	}{
+		{name: "active grant", grantID: "1", username: "admin", allowed: true},
+		{name: "expired grant", grantID: "2", username: "admin"},
+		{name: "revoked grant", grantID: "3", username: "admin"},
+		{name: "unknown grant", grantID: "99", username: "admin"},
+		{name: "grant of another user", grantID: "1", username: "2000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := server.token(t, jwt.MapClaims{"username": tt.username, "role": "admin", "break_glass": tt.grantID})
+			code := server.call(t, route, token, routeVars)
+			if tt.allowed && isDenied(code) {
+				t.Errorf("expected access, got %d", code)
+			}
+			if !tt.allowed && !isDenied(code) {
+				t.Errorf("expected access to be denied, got %d", code)
+			}
+		})
+	}
+}
+
+func TestPolicyUsesResourceAttributes(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	route := protectedRoutes(t, server.router)["NewTransaction"]
+	customer := server.token(t, jwt.MapClaims{"username": "2000", "role": "user", "customer_id": "2000"})
+	admin := server.token(t, jwt.MapClaims{"username": "admin", "role": "admin"})
+
+	tests := []struct {
+		name      string
+		token     string
+		accountID string
+		body      string
+		allowed   bool
+	}{
+		{name: "own account", token: customer, accountID: "3000", body: `{"transaction_type":"deposit","amount":100}`, allowed: true},
+		{name: "account of another customer", token: customer, accountID: "3001", body: `{"transaction_type":"deposit","amount":100}`},
+		{name: "large withdrawal by customer", token: customer, accountID: "3000", body: `{"transaction_type":"withdrawal","amount":60000}`},
+		{name: "large deposit by customer", token: customer, accountID: "3000", body: `{"transaction_type":"deposit","amount":60000}`, allowed: true},
+		{name: "large withdrawal by admin", token: admin, accountID: "3000", body: `{"transaction_type":"withdrawal","amount":60000}`, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{"customer_id": "2000", "account_id": tt.accountID}
+			code := server.callWithBody(t, route, tt.token, vars, tt.body)
+			if tt.allowed && isDenied(code) {
+				t.Errorf("expected access, got %d", code)
+			}
+			if !tt.allowed && code != http.StatusForbidden {
+				t.Errorf("expected access to be forbidden, got %d", code)
+			}
+		})
+	}
+}
+
+func TestAPIKeyAccess(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	routes := protectedRoutes(t, server.router)
+	ctx := context.Background()
+
+	partner, err := server.apiKeys.CreateAPIKey(ctx, dto.APIKeyRequest{Name: "partner", Routes: []string{"GetAllCustomers", "GetCustomer"}})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err.Message)
+	}
+	batch, err := server.apiKeys.CreateAPIKey(ctx, dto.APIKeyRequest{Name: "batch", Routes: []string{"NewTransaction"}, CustomerID: "2000"})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err.Message)
+	}
+	revoked, err := server.apiKeys.CreateAPIKey(ctx, dto.APIKeyRequest{Name: "revoked", Routes: []string{"GetAllCustomers"}})
+	if err != nil {
+		t.Fatalf("creating API key: %v", err.Message)
+	}
+	if err := server.apiKeys.RevokeAPIKey(ctx, revoked.ID); err != nil {
+		t.Fatalf("revoking API key: %v", err.Message)
+	}
+
+	tests := []struct {
+		name       string
+		key        string
+		route      string
+		customerID string
+		accountID  string
+		want       int
+	}{
+		{name: "scoped route", key: partner.Key, route: "GetAllCustomers", want: http.StatusOK},
+		{name: "route outside scope", key: partner.Key, route: "NewAccount", customerID: "2000", want: http.StatusForbidden},
+		{name: "own customer", key: batch.Key, route: "NewTransaction", customerID: "2000", accountID: "3000"},
+		{name: "other customer", key: batch.Key, route: "NewTransaction", customerID: "2001", accountID: "3001", want: http.StatusForbidden},
+		{name: "revoked key", key: revoked.Key, route: "GetAllCustomers", want: http.StatusUnauthorized},
+		{name: "wrong secret", key: partner.Key + "x", route: "GetAllCustomers", want: http.StatusUnauthorized},
+		{name: "malformed key", key: "not-a-key", route: "GetAllCustomers", want: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{"customer_id": tt.customerID, "account_id": tt.accountID}
+			header := http.Header{APIKeyHeader: {tt.key}}
+			code := server.callWithHeader(t, routes[tt.route], header, vars, `{"transaction_type":"deposit","amount":10}`)
+			if tt.want == 0 {
+				if isDenied(code) {
+					t.Errorf("expected access, got %d", code)
+				}
+				return
+			}
+			if code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, code)
+			}
+		})
+	}
+}
+
+func TestImpersonationBlocksMoneyMovingRoutes(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	routes := protectedRoutes(t, server.router)
+	token := server.token(t, jwt.MapClaims{
+		"username":    "2000",
+		"role":        "user",
+		"customer_id": "2000",
+		"act":         map[string]interface{}{"sub": "admin", "role": "admin"},
+	})
+
+	if code := server.call(t, routes["GetCustomer"], token, routeVars); isDenied(code) {
+		t.Errorf("expected impersonated GetCustomer to be allowed, got %d", code)
+	}
+	for _, name := range []string{"NewTransaction", "NewAccount"} {
+		if code := server.call(t, routes[name], token, routeVars); code != http.StatusForbidden {
+			t.Errorf("expected impersonated %s to be forbidden, got %d", name, code)
+		}
+	}
+}
+
+func TestResourceResolverKeepsTheBody(t *testing.T) {
+	resolver := NewResourceResolver(nil)
+
+	body := `{"amount": 250, "transaction_type": "withdrawal"}`
+	r := httptest.NewRequest(http.MethodPost, "/customers/2000/account/95470", strings.NewReader(body))
+	resource, err := resolver.Resolve(r, map[string]string{"customer_id": "2000"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if resource["amount"] != float64(250) || resource["transaction_type"] != "withdrawal" {
+		t.Errorf("expected the body attributes, got %v", resource)
+	}
+	if read, _ := io.ReadAll(r.Body); string(read) != body {
+		t.Errorf("expected the handler to read the whole body, got %q", read)
+	}
+
+	padded := `{"amount": 250` + strings.Repeat(" ", maxPeekBodyBytes) + `}`
+	r = httptest.NewRequest(http.MethodPost, "/customers/2000/account/95470", strings.NewReader(padded))
+	if _, err := resolver.Resolve(r, nil); err == nil || err.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected a body over the peek limit to be refused with 413, got %v", err)
+	}
+}
+
+func TestUnauthenticatedCallersAreRefusedBeforeTheResourceIsResolved(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	route := protectedRoutes(t, server.router)["NewTransaction"]
+	vars := map[string]string{"customer_id": "2000", "account_id": "3000"}
+	oversized := `{"amount": 250` + strings.Repeat(" ", maxPeekBodyBytes) + `}`
+
+	expired := server.token(t, jwt.MapClaims{"username": "2000", "role": "user", "customer_id": "2000", "exp": time.Now().Add(-time.Minute).Unix()})
+	headers := map[string]http.Header{
+		"forged token":  {"Authorization": {"Bearer not-a-token"}},
+		"expired token": {"Authorization": {"Bearer " + expired}},
+		"unknown key":   {APIKeyHeader: {"bk_unknown_secret"}},
+	}
+	for name, header := range headers {
+		t.Run(name, func(t *testing.T) {
+			if code := server.callWithHeader(t, route, header, vars, oversized); code != http.StatusUnauthorized {
+				t.Errorf("expected 401 before the body is read, got %d", code)
+			}
+		})
+	}
+}
+
+func TestPolicyAttributesMatchWhatTheHandlerDecodes(t *testing.T) {
+	server := newTestServer(t, seedAssignments, nil)
+	route := protectedRoutes(t, server.router)["NewTransaction"]
+	customer := server.token(t, jwt.MapClaims{"username": "2000", "role": "user", "customer_id": "2000"})
+	vars := map[string]string{"customer_id": "2000", "account_id": "3000"}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "upper case amount", body: `{"transaction_type":"withdrawal","AMOUNT":60000}`, want: http.StatusForbidden},
+		{name: "folded transaction type", body: `{"tran\u017faction_type":"withdrawal","amount":60000}`, want: http.StatusForbidden},
+		{name: "case variant duplicate", body: `{"transaction_type":"withdrawal","amount":100,"Amount":60000}`, want: http.StatusBadRequest},
+		{name: "exact duplicate", body: `{"transaction_type":"withdrawal","amount":100,"amount":60000}`, want: http.StatusBadRequest},
+		{name: "trailing junk", body: `{"transaction_type":"withdrawal","amount":60000} junk`, want: http.StatusBadRequest},
+		{name: "second object", body: `{"transaction_type":"withdrawal","amount":60000}{"amount":100}`, want: http.StatusBadRequest},
+		{name: "trailing whitespace", body: "{\"transaction_type\":\"withdrawal\",\"amount\":100}\n", want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := server.callWithBody(t, route, customer, vars, tt.body)
+			if tt.want == http.StatusOK && isDenied(code) {
+				t.Errorf("expected access, got %d", code)
+			}
+			if tt.want != http.StatusOK && code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, code)
+			}
+		})
+	}
+}
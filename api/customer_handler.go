@@ -19,7 +19,7 @@ func NewCustomerHandler(service ports.CustomerService) *CustomerHandler {
 func (ch *CustomerHandler) GetAllCustomers(w http.ResponseWriter, r *http.Request) {
 	status := r.URL.Query().Get("status")
 
-	customers, err := ch.service.GetAllCustomer(status)
+	customers, err := ch.service.GetAllCustomer(r.Context(), status)
 	if err != nil {
 		utils.WriteResponse(w, err.Code, err.AsMessage())
 		return
@@ -31,7 +31,7 @@ func (ch *CustomerHandler) GetCustomer(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	id := vars["customer_id"]
 
-	customer, err := ch.service.GetCustomer(id)
+	customer, err := ch.service.GetCustomer(r.Context(), id)
 	if err != nil {
 		utils.WriteResponse(w, err.Code, err.AsMessage())
 		return
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+const maxAccountStatusReasonLength = 255
+
+// AccountStatusRequest freezes or closes an account.
+type AccountStatusRequest struct {
+	AccountID string `json:"-"`
+	Reason    string `json:"reason,omitempty"`
+	Actor     string `json:"-"`
+	SourceIP  string `json:"-"`
+	RequestID string `json:"-"`
+}
+
+func (r AccountStatusRequest) Validate() *errs.AppError {
+	if len(r.Reason) > maxAccountStatusReasonLength {
+		return errs.NewValidationError("Reason must have at most 255 characters")
+	}
+	return nil
+}
+
+// AccountResponse is the state of an account rebuilt from its events;
+// Version is the number of events it has.
+type AccountResponse struct {
+	AccountID   string  `json:"account_id"`
+	CustomerID  string  `json:"customer_id"`
+	AccountType string  `json:"account_type"`
+	OpeningDate string  `json:"opening_date"`
+	Balance     float64 `json:"balance"`
+	Status      string  `json:"status"`
+	Version     int64   `json:"version"`
+}
+
+type AccountEventResponse struct {
+	Version    int64           `json:"version"`
+	Type       string          `json:"type"`
+	Data       json.RawMessage `json:"data"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type APIKeyRequest struct {
+	Name          string   `json:"name"`
+	Routes        []string `json:"routes"`
+	CustomerID    string   `json:"customer_id,omitempty"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+	CreatedBy     string   `json:"-"`
+}
+
+func (r APIKeyRequest) Validate() *errs.AppError {
+	if strings.TrimSpace(r.Name) == "" {
+		return errs.NewValidationError("Name is required")
+	}
+	if len(r.Routes) == 0 {
+		return errs.NewValidationError("At least one route is required")
+	}
+	for _, route := range r.Routes {
+		if !identifierPattern.MatchString(strings.TrimSpace(route)) {
+			return errs.NewValidationError("Invalid route name: " + route)
+		}
+	}
+	if r.ExpiresInDays < 0 {
+		return errs.NewValidationError("Expiry cannot be negative")
+	}
+	return nil
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"key_id"`
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	Routes     []string   `json:"routes"`
+	CustomerID string     `json:"customer_id,omitempty"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedOn  time.Time  `json:"created_on"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedOn *time.Time `json:"last_used_on,omitempty"`
+	RevokedOn  *time.Time `json:"revoked_on,omitempty"`
+}
+
+// APIKeyCreatedResponse is the only response that contains the key itself.
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	Key string `json:"api_key"`
+}
@@ -0,0 +1,51 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// AuditQueryRequest is read from the query string of GET /admin/audit.
+type AuditQueryRequest struct {
+	Actor    string
+	Action   string
+	Resource string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
+
+func (r AuditQueryRequest) Validate() *errs.AppError {
+	switch r.Outcome {
+	case "", "success", "failure", "denied":
+	default:
+		return errs.NewValidationError("Outcome must be success, failure or denied")
+	}
+	if r.From != nil && r.To != nil && r.To.Before(*r.From) {
+		return errs.NewValidationError("to must not be before from")
+	}
+	return nil
+}
+
+type AuditEventResponse struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	Outcome    string    `json:"outcome"`
+	SourceIP   string    `json:"source_ip"`
+	RequestID  string    `json:"request_id"`
+	Details    string    `json:"details"`
+	Hash       string    `json:"hash"`
+}
+
+type AuditVerifyResponse struct {
+	Valid         bool   `json:"valid"`
+	Checked       int    `json:"checked"`
+	FirstBrokenID int64  `json:"first_broken_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
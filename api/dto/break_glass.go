@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type BreakGlassRequest struct {
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	Reason          string `json:"reason"`
+	DurationMinutes int    `json:"duration_minutes"`
+	// MFACode is a TOTP or recovery code, required of users who enabled MFA
+	// or whose role requires it.
+	MFACode  string `json:"mfa_code"`
+	SourceIP string `json:"-"`
+}
+
+func (r BreakGlassRequest) Validate() *errs.AppError {
+	if r.Username == "" || r.Password == "" {
+		return errs.NewValidationError("Username and password are required")
+	}
+	if len(strings.TrimSpace(r.Reason)) < 10 {
+		return errs.NewValidationError("A reason of at least 10 characters is required")
+	}
+	if r.DurationMinutes <= 0 {
+		return errs.NewValidationError("Duration must be greater than zero")
+	}
+	return nil
+}
+
+type BreakGlassResponse struct {
+	Token     string    `json:"token"`
+	GrantID   string    `json:"grant_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
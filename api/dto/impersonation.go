@@ -0,0 +1,14 @@
+package dto
+
+import "time"
+
+type ImpersonationRequest struct {
+	Username string `json:"-"`
+	Reason   string `json:"reason"`
+}
+
+type ImpersonationResponse struct {
+	Token     string    `json:"token"`
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -1,11 +1,19 @@
 package dto
 
 type LoginRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	SourceIP  string `json:"-"`
+	RequestID string `json:"-"`
 }
 
+// LoginResponse carries the tokens, or an MFA challenge token to exchange for
+// them at /auth/mfa/verify when the user has to present a second factor.
 type LoginResponse struct {
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token,omitempty"`
+	Token                 string `json:"token,omitempty"`
+	RefreshToken          string `json:"refresh_token,omitempty"`
+	IDToken               string `json:"id_token,omitempty"`
+	MFARequired           bool   `json:"mfa_required,omitempty"`
+	MFAToken              string `json:"mfa_token,omitempty"`
+	MFAEnrollmentRequired bool   `json:"mfa_enrollment_required,omitempty"`
 }
@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type MFAEnrollResponse struct {
+	Secret          string   `json:"secret"`
+	ProvisioningURI string   `json:"provisioning_uri"`
+	RecoveryCodes   []string `json:"recovery_codes"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+func (r MFACodeRequest) Validate() *errs.AppError {
+	if strings.TrimSpace(r.Code) == "" {
+		return errs.NewValidationError("Code is required")
+	}
+	return nil
+}
+
+type MFAVerifyRequest struct {
+	MFAToken  string `json:"mfa_token"`
+	Code      string `json:"code"`
+	SourceIP  string `json:"-"`
+	RequestID string `json:"-"`
+}
+
+func (r MFAVerifyRequest) Validate() *errs.AppError {
+	if r.MFAToken == "" {
+		return errs.NewValidationError("MFA token is required")
+	}
+	return MFACodeRequest{Code: r.Code}.Validate()
+}
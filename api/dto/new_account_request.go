@@ -10,6 +10,9 @@ type NewAccountRequest struct {
 	CustomerID  string  `json:"customer_id"`
 	AccountType string  `json:"account_type"`
 	Amount      float64 `json:"amount"`
+	Actor       string  `json:"-"`
+	SourceIP    string  `json:"-"`
+	RequestID   string  `json:"-"`
 }
 
 func (r NewAccountRequest) Validate() *errs.AppError {
@@ -0,0 +1,104 @@
+package dto
+
+import "net/http"
+
+// OAuthError is the error body of the OAuth endpoints (RFC 6749 section 5.2).
+type OAuthError struct {
+	Status      int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func NewOAuthError(status int, code, description string) *OAuthError {
+	return &OAuthError{Status: status, Code: code, Description: description}
+}
+
+func NewInvalidRequestError(description string) *OAuthError {
+	return NewOAuthError(http.StatusBadRequest, "invalid_request", description)
+}
+
+func NewInvalidClientError(description string) *OAuthError {
+	return NewOAuthError(http.StatusUnauthorized, "invalid_client", description)
+}
+
+func NewInvalidGrantError(description string) *OAuthError {
+	return NewOAuthError(http.StatusBadRequest, "invalid_grant", description)
+}
+
+// OAuthClientCredentials are read from HTTP Basic authentication or from the
+// client_id and client_secret form parameters.
+type OAuthClientCredentials struct {
+	ClientID     string
+	ClientSecret string
+}
+
+type OAuthAuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Nonce               string
+	// The resource owner authenticates either with an access token from
+	// /auth/login or with username and password.
+	AccessToken string
+	Username    string
+	Password    string
+	SourceIP    string
+}
+
+type OAuthTokenRequest struct {
+	OAuthClientCredentials
+	GrantType    string
+	Scope        string
+	Username     string
+	Password     string
+	RefreshToken string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	SourceIP     string
+}
+
+func (r OAuthTokenRequest) Validate() *OAuthError {
+	if r.GrantType == "" {
+		return NewInvalidRequestError("grant_type is required")
+	}
+	if r.ClientID == "" {
+		return NewInvalidClientError("Client authentication is required")
+	}
+	return nil
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+type OAuthIntrospectionRequest struct {
+	OAuthClientCredentials
+	Token         string
+	TokenTypeHint string
+}
+
+// OAuthIntrospectionResponse follows RFC 7662; inactive tokens only carry
+// active=false.
+type OAuthIntrospectionResponse struct {
+	Active     bool   `json:"active"`
+	Scope      string `json:"scope,omitempty"`
+	ClientID   string `json:"client_id,omitempty"`
+	Username   string `json:"username,omitempty"`
+	TokenType  string `json:"token_type,omitempty"`
+	Exp        int64  `json:"exp,omitempty"`
+	Iat        int64  `json:"iat,omitempty"`
+	Sub        string `json:"sub,omitempty"`
+	Iss        string `json:"iss,omitempty"`
+	Role       string `json:"role,omitempty"`
+	CustomerID string `json:"customer_id,omitempty"`
+}
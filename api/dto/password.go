@@ -0,0 +1,40 @@
+package dto
+
+import "github.com/titi0001/Microservices-API-in-Go/errs"
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	SourceIP        string `json:"-"`
+}
+
+func (r PasswordChangeRequest) Validate() *errs.AppError {
+	if r.CurrentPassword == "" || r.NewPassword == "" {
+		return errs.NewValidationError("Current and new password are required")
+	}
+	return nil
+}
+
+type PasswordForgotRequest struct {
+	Username string `json:"username"`
+	SourceIP string `json:"-"`
+}
+
+func (r PasswordForgotRequest) Validate() *errs.AppError {
+	if r.Username == "" {
+		return errs.NewValidationError("Username is required")
+	}
+	return nil
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+func (r PasswordResetRequest) Validate() *errs.AppError {
+	if r.Token == "" || r.NewPassword == "" {
+		return errs.NewValidationError("Token and new password are required")
+	}
+	return nil
+}
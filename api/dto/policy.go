@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/titi0001/Microservices-API-in-Go/errs"
+
+type PolicyTestRequest struct {
+	Subject   map[string]interface{} `json:"subject"`
+	RouteName string                 `json:"route_name"`
+	Vars      map[string]string      `json:"vars"`
+	Resource  map[string]interface{} `json:"resource"`
+}
+
+func (r PolicyTestRequest) Validate() *errs.AppError {
+	if r.RouteName == "" {
+		return errs.NewValidationError("Route name is required")
+	}
+	if len(r.Subject) == 0 {
+		return errs.NewValidationError("Subject is required")
+	}
+	return nil
+}
+
+type PolicyTestResponse struct {
+	Allow    bool                   `json:"allow"`
+	RuleID   string                 `json:"rule_id,omitempty"`
+	Reason   string                 `json:"reason"`
+	Subject  map[string]interface{} `json:"subject"`
+	Vars     map[string]string      `json:"vars"`
+	Resource map[string]interface{} `json:"resource"`
+}
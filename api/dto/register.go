@@ -3,6 +3,6 @@ package dto
 type RegisterRequest struct {
 	Username   string `json:"username"`
 	Password   string `json:"password"`
-	Role       string `json:"role"`
-	CustomerID string `json:"customer_id"`
+	SourceIP   string `json:"-"`
+	RequestID  string `json:"-"`
 }
\ No newline at end of file
@@ -0,0 +1,76 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+const reportDayLayout = "2006-01-02"
+
+// ReportRangeRequest is read from the query string of the reports over a
+// range of days: from and to are UTC dates, both included, and either may
+// be left out.
+type ReportRangeRequest struct {
+	AccountID string
+	From      string
+	To        string
+}
+
+func (r ReportRangeRequest) Validate() *errs.AppError {
+	for param, value := range map[string]string{"from": r.From, "to": r.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(reportDayLayout, value); err != nil {
+			return errs.NewValidationError("Invalid " + param + ", expected YYYY-MM-DD")
+		}
+	}
+	if r.From != "" && r.To != "" && r.To < r.From {
+		return errs.NewValidationError("to must not be before from")
+	}
+	return nil
+}
+
+type CityReportResponse struct {
+	City         string  `json:"city"`
+	Customers    int     `json:"customers"`
+	Accounts     int     `json:"accounts"`
+	Balance      float64 `json:"balance"`
+	Deposits     float64 `json:"deposits"`
+	Withdrawals  float64 `json:"withdrawals"`
+	Transactions int     `json:"transactions"`
+}
+
+// AccountTypeReportResponse gives the current balance of the open accounts
+// of a type, and the accounts opened and the transactions in the range.
+type AccountTypeReportResponse struct {
+	AccountType     string  `json:"account_type"`
+	OpenAccounts    int     `json:"open_accounts"`
+	Balance         float64 `json:"balance"`
+	AccountsOpened  int     `json:"accounts_opened"`
+	Deposits        float64 `json:"deposits"`
+	DepositCount    int     `json:"deposit_count"`
+	Withdrawals     float64 `json:"withdrawals"`
+	WithdrawalCount int     `json:"withdrawal_count"`
+}
+
+type CustomerReportResponse struct {
+	CustomerID   string  `json:"customer_id"`
+	City         string  `json:"city"`
+	Accounts     int     `json:"accounts"`
+	Balance      float64 `json:"balance"`
+	Deposits     float64 `json:"deposits"`
+	Withdrawals  float64 `json:"withdrawals"`
+	Transactions int     `json:"transactions"`
+}
+
+// DailyBalanceResponse is a day with activity on the account; the balance
+// of a day without a row is the closing balance of the day before.
+type DailyBalanceResponse struct {
+	Day            string  `json:"day"`
+	ClosingBalance float64 `json:"closing_balance"`
+	Deposits       float64 `json:"deposits"`
+	Withdrawals    float64 `json:"withdrawals"`
+	Transactions   int     `json:"transactions"`
+}
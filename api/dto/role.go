@@ -0,0 +1,87 @@
+package dto
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// The patterns match the columns: role names are varchar(20) like the role of
+// a user, permission names varchar(50).
+var (
+	roleNamePattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,19}$`)
+	identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]{0,49}$`)
+)
+
+type RoleRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r RoleRequest) Validate() *errs.AppError {
+	if !roleNamePattern.MatchString(strings.TrimSpace(r.Name)) {
+		return errs.NewValidationError("Role name must start with a letter, contain only letters, digits, '_' or '-' and be at most 20 characters")
+	}
+	return nil
+}
+
+type RoleResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MFARequired bool   `json:"mfa_required"`
+}
+
+type RoleMFARequest struct {
+	RoleName string `json:"-"`
+	Required *bool  `json:"required"`
+}
+
+func (r RoleMFARequest) Validate() *errs.AppError {
+	if r.RoleName == "" {
+		return errs.NewValidationError("Role is required")
+	}
+	if r.Required == nil {
+		return errs.NewValidationError("Field 'required' must be true or false")
+	}
+	return nil
+}
+
+type PermissionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r PermissionRequest) Validate() *errs.AppError {
+	if !identifierPattern.MatchString(strings.TrimSpace(r.Name)) {
+		return errs.NewValidationError("Permission name must be a route name made of letters, digits, '_' or '-'")
+	}
+	return nil
+}
+
+type PermissionResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type AssignPermissionRequest struct {
+	RoleName       string `json:"-"`
+	PermissionName string `json:"-"`
+	Scope          string `json:"scope"`
+}
+
+func (r AssignPermissionRequest) Validate() *errs.AppError {
+	if r.RoleName == "" || r.PermissionName == "" {
+		return errs.NewValidationError("Role and permission are required")
+	}
+	if r.Scope != "all" && r.Scope != "own" {
+		return errs.NewValidationError("Scope must be 'all' or 'own'")
+	}
+	return nil
+}
+
+type RolePermissionResponse struct {
+	Role       string `json:"role"`
+	Permission string `json:"permission"`
+	Scope      string `json:"scope"`
+}
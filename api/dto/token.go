@@ -0,0 +1,12 @@
+package dto
+
+type VerifyRequest struct {
+	Token     string                 `json:"token,omitempty"`
+	RouteName string                 `json:"route_name"`
+	Vars      map[string]string      `json:"vars,omitempty"`
+	Resource  map[string]interface{} `json:"resource,omitempty"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
@@ -16,6 +16,9 @@ type TransactionRequest struct {
 	TransactionType string  `json:"transaction_type"`
 	TransactionDate string  `json:"transaction_date"`
 	CustomerID      string  `json:"-"` 
+	Actor           string  `json:"-"`
+	SourceIP        string  `json:"-"`
+	RequestID       string  `json:"-"`
 }
 
 func (r TransactionRequest) IsTransactionTypeWithdrawal() bool {
@@ -1,8 +1,66 @@
 package dto
 
+import (
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
 type User struct {
 	Username   string `json:"username"`
 	Role       string `json:"role"`
 	CustomerID string `json:"customer_id"`
+	Status     string `json:"status"`
 	CreatedOn  string `json:"created_on"`
-}
\ No newline at end of file
+}
+
+// UserSearchRequest is read from the query string of GET /admin/users.
+type UserSearchRequest struct {
+	Query      string
+	Role       string
+	Status     string
+	CustomerID string
+	Limit      int
+	Offset     int
+}
+
+type UserRoleRequest struct {
+	Username string `json:"-"`
+	Role     string `json:"role"`
+}
+
+func (r UserRoleRequest) Validate() *errs.AppError {
+	if strings.TrimSpace(r.Role) == "" {
+		return errs.NewValidationError("Role is required")
+	}
+	return nil
+}
+
+type UserCustomerRequest struct {
+	Username   string `json:"-"`
+	CustomerID string `json:"customer_id"`
+}
+
+func (r UserCustomerRequest) Validate() *errs.AppError {
+	if strings.TrimSpace(r.CustomerID) == "" {
+		return errs.NewValidationError("Customer id is required")
+	}
+	return nil
+}
+
+type UserStatusRequest struct {
+	Username string `json:"-"`
+	Status   string `json:"status"`
+}
+
+func (r UserStatusRequest) Validate() *errs.AppError {
+	if r.Status != "active" && r.Status != "disabled" {
+		return errs.NewValidationError("Status must be 'active' or 'disabled'")
+	}
+	return nil
+}
+
+type ForceLogoutResponse struct {
+	Username             string `json:"username"`
+	RevokedRefreshTokens int64  `json:"revoked_refresh_tokens"`
+}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+const minWebhookSecretLength = 16
+
+// WebhookRequest creates a subscription. CustomerID is taken from the path
+// on the customer routes. Without a Secret, one is generated.
+type WebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret,omitempty"`
+	EventTypes []string `json:"event_types,omitempty"`
+	CustomerID string   `json:"customer_id,omitempty"`
+	CreatedBy  string   `json:"-"`
+}
+
+func (r WebhookRequest) Validate() *errs.AppError {
+	u, err := url.Parse(strings.TrimSpace(r.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errs.NewValidationError("URL must be an absolute http or https URL")
+	}
+	if r.Secret != "" && len(r.Secret) < minWebhookSecretLength {
+		return errs.NewValidationError("Secret must be at least 16 characters")
+	}
+	for _, eventType := range r.EventTypes {
+		if !identifierPattern.MatchString(strings.TrimSpace(eventType)) {
+			return errs.NewValidationError("Invalid event type: " + eventType)
+		}
+	}
+	return nil
+}
+
+type WebhookResponse struct {
+	ID         string    `json:"webhook_id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CustomerID string    `json:"customer_id,omitempty"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedOn  time.Time `json:"created_on"`
+}
+
+// WebhookCreatedResponse is the only response that contains the secret.
+type WebhookCreatedResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryQueryRequest is read from the query string of the delivery
+// log.
+type WebhookDeliveryQueryRequest struct {
+	WebhookID  string
+	CustomerID string
+	Status     string
+	Limit      int
+	Offset     int
+}
+
+func (r WebhookDeliveryQueryRequest) Validate() *errs.AppError {
+	switch r.Status {
+	case "", "pending", "delivered", "dead":
+	default:
+		return errs.NewValidationError("Status must be pending, delivered or dead")
+	}
+	return nil
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string     `json:"delivery_id"`
+	WebhookID      string     `json:"webhook_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	LastStatusCode int        `json:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedOn      time.Time  `json:"created_on"`
+	DeliveredOn    *time.Time `json:"delivered_on,omitempty"`
+}
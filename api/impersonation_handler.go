@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type ImpersonationHandler struct {
+	service ports.ImpersonationService
+}
+
+func NewImpersonationHandler(service ports.ImpersonationService) *ImpersonationHandler {
+	return &ImpersonationHandler{service: service}
+}
+
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	var request dto.ImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && err != io.EOF {
+		logger.Warn("Invalid impersonation payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	request.Username = mux.Vars(r)["username"]
+
+	actor := VerifiedTokenFrom(r.Context())
+	if actor == nil {
+		utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
+		return
+	}
+
+	response, appError := h.service.Impersonate(r.Context(), *actor, request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteResponse(w, http.StatusCreated, response)
+}
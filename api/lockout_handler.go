@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+)
+
+type LockoutHandler struct {
+	service ports.LockoutService
+}
+
+func NewLockoutHandler(service ports.LockoutService) *LockoutHandler {
+	return &LockoutHandler{service: service}
+}
+
+func (h *LockoutHandler) ListLockouts(w http.ResponseWriter, r *http.Request) {
+	lockouts, appError := h.service.ListLockouts(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, lockouts)
+}
+
+func (h *LockoutHandler) ClearLockout(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if appError := h.service.ClearLockout(r.Context(), vars["scope"], vars["subject"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// OAuthHandler serves the OAuth2 and OpenID Connect endpoints. The OAuth2
+// endpoints take form-encoded requests as required by RFC 6749.
+type OAuthHandler struct {
+	service ports.OAuthService
+}
+
+func NewOAuthHandler(service ports.OAuthService) *OAuthHandler {
+	return &OAuthHandler{service: service}
+}
+
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, dto.NewInvalidRequestError("Invalid form payload"))
+		return
+	}
+
+	request := dto.OAuthAuthorizeRequest{
+		ResponseType:        r.PostForm.Get("response_type"),
+		ClientID:            r.PostForm.Get("client_id"),
+		RedirectURI:         r.PostForm.Get("redirect_uri"),
+		Scope:               r.PostForm.Get("scope"),
+		State:               r.PostForm.Get("state"),
+		CodeChallenge:       r.PostForm.Get("code_challenge"),
+		CodeChallengeMethod: r.PostForm.Get("code_challenge_method"),
+		Nonce:               r.PostForm.Get("nonce"),
+		AccessToken:         utils.GetTokenFromHeader(r.Header.Get("Authorization")),
+		Username:            r.PostForm.Get("username"),
+		Password:            r.PostForm.Get("password"),
+		SourceIP:            utils.ClientIP(r),
+	}
+
+	redirectURL, oauthErr := h.service.Authorize(r.Context(), request)
+	if oauthErr != nil {
+		logger.Warn("Authorization request rejected", logger.String("error", oauthErr.Code))
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, dto.NewInvalidRequestError("Invalid form payload"))
+		return
+	}
+	credentials, basic := clientCredentials(r)
+
+	request := dto.OAuthTokenRequest{
+		OAuthClientCredentials: credentials,
+		GrantType:              r.PostForm.Get("grant_type"),
+		Scope:                  r.PostForm.Get("scope"),
+		Username:               r.PostForm.Get("username"),
+		Password:               r.PostForm.Get("password"),
+		RefreshToken:           r.PostForm.Get("refresh_token"),
+		Code:                   r.PostForm.Get("code"),
+		RedirectURI:            r.PostForm.Get("redirect_uri"),
+		CodeVerifier:           r.PostForm.Get("code_verifier"),
+		SourceIP:               utils.ClientIP(r),
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	response, oauthErr := h.service.Token(r.Context(), request)
+	if oauthErr != nil {
+		logger.Warn("Token request rejected",
+			logger.String("grant_type", request.GrantType),
+			logger.String("client_id", request.ClientID),
+			logger.String("error", oauthErr.Code))
+		if oauthErr.Status == http.StatusUnauthorized && basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, response)
+}
+
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, dto.NewInvalidRequestError("Invalid form payload"))
+		return
+	}
+	credentials, basic := clientCredentials(r)
+
+	request := dto.OAuthIntrospectionRequest{
+		OAuthClientCredentials: credentials,
+		Token:                  r.PostForm.Get("token"),
+		TokenTypeHint:          r.PostForm.Get("token_type_hint"),
+	}
+
+	response, oauthErr := h.service.Introspect(r.Context(), request)
+	if oauthErr != nil {
+		if oauthErr.Status == http.StatusUnauthorized && basic {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, oauthErr)
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, response)
+}
+
+func (h *OAuthHandler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	utils.WriteResponse(w, http.StatusOK, h.service.OpenIDConfiguration(r.Context()))
+}
+
+func (h *OAuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	token := utils.GetTokenFromHeader(r.Header.Get("Authorization"))
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		utils.WriteResponse(w, http.StatusUnauthorized, map[string]string{"error": "Missing token"})
+		return
+	}
+
+	userInfo, appError := h.service.UserInfo(r.Context(), token)
+	if appError != nil {
+		logger.Warn("UserInfo request rejected", logger.String("error", appError.Message))
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, userInfo)
+}
+
+// clientCredentials reads the client from HTTP Basic authentication, whose
+// values are form-encoded (RFC 6749 2.3.1), or from the request body.
+func clientCredentials(r *http.Request) (dto.OAuthClientCredentials, bool) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		clientID, idErr := url.QueryUnescape(id)
+		clientSecret, secretErr := url.QueryUnescape(secret)
+		if idErr == nil && secretErr == nil {
+			return dto.OAuthClientCredentials{ClientID: clientID, ClientSecret: clientSecret}, true
+		}
+	}
+	return dto.OAuthClientCredentials{
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}, false
+}
+
+func writeOAuthError(w http.ResponseWriter, err *dto.OAuthError) {
+	utils.WriteResponse(w, err.Status, err)
+}
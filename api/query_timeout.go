@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// QueryTimeouts bound how long a request may wait on the database. The
+// deadline is set on the request context, which the services pass down to
+// every query; a zero timeout leaves the request without a deadline.
+type QueryTimeouts struct {
+	Default time.Duration
+	Routes  map[string]time.Duration
+}
+
+// QueryTimeoutsFromEnv reads QUERY_TIMEOUT and ROUTE_QUERY_TIMEOUTS, a comma
+// separated list of RouteName=duration overrides such as
+// "NewTransaction=10s,ListUsers=2s".
+func QueryTimeoutsFromEnv() QueryTimeouts {
+	timeouts := QueryTimeouts{
+		Default: config.Duration("QUERY_TIMEOUT", 5*time.Second),
+		Routes:  make(map[string]time.Duration),
+	}
+	for _, entry := range strings.Split(config.String("ROUTE_QUERY_TIMEOUTS", ""), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, value, _ := strings.Cut(entry, "=")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout < 0 {
+			logger.Warn("Invalid route query timeout, ignoring it", logger.String("entry", entry))
+			continue
+		}
+		timeouts.Routes[strings.TrimSpace(route)] = timeout
+	}
+	return timeouts
+}
+
+func (q QueryTimeouts) For(routeName string) time.Duration {
+	if timeout, ok := q.Routes[routeName]; ok {
+		return timeout
+	}
+	return q.Default
+}
+
+// Middleware sets the deadline of the matched route on the request context.
+func (q QueryTimeouts) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		routeName := ""
+		if route := mux.CurrentRoute(r); route != nil {
+			routeName = route.GetName()
+		}
+		timeout := q.For(routeName)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+)
+
+type ReportHandler struct {
+	service ports.ReportService
+}
+
+func NewReportHandler(service ports.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+func (h *ReportHandler) GetCityReport(w http.ResponseWriter, r *http.Request) {
+	report, appError := h.service.CityReport(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, report)
+}
+
+func (h *ReportHandler) GetAccountTypeReport(w http.ResponseWriter, r *http.Request) {
+	request, ok := reportRange(w, r)
+	if !ok {
+		return
+	}
+	report, appError := h.service.AccountTypeReport(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, report)
+}
+
+func (h *ReportHandler) GetCustomerReport(w http.ResponseWriter, r *http.Request) {
+	report, appError := h.service.CustomerReport(r.Context(), mux.Vars(r)["customer_id"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, report)
+}
+
+func (h *ReportHandler) GetAccountDailyReport(w http.ResponseWriter, r *http.Request) {
+	request, ok := reportRange(w, r)
+	if !ok {
+		return
+	}
+	request.AccountID = mux.Vars(r)["account_id"]
+	report, appError := h.service.AccountDailyReport(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, report)
+}
+
+// reportRange reads from and to from the query string, answering 400 when
+// they are invalid.
+func reportRange(w http.ResponseWriter, r *http.Request) (dto.ReportRangeRequest, bool) {
+	query := r.URL.Query()
+	request := dto.ReportRangeRequest{From: query.Get("from"), To: query.Get("to")}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return request, false
+	}
+	return request, true
+}
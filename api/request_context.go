@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"regexp"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+)
+
+type contextKey int
+
+const (
+	verifiedTokenKey contextKey = iota
+	requestIDKey
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+func withVerifiedToken(r *http.Request, verified *domain.VerifiedToken) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), verifiedTokenKey, verified))
+}
+
+// VerifiedTokenFrom returns the caller authorized by AuthMiddleware, or nil on
+// public routes.
+func VerifiedTokenFrom(ctx context.Context) *domain.VerifiedToken {
+	verified, _ := ctx.Value(verifiedTokenKey).(*domain.VerifiedToken)
+	return verified
+}
+
+// RequestIDMiddleware keeps a well-formed X-Request-ID from the caller or
+// generates one, and echoes it in the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, requestID)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, requestID)))
+	})
+}
+
+// RequestIDFrom returns the id set by RequestIDMiddleware.
+func RequestIDFrom(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// auditContext returns who made the request, from where and under which
+// request id. An impersonated request is attributed to the impersonating
+// admin.
+func auditContext(r *http.Request) (actor, sourceIP, requestID string) {
+	if verified := VerifiedTokenFrom(r.Context()); verified != nil {
+		actor = verified.Username
+		if verified.Actor != "" {
+			actor = verified.Actor
+		}
+	}
+	return actor, utils.ClientIP(r), RequestIDFrom(r.Context())
+}
+
+func newRequestID() string {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const maxPeekBodyBytes = 1 << 20
+
+// ResourceResolver collects the attributes of the resource a request targets,
+// such as the owner of an account or the amount of a transaction, so the
+// authorization policy can decide on them.
+type ResourceResolver struct {
+	accounts ports.AccountRepository
+}
+
+func NewResourceResolver(accounts ports.AccountRepository) *ResourceResolver {
+	return &ResourceResolver{accounts: accounts}
+}
+
+func (rr *ResourceResolver) Resolve(r *http.Request, vars map[string]string) (map[string]interface{}, *errs.AppError) {
+	resource := make(map[string]interface{})
+	if rr == nil {
+		return resource, nil
+	}
+
+	if customerID, ok := vars["customer_id"]; ok {
+		resource["customer_id"] = customerID
+		resource["owner_id"] = customerID
+	}
+
+	if accountID, ok := vars["account_id"]; ok && rr.accounts != nil {
+		if account, err := rr.accounts.FindBy(r.Context(), accountID); err == nil {
+			resource["account_id"] = account.AccountID
+			resource["owner_id"] = account.CustomerID
+			resource["balance"] = account.Amount
+			resource["account_type"] = account.AccountType
+		}
+	}
+
+	fields, err := peekJSONBody(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range []string{"amount", "transaction_type", "account_type"} {
+		if value, ok := fields[foldFieldName(key)]; ok {
+			resource[key] = value
+		}
+	}
+	return resource, nil
+}
+
+// peekedBody is a request body with its peeked bytes put back in front.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// peekJSONBody decodes the request body and puts it back so the handler can
+// still read it. A body over maxPeekBodyBytes is refused rather than peeked in
+// part, as the policy would then decide without its attributes.
+//
+// The handlers decode bodies with encoding/json, which matches field names
+// case-insensitively, lets the last of duplicate fields win and stops after
+// the first value. The fields are therefore returned under their folded name,
+// and a body whose fields fold to the same name or that goes on after the
+// object is refused, so the policy sees the values the handler will use.
+func peekJSONBody(r *http.Request) (map[string]interface{}, *errs.AppError) {
+	if r.Body == nil || r.Method == http.MethodGet {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPeekBodyBytes+1))
+	r.Body = peekedBody{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+	if len(body) > maxPeekBodyBytes {
+		logger.Warn("Request body too large to authorize", logger.String("path", r.URL.Path))
+		return nil, errs.NewPayloadTooLargeError("Request body too large")
+	}
+	if err != nil || len(body) == 0 {
+		return nil, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if start, err := decoder.Token(); err != nil || start != json.Delim('{') {
+		logger.Debug("Request body is not a JSON object, no resource attributes read")
+		return nil, nil
+	}
+	fields := make(map[string]interface{})
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, invalidBody(r, "malformed JSON object")
+		}
+		name, _ := token.(string)
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, invalidBody(r, "malformed JSON object")
+		}
+		folded := foldFieldName(name)
+		if _, seen := fields[folded]; seen {
+			return nil, invalidBody(r, "duplicate field "+name)
+		}
+		fields[folded] = value
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, invalidBody(r, "malformed JSON object")
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, invalidBody(r, "data after the JSON object")
+	}
+	return fields, nil
+}
+
+func invalidBody(r *http.Request, reason string) *errs.AppError {
+	logger.Warn("Request body refused before authorization",
+		logger.String("path", r.URL.Path),
+		logger.String("reason", reason))
+	return errs.NewBadRequestError("Invalid request payload")
+}
+
+// foldFieldName folds a field name the way encoding/json does when it matches
+// a field, so names it treats as equal fold to the same string.
+func foldFieldName(name string) string {
+	return strings.Map(func(r rune) rune {
+		// The smallest rune of the fold set, as in encoding/json.
+		for {
+			next := unicode.SimpleFold(r)
+			if next <= r {
+				return next
+			}
+			r = next
+		}
+	}, name)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type RoleHandler struct {
+	service ports.RoleService
+}
+
+func NewRoleHandler(service ports.RoleService) *RoleHandler {
+	return &RoleHandler{service: service}
+}
+
+func (h *RoleHandler) ListRoles(w http.ResponseWriter, r *http.Request) {
+	roles, appError := h.service.ListRoles(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, roles)
+}
+
+func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
+	var request dto.RoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid role request payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	role, appError := h.service.CreateRole(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusCreated, role)
+}
+
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	if appError := h.service.DeleteRole(r.Context(), mux.Vars(r)["role"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoleHandler) SetRoleMFA(w http.ResponseWriter, r *http.Request) {
+	var request dto.RoleMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid role MFA payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	request.RoleName = mux.Vars(r)["role"]
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	if appError := h.service.SetRoleMFA(r.Context(), request); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoleHandler) ListPermissions(w http.ResponseWriter, r *http.Request) {
+	permissions, appError := h.service.ListPermissions(r.Context())
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, permissions)
+}
+
+func (h *RoleHandler) CreatePermission(w http.ResponseWriter, r *http.Request) {
+	var request dto.PermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid permission request payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	permission, appError := h.service.CreatePermission(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusCreated, permission)
+}
+
+func (h *RoleHandler) DeletePermission(w http.ResponseWriter, r *http.Request) {
+	if appError := h.service.DeletePermission(r.Context(), mux.Vars(r)["permission"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RoleHandler) ListRolePermissions(w http.ResponseWriter, r *http.Request) {
+	assignments, appError := h.service.ListRolePermissions(r.Context(), mux.Vars(r)["role"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, assignments)
+}
+
+func (h *RoleHandler) AssignPermission(w http.ResponseWriter, r *http.Request) {
+	var request dto.AssignPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid assign permission payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+
+	vars := mux.Vars(r)
+	request.RoleName = vars["role"]
+	request.PermissionName = vars["permission"]
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	assignment, appError := h.service.AssignPermission(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, assignment)
+}
+
+func (h *RoleHandler) RevokePermission(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if appError := h.service.RevokePermission(r.Context(), vars["role"], vars["permission"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
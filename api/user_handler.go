@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type UserHandler struct {
+	service ports.UserService
+}
+
+func NewUserHandler(service ports.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+func (h *UserHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	request := dto.UserSearchRequest{
+		Query:      query.Get("q"),
+		Role:       query.Get("role"),
+		Status:     query.Get("status"),
+		CustomerID: query.Get("customer_id"),
+	}
+	for param, target := range map[string]*int{"limit": &request.Limit, "offset": &request.Offset} {
+		if raw := query.Get(param); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + param})
+				return
+			}
+			*target = n
+		}
+	}
+
+	users, appError := h.service.ListUsers(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, users)
+}
+
+func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, appError := h.service.GetUser(r.Context(), mux.Vars(r)["username"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, user)
+}
+
+func (h *UserHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	var request dto.UserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid user role payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	request.Username = mux.Vars(r)["username"]
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	if appError := h.service.SetUserRole(r.Context(), request); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) LinkCustomer(w http.ResponseWriter, r *http.Request) {
+	var request dto.UserCustomerRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid user customer payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	request.Username = mux.Vars(r)["username"]
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	if appError := h.service.LinkCustomer(r.Context(), request); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) UnlinkCustomer(w http.ResponseWriter, r *http.Request) {
+	if appError := h.service.UnlinkCustomer(r.Context(), mux.Vars(r)["username"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) SetUserStatus(w http.ResponseWriter, r *http.Request) {
+	var request dto.UserStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid user status payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	request.Username = mux.Vars(r)["username"]
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	if appError := h.service.SetUserStatus(r.Context(), request); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	response, appError := h.service.ForceLogout(r.Context(), mux.Vars(r)["username"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, response)
+}
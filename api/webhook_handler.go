@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// WebhookHandler serves both the admin routes and the customer routes; on
+// the latter the customer_id of the path limits every call to the
+// subscriptions of that customer.
+type WebhookHandler struct {
+	service ports.WebhookService
+}
+
+func NewWebhookHandler(service ports.WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var request dto.WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Warn("Invalid webhook request payload", logger.Any("error", err))
+		utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+	if customerID, ok := mux.Vars(r)["customer_id"]; ok {
+		request.CustomerID = customerID
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+	if caller := VerifiedTokenFrom(r.Context()); caller != nil {
+		request.CreatedBy = caller.Username
+	}
+
+	webhook, appError := h.service.CreateWebhook(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteResponse(w, http.StatusCreated, webhook)
+}
+
+func (h *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	webhooks, appError := h.service.ListWebhooks(r.Context(), mux.Vars(r)["customer_id"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, webhooks)
+}
+
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if appError := h.service.DeleteWebhook(r.Context(), vars["customer_id"], vars["webhook_id"]); appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+	request := dto.WebhookDeliveryQueryRequest{
+		WebhookID:  vars["webhook_id"],
+		CustomerID: vars["customer_id"],
+		Status:     query.Get("status"),
+	}
+	for param, target := range map[string]*int{"limit": &request.Limit, "offset": &request.Offset} {
+		if raw := query.Get(param); raw != "" {
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				utils.WriteResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid " + param})
+				return
+			}
+			*target = n
+		}
+	}
+	if err := request.Validate(); err != nil {
+		utils.WriteResponse(w, err.Code, map[string]string{"error": err.Message})
+		return
+	}
+
+	deliveries, appError := h.service.ListDeliveries(r.Context(), request)
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	delivery, appError := h.service.Redeliver(r.Context(), vars["customer_id"], vars["webhook_id"], vars["delivery_id"])
+	if appError != nil {
+		utils.WriteResponse(w, appError.Code, map[string]string{"error": appError.Message})
+		return
+	}
+	utils.WriteResponse(w, http.StatusAccepted, delivery)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"flag"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,9 +12,16 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/joho/godotenv"
 	"github.com/titi0001/Microservices-API-in-Go/api"
+	"github.com/titi0001/Microservices-API-in-Go/db"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
 	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/eventsink"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/repository"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
 )
 
@@ -22,6 +31,10 @@ const (
 )
 
 func main() {
+	storage := flag.String("storage", "sql", "where customers, accounts and users are kept: sql or memory")
+	fixtures := flag.String("fixtures", "", "JSON fixtures seeding -storage=memory (default: the seed data of the initial migration)")
+	flag.Parse()
+
 	if err := godotenv.Load(); err != nil {
 		logger.Fatal("Error loading .env file", logger.Any("error", err))
 	}
@@ -39,35 +52,171 @@ func main() {
 		authServiceURL = "http://" + authServiceURL
 	}
 
-	dbClient := database.GetClient()
-	if dbClient == nil {
-		logger.Fatal("Failed to initialize database client")
+	signingKeys, err := utils.LoadSigningKeysFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to load JWT signing keys", logger.Any("error", err))
 	}
+
+	dbClient, repos := openStorage(*storage, *fixtures)
 	defer dbClient.Close()
 
 	var wg sync.WaitGroup
 	wg.Add(2)
 
-	authServer := api.SetupAuthServer(authHost, authServiceURL, dbClient)
+	authServer := api.SetupAuthServer(authHost, authServiceURL, dbClient, repos, signingKeys)
+	cancelAuthRequests := cancelOnShutdown(authServer)
 	go startServer(authServer, authHost, "auth server", &wg)
 
 	time.Sleep(200 * time.Millisecond)
 
-	mainServer := api.SetupMainServer(localHost, authServiceURL, dbClient)
+	mainServer := api.SetupMainServer(localHost, authServiceURL, dbClient, repos)
+	cancelMainRequests := cancelOnShutdown(mainServer)
 	go startServer(mainServer, localHost, "main server", &wg)
 
+	stopRelay := startOutboxRelay(dbClient, repos, &wg)
+	stopProjector := startReportProjector(dbClient, repos, &wg)
+
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	go reloadSigningKeys(signingKeys, reloadChan)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 
 	logger.Info("Received shutdown signal, stopping servers")
-	shutdownServer(mainServer, "main server", mainServerShutdownTimeout)
-	shutdownServer(authServer, "auth server", authServerShutdownTimeout)
+	shutdownServer(mainServer, "main server", mainServerShutdownTimeout, cancelMainRequests)
+	shutdownServer(authServer, "auth server", authServerShutdownTimeout, cancelAuthRequests)
+	stopRelay()
+	stopProjector()
 
 	wg.Wait()
 	logger.Info("All servers shut down successfully")
 }
 
+// openStorage connects the database and picks the repositories of customers,
+// accounts and users. In memory mode those are seeded from fixtures and the
+// other tables live in an in-memory SQLite database, so the service runs
+// without a database server and forgets everything when it stops.
+func openStorage(storage, fixturesPath string) (*sqlx.DB, api.Repositories) {
+	switch storage {
+	case "sql":
+		dbClient, err := database.GetClient()
+		if err != nil {
+			logger.Fatal("Failed to initialize database client", logger.Any("error", err))
+		}
+		if config.Bool("MIGRATE_ON_STARTUP", false) {
+			migrateOnStartup(dbClient)
+		}
+		return dbClient, api.SQLRepositories(dbClient)
+
+	case "memory":
+		cfg := database.DefaultConfig()
+		cfg.Driver, cfg.Path = database.DriverSQLite, ":memory:"
+		dbClient, err := database.Open(cfg)
+		if err != nil {
+			logger.Fatal("Failed to open in-memory database", logger.Any("error", err))
+		}
+		migrateOnStartup(dbClient)
+
+		data := db.SeedFixtures
+		if fixturesPath != "" {
+			if data, err = os.ReadFile(fixturesPath); err != nil {
+				logger.Fatal("Failed to read fixtures", logger.String("path", fixturesPath), logger.Any("error", err))
+			}
+		}
+		fixtures, err := repository.LoadFixtures(data)
+		if err != nil {
+			logger.Fatal("Failed to load fixtures", logger.String("path", fixturesPath), logger.Any("error", err))
+		}
+		store, err := repository.NewMemoryStore(fixtures)
+		if err != nil {
+			logger.Fatal("Invalid fixtures", logger.String("path", fixturesPath), logger.Any("error", err))
+		}
+		logger.Info("Using in-memory storage",
+			logger.Int("customers", len(fixtures.Customers)),
+			logger.Int("accounts", len(fixtures.Accounts)),
+			logger.Int("users", len(fixtures.Users)))
+		return dbClient, api.MemoryRepositories(store)
+
+	default:
+		logger.Fatal("Unknown storage, expected sql or memory", logger.String("storage", storage))
+		return nil, api.Repositories{}
+	}
+}
+
+// migrateOnStartup applies pending migrations before serving. The migration
+// lock makes instances started together apply them only once.
+func migrateOnStartup(dbClient *sqlx.DB) {
+	migrator, err := database.NewEmbeddedMigrator(dbClient)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", logger.Any("error", err))
+	}
+	applied, err := migrator.Up(0)
+	if err != nil {
+		logger.Fatal("Failed to migrate database", logger.Any("error", err))
+	}
+	logger.Info("Database migrated", logger.Int("applied", len(applied)))
+}
+
+// startOutboxRelay publishes the events of the outbox, and delivers the
+// webhooks they queue, until the returned function is called. Only one relay
+// may run per database, so the instances take a leader lock and the one
+// holding it runs the relay; OUTBOX_RELAY=false keeps an instance out.
+func startOutboxRelay(dbClient *sqlx.DB, repos api.Repositories, wg *sync.WaitGroup) context.CancelFunc {
+	if !config.Bool("OUTBOX_RELAY", true) {
+		logger.Info("Outbox relay disabled")
+		return func() {}
+	}
+	sink, err := eventsink.NewFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to configure event sinks", logger.Any("error", err))
+	}
+
+	webhooks := service.NewWebhookDispatcher(repository.NewWebhookRepositoryDb(dbClient))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	relay := service.NewOutboxRelay(repos.Outbox, eventsink.Multi{sink, webhooks})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		database.RunAsLeader(ctx, dbClient, "outbox_relay", config.Duration("OUTBOX_LEADER_RETRY", 10*time.Second), func(ctx context.Context) {
+			var leaderWG sync.WaitGroup
+			leaderWG.Add(1)
+			go func() {
+				defer leaderWG.Done()
+				webhooks.Run(ctx)
+				logger.Info("Webhook dispatcher stopped")
+			}()
+			relay.Run(ctx)
+			logger.Info("Outbox relay stopped")
+			leaderWG.Wait()
+		})
+	}()
+	return cancel
+}
+
+// startReportProjector keeps the report tables up with the account events
+// until the returned function is called. REPORT_PROJECTOR=false leaves it to
+// another instance; projectors sharing a database take turns, so running
+// more than one only wastes work.
+func startReportProjector(dbClient *sqlx.DB, repos api.Repositories, wg *sync.WaitGroup) context.CancelFunc {
+	if !config.Bool("REPORT_PROJECTOR", true) {
+		logger.Info("Report projector disabled")
+		return func() {}
+	}
+	projector := service.NewReportProjector(repository.NewReportRepositoryDb(dbClient), repos.UnitOfWork, repos.Customers)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		projector.Run(ctx)
+		logger.Info("Report projector stopped")
+	}()
+	return cancel
+}
+
 func startServer(server *http.Server, address, name string, wg *sync.WaitGroup) {
 	defer wg.Done()
 	logger.Info("Starting server", logger.String("name", name), logger.String("address", address))
@@ -76,13 +225,36 @@ func startServer(server *http.Server, address, name string, wg *sync.WaitGroup)
 	}
 }
 
-func shutdownServer(server *http.Server, name string, timeout time.Duration) {
+// cancelOnShutdown derives the context of every request of server from one
+// that shutdownServer cancels when the grace period runs out, so queries still
+// in flight are aborted instead of outliving the server.
+func cancelOnShutdown(server *http.Server) context.CancelFunc {
+	ctx, cancel := context.WithCancel(context.Background())
+	server.BaseContext = func(net.Listener) context.Context { return ctx }
+	return cancel
+}
+
+func shutdownServer(server *http.Server, name string, timeout time.Duration, cancelRequests context.CancelFunc) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Error shutting down server", logger.String("name", name), logger.Any("error", err))
+		cancelRequests()
+		if err := server.Close(); err != nil {
+			logger.Error("Error closing server", logger.String("name", name), logger.Any("error", err))
+		}
 		return
 	}
 	logger.Info("Server shut down successfully", logger.String("name", name))
+}
+
+func reloadSigningKeys(keys *utils.SigningKeySet, reloadChan <-chan os.Signal) {
+	for range reloadChan {
+		if err := keys.Reload(); err != nil {
+			logger.Error("Error reloading signing keys, keeping current keys", logger.Any("error", err))
+			continue
+		}
+		logger.Info("Signing keys reloaded")
+	}
 }
\ No newline at end of file
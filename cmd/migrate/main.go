@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/titi0001/Microservices-API-in-Go/db"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const usage = `usage: migrate <command> [args]
+
+commands:
+  up [n]         apply all pending migrations, or the next n
+  down [n]       revert the last applied migration, or the last n
+  status         list migrations and when they were applied
+  create <name>  write empty up and down files for every database to ` + db.MigrationsDir + `/<dialect>`
+
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, args := os.Args[1], os.Args[2:]
+
+	if command == "create" {
+		if len(args) != 1 || !migrationName.MatchString(args[0]) {
+			logger.Fatal("create needs a name of lowercase letters, digits and underscores")
+		}
+		create(args[0])
+		return
+	}
+
+	if err := godotenv.Load(); err != nil {
+		logger.Warn("No .env file loaded", logger.Any("error", err))
+	}
+	dbClient, err := database.GetClient()
+	if err != nil {
+		logger.Fatal("Failed to initialize database client", logger.Any("error", err))
+	}
+	defer dbClient.Close()
+
+	migrator, err := database.NewEmbeddedMigrator(dbClient)
+	if err != nil {
+		logger.Fatal("Failed to load migrations", logger.Any("error", err))
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(count(args, 0))
+		printMigrations("applied", applied)
+		if err != nil {
+			logger.Fatal("Migration failed", logger.Any("error", err))
+		}
+	case "down":
+		reverted, err := migrator.Down(count(args, 1))
+		printMigrations("reverted", reverted)
+		if err != nil {
+			logger.Fatal("Migration failed", logger.Any("error", err))
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			logger.Fatal("Failed to read migration status", logger.Any("error", err))
+		}
+		printStatus(statuses)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// count reads the optional step count of up and down.
+func count(args []string, fallback int) int {
+	if len(args) == 0 {
+		return fallback
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 {
+		logger.Fatal("Step count must be a positive integer", logger.String("value", args[0]))
+	}
+	return n
+}
+
+// create numbers the new migration after the highest existing version and
+// writes it for every dialect, so that the databases keep the same versions.
+func create(name string) {
+	dialects := []string{database.DriverMySQL, database.DriverPostgres, database.DriverSQLite}
+	next := int64(1)
+	for _, dialect := range dialects {
+		migrations, err := database.LoadMigrations(os.DirFS(db.MigrationsDir), dialect)
+		if err != nil {
+			logger.Fatal("Failed to read migrations", logger.String("dialect", dialect), logger.Any("error", err))
+		}
+		if len(migrations) > 0 {
+			next = max(next, migrations[len(migrations)-1].Version+1)
+		}
+	}
+
+	for _, dialect := range dialects {
+		for _, direction := range []string{"up", "down"} {
+			path := filepath.Join(db.MigrationsDir, dialect, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+			content := fmt.Sprintf("-- %s %s migration %04d_%s\n", dialect, direction, next, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				logger.Fatal("Failed to write migration", logger.String("path", path), logger.Any("error", err))
+			}
+			fmt.Println(path)
+		}
+	}
+}
+
+func printMigrations(verb string, migrations []database.Migration) {
+	for _, migration := range migrations {
+		fmt.Printf("%s %04d_%s\n", verb, migration.Version, migration.Name)
+	}
+	if len(migrations) == 0 {
+		fmt.Printf("nothing %s\n", verb)
+	}
+}
+
+func printStatus(statuses []database.MigrationStatus) {
+	out := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, "VERSION\tNAME\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.AppliedAt != nil {
+			appliedAt = status.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(out, "%04d\t%s\t%s\n", status.Version, status.Name, appliedAt)
+	}
+	out.Flush()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/repository"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const usage = `usage: reports <command>
+
+commands:
+  rebuild   empty the report tables and project every account event again`
+
+func main() {
+	if len(os.Args) != 2 || os.Args[1] != "rebuild" {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err := godotenv.Load(); err != nil {
+		logger.Warn("No .env file loaded", logger.Any("error", err))
+	}
+	dbClient, err := database.GetClient()
+	if err != nil {
+		logger.Fatal("Failed to initialize database client", logger.Any("error", err))
+	}
+	defer dbClient.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	projector := service.NewReportProjector(
+		repository.NewReportRepositoryDb(dbClient),
+		repository.NewUnitOfWorkDb(dbClient),
+		repository.NewCustomerRepositoryDb(dbClient),
+	)
+	started := time.Now()
+	projected, appErr := projector.Rebuild(ctx)
+	if appErr != nil {
+		logger.Fatal("Failed to rebuild reports", logger.Int("projected", projected), logger.Any("error", appErr))
+	}
+	fmt.Printf("projected %d account events in %s\n", projected, time.Since(started).Round(time.Millisecond))
+}
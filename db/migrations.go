@@ -0,0 +1,21 @@
+// Package db holds the SQL migrations and the seed fixtures, embedded in the
+// binary.
+package db
+
+import "embed"
+
+// Migrations contains migrations/<dialect>/<version>_<name>.up.sql and the
+// matching .down.sql for every schema version, once per supported database.
+//
+//go:embed migrations/*/*.sql
+var Migrations embed.FS
+
+// MigrationsDir is where `migrate create` writes new migrations, one
+// directory per dialect, relative to the repository root.
+const MigrationsDir = "db/migrations"
+
+// SeedFixtures holds the customers, accounts and users of the initial
+// migration, for the in-memory repositories.
+//
+//go:embed fixtures/seed.json
+var SeedFixtures []byte
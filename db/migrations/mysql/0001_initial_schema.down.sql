@@ -0,0 +1,24 @@
+DROP TRIGGER IF EXISTS `audit_log_no_delete`;
+DROP TRIGGER IF EXISTS `audit_log_no_update`;
+DROP TABLE IF EXISTS `audit_chain_head`;
+DROP TABLE IF EXISTS `audit_log`;
+DROP TABLE IF EXISTS `oauth_authorization_codes`;
+DROP TABLE IF EXISTS `oauth_scope_permissions`;
+DROP TABLE IF EXISTS `oauth_scopes`;
+DROP TABLE IF EXISTS `oauth_clients`;
+DROP TABLE IF EXISTS `api_keys`;
+DROP TABLE IF EXISTS `password_reset_tokens`;
+DROP TABLE IF EXISTS `login_attempts`;
+DROP TABLE IF EXISTS `mfa_recovery_codes`;
+DROP TABLE IF EXISTS `user_mfa`;
+DROP TABLE IF EXISTS `break_glass_events`;
+DROP TABLE IF EXISTS `break_glass_grants`;
+DROP TABLE IF EXISTS `permissions_version`;
+DROP TABLE IF EXISTS `role_permissions`;
+DROP TABLE IF EXISTS `permissions`;
+DROP TABLE IF EXISTS `roles`;
+DROP TABLE IF EXISTS `refresh_token_store`;
+DROP TABLE IF EXISTS `users`;
+DROP TABLE IF EXISTS `transactions`;
+DROP TABLE IF EXISTS `accounts`;
+DROP TABLE IF EXISTS `customers`;
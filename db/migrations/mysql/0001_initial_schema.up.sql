@@ -0,0 +1,305 @@
+CREATE TABLE `customers` (
+  `customer_id` int(11) NOT NULL AUTO_INCREMENT,
+  `name` varchar(100) NOT NULL,
+  `date_of_birth` date NOT NULL,
+  `city` varchar(100) NOT NULL,
+  `zipcode` varchar(10) NOT NULL,
+  `status` tinyint(1) NOT NULL DEFAULT '1',
+  PRIMARY KEY (`customer_id`)
+) ENGINE=InnoDB AUTO_INCREMENT=2006 DEFAULT CHARSET=latin1;
+INSERT INTO `customers` VALUES
+	(2000,'Steve','1978-12-15','Delhi','110075',1),
+	(2001,'Arian','1988-05-21','Newburgh, NY','12550',1),
+	(2002,'Hadley','1988-04-30','Englewood, NJ','07631',1),
+	(2003,'Ben','1988-01-04','Manchester, NH','03102',0),
+	(2004,'Nina','1988-05-14','Clarkston, MI','48348',1),
+	(2005,'Osman','1988-11-08','Hyattsville, MD','20782',0);
+
+CREATE TABLE `accounts` (
+  `account_id` int(11) NOT NULL AUTO_INCREMENT,
+  `customer_id` int(11) NOT NULL,
+  `opening_date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `account_type` varchar(10) NOT NULL,
+  `amount` decimal(10,2) NOT NULL,
+  `status` tinyint(1) NOT NULL DEFAULT '1',
+  PRIMARY KEY (`account_id`),
+  KEY `accounts_FK` (`customer_id`),
+  CONSTRAINT `accounts_FK` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`customer_id`)
+) ENGINE=InnoDB AUTO_INCREMENT=95471 DEFAULT CHARSET=latin1;
+INSERT INTO `accounts` VALUES
+	(95470,2000,'2020-08-22 10:20:06', 'saving', 6823.23, 1),
+	(95471,2002,'2020-08-09 10:27:22', 'checking', 3342.96, 1),
+  (95472,2001,'2020-08-09 10:35:22', 'saving', 7000, 1),
+  (95473,2001,'2020-08-09 10:38:22', 'saving', 5861.86, 1);
+
+CREATE TABLE `transactions` (
+  `transaction_id` int(11) NOT NULL AUTO_INCREMENT,
+  `account_id` int(11) NOT NULL,
+  `amount` decimal(10,2) NOT NULL,
+  `transaction_type` varchar(10) NOT NULL,
+  `transaction_date` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`transaction_id`),
+  KEY `transactions_FK` (`account_id`),
+  CONSTRAINT `transactions_FK` FOREIGN KEY (`account_id`) REFERENCES `accounts` (`account_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `users` (
+  `username` varchar(20) NOT NULL,
+  `password` varchar(64) NOT NULL,
+  `role` varchar(20) NOT NULL,
+  `customer_id` int(11) DEFAULT NULL,
+  `status` varchar(10) NOT NULL DEFAULT 'active',
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `users` (`username`, `password`, `role`, `customer_id`, `created_on`) VALUES
+  ('admin','$2a$10$6FxXFL2JcXGrBhDySd4cQewKKTm2tKfWqp0M5xGnSBOnxKsSrp8jO','admin', NULL, '2020-08-09 10:27:22'),
+  ('2001','$2a$10$6FxXFL2JcXGrBhDySd4cQewKKTm2tKfWqp0M5xGnSBOnxKsSrp8jO','user', 2001, '2020-08-09 10:27:22'),
+  ('2000','$2a$10$6FxXFL2JcXGrBhDySd4cQewKKTm2tKfWqp0M5xGnSBOnxKsSrp8jO','user', 2000, '2020-08-09 10:27:22');
+
+CREATE TABLE `refresh_token_store` (
+    `refresh_token` varchar(300) NOT NULL,
+    `username` varchar(20) NOT NULL DEFAULT '',
+    created_on TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`refresh_token`),
+    KEY `refresh_token_store_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `roles` (
+  `name` varchar(20) NOT NULL,
+  `description` varchar(255) NOT NULL DEFAULT '',
+  `mfa_required` tinyint(1) NOT NULL DEFAULT 0,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `roles` (`name`, `description`) VALUES
+  ('admin', 'Bank staff with access to every customer'),
+  ('user', 'Customer with access to their own data');
+
+CREATE TABLE `permissions` (
+  `name` varchar(50) NOT NULL,
+  `description` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `permissions` VALUES
+  ('GetAllCustomers', 'List customers'),
+  ('GetCustomer', 'View a customer'),
+  ('NewAccount', 'Open an account for a customer'),
+  ('NewTransaction', 'Deposit to or withdraw from an account'),
+  ('GetRolePermissions', 'List the permission names in use'),
+  ('ListRoles', 'List roles'),
+  ('CreateRole', 'Create a role'),
+  ('DeleteRole', 'Delete a role'),
+  ('SetRoleMFA', 'Require MFA for the users of a role'),
+  ('ListRolePermissions', 'List the permissions of a role'),
+  ('AssignPermission', 'Grant a permission to a role'),
+  ('RevokePermission', 'Revoke a permission from a role'),
+  ('ListPermissions', 'List permissions'),
+  ('CreatePermission', 'Create a permission'),
+  ('DeletePermission', 'Delete a permission'),
+  ('ListLockouts', 'List locked out usernames and IPs'),
+  ('ClearLockout', 'Unlock a username or an IP'),
+  ('ListAPIKeys', 'List API keys'),
+  ('CreateAPIKey', 'Issue an API key'),
+  ('RevokeAPIKey', 'Revoke an API key'),
+  ('ListUsers', 'List and search users'),
+  ('GetUser', 'View a user'),
+  ('SetUserRole', 'Change the role of a user'),
+  ('LinkUserCustomer', 'Link a user to a customer'),
+  ('UnlinkUserCustomer', 'Unlink a user from its customer'),
+  ('SetUserStatus', 'Disable or enable a user'),
+  ('ForceLogout', 'Revoke the refresh tokens of a user'),
+  ('Impersonate', 'Act as another user for support'),
+  ('ListAuditEvents', 'Search and export the audit log'),
+  ('VerifyAuditLog', 'Check the audit log hash chain'),
+  ('TestPolicy', 'Dry-run the authorization policy');
+
+-- scope 'all' grants the route on any customer, 'own' only on the caller's customer_id
+CREATE TABLE `role_permissions` (
+  `role_name` varchar(20) NOT NULL,
+  `permission_name` varchar(50) NOT NULL,
+  `scope` varchar(10) NOT NULL DEFAULT 'all',
+  PRIMARY KEY (`role_name`, `permission_name`),
+  KEY `role_permissions_permission_FK` (`permission_name`),
+  CONSTRAINT `role_permissions_role_FK` FOREIGN KEY (`role_name`) REFERENCES `roles` (`name`) ON DELETE CASCADE,
+  CONSTRAINT `role_permissions_permission_FK` FOREIGN KEY (`permission_name`) REFERENCES `permissions` (`name`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `role_permissions` (`role_name`, `permission_name`, `scope`)
+  SELECT 'admin', `name`, 'all' FROM `permissions`;
+INSERT INTO `role_permissions` (`role_name`, `permission_name`, `scope`) VALUES
+  ('user', 'GetCustomer', 'own'),
+  ('user', 'NewTransaction', 'own');
+
+CREATE TABLE `permissions_version` (
+  `id` tinyint NOT NULL,
+  `version` bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `permissions_version` VALUES (1, 0);
+
+CREATE TABLE `break_glass_grants` (
+  `grant_id` int(11) NOT NULL AUTO_INCREMENT,
+  `username` varchar(20) NOT NULL,
+  `reason` varchar(500) NOT NULL,
+  `source_ip` varchar(45) NOT NULL DEFAULT '',
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` datetime NOT NULL,
+  `revoked_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`grant_id`),
+  KEY `break_glass_grants_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `break_glass_events` (
+  `event_id` int(11) NOT NULL AUTO_INCREMENT,
+  `grant_id` int(11) NOT NULL,
+  `event` varchar(20) NOT NULL,
+  `username` varchar(20) NOT NULL,
+  `route_name` varchar(50) NOT NULL DEFAULT '',
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`event_id`),
+  KEY `break_glass_events_FK` (`grant_id`),
+  CONSTRAINT `break_glass_events_FK` FOREIGN KEY (`grant_id`) REFERENCES `break_glass_grants` (`grant_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `user_mfa` (
+  `username` varchar(20) NOT NULL,
+  `secret` varchar(64) NOT NULL,
+  `enabled` tinyint(1) NOT NULL DEFAULT 0,
+  `last_used_step` bigint NOT NULL DEFAULT 0,
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `confirmed_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `mfa_recovery_codes` (
+  `username` varchar(20) NOT NULL,
+  `code_hash` char(64) NOT NULL,
+  `used_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`username`, `code_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `login_attempts` (
+  `scope` varchar(10) NOT NULL,
+  `subject` varchar(64) NOT NULL,
+  `failures` int(11) NOT NULL DEFAULT 0,
+  `first_failure` datetime NOT NULL,
+  `last_failure` datetime NOT NULL,
+  `locked_until` datetime DEFAULT NULL,
+  PRIMARY KEY (`scope`, `subject`),
+  KEY `login_attempts_locked_until` (`locked_until`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `password_reset_tokens` (
+  `token_hash` char(64) NOT NULL,
+  `username` varchar(20) NOT NULL,
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` datetime NOT NULL,
+  `used_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`token_hash`),
+  KEY `password_reset_tokens_username` (`username`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `api_keys` (
+  `key_id` int(11) NOT NULL AUTO_INCREMENT,
+  `prefix` char(8) NOT NULL,
+  `key_hash` char(64) NOT NULL,
+  `name` varchar(100) NOT NULL,
+  `routes` varchar(1000) NOT NULL,
+  `customer_id` int(11) DEFAULT NULL,
+  `created_by` varchar(20) NOT NULL DEFAULT '',
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` datetime DEFAULT NULL,
+  `last_used_on` datetime DEFAULT NULL,
+  `revoked_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`key_id`),
+  UNIQUE KEY `api_keys_prefix` (`prefix`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- grant_types, redirect_uris and scopes are space separated; public clients have no secret_hash
+CREATE TABLE `oauth_clients` (
+  `client_id` varchar(50) NOT NULL,
+  `secret_hash` char(64) DEFAULT NULL,
+  `name` varchar(100) NOT NULL,
+  `grant_types` varchar(200) NOT NULL,
+  `redirect_uris` varchar(1000) NOT NULL DEFAULT '',
+  `scopes` varchar(500) NOT NULL DEFAULT '',
+  `role` varchar(20) NOT NULL DEFAULT '',
+  `customer_id` int(11) DEFAULT NULL,
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`client_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `oauth_clients` (`client_id`, `secret_hash`, `name`, `grant_types`, `redirect_uris`, `scopes`, `role`) VALUES
+  ('banking-web', NULL, 'Online banking', 'authorization_code refresh_token', 'http://localhost:3000/callback', 'openid profile customers.read transactions.write', ''),
+  ('reporting-service', 'c980fa86e43fd26b9bba4f8e752d2a072f3b23730c72c3791eb50878dc3b1075', 'Reporting', 'client_credentials', '', 'customers.read', 'admin');
+
+CREATE TABLE `oauth_scopes` (
+  `name` varchar(50) NOT NULL,
+  `description` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `oauth_scopes` VALUES
+  ('openid', 'Sign in with OpenID Connect'),
+  ('profile', 'Read the linked customer profile from /userinfo'),
+  ('customers.read', 'Read customers'),
+  ('accounts.write', 'Open accounts'),
+  ('transactions.write', 'Make deposits and withdrawals');
+
+CREATE TABLE `oauth_scope_permissions` (
+  `scope_name` varchar(50) NOT NULL,
+  `permission_name` varchar(50) NOT NULL,
+  PRIMARY KEY (`scope_name`, `permission_name`),
+  CONSTRAINT `oauth_scope_permissions_scope_FK` FOREIGN KEY (`scope_name`) REFERENCES `oauth_scopes` (`name`) ON DELETE CASCADE,
+  CONSTRAINT `oauth_scope_permissions_permission_FK` FOREIGN KEY (`permission_name`) REFERENCES `permissions` (`name`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `oauth_scope_permissions` VALUES
+  ('customers.read', 'GetAllCustomers'),
+  ('customers.read', 'GetCustomer'),
+  ('accounts.write', 'NewAccount'),
+  ('transactions.write', 'NewTransaction');
+
+CREATE TABLE `oauth_authorization_codes` (
+  `code_hash` char(64) NOT NULL,
+  `client_id` varchar(50) NOT NULL,
+  `username` varchar(20) NOT NULL,
+  `redirect_uri` varchar(500) NOT NULL,
+  `scope` varchar(500) NOT NULL,
+  `code_challenge` varchar(128) NOT NULL,
+  `nonce` varchar(255) NOT NULL DEFAULT '',
+  `created_on` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` datetime NOT NULL,
+  `used_on` datetime DEFAULT NULL,
+  PRIMARY KEY (`code_hash`),
+  CONSTRAINT `oauth_authorization_codes_client_FK` FOREIGN KEY (`client_id`) REFERENCES `oauth_clients` (`client_id`) ON DELETE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+-- audit_log is append-only: every entry stores the hash of the previous one
+-- and audit_chain_head the hash of the last, so edits and deletions are
+-- detected by GET /admin/audit/verify. The triggers refuse them outright.
+CREATE TABLE `audit_log` (
+  `event_id` bigint NOT NULL AUTO_INCREMENT,
+  `occurred_at` datetime(6) NOT NULL,
+  `actor` varchar(100) NOT NULL DEFAULT '',
+  `action` varchar(100) NOT NULL,
+  `resource` varchar(255) NOT NULL DEFAULT '',
+  `outcome` varchar(10) NOT NULL,
+  `source_ip` varchar(45) NOT NULL DEFAULT '',
+  `request_id` varchar(64) NOT NULL DEFAULT '',
+  `details` varchar(1000) NOT NULL DEFAULT '',
+  `prev_hash` char(64) NOT NULL DEFAULT '',
+  `hash` char(64) NOT NULL,
+  PRIMARY KEY (`event_id`),
+  KEY `audit_log_actor` (`actor`, `occurred_at`),
+  KEY `audit_log_action` (`action`, `occurred_at`),
+  KEY `audit_log_occurred_at` (`occurred_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `audit_chain_head` (
+  `id` tinyint NOT NULL,
+  `last_hash` char(64) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+INSERT INTO `audit_chain_head` VALUES (1, '');
+
+CREATE TRIGGER `audit_log_no_update` BEFORE UPDATE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+CREATE TRIGGER `audit_log_no_delete` BEFORE DELETE ON `audit_log`
+FOR EACH ROW SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
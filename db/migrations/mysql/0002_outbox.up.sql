@@ -0,0 +1,17 @@
+-- outbox_events holds the domain events written in the same transaction as
+-- the change they describe, until the relay has published them.
+CREATE TABLE `outbox_events` (
+  `event_id` bigint NOT NULL AUTO_INCREMENT,
+  `aggregate_type` varchar(50) NOT NULL,
+  `aggregate_id` varchar(100) NOT NULL,
+  `event_type` varchar(100) NOT NULL,
+  `payload` text NOT NULL,
+  `occurred_at` datetime(6) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `next_attempt_at` datetime(6) NOT NULL,
+  `last_error` varchar(1000) NOT NULL DEFAULT '',
+  `published_at` datetime(6) DEFAULT NULL,
+  PRIMARY KEY (`event_id`),
+  KEY `outbox_events_aggregate` (`aggregate_type`, `aggregate_id`, `published_at`, `event_id`),
+  KEY `outbox_events_published_at` (`published_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
@@ -0,0 +1,4 @@
+DELETE FROM `permissions` WHERE `name` IN ('FreezeAccount', 'CloseAccount', 'ListAccountEvents');
+UPDATE `permissions_version` SET `version` = `version` + 1 WHERE `id` = 1;
+DROP TABLE IF EXISTS `account_snapshots`;
+DROP TABLE IF EXISTS `account_events`;
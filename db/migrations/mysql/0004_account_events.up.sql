@@ -0,0 +1,33 @@
+-- account_events is the history of every account and its source of truth;
+-- accounts and transactions are kept as its read model. Events are only
+-- ever inserted, and the unique key on (account_id, version) rejects a
+-- second writer appending the same version. account_snapshots holds the
+-- latest state of an account every few events, to shorten replays.
+CREATE TABLE `account_events` (
+  `sequence` bigint NOT NULL AUTO_INCREMENT,
+  `account_id` int(11) NOT NULL,
+  `version` int NOT NULL,
+  `event_type` varchar(20) NOT NULL,
+  `data` text NOT NULL,
+  `occurred_at` datetime(6) NOT NULL,
+  PRIMARY KEY (`sequence`),
+  UNIQUE KEY `account_events_version` (`account_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+CREATE TABLE `account_snapshots` (
+  `account_id` int(11) NOT NULL,
+  `version` int NOT NULL,
+  `state` text NOT NULL,
+  `taken_on` datetime(6) NOT NULL,
+  PRIMARY KEY (`account_id`, `version`)
+) ENGINE=InnoDB DEFAULT CHARSET=latin1;
+
+INSERT INTO `permissions` VALUES
+  ('FreezeAccount', 'Stop the transactions of an account'),
+  ('CloseAccount', 'Close an account'),
+  ('ListAccountEvents', 'View the history of an account');
+INSERT INTO `role_permissions` (`role_name`, `permission_name`, `scope`) VALUES
+  ('admin', 'FreezeAccount', 'all'),
+  ('admin', 'CloseAccount', 'all'),
+  ('admin', 'ListAccountEvents', 'all');
+UPDATE `permissions_version` SET `version` = `version` + 1 WHERE `id` = 1;
@@ -0,0 +1,17 @@
+-- outbox_events holds the domain events written in the same transaction as
+-- the change they describe, until the relay has published them.
+CREATE TABLE outbox_events (
+  event_id bigserial NOT NULL,
+  aggregate_type varchar(50) NOT NULL,
+  aggregate_id varchar(100) NOT NULL,
+  event_type varchar(100) NOT NULL,
+  payload text NOT NULL,
+  occurred_at timestamp(6) NOT NULL,
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamp(6) NOT NULL,
+  last_error varchar(1000) NOT NULL DEFAULT '',
+  published_at timestamp(6) DEFAULT NULL,
+  PRIMARY KEY (event_id)
+);
+CREATE INDEX outbox_events_aggregate ON outbox_events (aggregate_type, aggregate_id, published_at, event_id);
+CREATE INDEX outbox_events_published_at ON outbox_events (published_at);
@@ -0,0 +1,64 @@
+-- A webhook subscription receives the domain events matching its event_types
+-- (comma separated, empty for all), only those of its customer when
+-- customer_id is set. webhook_deliveries is the queue and the log of the
+-- requests: one row per subscription and event.
+CREATE TABLE webhook_subscriptions (
+  subscription_id serial NOT NULL,
+  url varchar(2000) NOT NULL,
+  secret varchar(255) NOT NULL,
+  event_types varchar(1000) NOT NULL DEFAULT '',
+  customer_id integer DEFAULT NULL,
+  created_by varchar(20) NOT NULL DEFAULT '',
+  created_on timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (subscription_id)
+);
+CREATE INDEX webhook_subscriptions_customer ON webhook_subscriptions (customer_id);
+
+CREATE TABLE webhook_deliveries (
+  delivery_id bigserial NOT NULL,
+  subscription_id integer NOT NULL,
+  event_id bigint NOT NULL,
+  event_type varchar(100) NOT NULL,
+  payload text NOT NULL,
+  status varchar(10) NOT NULL DEFAULT 'pending',
+  attempts integer NOT NULL DEFAULT 0,
+  next_attempt_at timestamp(6) NOT NULL,
+  last_attempt_at timestamp(6) DEFAULT NULL,
+  last_status_code integer NOT NULL DEFAULT 0,
+  last_error varchar(1000) NOT NULL DEFAULT '',
+  created_on timestamp(6) NOT NULL,
+  delivered_on timestamp(6) DEFAULT NULL,
+  PRIMARY KEY (delivery_id),
+  CONSTRAINT webhook_deliveries_event UNIQUE (subscription_id, event_id),
+  CONSTRAINT webhook_deliveries_subscription_FK FOREIGN KEY (subscription_id) REFERENCES webhook_subscriptions (subscription_id) ON DELETE CASCADE
+);
+CREATE INDEX webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+
+INSERT INTO permissions VALUES
+  ('ListWebhooks', 'List webhook subscriptions'),
+  ('CreateWebhook', 'Subscribe a URL to events'),
+  ('DeleteWebhook', 'Delete a webhook subscription'),
+  ('ListWebhookDeliveries', 'View the delivery log of a webhook'),
+  ('RedeliverWebhook', 'Send a webhook delivery again'),
+  ('ListCustomerWebhooks', 'List a customer''s webhook subscriptions'),
+  ('CreateCustomerWebhook', 'Subscribe a URL to a customer''s events'),
+  ('DeleteCustomerWebhook', 'Delete a customer''s webhook subscription'),
+  ('ListCustomerWebhookDeliveries', 'View the delivery log of a customer''s webhook'),
+  ('RedeliverCustomerWebhook', 'Send a customer''s webhook delivery again');
+INSERT INTO role_permissions (role_name, permission_name, scope) VALUES
+  ('admin', 'ListWebhooks', 'all'),
+  ('admin', 'CreateWebhook', 'all'),
+  ('admin', 'DeleteWebhook', 'all'),
+  ('admin', 'ListWebhookDeliveries', 'all'),
+  ('admin', 'RedeliverWebhook', 'all'),
+  ('admin', 'ListCustomerWebhooks', 'all'),
+  ('admin', 'CreateCustomerWebhook', 'all'),
+  ('admin', 'DeleteCustomerWebhook', 'all'),
+  ('admin', 'ListCustomerWebhookDeliveries', 'all'),
+  ('admin', 'RedeliverCustomerWebhook', 'all'),
+  ('user', 'ListCustomerWebhooks', 'own'),
+  ('user', 'CreateCustomerWebhook', 'own'),
+  ('user', 'DeleteCustomerWebhook', 'own'),
+  ('user', 'ListCustomerWebhookDeliveries', 'own'),
+  ('user', 'RedeliverCustomerWebhook', 'own');
+UPDATE permissions_version SET version = version + 1 WHERE id = 1;
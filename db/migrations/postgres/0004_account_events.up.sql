@@ -0,0 +1,33 @@
+-- account_events is the history of every account and its source of truth;
+-- accounts and transactions are kept as its read model. Events are only
+-- ever inserted, and the unique key on (account_id, version) rejects a
+-- second writer appending the same version. account_snapshots holds the
+-- latest state of an account every few events, to shorten replays.
+CREATE TABLE account_events (
+  sequence bigserial NOT NULL,
+  account_id integer NOT NULL,
+  version integer NOT NULL,
+  event_type varchar(20) NOT NULL,
+  data text NOT NULL,
+  occurred_at timestamp(6) NOT NULL,
+  PRIMARY KEY (sequence),
+  CONSTRAINT account_events_version UNIQUE (account_id, version)
+);
+
+CREATE TABLE account_snapshots (
+  account_id integer NOT NULL,
+  version integer NOT NULL,
+  state text NOT NULL,
+  taken_on timestamp(6) NOT NULL,
+  PRIMARY KEY (account_id, version)
+);
+
+INSERT INTO permissions VALUES
+  ('FreezeAccount', 'Stop the transactions of an account'),
+  ('CloseAccount', 'Close an account'),
+  ('ListAccountEvents', 'View the history of an account');
+INSERT INTO role_permissions (role_name, permission_name, scope) VALUES
+  ('admin', 'FreezeAccount', 'all'),
+  ('admin', 'CloseAccount', 'all'),
+  ('admin', 'ListAccountEvents', 'all');
+UPDATE permissions_version SET version = version + 1 WHERE id = 1;
@@ -0,0 +1,4 @@
+DELETE FROM permissions WHERE name IN ('ListWebhooks', 'CreateWebhook', 'DeleteWebhook', 'ListWebhookDeliveries', 'RedeliverWebhook', 'ListCustomerWebhooks', 'CreateCustomerWebhook', 'DeleteCustomerWebhook', 'ListCustomerWebhookDeliveries', 'RedeliverCustomerWebhook');
+UPDATE permissions_version SET version = version + 1 WHERE id = 1;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
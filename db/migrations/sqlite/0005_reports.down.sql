@@ -0,0 +1,7 @@
+DELETE FROM permissions WHERE name IN ('GetCityReport', 'GetAccountTypeReport', 'GetCustomerReport', 'GetAccountDailyReport');
+UPDATE permissions_version SET version = version + 1 WHERE id = 1;
+DROP TABLE IF EXISTS report_checkpoints;
+DROP TABLE IF EXISTS report_type_volumes;
+DROP TABLE IF EXISTS report_customer_totals;
+DROP TABLE IF EXISTS report_daily_balances;
+DROP TABLE IF EXISTS report_accounts;
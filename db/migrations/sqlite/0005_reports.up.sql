@@ -0,0 +1,68 @@
+-- The report tables are a read model projected from account_events, so that
+-- reports do not aggregate customers, accounts and transactions on every
+-- request. report_accounts is the state of each account the projection
+-- starts a batch from; the other tables hold the sums reports read.
+-- report_checkpoints records the sequence of the last event projected.
+-- Days are UTC dates formatted as YYYY-MM-DD.
+CREATE TABLE report_accounts (
+  account_id integer NOT NULL,
+  customer_id integer NOT NULL,
+  account_type varchar(10) NOT NULL,
+  city varchar(100) NOT NULL,
+  balance decimal(14,2) NOT NULL,
+  status integer NOT NULL,
+  PRIMARY KEY (account_id)
+);
+CREATE INDEX report_accounts_type ON report_accounts (account_type);
+
+CREATE TABLE report_daily_balances (
+  account_id integer NOT NULL,
+  day char(10) NOT NULL,
+  closing_balance decimal(14,2) NOT NULL,
+  deposits decimal(14,2) NOT NULL DEFAULT 0,
+  withdrawals decimal(14,2) NOT NULL DEFAULT 0,
+  transactions integer NOT NULL DEFAULT 0,
+  PRIMARY KEY (account_id, day)
+);
+
+CREATE TABLE report_customer_totals (
+  customer_id integer NOT NULL,
+  city varchar(100) NOT NULL,
+  accounts integer NOT NULL DEFAULT 0,
+  balance decimal(14,2) NOT NULL DEFAULT 0,
+  deposits decimal(14,2) NOT NULL DEFAULT 0,
+  withdrawals decimal(14,2) NOT NULL DEFAULT 0,
+  transactions integer NOT NULL DEFAULT 0,
+  PRIMARY KEY (customer_id)
+);
+CREATE INDEX report_customer_totals_city ON report_customer_totals (city);
+
+CREATE TABLE report_type_volumes (
+  account_type varchar(10) NOT NULL,
+  day char(10) NOT NULL,
+  accounts_opened integer NOT NULL DEFAULT 0,
+  deposits decimal(14,2) NOT NULL DEFAULT 0,
+  deposit_count integer NOT NULL DEFAULT 0,
+  withdrawals decimal(14,2) NOT NULL DEFAULT 0,
+  withdrawal_count integer NOT NULL DEFAULT 0,
+  PRIMARY KEY (account_type, day)
+);
+
+CREATE TABLE report_checkpoints (
+  projection varchar(50) NOT NULL,
+  last_sequence bigint NOT NULL DEFAULT 0,
+  PRIMARY KEY (projection)
+);
+INSERT INTO report_checkpoints VALUES ('reports', 0);
+
+INSERT INTO permissions VALUES
+  ('GetCityReport', 'View the totals of each city'),
+  ('GetAccountTypeReport', 'View the totals of each account type'),
+  ('GetCustomerReport', 'View the totals of a customer'),
+  ('GetAccountDailyReport', 'View the daily balances of an account');
+INSERT INTO role_permissions (role_name, permission_name, scope) VALUES
+  ('admin', 'GetCityReport', 'all'),
+  ('admin', 'GetAccountTypeReport', 'all'),
+  ('admin', 'GetCustomerReport', 'all'),
+  ('admin', 'GetAccountDailyReport', 'all');
+UPDATE permissions_version SET version = version + 1 WHERE id = 1;
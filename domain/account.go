@@ -7,12 +7,12 @@ import (
 )
 
 type Account struct {
-	AccountID   string  `json:"account_id"`
-	CustomerID  string  `json:"customer_id"`
-	OpeningDate string  `json:"opening_date"`
-	AccountType string  `json:"account_type"`
-	Amount      float64 `json:"amount"`
-	Status      string  `json:"status"`
+	AccountID   string  `db:"account_id" json:"account_id"`
+	CustomerID  string  `db:"customer_id" json:"customer_id"`
+	OpeningDate string  `db:"opening_date" json:"opening_date"`
+	AccountType string  `db:"account_type" json:"account_type"`
+	Amount      float64 `db:"amount" json:"amount"`
+	Status      string  `db:"status" json:"status"`
 }
 
 func NewAccount(customerID, accountType string, amount float64) Account {
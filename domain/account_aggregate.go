@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// AccountAggregate is an account rebuilt from its events. Commands check
+// the state, then record an event and apply it; the recorded events are
+// the changes to append to the event store, which must still be at the
+// version the aggregate was loaded at. Rule violations are validation
+// errors, so a 409 conflict only ever means a lost append race.
+type AccountAggregate struct {
+	Account Account
+	Version int64
+	changes []AccountEvent
+}
+
+// OpenAccount starts the history of a new account, whose id the accounts
+// table has already allocated.
+func OpenAccount(account Account, at time.Time) (*AccountAggregate, *errs.AppError) {
+	a := &AccountAggregate{}
+	if err := a.record(account.AccountID, AccountEventOpened, AccountOpenedData{
+		CustomerID:  account.CustomerID,
+		AccountType: account.AccountType,
+		Amount:      account.Amount,
+		OpeningDate: account.OpeningDate,
+	}, at); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ImportAccount starts the history of an account that was written before
+// accounts were event sourced: it is opened with its current balance and
+// given its current status.
+func ImportAccount(account Account, at time.Time) (*AccountAggregate, *errs.AppError) {
+	a, err := OpenAccount(account, at)
+	if err != nil {
+		return nil, err
+	}
+	switch account.Status {
+	case AccountStatusFrozen:
+		err = a.record(account.AccountID, AccountEventFrozen, AccountStatusData{Reason: "imported"}, at)
+	case AccountStatusClosed:
+		err = a.record(account.AccountID, AccountEventClosed, AccountStatusData{Reason: "imported"}, at)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// ReplayAccount rebuilds an account from a snapshot, nil to start from
+// scratch, and the events that follow it.
+func ReplayAccount(snapshot *AccountSnapshot, events []AccountEvent) (*AccountAggregate, error) {
+	a := &AccountAggregate{}
+	if snapshot != nil {
+		if err := json.Unmarshal([]byte(snapshot.State), &a.Account); err != nil {
+			return nil, fmt.Errorf("decoding snapshot %d of account %s: %w", snapshot.Version, snapshot.AccountID, err)
+		}
+		a.Version = snapshot.Version
+	}
+	for _, e := range events {
+		if err := a.apply(e); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// CheckPost tells whether a transaction can be posted: only active accounts
+// take transactions, and a withdrawal cannot exceed the balance.
+func (a *AccountAggregate) CheckPost(transactionType string, amount float64) *errs.AppError {
+	if err := a.mustBeActive(); err != nil {
+		return err
+	}
+	if transactionType == Withdrawal && !a.Account.CanWithdraw(amount) {
+		return errs.NewValidationError("Insufficient balance for withdrawal")
+	}
+	return nil
+}
+
+// Post records a deposit or a withdrawal of amount, already given an id by
+// the transactions table.
+func (a *AccountAggregate) Post(t Transaction, amount float64, at time.Time) *errs.AppError {
+	if err := a.CheckPost(t.TransactionType, amount); err != nil {
+		return err
+	}
+	eventType := AccountEventDeposited
+	if t.IsWithdrawal() {
+		eventType = AccountEventWithdrawn
+	}
+	return a.record(a.Account.AccountID, eventType, AccountPostedData{
+		TransactionID:   t.TransactionID,
+		Amount:          amount,
+		TransactionDate: t.TransactionDate,
+	}, at)
+}
+
+// Freeze stops the transactions of an active account. A frozen account can
+// still be closed.
+func (a *AccountAggregate) Freeze(reason, actor string, at time.Time) *errs.AppError {
+	if err := a.mustBeActive(); err != nil {
+		return err
+	}
+	return a.record(a.Account.AccountID, AccountEventFrozen, AccountStatusData{Reason: reason, Actor: actor}, at)
+}
+
+// Close ends the account for good. Its balance must have been withdrawn.
+func (a *AccountAggregate) Close(reason, actor string, at time.Time) *errs.AppError {
+	if a.Account.Status == AccountStatusClosed {
+		return errs.NewValidationError("Account is already closed")
+	}
+	if a.Account.Amount != 0 {
+		return errs.NewValidationError("Account balance must be zero to close it")
+	}
+	return a.record(a.Account.AccountID, AccountEventClosed, AccountStatusData{Reason: reason, Actor: actor}, at)
+}
+
+// Changes returns the events recorded since the aggregate was loaded.
+func (a *AccountAggregate) Changes() []AccountEvent {
+	return a.changes
+}
+
+// LoadedVersion is the version of the account before the recorded changes,
+// which the event store must still be at to append them.
+func (a *AccountAggregate) LoadedVersion() int64 {
+	return a.Version - int64(len(a.changes))
+}
+
+func (a *AccountAggregate) Snapshot(at time.Time) (AccountSnapshot, error) {
+	state, err := json.Marshal(a.Account)
+	if err != nil {
+		return AccountSnapshot{}, fmt.Errorf("encoding account %s: %w", a.Account.AccountID, err)
+	}
+	return AccountSnapshot{AccountID: a.Account.AccountID, Version: a.Version, State: string(state), TakenOn: at}, nil
+}
+
+func (a *AccountAggregate) mustBeActive() *errs.AppError {
+	switch a.Account.Status {
+	case AccountStatusFrozen:
+		return errs.NewValidationError("Account is frozen")
+	case AccountStatusClosed:
+		return errs.NewValidationError("Account is closed")
+	}
+	return nil
+}
+
+func (a *AccountAggregate) record(accountID, eventType string, data interface{}, at time.Time) *errs.AppError {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return errs.NewUnexpectedError("Error encoding " + eventType + " event")
+	}
+	e := AccountEvent{
+		AccountID:  accountID,
+		Version:    a.Version + 1,
+		Type:       eventType,
+		Data:       string(encoded),
+		OccurredAt: at,
+	}
+	if err := a.apply(e); err != nil {
+		return errs.NewUnexpectedError(err.Error())
+	}
+	a.changes = append(a.changes, e)
+	return nil
+}
+
+// apply moves the state to the one after e, which must be the next event.
+func (a *AccountAggregate) apply(e AccountEvent) error {
+	if e.Version != a.Version+1 {
+		return fmt.Errorf("event %d of account %s follows version %d", e.Version, e.AccountID, a.Version)
+	}
+	switch e.Type {
+	case AccountEventOpened:
+		var data AccountOpenedData
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			return fmt.Errorf("decoding %s event %d of account %s: %w", e.Type, e.Version, e.AccountID, err)
+		}
+		a.Account = Account{
+			AccountID:   e.AccountID,
+			CustomerID:  data.CustomerID,
+			OpeningDate: data.OpeningDate,
+			AccountType: data.AccountType,
+			Amount:      data.Amount,
+			Status:      AccountStatusActive,
+		}
+	case AccountEventDeposited, AccountEventWithdrawn:
+		var data AccountPostedData
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			return fmt.Errorf("decoding %s event %d of account %s: %w", e.Type, e.Version, e.AccountID, err)
+		}
+		if e.Type == AccountEventWithdrawn {
+			data.Amount = -data.Amount
+		}
+		// Balances are stored with two decimals; rounding each step keeps
+		// a long replay from drifting off the stored balance.
+		a.Account.Amount = math.Round((a.Account.Amount+data.Amount)*100) / 100
+	case AccountEventFrozen:
+		a.Account.Status = AccountStatusFrozen
+	case AccountEventClosed:
+		a.Account.Status = AccountStatusClosed
+	default:
+		return fmt.Errorf("unknown event %s of account %s", e.Type, e.AccountID)
+	}
+	a.Version = e.Version
+	return nil
+}
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"time"
+)
+
+// The events an account's history is made of, in the event store.
+const (
+	AccountEventOpened    = "Opened"
+	AccountEventDeposited = "Deposited"
+	AccountEventWithdrawn = "Withdrawn"
+	AccountEventFrozen    = "Frozen"
+	AccountEventClosed    = "Closed"
+)
+
+// Account statuses, as stored in the status column of accounts.
+const (
+	AccountStatusClosed = "0"
+	AccountStatusActive = "1"
+	AccountStatusFrozen = "2"
+)
+
+// AccountEvent is one entry of an account's history. Version numbers the
+// events of an account from 1 without gaps; Sequence orders the events of
+// all accounts. Data is the JSON of the event's data struct.
+type AccountEvent struct {
+	Sequence   int64     `db:"sequence"`
+	AccountID  string    `db:"account_id"`
+	Version    int64     `db:"version"`
+	Type       string    `db:"event_type"`
+	Data       string    `db:"data"`
+	OccurredAt time.Time `db:"occurred_at"`
+}
+
+// AccountSnapshot is the state of an account after Version events, so a
+// replay can start from it instead of from the first event. State is the
+// JSON of the Account.
+type AccountSnapshot struct {
+	AccountID string    `db:"account_id"`
+	Version   int64     `db:"version"`
+	State     string    `db:"state"`
+	TakenOn   time.Time `db:"taken_on"`
+}
+
+type AccountOpenedData struct {
+	CustomerID  string  `json:"customer_id"`
+	AccountType string  `json:"account_type"`
+	Amount      float64 `json:"amount"`
+	OpeningDate string  `json:"opening_date"`
+}
+
+// AccountPostedData is the data of Deposited and Withdrawn.
+type AccountPostedData struct {
+	TransactionID   string  `json:"transaction_id"`
+	Amount          float64 `json:"amount"`
+	TransactionDate string  `json:"transaction_date"`
+}
+
+// AccountStatusData is the data of Frozen and Closed.
+type AccountStatusData struct {
+	Reason string `json:"reason,omitempty"`
+	Actor  string `json:"actor,omitempty"`
+}
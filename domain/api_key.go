@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so a leaked key is easy to recognize.
+const APIKeyPrefix = "bk_"
+
+// APIKey lets a machine client call the routes it is scoped to, optionally
+// only for one customer. Only the hash of the secret is stored; Prefix is the
+// public part of the key used to look it up and to identify it in listings.
+type APIKey struct {
+	ID         string     `db:"key_id"`
+	Prefix     string     `db:"prefix"`
+	Hash       string     `db:"key_hash"`
+	Name       string     `db:"name"`
+	Routes     string     `db:"routes"`
+	CustomerID *string    `db:"customer_id"`
+	CreatedBy  string     `db:"created_by"`
+	CreatedOn  time.Time  `db:"created_on"`
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedOn *time.Time `db:"last_used_on"`
+	RevokedOn  *time.Time `db:"revoked_on"`
+}
+
+func (k APIKey) IsActive(now time.Time) bool {
+	if k.RevokedOn != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// RouteNames returns the route names the key is scoped to.
+func (k APIKey) RouteNames() []string {
+	routes := make([]string, 0)
+	for _, route := range strings.Split(k.Routes, ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func (k APIKey) AllowsRoute(routeName string) bool {
+	for _, route := range k.RouteNames() {
+		if route == routeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+	AuditOutcomeDenied  = "denied"
+)
+
+// AuditEvent is an entry of the append-only audit log. Each entry stores the
+// hash of the previous one, so editing or deleting an entry breaks the chain.
+type AuditEvent struct {
+	ID         int64     `db:"event_id"`
+	OccurredAt time.Time `db:"occurred_at"`
+	Actor      string    `db:"actor"`
+	Action     string    `db:"action"`
+	Resource   string    `db:"resource"`
+	Outcome    string    `db:"outcome"`
+	SourceIP   string    `db:"source_ip"`
+	RequestID  string    `db:"request_id"`
+	Details    string    `db:"details"`
+	PrevHash   string    `db:"prev_hash"`
+	Hash       string    `db:"hash"`
+}
+
+// ComputeHash hashes the previous hash together with every field but the id,
+// which is assigned by the database.
+func (e AuditEvent) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		e.OccurredAt.UTC().Format(time.RFC3339Nano),
+		e.Actor,
+		e.Action,
+		e.Resource,
+		e.Outcome,
+		e.SourceIP,
+		e.RequestID,
+		e.Details,
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditFilter selects audit events; empty fields do not filter.
+type AuditFilter struct {
+	Actor    string
+	Action   string
+	Resource string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	Limit    int
+	Offset   int
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+const (
+	BreakGlassGranted = "granted"
+	BreakGlassUsed    = "used"
+	BreakGlassDenied  = "denied"
+)
+
+// BreakGlassGrant is a time-boxed elevation that lets a user reach every route
+// while the normal role permissions cannot be relied on.
+type BreakGlassGrant struct {
+	ID        string     `db:"grant_id" json:"grant_id"`
+	Username  string     `db:"username" json:"username"`
+	Reason    string     `db:"reason" json:"reason"`
+	SourceIP  string     `db:"source_ip" json:"source_ip"`
+	CreatedOn time.Time  `db:"created_on" json:"created_on"`
+	ExpiresAt time.Time  `db:"expires_at" json:"expires_at"`
+	RevokedOn *time.Time `db:"revoked_on" json:"revoked_on,omitempty"`
+}
+
+func (g BreakGlassGrant) IsActive(now time.Time) bool {
+	return g.RevokedOn == nil && now.Before(g.ExpiresAt)
+}
+
+// BreakGlassEvent is an entry of the break-glass audit trail.
+type BreakGlassEvent struct {
+	GrantID   string    `db:"grant_id" json:"grant_id"`
+	Event     string    `db:"event" json:"event"`
+	Username  string    `db:"username" json:"username"`
+	RouteName string    `db:"route_name" json:"route_name"`
+	CreatedOn time.Time `db:"created_on" json:"created_on"`
+}
@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	AggregateAccount = "account"
+	AggregateUser    = "user"
+)
+
+const (
+	EventAccountOpened     = "AccountOpened"
+	EventTransactionPosted = "TransactionPosted"
+	EventAccountFrozen     = "AccountFrozen"
+	EventAccountClosed     = "AccountClosed"
+	EventUserRegistered    = "UserRegistered"
+	EventUserStatusChanged = "UserStatusChanged"
+)
+
+// Event is a domain event: something that happened to one aggregate, an
+// account or a user. Events of the same aggregate are published in the order
+// they were recorded.
+type Event struct {
+	ID            int64           `db:"event_id" json:"id"`
+	AggregateType string          `db:"aggregate_type" json:"aggregate_type"`
+	AggregateID   string          `db:"aggregate_id" json:"aggregate_id"`
+	Type          string          `db:"event_type" json:"type"`
+	Payload       json.RawMessage `db:"payload" json:"payload"`
+	OccurredAt    time.Time       `db:"occurred_at" json:"occurred_at"`
+}
+
+func NewEvent(aggregateType, aggregateID, eventType string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("encoding %s payload: %w", eventType, err)
+	}
+	return Event{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Type:          eventType,
+		Payload:       data,
+		OccurredAt:    time.Now().UTC(),
+	}, nil
+}
+
+// AggregateKey identifies the aggregate of the event across aggregate types.
+func (e Event) AggregateKey() string {
+	return e.AggregateType + "/" + e.AggregateID
+}
+
+// OutboxEntry is an event in the outbox with the state of its delivery.
+type OutboxEntry struct {
+	Event
+	Attempts      int        `db:"attempts"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	LastError     string     `db:"last_error"`
+	PublishedAt   *time.Time `db:"published_at"`
+}
+
+type AccountOpenedPayload struct {
+	AccountID   string  `json:"account_id"`
+	CustomerID  string  `json:"customer_id"`
+	AccountType string  `json:"account_type"`
+	Amount      float64 `json:"amount"`
+	OpeningDate string  `json:"opening_date"`
+}
+
+type TransactionPostedPayload struct {
+	TransactionID   string  `json:"transaction_id"`
+	AccountID       string  `json:"account_id"`
+	CustomerID      string  `json:"customer_id"`
+	TransactionType string  `json:"transaction_type"`
+	Amount          float64 `json:"amount"`
+	Balance         float64 `json:"balance"`
+	TransactionDate string  `json:"transaction_date"`
+}
+
+// AccountStatusPayload is the payload of AccountFrozen and AccountClosed.
+type AccountStatusPayload struct {
+	AccountID  string `json:"account_id"`
+	CustomerID string `json:"customer_id"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type UserRegisteredPayload struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	CustomerID string `json:"customer_id,omitempty"`
+}
+
+type UserStatusChangedPayload struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+// LoginAttempt counts the recent failed logins of a username or a source IP.
+type LoginAttempt struct {
+	Scope        string     `db:"scope" json:"scope"`
+	Subject      string     `db:"subject" json:"subject"`
+	Failures     int        `db:"failures" json:"failures"`
+	FirstFailure time.Time  `db:"first_failure" json:"first_failure"`
+	LastFailure  time.Time  `db:"last_failure" json:"last_failure"`
+	LockedUntil  *time.Time `db:"locked_until" json:"locked_until,omitempty"`
+}
+
+func (a LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
+
+func IsValidLoginScope(scope string) bool {
+	return scope == LoginScopeUsername || scope == LoginScopeIP
+}
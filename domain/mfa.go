@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// MFAEnrollment is the TOTP secret of a user. It stays pending until the user
+// proves they can generate codes with it.
+type MFAEnrollment struct {
+	Username     string     `db:"username"`
+	Secret       string     `db:"secret"`
+	Enabled      bool       `db:"enabled"`
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedOn    time.Time  `db:"created_on"`
+	ConfirmedOn  *time.Time `db:"confirmed_on"`
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	GrantPassword          = "password"
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+	GrantAuthorizationCode = "authorization_code"
+)
+
+// OAuthClient is an application registered to request tokens at /oauth/token.
+// Public clients have no secret and must use PKCE; GrantTypes, RedirectURIs and
+// Scopes are stored as space separated lists. Role and CustomerID describe the
+// client itself in the client_credentials grant.
+type OAuthClient struct {
+	ClientID     string    `db:"client_id"`
+	SecretHash   *string   `db:"secret_hash"`
+	Name         string    `db:"name"`
+	GrantTypes   string    `db:"grant_types"`
+	RedirectURIs string    `db:"redirect_uris"`
+	Scopes       string    `db:"scopes"`
+	Role         string    `db:"role"`
+	CustomerID   *string   `db:"customer_id"`
+	CreatedOn    time.Time `db:"created_on"`
+}
+
+func (c OAuthClient) IsConfidential() bool {
+	return c.SecretHash != nil && *c.SecretHash != ""
+}
+
+func (c OAuthClient) AllowsGrant(grantType string) bool {
+	return containsField(c.GrantTypes, grantType)
+}
+
+func (c OAuthClient) AllowsRedirectURI(uri string) bool {
+	return containsField(c.RedirectURIs, uri)
+}
+
+// AllowsScopes reports whether every requested scope was granted to the client.
+func (c OAuthClient) AllowsScopes(scopes []string) bool {
+	for _, scope := range scopes {
+		if !containsField(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// OAuthScopeRoute maps an OAuth scope onto one of the route permissions.
+type OAuthScopeRoute struct {
+	Scope          string `db:"scope_name"`
+	PermissionName string `db:"permission_name"`
+}
+
+// AuthorizationCode is issued by /oauth/authorize and exchanged once at
+// /oauth/token together with the PKCE verifier. Only its hash is stored.
+type AuthorizationCode struct {
+	CodeHash      string     `db:"code_hash"`
+	ClientID      string     `db:"client_id"`
+	Username      string     `db:"username"`
+	RedirectURI   string     `db:"redirect_uri"`
+	Scope         string     `db:"scope"`
+	CodeChallenge string     `db:"code_challenge"`
+	Nonce         string     `db:"nonce"`
+	CreatedOn     time.Time  `db:"created_on"`
+	ExpiresAt     time.Time  `db:"expires_at"`
+	UsedOn        *time.Time `db:"used_on"`
+}
+
+func containsField(list, value string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// PasswordPolicy lists the rules a new password must follow.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+func (p PasswordPolicy) Validate(username, password string) *errs.AppError {
+	length := len([]rune(password))
+	if length < p.MinLength {
+		return errs.NewValidationError("Password must have at least " + strconv.Itoa(p.MinLength) + " characters")
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return errs.NewValidationError("Password must have at most " + strconv.Itoa(p.MaxLength) + " characters")
+	}
+	if username != "" && strings.EqualFold(password, username) {
+		return errs.NewValidationError("Password must differ from the username")
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	missing := make([]string, 0, 4)
+	if p.RequireUpper && !upper {
+		missing = append(missing, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		missing = append(missing, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		missing = append(missing, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		missing = append(missing, "a symbol")
+	}
+	if len(missing) > 0 {
+		return errs.NewValidationError("Password must contain " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// PasswordResetToken is a single-use token sent to a user who forgot their
+// password. Only the hash of the token is stored.
+type PasswordResetToken struct {
+	TokenHash string     `db:"token_hash"`
+	Username  string     `db:"username"`
+	CreatedOn time.Time  `db:"created_on"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedOn    *time.Time `db:"used_on"`
+}
+
+// Notification is a message for a user, delivered by a ports.Notifier.
+type Notification struct {
+	Username string            `json:"username"`
+	Subject  string            `json:"subject"`
+	Body     string            `json:"body"`
+	Data     map[string]string `json:"data,omitempty"`
+}
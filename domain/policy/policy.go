@@ -0,0 +1,289 @@
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+//go:embed default_policy.json
+var defaultPolicy []byte
+
+// Policy is a set of attribute-based rules evaluated in-process. A matching
+// deny rule always wins; otherwise the first matching allow rule allows the
+// request, and when nothing matches the request is denied.
+type Policy struct {
+	DefaultReason string `json:"default_reason"`
+	Rules         []Rule `json:"rules"`
+}
+
+type Rule struct {
+	ID          string      `json:"id"`
+	Effect      string      `json:"effect"`
+	Description string      `json:"description"`
+	Routes      []string    `json:"routes,omitempty"`
+	When        []Condition `json:"when"`
+}
+
+// Condition compares the attribute at Attr with Value, or with the attribute
+// at ValueAttr when set. Attributes are addressed as subject.<claim>,
+// route.name, vars.<path var> and resource.<attribute>.
+type Condition struct {
+	Attr      string      `json:"attr"`
+	Op        string      `json:"op"`
+	Value     interface{} `json:"value,omitempty"`
+	ValueAttr string      `json:"value_attr,omitempty"`
+}
+
+type Input struct {
+	Subject  map[string]interface{} `json:"subject"`
+	Route    string                 `json:"route_name"`
+	Vars     map[string]string      `json:"vars"`
+	Resource map[string]interface{} `json:"resource"`
+}
+
+type Decision struct {
+	Allow  bool   `json:"allow"`
+	RuleID string `json:"rule_id,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Default returns the policy shipped with the binary.
+func Default() (*Policy, error) {
+	return Parse(defaultPolicy)
+}
+
+// Load reads a policy from path, or returns the default policy when path is empty.
+func Load(path string) (*Policy, error) {
+	if path == "" {
+		return Default()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy %s: %w", path, err)
+	}
+	return Parse(data)
+}
+
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("decoding policy: %w", err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	if p.DefaultReason == "" {
+		p.DefaultReason = "No policy rule allows this request"
+	}
+	return &p, nil
+}
+
+func (p *Policy) Evaluate(in Input) Decision {
+	var allowedBy *Rule
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if !rule.appliesTo(in.Route) || !rule.matches(in) {
+			continue
+		}
+		if rule.Effect == EffectDeny {
+			return Decision{Allow: false, RuleID: rule.ID, Reason: rule.Description}
+		}
+		if allowedBy == nil {
+			allowedBy = rule
+		}
+	}
+
+	if allowedBy != nil {
+		return Decision{Allow: true, RuleID: allowedBy.ID, Reason: allowedBy.Description}
+	}
+	return Decision{Allow: false, Reason: p.DefaultReason}
+}
+
+func (p *Policy) validate() error {
+	seen := make(map[string]bool)
+	for _, rule := range p.Rules {
+		if rule.ID == "" {
+			return fmt.Errorf("policy rule without id")
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate policy rule %q", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+			return fmt.Errorf("rule %q: effect must be %q or %q", rule.ID, EffectAllow, EffectDeny)
+		}
+		for _, c := range rule.When {
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("rule %q: %w", rule.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *Rule) appliesTo(route string) bool {
+	if len(r.Routes) == 0 {
+		return true
+	}
+	for _, candidate := range r.Routes {
+		if candidate == "*" || candidate == route {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(in Input) bool {
+	for _, c := range r.When {
+		if !c.holds(in) {
+			return false
+		}
+	}
+	return true
+}
+
+var operators = map[string]bool{
+	"eq": true, "ne": true, "in": true, "not_in": true,
+	"gt": true, "gte": true, "lt": true, "lte": true,
+	"exists": true, "not_exists": true,
+}
+
+func (c Condition) validate() error {
+	if !operators[c.Op] {
+		return fmt.Errorf("unknown operator %q", c.Op)
+	}
+	if !validAttr(c.Attr) {
+		return fmt.Errorf("invalid attribute %q", c.Attr)
+	}
+	if c.ValueAttr != "" && !validAttr(c.ValueAttr) {
+		return fmt.Errorf("invalid attribute %q", c.ValueAttr)
+	}
+	return nil
+}
+
+func (c Condition) holds(in Input) bool {
+	actual, found := lookup(in, c.Attr)
+	switch c.Op {
+	case "exists":
+		return found
+	case "not_exists":
+		return !found
+	}
+	if !found {
+		return false
+	}
+
+	expected := c.Value
+	if c.ValueAttr != "" {
+		var ok bool
+		if expected, ok = lookup(in, c.ValueAttr); !ok {
+			return false
+		}
+	}
+
+	switch c.Op {
+	case "eq":
+		return equal(actual, expected)
+	case "ne":
+		return !equal(actual, expected)
+	case "in", "not_in":
+		list, _ := expected.([]interface{})
+		contained := false
+		for _, item := range list {
+			if equal(actual, item) {
+				contained = true
+				break
+			}
+		}
+		return contained == (c.Op == "in")
+	default:
+		a, okA := number(actual)
+		b, okB := number(expected)
+		if !okA || !okB {
+			return false
+		}
+		switch c.Op {
+		case "gt":
+			return a > b
+		case "gte":
+			return a >= b
+		case "lt":
+			return a < b
+		default:
+			return a <= b
+		}
+	}
+}
+
+func validAttr(attr string) bool {
+	if attr == "route.name" {
+		return true
+	}
+	for _, prefix := range []string{"subject.", "vars.", "resource."} {
+		if strings.HasPrefix(attr, prefix) && len(attr) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func lookup(in Input, attr string) (interface{}, bool) {
+	if attr == "route.name" {
+		return in.Route, in.Route != ""
+	}
+	namespace, key, _ := strings.Cut(attr, ".")
+	var value interface{}
+	var ok bool
+	switch namespace {
+	case "subject":
+		value, ok = in.Subject[key]
+	case "vars":
+		value, ok = in.Vars[key]
+	case "resource":
+		value, ok = in.Resource[key]
+	}
+	if ok && value == nil {
+		return nil, false
+	}
+	return value, ok
+}
+
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return x == y
+		}
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,189 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/policy"
+)
+
+func customer(id string) map[string]interface{} {
+	return map[string]interface{}{"username": id, "role": "user", "customer_id": id, "scope": "own"}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	p, err := policy.Default()
+	if err != nil {
+		t.Fatalf("loading default policy: %v", err)
+	}
+	admin := map[string]interface{}{"username": "admin", "role": "admin", "scope": "all"}
+	withdrawal := func(amount interface{}) map[string]interface{} {
+		return map[string]interface{}{"owner_id": "2000", "transaction_type": "withdrawal", "amount": amount}
+	}
+
+	tests := []struct {
+		name   string
+		in     policy.Input
+		allow  bool
+		ruleID string
+	}{
+		{
+			name:   "role granted on every customer",
+			in:     policy.Input{Subject: admin, Route: "GetCustomer", Vars: map[string]string{"customer_id": "2001"}},
+			allow:  true,
+			ruleID: "allow-role-grant",
+		},
+		{
+			name:   "own customer",
+			in:     policy.Input{Subject: customer("2000"), Route: "GetCustomer", Vars: map[string]string{"customer_id": "2000"}},
+			allow:  true,
+			ruleID: "allow-own-customer",
+		},
+		{
+			name: "another customer",
+			in:   policy.Input{Subject: customer("2000"), Route: "GetCustomer", Vars: map[string]string{"customer_id": "2001"}},
+		},
+		{
+			name: "account of another customer",
+			in: policy.Input{
+				Subject:  customer("2000"),
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000", "account_id": "3001"},
+				Resource: map[string]interface{}{"owner_id": "2001"},
+			},
+			ruleID: "deny-foreign-account",
+		},
+		{
+			name: "account of another customer for an admin",
+			in: policy.Input{
+				Subject:  admin,
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000", "account_id": "3001"},
+				Resource: map[string]interface{}{"owner_id": "2001"},
+			},
+			allow:  true,
+			ruleID: "allow-role-grant",
+		},
+		{
+			name: "withdrawal at the limit",
+			in: policy.Input{
+				Subject:  customer("2000"),
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000"},
+				Resource: withdrawal(float64(50000)),
+			},
+			allow:  true,
+			ruleID: "allow-own-customer",
+		},
+		{
+			name: "withdrawal above the limit",
+			in: policy.Input{
+				Subject:  customer("2000"),
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000"},
+				Resource: withdrawal(50000.01),
+			},
+			ruleID: "deny-large-customer-withdrawal",
+		},
+		{
+			name: "withdrawal above the limit as a string",
+			in: policy.Input{
+				Subject:  customer("2000"),
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000"},
+				Resource: withdrawal("60000"),
+			},
+			ruleID: "deny-large-customer-withdrawal",
+		},
+		{
+			name: "withdrawal above the limit by an admin",
+			in: policy.Input{
+				Subject:  admin,
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000"},
+				Resource: withdrawal(float64(60000)),
+			},
+			allow:  true,
+			ruleID: "allow-role-grant",
+		},
+		{
+			name: "deposit above the limit",
+			in: policy.Input{
+				Subject:  customer("2000"),
+				Route:    "NewTransaction",
+				Vars:     map[string]string{"customer_id": "2000"},
+				Resource: map[string]interface{}{"owner_id": "2000", "transaction_type": "deposit", "amount": float64(60000)},
+			},
+			allow:  true,
+			ruleID: "allow-own-customer",
+		},
+		{
+			name: "subject without scope",
+			in:   policy.Input{Subject: map[string]interface{}{"username": "2000", "role": "user"}, Route: "GetCustomer"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(tt.in)
+			if decision.Allow != tt.allow || decision.RuleID != tt.ruleID {
+				t.Errorf("expected allow=%v by %q, got allow=%v by %q", tt.allow, tt.ruleID, decision.Allow, decision.RuleID)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p, err := policy.Parse([]byte(`{
+		"default_reason": "nothing matched",
+		"rules": [
+			{"id": "allow-customers", "effect": "allow", "routes": ["GetCustomer"], "when": [{"attr": "subject.role", "op": "in", "value": ["user", "admin"]}]},
+			{"id": "deny-disabled", "effect": "deny", "description": "disabled", "when": [{"attr": "subject.status", "op": "eq", "value": "disabled"}]},
+			{"id": "allow-admin", "effect": "allow", "routes": ["*"], "when": [{"attr": "subject.role", "op": "eq", "value": "admin"}]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("parsing policy: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		subject map[string]interface{}
+		route   string
+		allow   bool
+		ruleID  string
+		reason  string
+	}{
+		{name: "first matching allow", subject: map[string]interface{}{"role": "admin"}, route: "GetCustomer", allow: true, ruleID: "allow-customers"},
+		{name: "wildcard route", subject: map[string]interface{}{"role": "admin"}, route: "NewAccount", allow: true, ruleID: "allow-admin"},
+		{name: "deny after an allow", subject: map[string]interface{}{"role": "admin", "status": "disabled"}, route: "GetCustomer", ruleID: "deny-disabled", reason: "disabled"},
+		{name: "default deny", subject: map[string]interface{}{"role": "user"}, route: "NewAccount", reason: "nothing matched"},
+		{name: "default deny without subject", route: "GetCustomer", reason: "nothing matched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := p.Evaluate(policy.Input{Subject: tt.subject, Route: tt.route})
+			if decision.Allow != tt.allow || decision.RuleID != tt.ruleID {
+				t.Errorf("expected allow=%v by %q, got allow=%v by %q", tt.allow, tt.ruleID, decision.Allow, decision.RuleID)
+			}
+			if tt.reason != "" && decision.Reason != tt.reason {
+				t.Errorf("expected reason %q, got %q", tt.reason, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"missing id":       `{"rules": [{"effect": "allow"}]}`,
+		"duplicate id":     `{"rules": [{"id": "a", "effect": "allow"}, {"id": "a", "effect": "deny"}]}`,
+		"unknown effect":   `{"rules": [{"id": "a", "effect": "maybe"}]}`,
+		"unknown operator": `{"rules": [{"id": "a", "effect": "allow", "when": [{"attr": "subject.role", "op": "like"}]}]}`,
+		"unknown attr":     `{"rules": [{"id": "a", "effect": "allow", "when": [{"attr": "claims.role", "op": "exists"}]}]}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := policy.Parse([]byte(data)); err == nil {
+				t.Error("expected the policy to be refused")
+			}
+		})
+	}
+}
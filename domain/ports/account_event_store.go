@@ -0,0 +1,29 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// AccountEventStore is the append-only history of accounts, their source of
+// truth.
+type AccountEventStore interface {
+	// Append adds events to the history of an account whose last version is
+	// expectedVersion, 0 for a new account. If another writer appended
+	// first it returns a 409 conflict and appends nothing.
+	Append(ctx context.Context, accountID string, expectedVersion int64, events ...domain.AccountEvent) *errs.AppError
+	// Load returns the events of an account after version afterVersion,
+	// oldest first.
+	Load(ctx context.Context, accountID string, afterVersion int64) ([]domain.AccountEvent, *errs.AppError)
+	// Since returns up to limit events of every account after sequence
+	// afterSequence, in sequence order. Sequences of rolled back appends
+	// are never used, and a concurrent append may commit after a later one,
+	// so the sequences read can have gaps.
+	Since(ctx context.Context, afterSequence int64, limit int) ([]domain.AccountEvent, *errs.AppError)
+	// SaveSnapshot keeps the snapshot and drops the older ones of the account.
+	SaveSnapshot(ctx context.Context, snapshot domain.AccountSnapshot) *errs.AppError
+	// LatestSnapshot returns nil when the account has no snapshot.
+	LatestSnapshot(ctx context.Context, accountID string) (*domain.AccountSnapshot, *errs.AppError)
+}
@@ -1,12 +1,15 @@
 package ports
 
 import (
+    "context"
+
     "github.com/titi0001/Microservices-API-in-Go/domain"
     "github.com/titi0001/Microservices-API-in-Go/errs"
 )
 
 type AccountRepository interface {
-    Save(account domain.Account) (*domain.Account, *errs.AppError)
-    SaveTransaction(transaction domain.Transaction) (*domain.Transaction, *errs.AppError)
-    FindBy(accountID string) (*domain.Account, *errs.AppError)
+    Save(ctx context.Context, account domain.Account) (*domain.Account, *errs.AppError)
+    SaveTransaction(ctx context.Context, transaction domain.Transaction) (*domain.Transaction, *errs.AppError)
+    FindBy(ctx context.Context, accountID string) (*domain.Account, *errs.AppError)
+    UpdateStatus(ctx context.Context, accountID, status string) *errs.AppError
 }
\ No newline at end of file
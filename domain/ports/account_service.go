@@ -1,11 +1,16 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
 )
 
 type AccountService interface {
-	NewAccount(req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError)
-	MakeTransaction(req dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError)
+	NewAccount(ctx context.Context, req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError)
+	MakeTransaction(ctx context.Context, req dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError)
+	FreezeAccount(ctx context.Context, req dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError)
+	CloseAccount(ctx context.Context, req dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError)
+	ListAccountEvents(ctx context.Context, accountID string) ([]dto.AccountEventResponse, *errs.AppError)
 }
\ No newline at end of file
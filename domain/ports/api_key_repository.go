@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type APIKeyRepository interface {
+	Save(ctx context.Context, key domain.APIKey) (*domain.APIKey, *errs.AppError)
+	FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, *errs.AppError)
+	FindAll(ctx context.Context) ([]domain.APIKey, *errs.AppError)
+	Revoke(ctx context.Context, keyID string, now time.Time) *errs.AppError
+	// TouchLastUsed records a use of the key, at most once per minute.
+	TouchLastUsed(ctx context.Context, keyID string, now time.Time) *errs.AppError
+}
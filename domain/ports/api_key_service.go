@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, req dto.APIKeyRequest) (*dto.APIKeyCreatedResponse, *errs.AppError)
+	ListAPIKeys(ctx context.Context) ([]dto.APIKeyResponse, *errs.AppError)
+	RevokeAPIKey(ctx context.Context, keyID string) *errs.AppError
+}
+
+// APIKeyAuthenticator authorizes a request made with an API key, the way a
+// TokenVerifier does for access tokens.
+type APIKeyAuthenticator interface {
+	VerifyAPIKey(ctx context.Context, key, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError)
+}
@@ -0,0 +1,19 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type AuditRepository interface {
+	// Append links the event to the current end of the chain, sets its
+	// hashes and stores it.
+	Append(ctx context.Context, event domain.AuditEvent) (*domain.AuditEvent, *errs.AppError)
+	Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, *errs.AppError)
+	// FindAfter returns up to limit events with an id above afterID, in
+	// chain order.
+	FindAfter(ctx context.Context, afterID int64, limit int) ([]domain.AuditEvent, *errs.AppError)
+	ChainHead(ctx context.Context) (string, *errs.AppError)
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// AuditLogger records security-relevant events. Recording never fails the
+// operation being audited.
+type AuditLogger interface {
+	Record(ctx context.Context, event domain.AuditEvent)
+}
+
+type AuditService interface {
+	ListAuditEvents(ctx context.Context, req dto.AuditQueryRequest) ([]dto.AuditEventResponse, *errs.AppError)
+	VerifyAuditLog(ctx context.Context) (*dto.AuditVerifyResponse, *errs.AppError)
+}
@@ -1,14 +1,21 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/titi0001/Microservices-API-in-Go/domain"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
 )
 
 type AuthRepository interface {
-	FindUser(username, password string) (*domain.User, *errs.AppError)
-	VerifyPermission(role string, customerId string, routeName string, vars map[string]string) bool
-	SaveUser(user domain.User) (*domain.User, *errs.AppError)
-	SaveRefreshToken(refreshToken string) *errs.AppError
-	VerifyRefreshToken(refreshToken string) (bool, *errs.AppError)
+	// FindCredentials returns the user with its stored password hash, for
+	// the service to compare; an unknown username is an authentication error.
+	FindCredentials(ctx context.Context, username string) (*domain.User, *errs.AppError)
+	FindUserByUsername(ctx context.Context, username string) (*domain.User, *errs.AppError)
+	SaveUser(ctx context.Context, user domain.User) (*domain.User, *errs.AppError)
+	UpdatePassword(ctx context.Context, username, password string) *errs.AppError
+	SaveRefreshToken(ctx context.Context, username, refreshToken string) *errs.AppError
+	VerifyRefreshToken(ctx context.Context, refreshToken string) (bool, *errs.AppError)
+	DeleteRefreshToken(ctx context.Context, refreshToken string) (int64, *errs.AppError)
+	RevokeRefreshTokens(ctx context.Context, username string) (int64, *errs.AppError)
 }
@@ -1,16 +1,29 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/domain"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
 )
 
 type AuthService interface {
-	RemoteLogin(req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError)
-	RemoteIsAuthorized(token, routeName string, vars map[string]string) (bool, *errs.AppError)
-	GetSecretKey() []byte
-	GetRolePermissions() domain.RolePermissions
-	Register(req dto.RegisterRequest) (*dto.LoginResponse, *errs.AppError)
-	Refresh(token string) (*dto.LoginResponse, *errs.AppError)
+	RemoteLogin(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError)
+	RemoteIsAuthorized(ctx context.Context, token, routeName string, vars map[string]string) (bool, *errs.AppError)
+	Verify(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError)
+	TestPolicy(ctx context.Context, callerToken string, req dto.PolicyTestRequest) (*dto.PolicyTestResponse, *errs.AppError)
+	GetKeyResolver() utils.KeyResolver
+	GetJWKS() utils.JWKS
+	GetRolePermissions() *domain.RolePermissions
+	Register(ctx context.Context, req dto.RegisterRequest) (*dto.LoginResponse, *errs.AppError)
+	Refresh(ctx context.Context, token string) (*dto.LoginResponse, *errs.AppError)
+	BreakGlass(ctx context.Context, req dto.BreakGlassRequest) (*dto.BreakGlassResponse, *errs.AppError)
+	EnrollMFA(ctx context.Context, token string) (*dto.MFAEnrollResponse, *errs.AppError)
+	ConfirmMFA(ctx context.Context, token string, req dto.MFACodeRequest) *errs.AppError
+	VerifyMFA(ctx context.Context, req dto.MFAVerifyRequest) (*dto.LoginResponse, *errs.AppError)
+	ChangePassword(ctx context.Context, token string, req dto.PasswordChangeRequest) *errs.AppError
+	ForgotPassword(ctx context.Context, req dto.PasswordForgotRequest) *errs.AppError
+	ResetPassword(ctx context.Context, req dto.PasswordResetRequest) *errs.AppError
 }
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type BreakGlassRepository interface {
+	SaveGrant(ctx context.Context, grant domain.BreakGlassGrant) (*domain.BreakGlassGrant, *errs.AppError)
+	FindGrant(ctx context.Context, grantID string) (*domain.BreakGlassGrant, *errs.AppError)
+	SaveEvent(ctx context.Context, event domain.BreakGlassEvent) *errs.AppError
+}
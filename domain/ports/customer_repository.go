@@ -1,11 +1,13 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/titi0001/Microservices-API-in-Go/domain"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
 )
 
 type CustomerRepository interface {
-	ByID(id string) (*domain.Customer, *errs.AppError)
-	FindAll(status string) ([]domain.Customer, *errs.AppError)
+	ByID(ctx context.Context, id string) (*domain.Customer, *errs.AppError)
+	FindAll(ctx context.Context, status string) ([]domain.Customer, *errs.AppError)
 }
\ No newline at end of file
@@ -1,11 +1,13 @@
 package ports
 
 import (
+	"context"
+
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
 )
 
 type CustomerService interface {
-	GetCustomer(id string) (*dto.CustomerResponse, *errs.AppError)
-	GetAllCustomer(status string) ([]dto.CustomerResponse, *errs.AppError)
+	GetCustomer(ctx context.Context, id string) (*dto.CustomerResponse, *errs.AppError)
+	GetAllCustomer(ctx context.Context, status string) ([]dto.CustomerResponse, *errs.AppError)
 }
\ No newline at end of file
@@ -0,0 +1,14 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// EventSink publishes domain events outside the service, e.g. to a message
+// broker. An event may be published more than once, so consumers should
+// deduplicate on its id.
+type EventSink interface {
+	Publish(ctx context.Context, event domain.Event) error
+}
@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type ImpersonationService interface {
+	Impersonate(ctx context.Context, actor domain.VerifiedToken, req dto.ImpersonationRequest) (*dto.ImpersonationResponse, *errs.AppError)
+}
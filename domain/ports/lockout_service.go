@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type LockoutService interface {
+	ListLockouts(ctx context.Context) ([]dto.LockoutResponse, *errs.AppError)
+	ClearLockout(ctx context.Context, scope, subject string) *errs.AppError
+}
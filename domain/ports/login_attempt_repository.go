@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type LoginAttemptRepository interface {
+	// Find returns a zero LoginAttempt when the subject has no recorded failures.
+	Find(ctx context.Context, scope, subject string) (*domain.LoginAttempt, *errs.AppError)
+	// RecordFailure counts a failure, starting a new count when the previous one
+	// began before windowStart or its lockout has ended.
+	RecordFailure(ctx context.Context, scope, subject string, now, windowStart time.Time) (*domain.LoginAttempt, *errs.AppError)
+	Lock(ctx context.Context, scope, subject string, until time.Time) *errs.AppError
+	Reset(ctx context.Context, scope, subject string) (bool, *errs.AppError)
+	FindLocked(ctx context.Context, now time.Time) ([]domain.LoginAttempt, *errs.AppError)
+}
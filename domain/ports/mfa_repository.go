@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type MFARepository interface {
+	FindEnrollment(ctx context.Context, username string) (*domain.MFAEnrollment, *errs.AppError)
+	// SaveEnrollment replaces any pending enrollment of the user together with
+	// its recovery codes.
+	SaveEnrollment(ctx context.Context, enrollment domain.MFAEnrollment, recoveryCodeHashes []string) *errs.AppError
+	EnableEnrollment(ctx context.Context, username string, step int64) *errs.AppError
+	// UseStep records the time step of an accepted code. It returns false when
+	// the step is not newer than the last one used, i.e. the code is a replay.
+	UseStep(ctx context.Context, username string, step int64) (bool, *errs.AppError)
+	// UseRecoveryCode marks an unused recovery code as used and reports whether
+	// one matched.
+	UseRecoveryCode(ctx context.Context, username, codeHash string) (bool, *errs.AppError)
+	RoleRequiresMFA(ctx context.Context, role string) (bool, *errs.AppError)
+}
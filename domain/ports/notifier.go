@@ -0,0 +1,8 @@
+package ports
+
+import "github.com/titi0001/Microservices-API-in-Go/domain"
+
+// Notifier delivers messages to users, e.g. password reset links.
+type Notifier interface {
+	Notify(notification domain.Notification) error
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type OAuthRepository interface {
+	FindClient(ctx context.Context, clientID string) (*domain.OAuthClient, *errs.AppError)
+	// FindScopeRoutes lists every scope with its route permissions; scopes
+	// without routes, such as openid, have an empty PermissionName.
+	FindScopeRoutes(ctx context.Context) ([]domain.OAuthScopeRoute, *errs.AppError)
+	SaveAuthorizationCode(ctx context.Context, code domain.AuthorizationCode) *errs.AppError
+	// ConsumeAuthorizationCode marks an unexpired, unused code as used and
+	// returns it.
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (*domain.AuthorizationCode, *errs.AppError)
+}
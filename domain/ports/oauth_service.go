@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type OAuthService interface {
+	// Authorize issues an authorization code and returns the URL to redirect
+	// the user agent to. Errors that can be reported to the client are encoded
+	// in that URL; an OAuthError is returned only when the redirect URI itself
+	// cannot be trusted.
+	Authorize(ctx context.Context, req dto.OAuthAuthorizeRequest) (string, *dto.OAuthError)
+	Token(ctx context.Context, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError)
+	Introspect(ctx context.Context, req dto.OAuthIntrospectionRequest) (*dto.OAuthIntrospectionResponse, *dto.OAuthError)
+	OpenIDConfiguration(ctx context.Context) dto.OpenIDConfiguration
+	UserInfo(ctx context.Context, accessToken string) (*dto.UserInfoResponse, *errs.AppError)
+}
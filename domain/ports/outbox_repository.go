@@ -0,0 +1,25 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// OutboxRepository stores domain events until they are published. Appended
+// through the repositories of a unit of work, events are committed or rolled
+// back with the change they describe.
+type OutboxRepository interface {
+	Append(ctx context.Context, events ...domain.Event) *errs.AppError
+	// Pending returns, for up to limit aggregates, the oldest unpublished
+	// event of the aggregate if it is due by now. A later event of an
+	// aggregate is only returned once the earlier ones are published.
+	Pending(ctx context.Context, now time.Time, limit int) ([]domain.OutboxEntry, *errs.AppError)
+	MarkPublished(ctx context.Context, id int64, publishedAt time.Time) *errs.AppError
+	// MarkFailed counts a failed attempt and defers the next one.
+	MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) *errs.AppError
+	// PurgePublished deletes the events published before the given time.
+	PurgePublished(ctx context.Context, before time.Time) (int64, *errs.AppError)
+}
@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type PasswordResetRepository interface {
+	// SaveResetToken stores a token and discards the unused tokens previously
+	// issued to the same user.
+	SaveResetToken(ctx context.Context, token domain.PasswordResetToken) *errs.AppError
+	// FindResetToken returns the username of an unexpired, unused token
+	// without consuming it.
+	FindResetToken(ctx context.Context, tokenHash string, now time.Time) (string, *errs.AppError)
+	// ConsumeResetToken marks an unexpired, unused token as used and returns
+	// the username it was issued to.
+	ConsumeResetToken(ctx context.Context, tokenHash string, now time.Time) (string, *errs.AppError)
+}
@@ -0,0 +1,32 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// ReportRepository keeps the report tables, a read model projected from the
+// account events, and the checkpoint of the last event they include.
+type ReportRepository interface {
+	// Checkpoint returns the sequence of the last account event projected.
+	Checkpoint(ctx context.Context) (int64, *errs.AppError)
+	// FindAccounts returns the report rows of the accounts that have one,
+	// by account id.
+	FindAccounts(ctx context.Context, accountIDs []string) (map[string]domain.ReportAccount, *errs.AppError)
+	// Apply writes the batch and moves the checkpoint from from to to in one
+	// transaction. If the checkpoint is no longer at from, because another
+	// projector or a rebuild moved it, it returns a 409 conflict and writes
+	// nothing.
+	Apply(ctx context.Context, batch domain.ReportBatch, from, to int64) *errs.AppError
+	// Reset empties the report tables and moves the checkpoint back to 0.
+	Reset(ctx context.Context) *errs.AppError
+
+	CityTotals(ctx context.Context) ([]domain.CityTotals, *errs.AppError)
+	// AccountTypeTotals sums the volumes of the days from fromDay to toDay,
+	// either of which may be empty to leave the range open.
+	AccountTypeTotals(ctx context.Context, fromDay, toDay string) ([]domain.AccountTypeTotals, *errs.AppError)
+	CustomerTotals(ctx context.Context, customerID string) (*domain.CustomerTotals, *errs.AppError)
+	DailyBalances(ctx context.Context, accountID, fromDay, toDay string) ([]domain.DailyBalance, *errs.AppError)
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// ReportService answers the reports from the report tables only; they trail
+// the account events by the projector's poll interval.
+type ReportService interface {
+	CityReport(ctx context.Context) ([]dto.CityReportResponse, *errs.AppError)
+	AccountTypeReport(ctx context.Context, req dto.ReportRangeRequest) ([]dto.AccountTypeReportResponse, *errs.AppError)
+	CustomerReport(ctx context.Context, customerID string) (*dto.CustomerReportResponse, *errs.AppError)
+	AccountDailyReport(ctx context.Context, req dto.ReportRangeRequest) ([]dto.DailyBalanceResponse, *errs.AppError)
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type RoleRepository interface {
+	FindAllRoles(ctx context.Context) ([]domain.Role, *errs.AppError)
+	SaveRole(ctx context.Context, role domain.Role) (*domain.Role, *errs.AppError)
+	DeleteRole(ctx context.Context, name string) *errs.AppError
+	SetMFARequired(ctx context.Context, name string, required bool) *errs.AppError
+	FindAllPermissions(ctx context.Context) ([]domain.Permission, *errs.AppError)
+	SavePermission(ctx context.Context, permission domain.Permission) (*domain.Permission, *errs.AppError)
+	DeletePermission(ctx context.Context, name string) *errs.AppError
+	FindAllAssignments(ctx context.Context) ([]domain.RolePermission, *errs.AppError)
+	SaveAssignment(ctx context.Context, assignment domain.RolePermission) (*domain.RolePermission, *errs.AppError)
+	DeleteAssignment(ctx context.Context, roleName, permissionName string) *errs.AppError
+	PermissionsVersion(ctx context.Context) (int64, *errs.AppError)
+}
@@ -0,0 +1,29 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type RoleService interface {
+	ListRoles(ctx context.Context) ([]dto.RoleResponse, *errs.AppError)
+	CreateRole(ctx context.Context, req dto.RoleRequest) (*dto.RoleResponse, *errs.AppError)
+	DeleteRole(ctx context.Context, name string) *errs.AppError
+	SetRoleMFA(ctx context.Context, req dto.RoleMFARequest) *errs.AppError
+	ListPermissions(ctx context.Context) ([]dto.PermissionResponse, *errs.AppError)
+	CreatePermission(ctx context.Context, req dto.PermissionRequest) (*dto.PermissionResponse, *errs.AppError)
+	DeletePermission(ctx context.Context, name string) *errs.AppError
+	ListRolePermissions(ctx context.Context, roleName string) ([]dto.RolePermissionResponse, *errs.AppError)
+	AssignPermission(ctx context.Context, req dto.AssignPermissionRequest) (*dto.RolePermissionResponse, *errs.AppError)
+	RevokePermission(ctx context.Context, roleName, permissionName string) *errs.AppError
+}
+
+// RolePermissionsProvider is the single source of role permissions shared by
+// the middleware and the permission checks of the auth repository.
+type RolePermissionsProvider interface {
+	Current() *domain.RolePermissions
+	Reload(ctx context.Context) *errs.AppError
+}
@@ -0,0 +1,20 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type TokenVerifier interface {
+	Verify(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError)
+}
+
+// CallerAuthenticator checks the credentials of a request, the signature and
+// expiry of a token or the secret of an API key, without authorizing it, so
+// nothing else about the request is read for an unknown caller.
+type CallerAuthenticator interface {
+	AuthenticateToken(ctx context.Context, token string) *errs.AppError
+	AuthenticateAPIKey(ctx context.Context, key string) *errs.AppError
+}
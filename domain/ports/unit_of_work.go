@@ -0,0 +1,28 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// TxRepositories are repositories bound to the transaction of a unit of
+// work: their writes are committed or rolled back together.
+type TxRepositories struct {
+	Accounts      AccountRepository
+	AccountEvents AccountEventStore
+	Customers     CustomerRepository
+	Auth          AuthRepository
+	Users         UserRepository
+	Outbox        OutboxRepository
+}
+
+// UnitOfWork runs fn in one transaction, committed when fn returns nil and
+// rolled back when it returns an error. Calling Do again with the context
+// passed to fn runs the inner fn in a savepoint, so its failure only undoes
+// its own writes. A transaction aborted by a deadlock or serialization
+// conflict is run again from the start, so fn must not have effects outside
+// the repositories it is given.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos TxRepositories) *errs.AppError) *errs.AppError
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// UserRepository manages users for admins. Authentication reads users through
+// AuthRepository.
+type UserRepository interface {
+	FindAll(ctx context.Context, filter domain.UserFilter) ([]domain.User, *errs.AppError)
+	UpdateRole(ctx context.Context, username, role string) *errs.AppError
+	UpdateCustomerID(ctx context.Context, username string, customerID *string) *errs.AppError
+	UpdateStatus(ctx context.Context, username, status string) *errs.AppError
+}
@@ -0,0 +1,18 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type UserService interface {
+	ListUsers(ctx context.Context, req dto.UserSearchRequest) ([]dto.User, *errs.AppError)
+	GetUser(ctx context.Context, username string) (*dto.User, *errs.AppError)
+	SetUserRole(ctx context.Context, req dto.UserRoleRequest) *errs.AppError
+	LinkCustomer(ctx context.Context, req dto.UserCustomerRequest) *errs.AppError
+	UnlinkCustomer(ctx context.Context, username string) *errs.AppError
+	SetUserStatus(ctx context.Context, req dto.UserStatusRequest) *errs.AppError
+	ForceLogout(ctx context.Context, username string) (*dto.ForceLogoutResponse, *errs.AppError)
+}
@@ -0,0 +1,30 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+type WebhookRepository interface {
+	SaveSubscription(ctx context.Context, subscription domain.WebhookSubscription) (*domain.WebhookSubscription, *errs.AppError)
+	FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, *errs.AppError)
+	// FindSubscriptions returns every subscription, or those of one customer
+	// when customerID is not empty.
+	FindSubscriptions(ctx context.Context, customerID string) ([]domain.WebhookSubscription, *errs.AppError)
+	// DeleteSubscription also deletes its deliveries.
+	DeleteSubscription(ctx context.Context, id string) *errs.AppError
+
+	// EnqueueDelivery stores a pending delivery, unless the subscription
+	// already has one for the event.
+	EnqueueDelivery(ctx context.Context, delivery domain.WebhookDelivery) *errs.AppError
+	FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, *errs.AppError)
+	FindDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, *errs.AppError)
+	// DueDeliveries returns up to limit pending deliveries due by now,
+	// oldest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, *errs.AppError)
+	// UpdateDelivery stores the status and attempt fields of the delivery.
+	UpdateDelivery(ctx context.Context, delivery domain.WebhookDelivery) *errs.AppError
+}
@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// WebhookService manages subscriptions and their delivery log. Every method
+// takes the customer of the route: a customer only sees its own
+// subscriptions, while an empty customerID, on the admin routes, sees all.
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, req dto.WebhookRequest) (*dto.WebhookCreatedResponse, *errs.AppError)
+	ListWebhooks(ctx context.Context, customerID string) ([]dto.WebhookResponse, *errs.AppError)
+	DeleteWebhook(ctx context.Context, customerID, webhookID string) *errs.AppError
+	ListDeliveries(ctx context.Context, req dto.WebhookDeliveryQueryRequest) ([]dto.WebhookDeliveryResponse, *errs.AppError)
+	// Redeliver queues a delivered or dead delivery again, with a fresh
+	// series of attempts.
+	Redeliver(ctx context.Context, customerID, webhookID, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError)
+}
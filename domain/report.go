@@ -0,0 +1,277 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// ReportProjectionName names the checkpoint of the report tables.
+const ReportProjectionName = "reports"
+
+// ReportAccount is the row of an account in the reports: the state its
+// events left it in, with the city of its customer copied in when it was
+// opened.
+type ReportAccount struct {
+	AccountID   string  `db:"account_id"`
+	CustomerID  string  `db:"customer_id"`
+	AccountType string  `db:"account_type"`
+	City        string  `db:"city"`
+	Balance     float64 `db:"balance"`
+	Status      string  `db:"status"`
+}
+
+// DailyBalance is the activity of an account on a day, a UTC date, and its
+// balance after the last event of that day. Days without events have no row.
+type DailyBalance struct {
+	AccountID      string  `db:"account_id"`
+	Day            string  `db:"day"`
+	ClosingBalance float64 `db:"closing_balance"`
+	Deposits       float64 `db:"deposits"`
+	Withdrawals    float64 `db:"withdrawals"`
+	Transactions   int     `db:"transactions"`
+}
+
+// CustomerTotals sums the accounts of a customer.
+type CustomerTotals struct {
+	CustomerID   string  `db:"customer_id"`
+	City         string  `db:"city"`
+	Accounts     int     `db:"accounts"`
+	Balance      float64 `db:"balance"`
+	Deposits     float64 `db:"deposits"`
+	Withdrawals  float64 `db:"withdrawals"`
+	Transactions int     `db:"transactions"`
+}
+
+// TypeVolume is what the accounts of a type saw on a day.
+type TypeVolume struct {
+	AccountType     string  `db:"account_type"`
+	Day             string  `db:"day"`
+	AccountsOpened  int     `db:"accounts_opened"`
+	Deposits        float64 `db:"deposits"`
+	DepositCount    int     `db:"deposit_count"`
+	Withdrawals     float64 `db:"withdrawals"`
+	WithdrawalCount int     `db:"withdrawal_count"`
+}
+
+// CityTotals sums the customer totals of a city.
+type CityTotals struct {
+	City         string  `db:"city"`
+	Customers    int     `db:"customers"`
+	Accounts     int     `db:"accounts"`
+	Balance      float64 `db:"balance"`
+	Deposits     float64 `db:"deposits"`
+	Withdrawals  float64 `db:"withdrawals"`
+	Transactions int     `db:"transactions"`
+}
+
+// AccountTypeTotals is the current balance of the open accounts of a type
+// and the volumes of the type over a range of days.
+type AccountTypeTotals struct {
+	AccountType     string  `db:"account_type"`
+	OpenAccounts    int     `db:"open_accounts"`
+	Balance         float64 `db:"balance"`
+	AccountsOpened  int     `db:"accounts_opened"`
+	Deposits        float64 `db:"deposits"`
+	DepositCount    int     `db:"deposit_count"`
+	Withdrawals     float64 `db:"withdrawals"`
+	WithdrawalCount int     `db:"withdrawal_count"`
+}
+
+// ReportBatch is what a batch of account events changes in the report
+// tables. Accounts are whole rows and a daily row's ClosingBalance replaces
+// the stored one; every other number is added to the stored row.
+type ReportBatch struct {
+	Accounts  []ReportAccount
+	Daily     []DailyBalance
+	Customers []CustomerTotals
+	Types     []TypeVolume
+	// Skipped are the events of accounts the reports do not know, whose
+	// Opened event was lost; a rebuild picks them up.
+	Skipped []AccountEvent
+}
+
+// ProjectReports folds events, in sequence order, into the changes to the
+// report tables. accounts holds the report rows the events start from, of
+// the accounts opened before the batch; city looks up the city of the
+// customer of an account opened in it.
+func ProjectReports(events []AccountEvent, accounts map[string]ReportAccount, city func(customerID string) (string, error)) (ReportBatch, error) {
+	p := reportProjection{
+		accounts:  make(map[string]*ReportAccount),
+		daily:     make(map[[2]string]*DailyBalance),
+		customers: make(map[string]*CustomerTotals),
+		types:     make(map[[2]string]*TypeVolume),
+	}
+	for id, account := range accounts {
+		p.accounts[id] = &account
+	}
+
+	var batch ReportBatch
+	for _, e := range events {
+		known, err := p.apply(e, city)
+		if err != nil {
+			return ReportBatch{}, err
+		}
+		if !known {
+			batch.Skipped = append(batch.Skipped, e)
+		}
+	}
+
+	for _, id := range p.changed {
+		batch.Accounts = append(batch.Accounts, *p.accounts[id])
+	}
+	for _, key := range p.dailyOrder {
+		batch.Daily = append(batch.Daily, *p.daily[key])
+	}
+	for _, id := range p.customerOrder {
+		batch.Customers = append(batch.Customers, *p.customers[id])
+	}
+	for _, key := range p.typeOrder {
+		batch.Types = append(batch.Types, *p.types[key])
+	}
+	return batch, nil
+}
+
+// reportProjection keeps the rows a batch changes, in the order they were
+// first changed, so that the writes of a batch follow its events.
+type reportProjection struct {
+	accounts      map[string]*ReportAccount
+	changed       []string
+	daily         map[[2]string]*DailyBalance
+	dailyOrder    [][2]string
+	customers     map[string]*CustomerTotals
+	customerOrder []string
+	types         map[[2]string]*TypeVolume
+	typeOrder     [][2]string
+}
+
+// apply folds e in, or reports false when e is not an Opened event and its
+// account is unknown.
+func (p *reportProjection) apply(e AccountEvent, city func(string) (string, error)) (bool, error) {
+	day := e.OccurredAt.UTC().Format("2006-01-02")
+
+	if e.Type == AccountEventOpened {
+		var data AccountOpenedData
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			return false, fmt.Errorf("decoding %s event %d of account %s: %w", e.Type, e.Version, e.AccountID, err)
+		}
+		customerCity, err := city(data.CustomerID)
+		if err != nil {
+			return false, err
+		}
+		account := &ReportAccount{
+			AccountID:   e.AccountID,
+			CustomerID:  data.CustomerID,
+			AccountType: data.AccountType,
+			City:        customerCity,
+			Balance:     RoundCents(data.Amount),
+			Status:      AccountStatusActive,
+		}
+		p.accounts[e.AccountID] = account
+		p.touch(e.AccountID)
+
+		customer := p.customer(account)
+		customer.Accounts++
+		customer.Balance = RoundCents(customer.Balance + account.Balance)
+		p.typeVolume(account.AccountType, day).AccountsOpened++
+		p.dailyBalance(account, day)
+		return true, nil
+	}
+
+	account, known := p.accounts[e.AccountID]
+	if !known {
+		return false, nil
+	}
+	p.touch(e.AccountID)
+
+	switch e.Type {
+	case AccountEventDeposited, AccountEventWithdrawn:
+		var data AccountPostedData
+		if err := json.Unmarshal([]byte(e.Data), &data); err != nil {
+			return false, fmt.Errorf("decoding %s event %d of account %s: %w", e.Type, e.Version, e.AccountID, err)
+		}
+		amount := RoundCents(data.Amount)
+		customer := p.customer(account)
+		volume := p.typeVolume(account.AccountType, day)
+		change := amount
+		if e.Type == AccountEventWithdrawn {
+			change = -amount
+			customer.Withdrawals = RoundCents(customer.Withdrawals + amount)
+			volume.Withdrawals = RoundCents(volume.Withdrawals + amount)
+			volume.WithdrawalCount++
+		} else {
+			customer.Deposits = RoundCents(customer.Deposits + amount)
+			volume.Deposits = RoundCents(volume.Deposits + amount)
+			volume.DepositCount++
+		}
+		account.Balance = RoundCents(account.Balance + change)
+		customer.Balance = RoundCents(customer.Balance + change)
+		customer.Transactions++
+
+		daily := p.dailyBalance(account, day)
+		if e.Type == AccountEventWithdrawn {
+			daily.Withdrawals = RoundCents(daily.Withdrawals + amount)
+		} else {
+			daily.Deposits = RoundCents(daily.Deposits + amount)
+		}
+		daily.Transactions++
+	case AccountEventFrozen:
+		account.Status = AccountStatusFrozen
+	case AccountEventClosed:
+		account.Status = AccountStatusClosed
+	default:
+		return false, fmt.Errorf("unknown event %s of account %s", e.Type, e.AccountID)
+	}
+	return true, nil
+}
+
+func (p *reportProjection) touch(accountID string) {
+	for _, id := range p.changed {
+		if id == accountID {
+			return
+		}
+	}
+	p.changed = append(p.changed, accountID)
+}
+
+// customer returns the increments of the customer of account, which also
+// carry the city it was given when the account was opened.
+func (p *reportProjection) customer(account *ReportAccount) *CustomerTotals {
+	totals, exists := p.customers[account.CustomerID]
+	if !exists {
+		totals = &CustomerTotals{CustomerID: account.CustomerID, City: account.City}
+		p.customers[account.CustomerID] = totals
+		p.customerOrder = append(p.customerOrder, account.CustomerID)
+	}
+	return totals
+}
+
+func (p *reportProjection) typeVolume(accountType, day string) *TypeVolume {
+	key := [2]string{accountType, day}
+	volume, exists := p.types[key]
+	if !exists {
+		volume = &TypeVolume{AccountType: accountType, Day: day}
+		p.types[key] = volume
+		p.typeOrder = append(p.typeOrder, key)
+	}
+	return volume
+}
+
+// dailyBalance returns the increments of the account on day, closing at
+// the account's current balance.
+func (p *reportProjection) dailyBalance(account *ReportAccount, day string) *DailyBalance {
+	key := [2]string{account.AccountID, day}
+	daily, exists := p.daily[key]
+	if !exists {
+		daily = &DailyBalance{AccountID: account.AccountID, Day: day}
+		p.daily[key] = daily
+		p.dailyOrder = append(p.dailyOrder, key)
+	}
+	daily.ClosingBalance = account.Balance
+	return daily
+}
+
+// RoundCents rounds to the two decimals balances are stored with.
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -1,77 +1,72 @@
 package domain
 
 import (
+	"sort"
 	"strings"
-	"sync"
-
-	"github.com/titi0001/Microservices-API-in-Go/logger"
 )
 
-var (
-	singletonRolePermissions *RolePermissions
-	once                     sync.Once
+const (
+	// ScopeAll grants the permission on any resource.
+	ScopeAll = "all"
+	// ScopeOwn restricts the permission to the caller's own customer_id.
+	ScopeOwn = "own"
 )
 
-// RolePermissions gerencia permissões por papel
-type RolePermissions struct {
-	rolePermissions map[string][]string
+type Role struct {
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	MFARequired bool   `db:"mfa_required" json:"mfa_required"`
 }
 
-// GetRolePermissions retorna a instância singleton de permissões
-func GetRolePermissions() *RolePermissions {
-	once.Do(func() {
-		permissions := map[string][]string{
-			"admin": {"GetAllCustomers", "GetCustomer", "NewAccount", "NewTransaction", "GetRolePermissions"},
-			"user":  {"GetCustomer", "NewTransaction"},
-		}
-		singletonRolePermissions = &RolePermissions{rolePermissions: permissions}
-		logger.Info("Initialized RolePermissions singleton",
-			logger.Int("role_count", len(permissions)))
-	})
-	return singletonRolePermissions
+type Permission struct {
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
 }
 
-// IsAuthorizedFor verifica se um papel tem permissão para uma rota
-func (p *RolePermissions) IsAuthorizedFor(role, routeName string) bool {
-	normalizedRole := strings.ToLower(strings.TrimSpace(role))
-	normalizedRouteName := strings.TrimSpace(routeName)
+type RolePermission struct {
+	RoleName       string `db:"role_name" json:"role"`
+	PermissionName string `db:"permission_name" json:"permission"`
+	Scope          string `db:"scope" json:"scope"`
+}
 
-	if len(p.rolePermissions) == 0 {
-		logger.Warn("RolePermissions map is empty, using default permissions")
-		p.rolePermissions = defaultPermissions()
-	}
+func IsValidScope(scope string) bool {
+	return scope == ScopeAll || scope == ScopeOwn
+}
 
-	perms, exists := p.rolePermissions[normalizedRole]
-	if !exists {
-		logger.Warn("Role not found", logger.String("role", normalizedRole))
-		if normalizedRole == "admin" {
-			logger.Info("Emergency bypass for admin role")
-			return true
-		}
-		return false
-	}
+// RolePermissions gerencia permissões por papel
+type RolePermissions struct {
+	rolePermissions map[string]map[string]string
+}
 
-	for _, perm := range perms {
-		if perm == normalizedRouteName {
-			return true
+// NewRolePermissions monta as permissões a partir das atribuições armazenadas
+func NewRolePermissions(assignments []RolePermission) *RolePermissions {
+	permissions := make(map[string]map[string]string)
+	for _, a := range assignments {
+		role := normalizeRole(a.RoleName)
+		if permissions[role] == nil {
+			permissions[role] = make(map[string]string)
 		}
+		permissions[role][strings.TrimSpace(a.PermissionName)] = a.Scope
 	}
-	logger.Warn("Permission not found",
-		logger.String("role", normalizedRole),
-		logger.String("routeName", normalizedRouteName))
-	return false
+	return &RolePermissions{rolePermissions: permissions}
+}
+
+// Scope retorna o escopo concedido a um papel para uma rota
+func (p *RolePermissions) Scope(role, routeName string) (string, bool) {
+	scope, ok := p.rolePermissions[normalizeRole(role)][strings.TrimSpace(routeName)]
+	return scope, ok
+}
+
+// IsEmpty indica se nenhuma permissão foi carregada
+func (p *RolePermissions) IsEmpty() bool {
+	return len(p.rolePermissions) == 0
 }
 
 // GetAllPermissions retorna todas as permissões únicas
 func (p *RolePermissions) GetAllPermissions() []string {
-	if len(p.rolePermissions) == 0 {
-		logger.Warn("RolePermissions map is empty, using default permissions")
-		p.rolePermissions = defaultPermissions()
-	}
-
 	uniquePerms := make(map[string]struct{})
 	for _, perms := range p.rolePermissions {
-		for _, perm := range perms {
+		for perm := range perms {
 			uniquePerms[perm] = struct{}{}
 		}
 	}
@@ -80,13 +75,10 @@ func (p *RolePermissions) GetAllPermissions() []string {
 	for perm := range uniquePerms {
 		result = append(result, perm)
 	}
+	sort.Strings(result)
 	return result
 }
 
-// defaultPermissions retorna as permissões padrão
-func defaultPermissions() map[string][]string {
-	return map[string][]string{
-		"admin": {"GetAllCustomers", "GetCustomer", "NewAccount", "NewTransaction", "GetRolePermissions"},
-		"user":  {"GetCustomer", "NewTransaction"},
-	}
-}
\ No newline at end of file
+func normalizeRole(role string) string {
+	return strings.ToLower(strings.TrimSpace(role))
+}
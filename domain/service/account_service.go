@@ -1,60 +1,330 @@
 package service
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/domain"
 	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
 )
 
 type DefaultAccountService struct {
-	repo ports.AccountRepository
+	repo           ports.AccountRepository
+	uow            ports.UnitOfWork
+	audit          ports.AuditLogger
+	snapshotEvery  int64
+	appendAttempts int
 }
 
-func NewAccountService(repo ports.AccountRepository) ports.AccountService {
-	return &DefaultAccountService{repo: repo}
+// NewAccountService reads ACCOUNT_SNAPSHOT_EVERY, how many events an
+// account gets between snapshots (0 disables them), and
+// ACCOUNT_APPEND_ATTEMPTS, how many times a change that loses an append
+// race to another request is run before the conflict is reported.
+func NewAccountService(repo ports.AccountRepository, uow ports.UnitOfWork, audit ports.AuditLogger) ports.AccountService {
+	return &DefaultAccountService{
+		repo:           repo,
+		uow:            uow,
+		audit:          audit,
+		snapshotEvery:  int64(max(config.Int("ACCOUNT_SNAPSHOT_EVERY", 50), 0)),
+		appendAttempts: max(config.Int("ACCOUNT_APPEND_ATTEMPTS", 3), 1),
+	}
 }
 
 
-func (s *DefaultAccountService) NewAccount(req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
-	account := domain.NewAccount(req.CustomerID, req.AccountType, req.Amount)
-	savedAccount, err := s.repo.Save(account)
+func (s *DefaultAccountService) NewAccount(ctx context.Context, req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
+	response, err := s.newAccount(ctx, req)
+	details := fmt.Sprintf("account_type=%s amount=%.2f", req.AccountType, req.Amount)
+	if response != nil {
+		details += " account_id=" + response.AccountID
+	}
+	s.record(ctx, "account.create", "customer:"+req.CustomerID, req.Actor, req.SourceIP, req.RequestID, details, err)
+	return response, err
+}
+
+// newAccount saves the account, the Opened event that starts its history
+// and its AccountOpened event together. The accounts table gives the
+// account its id.
+func (s *DefaultAccountService) newAccount(ctx context.Context, req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
+	var savedAccount *domain.Account
+	err := s.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		var err *errs.AppError
+		savedAccount, err = repos.Accounts.Save(ctx, domain.NewAccount(req.CustomerID, req.AccountType, req.Amount))
+		if err != nil {
+			logger.Error("Error saving new account", logger.Any("error", err))
+			return err
+		}
+		account, err := domain.OpenAccount(*savedAccount, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if err := s.saveHistory(ctx, repos, account); err != nil {
+			return err
+		}
+		return recordEvent(ctx, repos.Outbox, domain.AggregateAccount, savedAccount.AccountID, domain.EventAccountOpened, domain.AccountOpenedPayload{
+			AccountID:   savedAccount.AccountID,
+			CustomerID:  savedAccount.CustomerID,
+			AccountType: savedAccount.AccountType,
+			Amount:      savedAccount.Amount,
+			OpeningDate: savedAccount.OpeningDate,
+		})
+	})
 	if err != nil {
-		logger.Error("Error saving new account", logger.Any("error", err))
 		return nil, err
 	}
 	return savedAccount.ToNewAccountResponseDto(), nil
 }
 
 
-func (s *DefaultAccountService) MakeTransaction(req dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError) {
+func (s *DefaultAccountService) MakeTransaction(ctx context.Context, req dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError) {
+	response, err := s.makeTransaction(ctx, req)
+	details := fmt.Sprintf("transaction_type=%s amount=%.2f", req.TransactionType, req.Amount)
+	if response != nil {
+		details += " transaction_id=" + response.TransactionID
+	}
+	s.record(ctx, "account.transaction", "account:"+req.AccountID, req.Actor, req.SourceIP, req.RequestID, details, err)
+	return response, err
+}
+
+// makeTransaction rebuilds the account from its history, checks the
+// transaction against it, posts the transaction and appends its event with
+// the TransactionPosted event in one unit of work. If another request
+// appended to the account meanwhile, it starts over on the new history.
+func (s *DefaultAccountService) makeTransaction(ctx context.Context, req dto.TransactionRequest) (*dto.TransactionResponse, *errs.AppError) {
+	var savedTransaction *domain.Transaction
+	err := s.changeAccount(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		account, err := s.loadAccount(ctx, repos, req.AccountID)
+		if err != nil {
+			logger.Error("Error finding account", logger.String("account_id", req.AccountID), logger.Any("error", err))
+			return err
+		}
+
+		if err := account.CheckPost(req.TransactionType, req.Amount); err != nil {
+			logger.Warn("Transaction rejected", logger.String("account_id", req.AccountID), logger.Float64("amount", req.Amount), logger.String("reason", err.Message))
+			return err
+		}
 
-	account, err := s.repo.FindBy(req.AccountID)
+		transaction := domain.Transaction{
+			AccountID:       req.AccountID,
+			Amount:          req.Amount,
+			TransactionType: req.TransactionType,
+			TransactionDate: req.TransactionDate,
+		}
+
+		savedTransaction, err = repos.Accounts.SaveTransaction(ctx, transaction)
+		if err != nil {
+			logger.Error("Error saving transaction", logger.String("account_id", req.AccountID), logger.Any("error", err))
+			return err
+		}
+		if err := account.Post(*savedTransaction, req.Amount, time.Now().UTC()); err != nil {
+			return err
+		}
+		if err := s.saveHistory(ctx, repos, account); err != nil {
+			return err
+		}
+		if savedTransaction.Amount != account.Account.Amount {
+			logger.Warn("Account balance differs from its history",
+				logger.String("account_id", req.AccountID),
+				logger.Float64("stored", savedTransaction.Amount),
+				logger.Float64("replayed", account.Account.Amount))
+			savedTransaction.Amount = account.Account.Amount
+		}
+		return recordEvent(ctx, repos.Outbox, domain.AggregateAccount, req.AccountID, domain.EventTransactionPosted, domain.TransactionPostedPayload{
+			TransactionID:   savedTransaction.TransactionID,
+			AccountID:       savedTransaction.AccountID,
+			CustomerID:      account.Account.CustomerID,
+			TransactionType: savedTransaction.TransactionType,
+			Amount:          req.Amount,
+			Balance:         savedTransaction.Amount,
+			TransactionDate: savedTransaction.TransactionDate,
+		})
+	})
 	if err != nil {
-		logger.Error("Error finding account", logger.String("account_id", req.AccountID), logger.Any("error", err))
 		return nil, err
 	}
 
-	if req.IsTransactionTypeWithdrawal() && !account.CanWithdraw(req.Amount) {
-		logger.Warn("Insufficient balance for withdrawal", logger.String("account_id", req.AccountID), logger.Float64("amount", req.Amount))
-		return nil, errs.NewValidationError("Insufficient balance for withdrawal")
+	response := savedTransaction.ToDto()
+	return &response, nil
+}
+
+func (s *DefaultAccountService) FreezeAccount(ctx context.Context, req dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError) {
+	response, err := s.changeStatus(ctx, req, domain.EventAccountFrozen, func(account *domain.AccountAggregate, at time.Time) *errs.AppError {
+		return account.Freeze(req.Reason, req.Actor, at)
+	})
+	s.record(ctx, "account.freeze", "account:"+req.AccountID, req.Actor, req.SourceIP, req.RequestID, "reason="+req.Reason, err)
+	return response, err
+}
+
+func (s *DefaultAccountService) CloseAccount(ctx context.Context, req dto.AccountStatusRequest) (*dto.AccountResponse, *errs.AppError) {
+	response, err := s.changeStatus(ctx, req, domain.EventAccountClosed, func(account *domain.AccountAggregate, at time.Time) *errs.AppError {
+		return account.Close(req.Reason, req.Actor, at)
+	})
+	s.record(ctx, "account.close", "account:"+req.AccountID, req.Actor, req.SourceIP, req.RequestID, "reason="+req.Reason, err)
+	return response, err
+}
+
+// changeStatus runs a Freeze or Close command and projects the new status
+// onto the accounts table.
+func (s *DefaultAccountService) changeStatus(ctx context.Context, req dto.AccountStatusRequest, eventType string, command func(*domain.AccountAggregate, time.Time) *errs.AppError) (*dto.AccountResponse, *errs.AppError) {
+	var changed *domain.AccountAggregate
+	err := s.changeAccount(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		account, err := s.loadAccount(ctx, repos, req.AccountID)
+		if err != nil {
+			return err
+		}
+		if err := command(account, time.Now().UTC()); err != nil {
+			return err
+		}
+		if err := repos.Accounts.UpdateStatus(ctx, req.AccountID, account.Account.Status); err != nil {
+			return err
+		}
+		if err := s.saveHistory(ctx, repos, account); err != nil {
+			return err
+		}
+		changed = account
+		return recordEvent(ctx, repos.Outbox, domain.AggregateAccount, req.AccountID, eventType, domain.AccountStatusPayload{
+			AccountID:  req.AccountID,
+			CustomerID: account.Account.CustomerID,
+			Reason:     req.Reason,
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
+	return toAccountResponse(changed), nil
+}
 
+// ListAccountEvents returns the history of an account, oldest first. An
+// account from before event sourcing has none until its first change.
+func (s *DefaultAccountService) ListAccountEvents(ctx context.Context, accountID string) ([]dto.AccountEventResponse, *errs.AppError) {
+	var events []domain.AccountEvent
+	err := s.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		var err *errs.AppError
+		if events, err = repos.AccountEvents.Load(ctx, accountID, 0); err != nil || len(events) > 0 {
+			return err
+		}
+		_, err = repos.Accounts.FindBy(ctx, accountID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	transaction := domain.Transaction{
-		AccountID:       req.AccountID,
-		Amount:          req.Amount,
-		TransactionType: req.TransactionType,
-		TransactionDate: req.TransactionDate,
+	response := make([]dto.AccountEventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, dto.AccountEventResponse{
+			Version:    e.Version,
+			Type:       e.Type,
+			Data:       json.RawMessage(e.Data),
+			OccurredAt: e.OccurredAt,
+		})
 	}
+	return response, nil
+}
 
-	savedTransaction, saveErr := s.repo.SaveTransaction(transaction)
-	if saveErr != nil {
-		logger.Error("Error saving transaction", logger.String("account_id", req.AccountID), logger.Any("error", saveErr))
-		return nil, saveErr
+// changeAccount runs fn in a unit of work, again from the start when it
+// loses an append race: the event store reports those as its only 409
+// conflicts.
+func (s *DefaultAccountService) changeAccount(ctx context.Context, fn func(ctx context.Context, repos ports.TxRepositories) *errs.AppError) *errs.AppError {
+	for attempt := 1; ; attempt++ {
+		err := s.uow.Do(ctx, fn)
+		if err == nil || err.Code != http.StatusConflict || attempt >= s.appendAttempts {
+			return err
+		}
+		logger.Warn("Account changed concurrently, retrying", logger.Int("attempt", attempt))
 	}
+}
 
-	response := savedTransaction.ToDto()
-	return &response, nil
-}
\ No newline at end of file
+// loadAccount rebuilds an account from its latest snapshot and the events
+// after it. An account written before accounts were event sourced has no
+// history yet: it is imported from the accounts table, and the import is
+// appended with its first change.
+func (s *DefaultAccountService) loadAccount(ctx context.Context, repos ports.TxRepositories, accountID string) (*domain.AccountAggregate, *errs.AppError) {
+	snapshot, err := repos.AccountEvents.LatestSnapshot(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	var after int64
+	if snapshot != nil {
+		after = snapshot.Version
+	}
+	events, err := repos.AccountEvents.Load(ctx, accountID, after)
+	if err != nil {
+		return nil, err
+	}
+
+	if snapshot == nil && len(events) == 0 {
+		stored, err := repos.Accounts.FindBy(ctx, accountID)
+		if err != nil {
+			return nil, err
+		}
+		return domain.ImportAccount(*stored, time.Now().UTC())
+	}
+	account, replayErr := domain.ReplayAccount(snapshot, events)
+	if replayErr != nil {
+		logger.Error("Error replaying account", logger.String("account_id", accountID), logger.Any("error", replayErr))
+		return nil, errs.NewUnexpectedError("Error loading account")
+	}
+	return account, nil
+}
+
+// saveHistory appends the changes of the account, and saves a snapshot
+// whenever they carry its version past a multiple of snapshotEvery.
+func (s *DefaultAccountService) saveHistory(ctx context.Context, repos ports.TxRepositories, account *domain.AccountAggregate) *errs.AppError {
+	loaded := account.LoadedVersion()
+	if err := repos.AccountEvents.Append(ctx, account.Account.AccountID, loaded, account.Changes()...); err != nil {
+		return err
+	}
+	if s.snapshotEvery == 0 || account.Version/s.snapshotEvery == loaded/s.snapshotEvery {
+		return nil
+	}
+	snapshot, err := account.Snapshot(time.Now().UTC())
+	if err != nil {
+		logger.Error("Error taking account snapshot", logger.String("account_id", account.Account.AccountID), logger.Any("error", err))
+		return errs.NewUnexpectedError("Error saving account")
+	}
+	return repos.AccountEvents.SaveSnapshot(ctx, snapshot)
+}
+
+func toAccountResponse(a *domain.AccountAggregate) *dto.AccountResponse {
+	status := map[string]string{
+		domain.AccountStatusActive: "active",
+		domain.AccountStatusFrozen: "frozen",
+		domain.AccountStatusClosed: "closed",
+	}[a.Account.Status]
+	return &dto.AccountResponse{
+		AccountID:   a.Account.AccountID,
+		CustomerID:  a.Account.CustomerID,
+		AccountType: a.Account.AccountType,
+		OpeningDate: a.Account.OpeningDate,
+		Balance:     a.Account.Amount,
+		Status:      status,
+		Version:     a.Version,
+	}
+}
+
+func (s *DefaultAccountService) record(ctx context.Context, action, resource, actor, sourceIP, requestID, details string, err *errs.AppError) {
+	if s.audit == nil {
+		return
+	}
+	outcome := domain.AuditOutcomeSuccess
+	if err != nil {
+		outcome = domain.AuditOutcomeFailure
+		details += " error=" + err.Message
+	}
+	s.audit.Record(ctx, domain.AuditEvent{
+		Actor:     actor,
+		Action:    action,
+		Resource:  resource,
+		Outcome:   outcome,
+		SourceIP:  sourceIP,
+		RequestID: requestID,
+		Details:   details,
+	})
+}
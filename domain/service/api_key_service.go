@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	apiKeyRole     = "api_key"
+	apiKeyIDLength = 8
+)
+
+var apiKeyIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type DefaultAPIKeyService struct {
+	repo ports.APIKeyRepository
+}
+
+func NewAPIKeyService(repo ports.APIKeyRepository) ports.APIKeyService {
+	return &DefaultAPIKeyService{repo: repo}
+}
+
+// CreateAPIKey issues a key of the form bk_<id>_<secret>. The key is returned
+// once; only its hash is stored.
+func (s *DefaultAPIKeyService) CreateAPIKey(ctx context.Context, req dto.APIKeyRequest) (*dto.APIKeyCreatedResponse, *errs.AppError) {
+	prefix, key, genErr := newAPIKey()
+	if genErr != nil {
+		logger.Error("Failed to generate API key", logger.Any("error", genErr))
+		return nil, errs.NewUnexpectedError("Error generating API key")
+	}
+
+	routes := make([]string, 0, len(req.Routes))
+	for _, route := range req.Routes {
+		routes = append(routes, strings.TrimSpace(route))
+	}
+
+	apiKey := domain.APIKey{
+		Prefix:    prefix,
+		Hash:      hashAPIKey(key),
+		Name:      strings.TrimSpace(req.Name),
+		Routes:    strings.Join(routes, ","),
+		CreatedBy: req.CreatedBy,
+		CreatedOn: time.Now(),
+	}
+	if req.CustomerID != "" {
+		apiKey.CustomerID = &req.CustomerID
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := apiKey.CreatedOn.Add(time.Duration(req.ExpiresInDays) * 24 * time.Hour)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	saved, err := s.repo.Save(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("API key created",
+		logger.Bool("audit", true),
+		logger.String("key_id", saved.ID),
+		logger.String("prefix", saved.Prefix),
+		logger.String("created_by", saved.CreatedBy))
+	return &dto.APIKeyCreatedResponse{APIKeyResponse: toAPIKeyResponse(*saved), Key: key}, nil
+}
+
+func (s *DefaultAPIKeyService) ListAPIKeys(ctx context.Context) ([]dto.APIKeyResponse, *errs.AppError) {
+	keys, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, k := range keys {
+		response = append(response, toAPIKeyResponse(k))
+	}
+	return response, nil
+}
+
+func (s *DefaultAPIKeyService) RevokeAPIKey(ctx context.Context, keyID string) *errs.AppError {
+	if err := s.repo.Revoke(ctx, keyID, time.Now()); err != nil {
+		return err
+	}
+	logger.Info("API key revoked", logger.Bool("audit", true), logger.String("key_id", keyID))
+	return nil
+}
+
+// VerifyAPIKey authorizes a request made with an API key. The key must be
+// active and scoped to the route; the authorization policy then sees it as a
+// subject with role api_key and scope own when it is bound to a customer.
+func (s *AuthService) VerifyAPIKey(ctx context.Context, key, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError) {
+	now := time.Now()
+	apiKey, prefix, err := s.activeAPIKey(ctx, key, now)
+	if err != nil {
+		return nil, err
+	}
+	if !apiKey.AllowsRoute(routeName) {
+		logger.Warn("API key not scoped to route", logger.String("prefix", prefix), logger.String("routeName", routeName))
+		return nil, errs.NewForbiddenError("API key is not scoped to this route")
+	}
+
+	customerID := ""
+	scope := domain.ScopeAll
+	if apiKey.CustomerID != nil {
+		customerID = *apiKey.CustomerID
+		scope = domain.ScopeOwn
+	}
+	subject := map[string]interface{}{
+		"username":    domain.APIKeyPrefix + prefix,
+		"role":        apiKeyRole,
+		"customer_id": customerID,
+		"api_key_id":  apiKey.ID,
+		"scope":       scope,
+	}
+	decision := s.policy.Evaluate(s.policyInput(subject, routeName, vars, resource))
+	if !decision.Allow {
+		logger.Warn("Permission denied for API key",
+			logger.String("prefix", prefix),
+			logger.String("routeName", routeName),
+			logger.String("rule", decision.RuleID))
+		return nil, errs.NewForbiddenError(decision.Reason)
+	}
+
+	if err := s.apiKeys.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+		logger.Warn("Could not record API key use", logger.String("key_id", apiKey.ID))
+	}
+
+	return &domain.VerifiedToken{
+		Username:   domain.APIKeyPrefix + prefix,
+		Role:       apiKeyRole,
+		CustomerID: customerID,
+		APIKeyID:   apiKey.ID,
+	}, nil
+}
+
+// AuthenticateAPIKey checks that an API key exists, matches its secret and is
+// active, the part of VerifyAPIKey that does not depend on the route.
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) *errs.AppError {
+	_, _, err := s.activeAPIKey(ctx, key, time.Now())
+	return err
+}
+
+func (s *AuthService) activeAPIKey(ctx context.Context, key string, now time.Time) (*domain.APIKey, string, *errs.AppError) {
+	if s.apiKeys == nil {
+		return nil, "", errs.NewAuthenticationError("API keys are not enabled")
+	}
+
+	prefix, ok := apiKeyPrefix(key)
+	if !ok {
+		return nil, "", errs.NewAuthenticationError("Invalid API key")
+	}
+	apiKey, err := s.apiKeys.FindByPrefix(ctx, prefix)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			logger.Warn("Unknown API key", logger.String("prefix", prefix))
+			return nil, "", errs.NewAuthenticationError("Invalid API key")
+		}
+		return nil, "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.Hash), []byte(hashAPIKey(key))) != 1 {
+		logger.Warn("API key secret mismatch", logger.String("prefix", prefix))
+		return nil, "", errs.NewAuthenticationError("Invalid API key")
+	}
+	if !apiKey.IsActive(now) {
+		logger.Warn("Inactive API key used", logger.String("prefix", prefix))
+		return nil, "", errs.NewAuthenticationError("API key expired or revoked")
+	}
+	return apiKey, prefix, nil
+}
+
+func newAPIKey() (prefix string, key string, err error) {
+	id := make([]byte, 5)
+	if _, err := rand.Read(id); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	prefix = strings.ToLower(apiKeyIDEncoding.EncodeToString(id))
+	key = domain.APIKeyPrefix + prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return prefix, key, nil
+}
+
+// apiKeyPrefix extracts the public id from bk_<id>_<secret>.
+func apiKeyPrefix(key string) (string, bool) {
+	rest, found := strings.CutPrefix(key, domain.APIKeyPrefix)
+	if !found || len(rest) <= apiKeyIDLength+1 || rest[apiKeyIDLength] != '_' {
+		return "", false
+	}
+	return rest[:apiKeyIDLength], true
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func toAPIKeyResponse(k domain.APIKey) dto.APIKeyResponse {
+	customerID := ""
+	if k.CustomerID != nil {
+		customerID = *k.CustomerID
+	}
+	return dto.APIKeyResponse{
+		ID:         k.ID,
+		Prefix:     domain.APIKeyPrefix + k.Prefix,
+		Name:       k.Name,
+		Routes:     k.RouteNames(),
+		CustomerID: customerID,
+		CreatedBy:  k.CreatedBy,
+		CreatedOn:  k.CreatedOn,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedOn: k.LastUsedOn,
+		RevokedOn:  k.RevokedOn,
+	}
+}
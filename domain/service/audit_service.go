@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 10000
+	auditVerifyBatch     = 500
+)
+
+type DefaultAuditService struct {
+	repo ports.AuditRepository
+}
+
+func NewAuditService(repo ports.AuditRepository) *DefaultAuditService {
+	return &DefaultAuditService{repo: repo}
+}
+
+// Record appends the event to the audit log. A failure is logged and does
+// not fail the audited operation. The event is written even when the request
+// that caused it has been canceled, as the operation may already be done.
+func (s *DefaultAuditService) Record(ctx context.Context, event domain.AuditEvent) {
+	ctx = context.WithoutCancel(ctx)
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	// The database keeps microseconds; hash what will be read back.
+	event.OccurredAt = event.OccurredAt.UTC().Truncate(time.Microsecond)
+
+	if _, err := s.repo.Append(ctx, event); err != nil {
+		logger.Error("Failed to record audit event",
+			logger.String("action", event.Action),
+			logger.String("actor", event.Actor),
+			logger.String("outcome", event.Outcome))
+	}
+}
+
+func (s *DefaultAuditService) ListAuditEvents(ctx context.Context, req dto.AuditQueryRequest) ([]dto.AuditEventResponse, *errs.AppError) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultAuditPageSize
+	}
+	if limit > maxAuditPageSize {
+		limit = maxAuditPageSize
+	}
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	events, err := s.repo.Find(ctx, domain.AuditFilter{
+		Actor:    req.Actor,
+		Action:   req.Action,
+		Resource: req.Resource,
+		Outcome:  req.Outcome,
+		From:     req.From,
+		To:       req.To,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.AuditEventResponse, 0, len(events))
+	for _, e := range events {
+		response = append(response, dto.AuditEventResponse{
+			ID:         e.ID,
+			OccurredAt: e.OccurredAt,
+			Actor:      e.Actor,
+			Action:     e.Action,
+			Resource:   e.Resource,
+			Outcome:    e.Outcome,
+			SourceIP:   e.SourceIP,
+			RequestID:  e.RequestID,
+			Details:    e.Details,
+			Hash:       e.Hash,
+		})
+	}
+	return response, nil
+}
+
+// VerifyAuditLog walks the whole chain, checking that every entry links to
+// the previous one, that its hash matches its content and that the chain
+// ends at the recorded head.
+func (s *DefaultAuditService) VerifyAuditLog(ctx context.Context) (*dto.AuditVerifyResponse, *errs.AppError) {
+	response := &dto.AuditVerifyResponse{Valid: true}
+	prevHash := ""
+	var lastID int64
+
+	for {
+		events, err := s.repo.FindAfter(ctx, lastID, auditVerifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range events {
+			response.Checked++
+			switch {
+			case e.PrevHash != prevHash:
+				return brokenChain(response, e.ID, "entry does not link to the previous entry"), nil
+			case e.Hash != e.ComputeHash():
+				return brokenChain(response, e.ID, "entry content does not match its hash"), nil
+			}
+			prevHash = e.Hash
+			lastID = e.ID
+		}
+		if len(events) < auditVerifyBatch {
+			break
+		}
+	}
+
+	head, err := s.repo.ChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if head != prevHash {
+		return brokenChain(response, lastID, "chain does not end at the recorded head"), nil
+	}
+	return response, nil
+}
+
+func brokenChain(response *dto.AuditVerifyResponse, id int64, reason string) *dto.AuditVerifyResponse {
+	logger.Error("Audit log chain is broken", logger.Any("event_id", id), logger.String("reason", reason))
+	response.Valid = false
+	response.FirstBrokenID = id
+	response.Reason = reason
+	return response
+}
+
+var (
+	_ ports.AuditLogger  = (*DefaultAuditService)(nil)
+	_ ports.AuditService = (*DefaultAuditService)(nil)
+)
@@ -1,43 +1,124 @@
 package service
 
 import (
-	"os"
+	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/policy"
 	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
 	"github.com/titi0001/Microservices-API-in-Go/errs"
 	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthService struct {
-	serviceURL string
-	repo       ports.AuthRepository
-	secretKey  []byte
+	serviceURL     string
+	repo           ports.AuthRepository
+	permissions    ports.RolePermissionsProvider
+	policy         *policy.Policy
+	breakGlass     ports.BreakGlassRepository
+	mfa            ports.MFARepository
+	throttle       *LoginThrottle
+	passwordResets ports.PasswordResetRepository
+	notifier       ports.Notifier
+	passwordPolicy domain.PasswordPolicy
+	passwordCost   int
+	unknownHash    []byte
+	apiKeys        ports.APIKeyRepository
+	oauth          ports.OAuthRepository
+	customers      ports.CustomerRepository
+	uow            ports.UnitOfWork
+	scopes         *OAuthScopeCache
+	audit          ports.AuditLogger
+	signer         utils.TokenSigner
+	keys           utils.KeyResolver
 }
 
-func NewAuthService(serviceURL string, repo ports.AuthRepository) *AuthService {
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		logger.Fatal("JWT_SECRET_KEY environment variable not set")
+// AuthServiceDeps lists the collaborators of AuthService. Tokens are signed
+// with Signer and verified with Keys, which is either the signer itself (auth
+// server) or a JWKS cache (other services). Only the auth server has a
+// Signer; without one the service verifies tokens but issues none.
+type AuthServiceDeps struct {
+	ServiceURL     string
+	Repo           ports.AuthRepository
+	Permissions    ports.RolePermissionsProvider
+	Policy         *policy.Policy
+	BreakGlass     ports.BreakGlassRepository
+	MFA            ports.MFARepository
+	Throttle       *LoginThrottle
+	PasswordResets ports.PasswordResetRepository
+	Notifier       ports.Notifier
+	APIKeys        ports.APIKeyRepository
+	OAuth          ports.OAuthRepository
+	Customers      ports.CustomerRepository
+	UnitOfWork     ports.UnitOfWork
+	Audit          ports.AuditLogger
+	Signer         utils.TokenSigner
+	Keys           utils.KeyResolver
+}
+
+func NewAuthService(deps AuthServiceDeps) *AuthService {
+	if deps.Keys == nil {
+		logger.Fatal("AuthService requires a key resolver")
+	}
+	if deps.Policy == nil {
+		logger.Fatal("AuthService requires an authorization policy")
+	}
+
+	passwordCost := passwordHashCostFromEnv()
+	var scopes *OAuthScopeCache
+	if deps.OAuth != nil {
+		scopes = NewOAuthScopeCache(deps.OAuth)
 	}
 
 	return &AuthService{
-		serviceURL: serviceURL,
-		repo:       repo,
-		secretKey:  []byte(secretKey),
+		serviceURL:     deps.ServiceURL,
+		repo:           deps.Repo,
+		permissions:    deps.Permissions,
+		policy:         deps.Policy,
+		breakGlass:     deps.BreakGlass,
+		mfa:            deps.MFA,
+		throttle:       deps.Throttle,
+		passwordResets: deps.PasswordResets,
+		notifier:       deps.Notifier,
+		passwordPolicy: passwordPolicyFromEnv(),
+		passwordCost:   passwordCost,
+		unknownHash:    unknownUserHash(passwordCost),
+		apiKeys:        deps.APIKeys,
+		oauth:          deps.OAuth,
+		customers:      deps.Customers,
+		uow:            deps.UnitOfWork,
+		scopes:         scopes,
+		audit:          deps.Audit,
+		signer:         deps.Signer,
+		keys:           deps.Keys,
 	}
 }
 
-func (s *AuthService) RemoteLogin(req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError) {
-	user, err := s.repo.FindUser(req.Username, req.Password)
+func (s *AuthService) RemoteLogin(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError) {
+	response, err := s.remoteLogin(ctx, req)
+	s.recordAuthEvent(ctx, "auth.login", req.Username, req.SourceIP, req.RequestID, response, err)
+	return response, err
+}
+
+func (s *AuthService) remoteLogin(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError) {
+	user, err := s.checkPassword(ctx, req.Username, req.Password, req.SourceIP)
 	if err != nil {
 		return nil, err
 	}
 
+	challenge, err := s.challengeIfRequired(ctx, user)
+	if err != nil || challenge != nil {
+		return challenge, err
+	}
+	s.throttle.Success(ctx, user.Username)
+
 	customerIDClaim := ""
 	if user.CustomerID != nil {
 		customerIDClaim = *user.CustomerID
@@ -50,36 +131,99 @@ func (s *AuthService) RemoteLogin(req dto.LoginRequest) (*dto.LoginResponse, *er
 		"exp":         jwt.TimeFunc().Add(24 * time.Hour).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, signErr := token.SignedString(s.secretKey)
+	tokenString, signErr := s.sign(claims)
 	if signErr != nil {
 		logger.Error("Failed to generate JWT token", logger.Any("error", signErr))
 		return nil, errs.NewUnexpectedError("Error generating token: " + signErr.Error())
 	}
 
-	return &dto.LoginResponse{Token: tokenString}, nil
+	idToken, err := s.signIDToken(user.Username, loginAudience(), "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.LoginResponse{Token: tokenString, IDToken: idToken}, nil
 }
 
-func (s *AuthService) Register(req dto.RegisterRequest) (*dto.LoginResponse, *errs.AppError) {
-	var customerID *string
-	if req.CustomerID != "" {
-		customerID = &req.CustomerID
+// checkPassword authenticates a user through the login throttle, so failed
+// attempts count towards the lockout of the username and the source IP. It
+// leaves the failures of the username in place: the caller clears them once
+// the whole login, second factor included, has succeeded, so that a correct
+// password does not reset the count of wrong MFA codes.
+func (s *AuthService) checkPassword(ctx context.Context, username, password, sourceIP string) (*domain.User, *errs.AppError) {
+	if err := s.throttle.Check(ctx, username, sourceIP); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindCredentials(ctx, username)
+	if err != nil {
+		if err.Code == http.StatusUnauthorized {
+			bcrypt.CompareHashAndPassword(s.unknownHash, []byte(password))
+			s.throttle.Failure(ctx, username, sourceIP)
+		}
+		return nil, err
 	}
+	if !s.matchPassword(ctx, user, password) {
+		logger.Warn("Invalid credentials", logger.String("username", username))
+		s.throttle.Failure(ctx, username, sourceIP)
+		return nil, errs.NewAuthenticationError("Invalid credentials")
+	}
+	if !user.IsActive() {
+		logger.Warn("Login attempt for disabled user", logger.String("username", username))
+		return nil, errs.NewForbiddenError("User account is disabled")
+	}
+	user.Password = ""
+	return user, nil
+}
 
+func (s *AuthService) Register(ctx context.Context, req dto.RegisterRequest) (*dto.LoginResponse, *errs.AppError) {
+	response, err := s.register(ctx, req)
+	s.recordAuthEvent(ctx, "auth.register", req.Username, req.SourceIP, req.RequestID, response, err)
+	return response, err
+}
+
+func (s *AuthService) register(ctx context.Context, req dto.RegisterRequest) (*dto.LoginResponse, *errs.AppError) {
+	if err := s.passwordPolicy.Validate(req.Username, req.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := s.hashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Registration is public: the role and the customer are left to admins.
 	user := domain.User{
-		Username:   req.Username,
-		Password:   req.Password,
-		Role:       req.Role,
-		CustomerID: customerID,
-		CreatedOn:  time.Now(),
+		Username:  req.Username,
+		Password:  hash,
+		Role:      domain.RoleUser,
+		CreatedOn: time.Now(),
 	}
 
-	_, err := s.repo.SaveUser(user)
+	err = s.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if _, err := repos.Auth.SaveUser(ctx, user); err != nil {
+			logger.Error("Error saving new user", logger.Any("error", err))
+			return err
+		}
+		return recordEvent(ctx, repos.Outbox, domain.AggregateUser, user.Username, domain.EventUserRegistered, domain.UserRegisteredPayload{
+			Username: user.Username,
+			Role:     user.Role,
+		})
+	})
 	if err != nil {
-		logger.Error("Error saving new user", logger.Any("error", err))
 		return nil, err
 	}
 
+	challenge, err := s.challengeIfRequired(ctx, &user)
+	if err != nil || challenge != nil {
+		return challenge, err
+	}
+	return s.issueTokens(ctx, &user)
+}
+
+// issueTokens signs an access token, a stored refresh token and an ID token
+// for user.
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User) (*dto.LoginResponse, *errs.AppError) {
 	customerIDClaim := ""
 	if user.CustomerID != nil {
 		customerIDClaim = *user.CustomerID
@@ -92,10 +236,9 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*dto.LoginResponse, *er
 		"exp":         jwt.TimeFunc().Add(24 * time.Hour).Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	accessToken, signErr := token.SignedString(s.secretKey)
+	accessToken, signErr := s.sign(claims)
 	if signErr != nil {
-		logger.Error("Failed to generate JWT token for new user", logger.Any("error", signErr))
+		logger.Error("Failed to generate JWT token", logger.Any("error", signErr))
 		return nil, errs.NewUnexpectedError("Error generating token: " + signErr.Error())
 	}
 
@@ -103,26 +246,31 @@ func (s *AuthService) Register(req dto.RegisterRequest) (*dto.LoginResponse, *er
 		"username": user.Username,
 		"exp":      jwt.TimeFunc().Add(7 * 24 * time.Hour).Unix(),
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims)
-	refreshTokenString, signErr := refreshToken.SignedString(s.secretKey)
+	refreshTokenString, signErr := s.sign(refreshClaims)
 	if signErr != nil {
-		logger.Error("Failed to generate refresh token for new user", logger.Any("error", signErr))
+		logger.Error("Failed to generate refresh token", logger.Any("error", signErr))
 		return nil, errs.NewUnexpectedError("Error generating refresh token: " + signErr.Error())
 	}
 
-	if err := s.repo.SaveRefreshToken(refreshTokenString); err != nil {
+	if err := s.repo.SaveRefreshToken(ctx, user.Username, refreshTokenString); err != nil {
 		logger.Error("Failed to save refresh token", logger.Any("error", err))
 		return nil, err
 	}
 
+	idToken, err := s.signIDToken(user.Username, loginAudience(), "")
+	if err != nil {
+		return nil, err
+	}
+
 	return &dto.LoginResponse{
 		Token:        accessToken,
 		RefreshToken: refreshTokenString,
+		IDToken:      idToken,
 	}, nil
 }
 
-func (s *AuthService) Refresh(token string) (*dto.LoginResponse, *errs.AppError) {
-	exists, err := s.repo.VerifyRefreshToken(token)
+func (s *AuthService) Refresh(ctx context.Context, token string) (*dto.LoginResponse, *errs.AppError) {
+	exists, err := s.repo.VerifyRefreshToken(ctx, token)
 	if err != nil {
 		return nil, err
 	}
@@ -131,7 +279,7 @@ func (s *AuthService) Refresh(token string) (*dto.LoginResponse, *errs.AppError)
 		return nil, errs.NewAuthenticationError("Invalid refresh token")
 	}
 
-	claims, tokenErr := utils.ExtractClaimsFromToken(token, func() []byte { return s.secretKey })
+	claims, tokenErr := utils.ExtractClaimsFromToken(token, s.keys)
 	if tokenErr != nil {
 		logger.Error("Failed to parse refresh token", logger.Any("error", tokenErr))
 		return nil, errs.NewAuthenticationError("Invalid refresh token")
@@ -142,19 +290,29 @@ func (s *AuthService) Refresh(token string) (*dto.LoginResponse, *errs.AppError)
 		return nil, errs.NewAuthenticationError("Refresh token expired")
 	}
 
+	if clientID, _ := claims[oauthClientClaim].(string); clientID != "" {
+		logger.Warn("OAuth refresh token presented to /auth/refresh", logger.String("client_id", clientID))
+		return nil, errs.NewAuthenticationError("OAuth refresh tokens must be used at /oauth/token")
+	}
+
 	username, _ := claims["username"].(string)
-	role, _ := claims["role"].(string)
-	customerID, _ := claims["customer_id"].(string)
+	user, err := s.activeUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	customerID := ""
+	if user.CustomerID != nil {
+		customerID = *user.CustomerID
+	}
 
 	newClaims := jwt.MapClaims{
-		"username":    username,
-		"role":        role,
+		"username":    user.Username,
+		"role":        user.Role,
 		"customer_id": customerID,
 		"exp":         jwt.TimeFunc().Add(24 * time.Hour).Unix(),
 	}
 
-	newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims)
-	newTokenString, signErr := newToken.SignedString(s.secretKey)
+	newTokenString, signErr := s.sign(newClaims)
 	if signErr != nil {
 		logger.Error("Failed to generate new JWT token for refresh", logger.Any("error", signErr))
 		return nil, errs.NewUnexpectedError("Error generating new token: " + signErr.Error())
@@ -163,41 +321,149 @@ func (s *AuthService) Refresh(token string) (*dto.LoginResponse, *errs.AppError)
 	return &dto.LoginResponse{Token: newTokenString}, nil
 }
 
-func (s *AuthService) RemoteIsAuthorized(token, routeName string, vars map[string]string) (bool, *errs.AppError) {
-	claims, tokenErr := utils.ExtractClaimsFromToken(token, func() []byte { return s.secretKey })
-	if tokenErr != nil {
-		logger.Error("Failed to parse token", logger.Any("error", tokenErr))
-		return false, errs.NewAuthenticationError("Invalid token")
+// activeUser loads a user for refreshing or finishing a login, rejecting
+// disabled users.
+func (s *AuthService) activeUser(ctx context.Context, username string) (*domain.User, *errs.AppError) {
+	user, err := s.repo.FindUserByUsername(ctx, username)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			return nil, errs.NewAuthenticationError("Unknown user")
+		}
+		return nil, err
 	}
+	if !user.IsActive() {
+		logger.Warn("Token requested for disabled user", logger.String("username", username))
+		return nil, errs.NewForbiddenError("User account is disabled")
+	}
+	return user, nil
+}
 
-	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
-		logger.Warn("Token expired")
-		return false, errs.NewAuthenticationError("Token expired")
+func (s *AuthService) RemoteIsAuthorized(ctx context.Context, token, routeName string, vars map[string]string) (bool, *errs.AppError) {
+	if _, err := s.Verify(ctx, token, routeName, vars, nil); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Verify validates the token signature and expiry in-process and evaluates the
+// authorization policy, so it can serve as a local ports.TokenVerifier.
+func (s *AuthService) Verify(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError) {
+	claims, err := s.verifiedClaims(token)
+	if err != nil {
+		return nil, err
 	}
 
 	role, ok := claims["role"].(string)
 	if !ok {
 		logger.Warn("Role not found in token")
-		return false, errs.NewAuthenticationError("Invalid token format")
+		return nil, errs.NewAuthenticationError("Invalid token format")
 	}
 
+	username, _ := claims["username"].(string)
 	customerID, _ := claims["customer_id"].(string)
 
-	isAuthorized := s.repo.VerifyPermission(role, customerID, routeName, vars)
-	if !isAuthorized {
+	if _, isOAuth := claims[oauthClientClaim]; isOAuth {
+		if err := s.oauthScopesCover(ctx, claims, routeName); err != nil {
+			return nil, err
+		}
+	}
+
+	if grantID, _ := claims[breakGlassClaim].(string); grantID != "" {
+		if err := s.authorizeBreakGlass(ctx, grantID, username, routeName); err != nil {
+			return nil, err
+		}
+		return &domain.VerifiedToken{
+			Username:          username,
+			Role:              role,
+			CustomerID:        customerID,
+			BreakGlassGrantID: grantID,
+		}, nil
+	}
+
+	decision := s.evaluatePolicy(claims, routeName, vars, resource)
+	if !decision.Allow {
 		logger.Warn("Permission denied",
 			logger.String("role", role),
-			logger.String("routeName", routeName))
-		return false, errs.NewAuthenticationError("Unauthorized")
+			logger.String("routeName", routeName),
+			logger.String("rule", decision.RuleID),
+			logger.String("reason", decision.Reason))
+		return nil, errs.NewForbiddenError(decision.Reason)
 	}
 
-	return true, nil
+	return &domain.VerifiedToken{
+		Username:   username,
+		Role:       role,
+		CustomerID: customerID,
+		Actor:      impersonationActor(claims),
+	}, nil
+}
+
+// AuthenticateToken checks the signature and expiry of a token, the part of
+// Verify that does not depend on the route or the resource.
+func (s *AuthService) AuthenticateToken(_ context.Context, token string) *errs.AppError {
+	_, err := s.verifiedClaims(token)
+	return err
+}
+
+func (s *AuthService) verifiedClaims(token string) (jwt.MapClaims, *errs.AppError) {
+	claims, tokenErr := utils.ExtractClaimsFromToken(token, s.keys)
+	if tokenErr != nil {
+		logger.Error("Failed to parse token", logger.Any("error", tokenErr))
+		return nil, errs.NewAuthenticationError("Invalid token")
+	}
+
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		logger.Warn("Token expired")
+		return nil, errs.NewAuthenticationError("Token expired")
+	}
+	return claims, nil
 }
 
-func (s *AuthService) GetSecretKey() []byte {
-	return s.secretKey
+func (s *AuthService) GetKeyResolver() utils.KeyResolver {
+	return s.keys
 }
 
-func (s *AuthService) GetRolePermissions() domain.RolePermissions {
-	return *domain.GetRolePermissions()
+func (s *AuthService) GetJWKS() utils.JWKS {
+	if s.signer == nil {
+		return utils.JWKS{Keys: []utils.JSONWebKey{}}
+	}
+	return s.signer.JWKS()
+}
+
+// sign signs claims with the signing key, which only the auth server has.
+func (s *AuthService) sign(claims jwt.MapClaims) (string, error) {
+	if s.signer == nil {
+		return "", errors.New("this service does not issue tokens")
+	}
+	return s.signer.Sign(claims)
+}
+
+func (s *AuthService) GetRolePermissions() *domain.RolePermissions {
+	return s.permissions.Current()
+}
+
+// recordAuthEvent writes the outcome of a login step to the audit log. A
+// response asking for a second factor is a success of the password step.
+func (s *AuthService) recordAuthEvent(ctx context.Context, action, username, sourceIP, requestID string, response *dto.LoginResponse, err *errs.AppError) {
+	if s.audit == nil {
+		return
+	}
+	event := domain.AuditEvent{
+		Actor:     username,
+		Action:    action,
+		Resource:  "user:" + username,
+		Outcome:   domain.AuditOutcomeSuccess,
+		SourceIP:  sourceIP,
+		RequestID: requestID,
+	}
+	switch {
+	case err != nil:
+		event.Outcome = domain.AuditOutcomeFailure
+		event.Details = err.Message
+	case response.MFARequired:
+		event.Details = "mfa required"
+	case response.MFAEnrollmentRequired:
+		event.Details = "mfa enrollment required"
+	}
+	s.audit.Record(ctx, event)
 }
@@ -0,0 +1,31 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+)
+
+func TestRegisterIgnoresTheRoleAndCustomerOfTheRequest(t *testing.T) {
+	f := newAuthFixture(t)
+	var request dto.RegisterRequest
+	body := `{"username": "mallory", "password": "Secret123", "role": "admin", "customer_id": "2000"}`
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		t.Fatalf("decoding: %v", err)
+	}
+	if _, err := f.auth.Register(context.Background(), request); err != nil {
+		t.Fatalf("registering: %v", err)
+	}
+
+	var role string
+	var customerID sql.NullString
+	if err := f.db.QueryRow(`SELECT role, customer_id FROM users WHERE username = 'mallory'`).Scan(&role, &customerID); err != nil {
+		t.Fatalf("reading the user: %v", err)
+	}
+	if role != "user" || customerID.Valid {
+		t.Errorf("expected role user without a customer, got %s and %v", role, customerID)
+	}
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const breakGlassClaim = "break_glass"
+
+// BreakGlass re-authenticates an eligible user, with the MFA code in the same
+// request when MFA applies to them, and issues a short-lived token that is
+// authorized for every route. The grant and each use of it are written
+// to the break-glass audit trail.
+func (s *AuthService) BreakGlass(ctx context.Context, req dto.BreakGlassRequest) (*dto.BreakGlassResponse, *errs.AppError) {
+	if s.breakGlass == nil {
+		return nil, errs.NewForbiddenError("Break-glass access is not enabled")
+	}
+
+	maxDuration := config.Duration("BREAK_GLASS_MAX_DURATION", time.Hour)
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	if duration > maxDuration {
+		return nil, errs.NewValidationError("Duration exceeds the maximum of " + maxDuration.String())
+	}
+
+	user, err := s.checkPassword(ctx, req.Username, req.Password, req.SourceIP)
+	if err != nil {
+		logBreakGlass(domain.BreakGlassDenied, req.Username, "", "invalid credentials")
+		return nil, err
+	}
+	if !isBreakGlassEligible(user.Role) {
+		logBreakGlass(domain.BreakGlassDenied, user.Username, "", "role not eligible")
+		return nil, errs.NewForbiddenError("User is not eligible for break-glass access")
+	}
+	if err := s.checkSecondFactor(ctx, user, req.MFACode, req.SourceIP); err != nil {
+		logBreakGlass(domain.BreakGlassDenied, user.Username, "", "second factor refused")
+		return nil, err
+	}
+	s.throttle.Success(ctx, user.Username)
+
+	now := time.Now()
+	grant, err := s.breakGlass.SaveGrant(ctx, domain.BreakGlassGrant{
+		Username:  user.Username,
+		Reason:    strings.TrimSpace(req.Reason),
+		SourceIP:  req.SourceIP,
+		CreatedOn: now,
+		ExpiresAt: now.Add(duration),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.recordBreakGlass(ctx, grant.ID, domain.BreakGlassGranted, user.Username, ""); err != nil {
+		return nil, err
+	}
+
+	customerIDClaim := ""
+	if user.CustomerID != nil {
+		customerIDClaim = *user.CustomerID
+	}
+
+	claims := jwt.MapClaims{
+		"username":      user.Username,
+		"role":          user.Role,
+		"customer_id":   customerIDClaim,
+		breakGlassClaim: grant.ID,
+		"exp":           grant.ExpiresAt.Unix(),
+	}
+	token, signErr := s.sign(claims)
+	if signErr != nil {
+		logger.Error("Failed to generate break-glass token", logger.Any("error", signErr))
+		return nil, errs.NewUnexpectedError("Error generating token: " + signErr.Error())
+	}
+
+	return &dto.BreakGlassResponse{Token: token, GrantID: grant.ID, ExpiresAt: grant.ExpiresAt}, nil
+}
+
+// authorizeBreakGlass checks the grant referenced by an elevated token on every
+// request, so revoking or expiring the grant takes effect immediately.
+func (s *AuthService) authorizeBreakGlass(ctx context.Context, grantID, username, routeName string) *errs.AppError {
+	if s.breakGlass == nil {
+		return errs.NewForbiddenError("Break-glass access is not enabled")
+	}
+
+	grant, err := s.breakGlass.FindGrant(ctx, grantID)
+	if err != nil {
+		logBreakGlass(domain.BreakGlassDenied, username, routeName, "grant not found")
+		return errs.NewAuthenticationError("Invalid token")
+	}
+	if grant.Username != username || !grant.IsActive(time.Now()) {
+		logBreakGlass(domain.BreakGlassDenied, username, routeName, "grant inactive")
+		return errs.NewAuthenticationError("Break-glass grant expired")
+	}
+	return s.recordBreakGlass(ctx, grant.ID, domain.BreakGlassUsed, username, routeName)
+}
+
+func (s *AuthService) recordBreakGlass(ctx context.Context, grantID, event, username, routeName string) *errs.AppError {
+	logBreakGlass(event, username, routeName, "")
+	return s.breakGlass.SaveEvent(ctx, domain.BreakGlassEvent{
+		GrantID:   grantID,
+		Event:     event,
+		Username:  username,
+		RouteName: routeName,
+		CreatedOn: time.Now(),
+	})
+}
+
+func logBreakGlass(event, username, routeName, reason string) {
+	logger.Warn("Break-glass access",
+		logger.Bool("audit", true),
+		logger.String("event", event),
+		logger.String("username", username),
+		logger.String("routeName", routeName),
+		logger.String("reason", reason))
+}
+
+func isBreakGlassEligible(role string) bool {
+	for _, eligible := range strings.Split(config.String("BREAK_GLASS_ROLES", "admin"), ",") {
+		if strings.EqualFold(strings.TrimSpace(eligible), role) {
+			return true
+		}
+	}
+	return false
+}
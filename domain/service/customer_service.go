@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"github.com/titi0001/Microservices-API-in-Go/api/dto"
 	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
@@ -15,7 +16,7 @@ func NewCustomerService(repo ports.CustomerRepository) ports.CustomerService {
 	return &DefaultCustomerService{repo: repo}
 }
 
-func (s DefaultCustomerService) GetAllCustomer(status string) ([]dto.CustomerResponse, *errs.AppError) {
+func (s DefaultCustomerService) GetAllCustomer(ctx context.Context, status string) ([]dto.CustomerResponse, *errs.AppError) {
 	switch status {
 	case "active":
 		status = "1"
@@ -25,7 +26,7 @@ func (s DefaultCustomerService) GetAllCustomer(status string) ([]dto.CustomerRes
 		status = ""
 	}
 
-	customers, err := s.repo.FindAll(status)
+	customers, err := s.repo.FindAll(ctx, status)
 	if err != nil {
 		return nil, err
 	}
@@ -37,8 +38,8 @@ func (s DefaultCustomerService) GetAllCustomer(status string) ([]dto.CustomerRes
 	return response, nil
 }
 
-func (s DefaultCustomerService) GetCustomer(id string) (*dto.CustomerResponse, *errs.AppError) {
-	c, err := s.repo.ByID(id)
+func (s DefaultCustomerService) GetCustomer(ctx context.Context, id string) (*dto.CustomerResponse, *errs.AppError) {
+	c, err := s.repo.ByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// recordEvent appends an event to the outbox of a unit of work; the relay
+// publishes it once the unit commits.
+func recordEvent(ctx context.Context, outbox ports.OutboxRepository, aggregateType, aggregateID, eventType string, payload interface{}) *errs.AppError {
+	event, err := domain.NewEvent(aggregateType, aggregateID, eventType, payload)
+	if err != nil {
+		logger.Error("Error creating event", logger.String("event_type", eventType), logger.Any("error", err))
+		return errs.NewUnexpectedError("Error creating event")
+	}
+	return outbox.Append(ctx, event)
+}
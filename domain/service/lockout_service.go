@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type DefaultLockoutService struct {
+	repo ports.LoginAttemptRepository
+}
+
+func NewLockoutService(repo ports.LoginAttemptRepository) ports.LockoutService {
+	return &DefaultLockoutService{repo: repo}
+}
+
+func (s *DefaultLockoutService) ListLockouts(ctx context.Context) ([]dto.LockoutResponse, *errs.AppError) {
+	attempts, err := s.repo.FindLocked(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.LockoutResponse, 0, len(attempts))
+	for _, a := range attempts {
+		response = append(response, dto.LockoutResponse{
+			Scope:       a.Scope,
+			Subject:     a.Subject,
+			Failures:    a.Failures,
+			LastFailure: a.LastFailure,
+			LockedUntil: *a.LockedUntil,
+		})
+	}
+	return response, nil
+}
+
+// ClearLockout unlocks a username or an IP and forgets its failed attempts.
+func (s *DefaultLockoutService) ClearLockout(ctx context.Context, scope, subject string) *errs.AppError {
+	if !domain.IsValidLoginScope(scope) {
+		return errs.NewValidationError("Scope must be 'username' or 'ip'")
+	}
+	if scope == domain.LoginScopeUsername {
+		subject = normalizeLoginSubject(subject)
+	}
+
+	cleared, err := s.repo.Reset(ctx, scope, strings.TrimSpace(subject))
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return errs.NewNotFoundError("No failed logins recorded for " + subject)
+	}
+	logger.Warn("Login lockout cleared",
+		logger.Bool("audit", true),
+		logger.String("scope", scope),
+		logger.String("subject", subject))
+	return nil
+}
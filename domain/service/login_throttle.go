@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// LoginThrottle slows down and then locks out repeated failed logins, counted
+// separately for each username and each source IP.
+type LoginThrottle struct {
+	repo             ports.LoginAttemptRepository
+	maxFailures      int
+	maxFailuresPerIP int
+	window           time.Duration
+	lockout          time.Duration
+	baseDelay        time.Duration
+	maxDelay         time.Duration
+	now              func() time.Time
+}
+
+func NewLoginThrottle(repo ports.LoginAttemptRepository) *LoginThrottle {
+	return &LoginThrottle{
+		repo:             repo,
+		maxFailures:      config.Int("LOGIN_MAX_FAILURES", 5),
+		maxFailuresPerIP: config.Int("LOGIN_MAX_FAILURES_PER_IP", 20),
+		window:           config.Duration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		lockout:          config.Duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		baseDelay:        config.Duration("LOGIN_DELAY_BASE", 500*time.Millisecond),
+		maxDelay:         config.Duration("LOGIN_DELAY_MAX", 30*time.Second),
+		now:              time.Now,
+	}
+}
+
+// Check rejects an attempt while the username or the IP is locked out, or
+// when it comes sooner than the delay earned by the previous failures.
+func (t *LoginThrottle) Check(ctx context.Context, username, ip string) *errs.AppError {
+	if t == nil {
+		return nil
+	}
+	now := t.now()
+	for _, key := range loginKeys(username, ip) {
+		attempt, err := t.repo.Find(ctx, key.scope, key.subject)
+		if err != nil {
+			return err
+		}
+		if attempt.IsLocked(now) {
+			logger.Warn("Login rejected during lockout",
+				logger.Bool("audit", true),
+				logger.String("scope", key.scope),
+				logger.String("username", username),
+				logger.String("source_ip", ip))
+			return errs.NewTooManyRequestsError("Too many failed login attempts, try again later")
+		}
+		if attempt.Failures == 0 {
+			continue
+		}
+		if retryAt := attempt.LastFailure.Add(t.delay(attempt.Failures)); now.Before(retryAt) {
+			wait := int(math.Ceil(retryAt.Sub(now).Seconds()))
+			return errs.NewTooManyRequestsError(fmt.Sprintf("Too many failed login attempts, try again in %ds", wait))
+		}
+	}
+	return nil
+}
+
+// Failure counts a failed attempt and locks the username or the IP once it
+// reaches its limit. It is counted even when the request has been canceled,
+// so a client cannot drop the connection to escape the count.
+func (t *LoginThrottle) Failure(ctx context.Context, username, ip string) {
+	if t == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	now := t.now()
+	for _, key := range loginKeys(username, ip) {
+		attempt, err := t.repo.RecordFailure(ctx, key.scope, key.subject, now, now.Add(-t.window))
+		if err != nil {
+			continue
+		}
+
+		limit := t.maxFailures
+		if key.scope == domain.LoginScopeIP {
+			limit = t.maxFailuresPerIP
+		}
+		if attempt.Failures < limit || attempt.IsLocked(now) {
+			continue
+		}
+
+		until := now.Add(t.lockout)
+		if err := t.repo.Lock(ctx, key.scope, key.subject, until); err != nil {
+			continue
+		}
+		logger.Warn("Login locked out",
+			logger.Bool("audit", true),
+			logger.String("scope", key.scope),
+			logger.String("username", username),
+			logger.String("source_ip", ip),
+			logger.Int("failures", attempt.Failures),
+			logger.String("locked_until", until.Format(time.RFC3339)))
+	}
+}
+
+// Success clears the failures of the username. The IP count is kept, so one
+// valid account does not let an address keep guessing others.
+func (t *LoginThrottle) Success(ctx context.Context, username string) {
+	if t == nil {
+		return
+	}
+	if _, err := t.repo.Reset(ctx, domain.LoginScopeUsername, normalizeLoginSubject(username)); err != nil {
+		logger.Error("Failed to reset login failures", logger.String("username", username))
+	}
+}
+
+// delay doubles with every failure, from baseDelay up to maxDelay.
+func (t *LoginThrottle) delay(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	delay := t.baseDelay
+	for i := 1; i < failures && delay < t.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > t.maxDelay {
+		delay = t.maxDelay
+	}
+	return delay
+}
+
+type loginKey struct {
+	scope   string
+	subject string
+}
+
+func loginKeys(username, ip string) []loginKey {
+	keys := []loginKey{{scope: domain.LoginScopeUsername, subject: normalizeLoginSubject(username)}}
+	if ip != "" {
+		keys = append(keys, loginKey{scope: domain.LoginScopeIP, subject: ip})
+	}
+	return keys
+}
+
+func normalizeLoginSubject(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	mfaClaim          = "mfa"
+	mfaChallenge      = "challenge"
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// challengeIfRequired returns an MFA challenge for users who enabled MFA or
+// whose role requires it, and nil when the password alone is enough. The
+// challenge token carries no role, so it is rejected by every protected route.
+func (s *AuthService) challengeIfRequired(ctx context.Context, user *domain.User) (*dto.LoginResponse, *errs.AppError) {
+	if s.mfa == nil {
+		return nil, nil
+	}
+
+	enrollment, err := s.mfa.FindEnrollment(ctx, user.Username)
+	if err != nil && err.Code != http.StatusNotFound {
+		return nil, err
+	}
+	enabled := enrollment != nil && enrollment.Enabled
+
+	if !enabled {
+		required, err := s.mfa.RoleRequiresMFA(ctx, user.Role)
+		if err != nil {
+			return nil, err
+		}
+		if !required {
+			return nil, nil
+		}
+	}
+
+	claims := jwt.MapClaims{
+		"username": user.Username,
+		mfaClaim:   mfaChallenge,
+		"exp":      jwt.TimeFunc().Add(config.Duration("MFA_CHALLENGE_TTL", 5*time.Minute)).Unix(),
+	}
+	token, signErr := s.sign(claims)
+	if signErr != nil {
+		logger.Error("Failed to generate MFA challenge token", logger.Any("error", signErr))
+		return nil, errs.NewUnexpectedError("Error generating token: " + signErr.Error())
+	}
+
+	logger.Info("MFA challenge issued", logger.String("username", user.Username), logger.Bool("enrollment_required", !enabled))
+	return &dto.LoginResponse{MFARequired: true, MFAToken: token, MFAEnrollmentRequired: !enabled}, nil
+}
+
+// EnrollMFA creates a pending TOTP enrollment for the holder of token, which is
+// either an access token or the challenge token of a user whose role requires
+// MFA. Enrolling again before confirming replaces the pending secret.
+func (s *AuthService) EnrollMFA(ctx context.Context, token string) (*dto.MFAEnrollResponse, *errs.AppError) {
+	if s.mfa == nil {
+		return nil, errs.NewForbiddenError("MFA is not enabled")
+	}
+	username, _, err := s.mfaTokenSubject(token)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, genErr := utils.NewTOTPSecret()
+	if genErr != nil {
+		logger.Error("Failed to generate TOTP secret", logger.Any("error", genErr))
+		return nil, errs.NewUnexpectedError("Error generating MFA secret")
+	}
+	codes, hashes, genErr := newRecoveryCodes()
+	if genErr != nil {
+		logger.Error("Failed to generate recovery codes", logger.Any("error", genErr))
+		return nil, errs.NewUnexpectedError("Error generating recovery codes")
+	}
+
+	enrollment := domain.MFAEnrollment{Username: username, Secret: secret, CreatedOn: time.Now()}
+	if err := s.mfa.SaveEnrollment(ctx, enrollment, hashes); err != nil {
+		return nil, err
+	}
+
+	logger.Info("MFA enrollment started", logger.String("username", username))
+	return &dto.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(config.String("MFA_ISSUER", "Banking"), username, secret),
+		RecoveryCodes:   codes,
+	}, nil
+}
+
+// ConfirmMFA enables a pending enrollment once the user presents a valid code.
+func (s *AuthService) ConfirmMFA(ctx context.Context, token string, req dto.MFACodeRequest) *errs.AppError {
+	if s.mfa == nil {
+		return errs.NewForbiddenError("MFA is not enabled")
+	}
+	username, _, err := s.mfaTokenSubject(token)
+	if err != nil {
+		return err
+	}
+
+	enrollment, err := s.mfa.FindEnrollment(ctx, username)
+	if err != nil {
+		return err
+	}
+	if enrollment.Enabled {
+		return errs.NewConflictError("MFA is already enabled")
+	}
+	if _, err := s.checkMFACode(ctx, enrollment, req.Code); err != nil {
+		return err
+	}
+
+	logger.Info("MFA enabled", logger.String("username", username))
+	return nil
+}
+
+// VerifyMFA exchanges a challenge token and a TOTP or recovery code for the
+// access and refresh tokens. A valid code also confirms a pending enrollment.
+func (s *AuthService) VerifyMFA(ctx context.Context, req dto.MFAVerifyRequest) (*dto.LoginResponse, *errs.AppError) {
+	if s.mfa == nil {
+		return nil, errs.NewForbiddenError("MFA is not enabled")
+	}
+	username, challenge, err := s.mfaTokenSubject(req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+	if !challenge {
+		return nil, errs.NewAuthenticationError("Invalid MFA token")
+	}
+
+	response, err := s.completeMFA(ctx, username, req)
+	s.recordAuthEvent(ctx, "auth.mfa_verify", username, req.SourceIP, req.RequestID, response, err)
+	return response, err
+}
+
+func (s *AuthService) completeMFA(ctx context.Context, username string, req dto.MFAVerifyRequest) (*dto.LoginResponse, *errs.AppError) {
+	if err := s.throttle.Check(ctx, username, req.SourceIP); err != nil {
+		return nil, err
+	}
+
+	enrollment, err := s.mfa.FindEnrollment(ctx, username)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			return nil, errs.NewForbiddenError("MFA enrollment required")
+		}
+		return nil, err
+	}
+	if _, err := s.checkMFACode(ctx, enrollment, req.Code); err != nil {
+		if err.Code == http.StatusUnauthorized {
+			s.throttle.Failure(ctx, username, req.SourceIP)
+		}
+		return nil, err
+	}
+	s.throttle.Success(ctx, username)
+
+	user, err := s.activeUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, user)
+}
+
+// checkSecondFactor checks a code sent along with the password, for flows
+// without a challenge step, when the user enabled MFA or their role requires
+// it. A user whose role requires MFA but who has not enrolled is refused.
+func (s *AuthService) checkSecondFactor(ctx context.Context, user *domain.User, code, sourceIP string) *errs.AppError {
+	if s.mfa == nil {
+		return nil
+	}
+
+	enrollment, err := s.mfa.FindEnrollment(ctx, user.Username)
+	if err != nil && err.Code != http.StatusNotFound {
+		return err
+	}
+	if enrollment == nil || !enrollment.Enabled {
+		required, err := s.mfa.RoleRequiresMFA(ctx, user.Role)
+		if err != nil {
+			return err
+		}
+		if required {
+			return errs.NewForbiddenError("MFA enrollment required")
+		}
+		return nil
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return errs.NewAuthenticationError("MFA code required")
+	}
+	if _, err := s.checkMFACode(ctx, enrollment, code); err != nil {
+		if err.Code == http.StatusUnauthorized {
+			s.throttle.Failure(ctx, user.Username, sourceIP)
+		}
+		return err
+	}
+	return nil
+}
+
+// checkMFACode accepts a TOTP code that was not used before or, once the
+// enrollment is enabled, an unused recovery code. A pending enrollment is
+// enabled by its first valid TOTP code.
+func (s *AuthService) checkMFACode(ctx context.Context, enrollment *domain.MFAEnrollment, code string) (int64, *errs.AppError) {
+	if step, ok := utils.ValidateTOTP(enrollment.Secret, code, time.Now()); ok {
+		if !enrollment.Enabled {
+			return step, s.mfa.EnableEnrollment(ctx, enrollment.Username, step)
+		}
+		fresh, err := s.mfa.UseStep(ctx, enrollment.Username, step)
+		if err != nil {
+			return 0, err
+		}
+		if !fresh {
+			logger.Warn("MFA code replayed", logger.String("username", enrollment.Username))
+			return 0, errs.NewAuthenticationError("MFA code already used")
+		}
+		return step, nil
+	}
+
+	if enrollment.Enabled {
+		used, err := s.mfa.UseRecoveryCode(ctx, enrollment.Username, hashRecoveryCode(code))
+		if err != nil {
+			return 0, err
+		}
+		if used {
+			logger.Warn("MFA recovery code used", logger.String("username", enrollment.Username))
+			return 0, nil
+		}
+	}
+
+	logger.Warn("Invalid MFA code", logger.String("username", enrollment.Username))
+	return 0, errs.NewAuthenticationError("Invalid MFA code")
+}
+
+// mfaTokenSubject validates an access or challenge token and returns its
+// username and whether it is a challenge token. Refresh tokens carry no role,
+// ID tokens carry an audience and OAuth tokens a client: all of them are
+// refused, so that only the user's own session manages its credentials.
+func (s *AuthService) mfaTokenSubject(token string) (string, bool, *errs.AppError) {
+	claims, tokenErr := utils.ExtractClaimsFromToken(token, s.keys)
+	if tokenErr != nil {
+		logger.Warn("Failed to parse MFA token", logger.Any("error", tokenErr))
+		return "", false, errs.NewAuthenticationError("Invalid token")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return "", false, errs.NewAuthenticationError("Token expired")
+	}
+
+	username, _ := claims["username"].(string)
+	if username == "" {
+		return "", false, errs.NewAuthenticationError("Invalid token format")
+	}
+	if isImpersonated(claims) {
+		return "", false, errs.NewForbiddenError("Not allowed while impersonating")
+	}
+	if challenge, ok := claims[mfaClaim]; ok {
+		if challenge != mfaChallenge {
+			return "", false, errs.NewAuthenticationError("Invalid token")
+		}
+		return username, true, nil
+	}
+	role, _ := claims["role"].(string)
+	_, hasClient := claims[oauthClientClaim]
+	_, hasAudience := claims["aud"]
+	if role == "" || hasClient || hasAudience {
+		logger.Warn("Token is not an access token", logger.String("username", username))
+		return "", false, errs.NewAuthenticationError("Invalid token")
+	}
+	return username, false, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw := make([]byte, 5)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, err
+		}
+		encoded := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+		code := encoded[:4] + "-" + encoded[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
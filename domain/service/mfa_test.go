@@ -0,0 +1,312 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/policy"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/repository"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+)
+
+// seedPassword is the password of the users seeded by the migrations.
+const seedPassword = "abc123"
+
+// authFixture is an auth service over an in-memory SQLite database with the
+// embedded migrations applied, so the service runs against its real
+// repositories.
+type authFixture struct {
+	db       *sqlx.DB
+	auth     *service.AuthService
+	notifier *recordingNotifier
+}
+
+type recordingNotifier struct {
+	sent []domain.Notification
+}
+
+func (n *recordingNotifier) Notify(notification domain.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+// migratedDB opens an in-memory SQLite database with the embedded
+// migrations applied.
+func migratedDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	cfg := database.DefaultConfig()
+	cfg.Driver = database.DriverSQLite
+	cfg.Path = ":memory:"
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	migrator, err := database.NewEmbeddedMigrator(db)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	return db
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	t.Setenv("LOGIN_DELAY_BASE", "0s")
+	t.Setenv("PASSWORD_HASH_COST", "4")
+
+	db := migratedDB(t)
+	keys, err := utils.NewEphemeralSigningKeySet()
+	if err != nil {
+		t.Fatalf("generating signing key: %v", err)
+	}
+	defaultPolicy, err := policy.Default()
+	if err != nil {
+		t.Fatalf("loading default policy: %v", err)
+	}
+	notifier := &recordingNotifier{}
+	auth := service.NewAuthService(service.AuthServiceDeps{
+		ServiceURL:     "http://auth.test",
+		Repo:           repository.NewAuthRepositoryDb(db),
+		Permissions:    service.NewRolePermissionsCache(repository.NewRoleRepositoryDb(db)),
+		Policy:         defaultPolicy,
+		BreakGlass:     repository.NewBreakGlassRepositoryDb(db),
+		MFA:            repository.NewMFARepositoryDb(db),
+		Throttle:       service.NewLoginThrottle(repository.NewLoginAttemptRepositoryDb(db)),
+		PasswordResets: repository.NewPasswordResetRepositoryDb(db),
+		Notifier:       notifier,
+		OAuth:          repository.NewOAuthRepositoryDb(db),
+		Customers:      repository.NewCustomerRepositoryDb(db),
+		UnitOfWork:     repository.NewUnitOfWorkDb(db),
+		Signer:         keys,
+		Keys:           keys,
+	})
+	return authFixture{db: db, auth: auth, notifier: notifier}
+}
+
+func (f authFixture) login(t *testing.T, username string) *dto.LoginResponse {
+	t.Helper()
+	response, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: username, Password: seedPassword, SourceIP: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("login of %s: %v", username, err)
+	}
+	return response
+}
+
+// enroll enables TOTP for username and returns its secret, its recovery codes
+// and the step of the code that confirmed the enrollment.
+func (f authFixture) enroll(t *testing.T, username string) (string, []string, int64) {
+	t.Helper()
+	token := f.login(t, username).Token
+	enrollment, err := f.auth.EnrollMFA(context.Background(), token)
+	if err != nil {
+		t.Fatalf("enrolling: %v", err)
+	}
+	step := utils.TOTPStep(time.Now())
+	if err := f.auth.ConfirmMFA(context.Background(), token, dto.MFACodeRequest{Code: totpCode(t, enrollment.Secret, step)}); err != nil {
+		t.Fatalf("confirming: %v", err)
+	}
+	return enrollment.Secret, enrollment.RecoveryCodes, step
+}
+
+func (f authFixture) requireMFA(t *testing.T, role string) {
+	t.Helper()
+	if _, err := f.db.Exec(`UPDATE roles SET mfa_required = TRUE WHERE name = ?`, role); err != nil {
+		t.Fatalf("requiring MFA of %s: %v", role, err)
+	}
+}
+
+func totpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := utils.TOTPCode(secret, step)
+	if err != nil {
+		t.Fatalf("computing TOTP code: %v", err)
+	}
+	return code
+}
+
+func expectCode(t *testing.T, what string, err *errs.AppError, code int) {
+	t.Helper()
+	if err == nil || err.Code != code {
+		t.Errorf("expected %s to fail with %d, got %v", what, code, err)
+	}
+}
+
+func TestMFAChallengesAndVerifiesTOTP(t *testing.T) {
+	f := newAuthFixture(t)
+	secret, _, step := f.enroll(t, "2000")
+
+	challenge := f.login(t, "2000")
+	if !challenge.MFARequired || challenge.Token != "" || challenge.MFAToken == "" {
+		t.Fatalf("expected an MFA challenge instead of tokens, got %+v", challenge)
+	}
+
+	_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "000000"})
+	expectCode(t, "a wrong code", err, http.StatusUnauthorized)
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, step)})
+	expectCode(t, "the code that confirmed the enrollment", err, http.StatusUnauthorized)
+
+	tokens, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, step+1)})
+	if err != nil {
+		t.Fatalf("verifying a fresh code: %v", err)
+	}
+	if tokens.Token == "" || tokens.RefreshToken == "" {
+		t.Errorf("expected tokens, got %+v", tokens)
+	}
+
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, step+1)})
+	expectCode(t, "a replayed code", err, http.StatusUnauthorized)
+	_, err = f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: tokens.Token, Code: totpCode(t, secret, step-1)})
+	expectCode(t, "an access token as challenge", err, http.StatusUnauthorized)
+}
+
+func TestMFAAcceptsOnlyAccessAndChallengeTokens(t *testing.T) {
+	f := newAuthFixture(t)
+	oauthTokens := f.webTokens(t, "openid customers.read")
+	secret, _, step := f.enroll(t, "2000")
+	challenge := f.login(t, "2000")
+	tokens, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: totpCode(t, secret, step+1)})
+	if err != nil {
+		t.Fatalf("verifying a fresh code: %v", err)
+	}
+
+	for _, tt := range []struct {
+		what  string
+		token string
+	}{
+		{"a refresh token", tokens.RefreshToken},
+		{"an ID token", tokens.IDToken},
+		{"an OAuth access token", oauthTokens.AccessToken},
+		{"an OAuth refresh token", oauthTokens.RefreshToken},
+		{"an OAuth ID token", oauthTokens.IDToken},
+	} {
+		err := f.auth.ConfirmMFA(context.Background(), tt.token, dto.MFACodeRequest{Code: totpCode(t, secret, step+2)})
+		expectCode(t, tt.what+" confirming an enrollment", err, http.StatusUnauthorized)
+		err = f.auth.ChangePassword(context.Background(), tt.token, dto.PasswordChangeRequest{CurrentPassword: seedPassword, NewPassword: "N3w-passw0rd!"})
+		expectCode(t, tt.what+" changing the password", err, http.StatusUnauthorized)
+	}
+
+	if err := f.auth.ChangePassword(context.Background(), tokens.Token, dto.PasswordChangeRequest{CurrentPassword: seedPassword, NewPassword: "N3w-passw0rd!"}); err != nil {
+		t.Errorf("expected the access token to change the password: %v", err)
+	}
+}
+
+func TestMFARecoveryCodesAreSingleUse(t *testing.T) {
+	f := newAuthFixture(t)
+	_, codes, _ := f.enroll(t, "2000")
+
+	challenge := f.login(t, "2000")
+	if _, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: codes[0]}); err != nil {
+		t.Fatalf("verifying a recovery code: %v", err)
+	}
+	_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: codes[0]})
+	expectCode(t, "a used recovery code", err, http.StatusUnauthorized)
+
+	// Tokens issued in the same second are equal; move on to the next one.
+	defer func(timeFunc func() time.Time) { jwt.TimeFunc = timeFunc }(jwt.TimeFunc)
+	jwt.TimeFunc = func() time.Time { return time.Now().Add(time.Second) }
+	if _, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: codes[1]}); err != nil {
+		t.Errorf("expected another recovery code to work: %v", err)
+	}
+}
+
+func TestMFARequiredByRole(t *testing.T) {
+	f := newAuthFixture(t)
+	f.requireMFA(t, "user")
+
+	challenge := f.login(t, "2000")
+	if !challenge.MFARequired || !challenge.MFAEnrollmentRequired || challenge.Token != "" {
+		t.Fatalf("expected an enrollment challenge, got %+v", challenge)
+	}
+	if admin := f.login(t, "admin"); admin.MFARequired || admin.Token == "" {
+		t.Errorf("expected roles without the requirement to log in with the password, got %+v", admin)
+	}
+
+	// The challenge token enrolls; its first valid code enables MFA and logs in.
+	enrollment, err := f.auth.EnrollMFA(context.Background(), challenge.MFAToken)
+	if err != nil {
+		t.Fatalf("enrolling with the challenge token: %v", err)
+	}
+	code := totpCode(t, enrollment.Secret, utils.TOTPStep(time.Now()))
+	tokens, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: code})
+	if err != nil {
+		t.Fatalf("verifying the first code: %v", err)
+	}
+	if tokens.Token == "" {
+		t.Errorf("expected tokens, got %+v", tokens)
+	}
+	if again := f.login(t, "2000"); !again.MFARequired || again.MFAEnrollmentRequired {
+		t.Errorf("expected a code challenge once enrolled, got %+v", again)
+	}
+}
+
+func TestBreakGlassRequiresTheSecondFactor(t *testing.T) {
+	f := newAuthFixture(t)
+	secret, codes, step := f.enroll(t, "admin")
+	request := dto.BreakGlassRequest{
+		Username:        "admin",
+		Password:        seedPassword,
+		Reason:          "permissions table corrupted",
+		DurationMinutes: 10,
+		SourceIP:        "10.0.0.1",
+	}
+
+	_, err := f.auth.BreakGlass(context.Background(), request)
+	expectCode(t, "break-glass without a code", err, http.StatusUnauthorized)
+	request.MFACode = "000000"
+	_, err = f.auth.BreakGlass(context.Background(), request)
+	expectCode(t, "break-glass with a wrong code", err, http.StatusUnauthorized)
+
+	request.MFACode = totpCode(t, secret, step+1)
+	grant, err := f.auth.BreakGlass(context.Background(), request)
+	if err != nil {
+		t.Fatalf("break-glass with a code: %v", err)
+	}
+	if grant.Token == "" {
+		t.Errorf("expected a break-glass token, got %+v", grant)
+	}
+	request.MFACode = codes[0]
+	if _, err := f.auth.BreakGlass(context.Background(), request); err != nil {
+		t.Errorf("expected break-glass with a recovery code: %v", err)
+	}
+}
+
+func TestBreakGlassRequiresEnrollmentWhenTheRoleRequiresMFA(t *testing.T) {
+	f := newAuthFixture(t)
+	f.requireMFA(t, "admin")
+
+	_, err := f.auth.BreakGlass(context.Background(), dto.BreakGlassRequest{
+		Username:        "admin",
+		Password:        seedPassword,
+		Reason:          "permissions table corrupted",
+		DurationMinutes: 10,
+		MFACode:         "123456",
+	})
+	expectCode(t, "break-glass of a user who has not enrolled", err, http.StatusForbidden)
+}
+
+func TestPasswordDoesNotClearMFAFailures(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	f := newAuthFixture(t)
+	f.enroll(t, "2000")
+
+	for i := 0; i < 3; i++ {
+		challenge := f.login(t, "2000")
+		_, err := f.auth.VerifyMFA(context.Background(), dto.MFAVerifyRequest{MFAToken: challenge.MFAToken, Code: "000000", SourceIP: "10.0.0.1"})
+		expectCode(t, "a wrong code", err, http.StatusUnauthorized)
+	}
+
+	_, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: seedPassword, SourceIP: "10.0.0.2"})
+	expectCode(t, "a login after the MFA failures", err, http.StatusTooManyRequests)
+}
@@ -0,0 +1,594 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	oauthTokenType   = "Bearer"
+	oauthScopeClaim  = "scope"
+	oauthClientClaim = "client_id"
+	pkceMethodS256   = "S256"
+)
+
+// Authorize authenticates the resource owner and issues a single-use
+// authorization code bound to the client, the redirect URI and the PKCE
+// challenge. Only S256 challenges are accepted.
+func (s *AuthService) Authorize(ctx context.Context, req dto.OAuthAuthorizeRequest) (string, *dto.OAuthError) {
+	if s.oauth == nil {
+		return "", oauthNotEnabled()
+	}
+
+	client, err := s.oauth.FindClient(ctx, req.ClientID)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			return "", dto.NewInvalidRequestError("Unknown client_id")
+		}
+		return "", oauthServerError(err)
+	}
+
+	redirectURI := req.RedirectURI
+	if redirectURI == "" {
+		if registered := strings.Fields(client.RedirectURIs); len(registered) == 1 {
+			redirectURI = registered[0]
+		}
+	}
+	if redirectURI == "" || !client.AllowsRedirectURI(redirectURI) {
+		logger.Warn("Unregistered redirect URI", logger.String("client_id", client.ClientID))
+		return "", dto.NewInvalidRequestError("redirect_uri is not registered for this client")
+	}
+
+	redirectError := func(code, description string) (string, *dto.OAuthError) {
+		return withQuery(redirectURI, map[string]string{
+			"error":             code,
+			"error_description": description,
+			"state":             req.State,
+		}), nil
+	}
+
+	if req.ResponseType != "code" {
+		return redirectError("unsupported_response_type", "response_type must be code")
+	}
+	if !client.AllowsGrant(domain.GrantAuthorizationCode) {
+		return redirectError("unauthorized_client", "Client may not use the authorization_code grant")
+	}
+	if req.CodeChallenge == "" {
+		return redirectError("invalid_request", "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != pkceMethodS256 {
+		return redirectError("invalid_request", "code_challenge_method must be S256")
+	}
+
+	scopes, oauthErr := s.resolveScopes(ctx, client, req.Scope)
+	if oauthErr != nil {
+		return redirectError(oauthErr.Code, oauthErr.Description)
+	}
+
+	user, oauthErr := s.authenticateResourceOwner(ctx, req)
+	if oauthErr != nil {
+		return redirectError(oauthErr.Code, oauthErr.Description)
+	}
+
+	code, genErr := randomToken()
+	if genErr != nil {
+		logger.Error("Failed to generate authorization code", logger.Any("error", genErr))
+		return redirectError("server_error", "Error generating authorization code")
+	}
+	now := time.Now()
+	authCode := domain.AuthorizationCode{
+		CodeHash:      hashOAuthSecret(code),
+		ClientID:      client.ClientID,
+		Username:      user.Username,
+		RedirectURI:   redirectURI,
+		Scope:         strings.Join(scopes, " "),
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		CreatedOn:     now,
+		ExpiresAt:     now.Add(config.Duration("OAUTH_CODE_TTL", time.Minute)),
+	}
+	if err := s.oauth.SaveAuthorizationCode(ctx, authCode); err != nil {
+		return redirectError("server_error", err.Message)
+	}
+
+	logger.Info("Authorization code issued",
+		logger.String("client_id", client.ClientID),
+		logger.String("username", user.Username),
+		logger.String("scope", authCode.Scope))
+	return withQuery(redirectURI, map[string]string{"code": code, "state": req.State}), nil
+}
+
+// authenticateResourceOwner accepts an access token from /auth/login, which
+// already went through MFA, or a username and password when the user does not
+// need a second factor.
+func (s *AuthService) authenticateResourceOwner(ctx context.Context, req dto.OAuthAuthorizeRequest) (*domain.User, *dto.OAuthError) {
+	if req.AccessToken != "" {
+		claims, tokenErr := utils.ExtractClaimsFromToken(req.AccessToken, s.keys)
+		if tokenErr != nil {
+			return nil, dto.NewOAuthError(http.StatusUnauthorized, "access_denied", "Invalid access token")
+		}
+		if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+			return nil, dto.NewOAuthError(http.StatusUnauthorized, "access_denied", "Access token expired")
+		}
+		_, hasRole := claims["role"].(string)
+		_, isOAuth := claims[oauthClientClaim]
+		username, _ := claims["username"].(string)
+		if !hasRole || isOAuth || username == "" || isImpersonated(claims) {
+			return nil, dto.NewOAuthError(http.StatusUnauthorized, "access_denied", "A login access token is required")
+		}
+		user, err := s.activeUser(ctx, username)
+		if err != nil {
+			return nil, dto.NewOAuthError(err.Code, "access_denied", err.Message)
+		}
+		return user, nil
+	}
+
+	if req.Username == "" || req.Password == "" {
+		return nil, dto.NewOAuthError(http.StatusUnauthorized, "access_denied", "The resource owner must authenticate")
+	}
+	user, err := s.checkPassword(ctx, req.Username, req.Password, req.SourceIP)
+	if err != nil {
+		return nil, dto.NewOAuthError(err.Code, "access_denied", err.Message)
+	}
+	if challenge, err := s.challengeIfRequired(ctx, user); err != nil || challenge != nil {
+		return nil, dto.NewOAuthError(http.StatusUnauthorized, "access_denied",
+			"Multi-factor authentication is required; sign in at /auth/login and present the access token")
+	}
+	s.throttle.Success(ctx, user.Username)
+	return user, nil
+}
+
+// Token implements the token endpoint for the password, client_credentials,
+// refresh_token and authorization_code grants.
+func (s *AuthService) Token(ctx context.Context, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	if s.oauth == nil {
+		return nil, oauthNotEnabled()
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case domain.GrantPassword, domain.GrantClientCredentials, domain.GrantRefreshToken, domain.GrantAuthorizationCode:
+	default:
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "unsupported_grant_type", "Unsupported grant_type")
+	}
+
+	client, oauthErr := s.authenticateClient(ctx, req.OAuthClientCredentials)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		logger.Warn("Grant type not allowed for client",
+			logger.String("client_id", client.ClientID),
+			logger.String("grant_type", req.GrantType))
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "unauthorized_client", "Client may not use this grant_type")
+	}
+
+	switch req.GrantType {
+	case domain.GrantPassword:
+		return s.passwordGrant(ctx, client, req)
+	case domain.GrantClientCredentials:
+		return s.clientCredentialsGrant(ctx, client, req)
+	case domain.GrantRefreshToken:
+		return s.refreshTokenGrant(ctx, client, req)
+	default:
+		return s.authorizationCodeGrant(ctx, client, req)
+	}
+}
+
+func (s *AuthService) passwordGrant(ctx context.Context, client *domain.OAuthClient, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	if req.Username == "" || req.Password == "" {
+		return nil, dto.NewInvalidRequestError("username and password are required")
+	}
+	scopes, oauthErr := s.resolveScopes(ctx, client, req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	user, err := s.checkPassword(ctx, req.Username, req.Password, req.SourceIP)
+	if err != nil {
+		if err.Code == http.StatusUnauthorized {
+			return nil, dto.NewInvalidGrantError("Invalid username or password")
+		}
+		return nil, oauthErrorFrom(err)
+	}
+	if challenge, err := s.challengeIfRequired(ctx, user); err != nil || challenge != nil {
+		return nil, dto.NewInvalidGrantError("Multi-factor authentication is required; use the authorization_code grant")
+	}
+	s.throttle.Success(ctx, user.Username)
+
+	return s.issueOAuthTokens(ctx, client, oauthSubjectForUser(user), scopes)
+}
+
+func (s *AuthService) clientCredentialsGrant(ctx context.Context, client *domain.OAuthClient, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	if !client.IsConfidential() || client.Role == "" {
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "unauthorized_client", "Client is not configured for client_credentials")
+	}
+	scopes, oauthErr := s.resolveScopes(ctx, client, req.Scope)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	subject := oauthSubject{Username: client.ClientID, Role: client.Role, IsClient: true}
+	if client.CustomerID != nil {
+		subject.CustomerID = *client.CustomerID
+	}
+	return s.issueOAuthTokens(ctx, client, subject, scopes)
+}
+
+// refreshTokenGrant rotates the refresh token. The new access token takes the
+// user's current role and may narrow, but never widen, the original scope.
+func (s *AuthService) refreshTokenGrant(ctx context.Context, client *domain.OAuthClient, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	if req.RefreshToken == "" {
+		return nil, dto.NewInvalidRequestError("refresh_token is required")
+	}
+
+	exists, err := s.repo.VerifyRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, oauthServerError(err)
+	}
+	claims, tokenErr := utils.ExtractClaimsFromToken(req.RefreshToken, s.keys)
+	if !exists || tokenErr != nil {
+		return nil, dto.NewInvalidGrantError("Invalid refresh token")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, dto.NewInvalidGrantError("Refresh token expired")
+	}
+	if clientID, _ := claims[oauthClientClaim].(string); clientID != client.ClientID {
+		logger.Warn("Refresh token presented by another client", logger.String("client_id", client.ClientID))
+		return nil, dto.NewInvalidGrantError("Refresh token was issued to another client")
+	}
+
+	granted, _ := claims[oauthScopeClaim].(string)
+	scopes := strings.Fields(granted)
+	if req.Scope != "" {
+		requested := strings.Fields(req.Scope)
+		if !(domain.OAuthClient{Scopes: granted}).AllowsScopes(requested) {
+			return nil, dto.NewOAuthError(http.StatusBadRequest, "invalid_scope", "Requested scope exceeds the original grant")
+		}
+		scopes = requested
+	}
+
+	if _, err := s.repo.DeleteRefreshToken(ctx, req.RefreshToken); err != nil {
+		return nil, oauthServerError(err)
+	}
+
+	username, _ := claims["username"].(string)
+	user, err := s.activeUser(ctx, username)
+	if err != nil {
+		return nil, oauthErrorFrom(err)
+	}
+	return s.issueOAuthTokens(ctx, client, oauthSubjectForUser(user), scopes)
+}
+
+func (s *AuthService) authorizationCodeGrant(ctx context.Context, client *domain.OAuthClient, req dto.OAuthTokenRequest) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	if req.Code == "" || req.CodeVerifier == "" || req.RedirectURI == "" {
+		return nil, dto.NewInvalidRequestError("code, code_verifier and redirect_uri are required")
+	}
+	if len(req.CodeVerifier) < 43 || len(req.CodeVerifier) > 128 {
+		return nil, dto.NewInvalidRequestError("code_verifier must be 43 to 128 characters")
+	}
+
+	code, err := s.oauth.ConsumeAuthorizationCode(ctx, hashOAuthSecret(req.Code), time.Now())
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			return nil, dto.NewInvalidGrantError("Invalid or expired authorization code")
+		}
+		return nil, oauthServerError(err)
+	}
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		logger.Warn("Authorization code presented with another client or redirect URI",
+			logger.String("client_id", client.ClientID))
+		return nil, dto.NewInvalidGrantError("Authorization code does not match the client or redirect_uri")
+	}
+	if subtle.ConstantTimeCompare([]byte(pkceChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		logger.Warn("PKCE verification failed", logger.String("client_id", client.ClientID))
+		return nil, dto.NewInvalidGrantError("code_verifier does not match the code_challenge")
+	}
+
+	user, err := s.activeUser(ctx, code.Username)
+	if err != nil {
+		return nil, oauthErrorFrom(err)
+	}
+	subject := oauthSubjectForUser(user)
+	subject.Nonce = code.Nonce
+	return s.issueOAuthTokens(ctx, client, subject, strings.Fields(code.Scope))
+}
+
+// Introspect reports whether a token is active (RFC 7662). Only confidential
+// clients may introspect tokens.
+func (s *AuthService) Introspect(ctx context.Context, req dto.OAuthIntrospectionRequest) (*dto.OAuthIntrospectionResponse, *dto.OAuthError) {
+	if s.oauth == nil {
+		return nil, oauthNotEnabled()
+	}
+	if req.ClientID == "" {
+		return nil, dto.NewInvalidClientError("Client authentication is required")
+	}
+	client, oauthErr := s.authenticateClient(ctx, req.OAuthClientCredentials)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+	if !client.IsConfidential() {
+		return nil, dto.NewInvalidClientError("Only confidential clients may introspect tokens")
+	}
+	if req.Token == "" {
+		return nil, dto.NewInvalidRequestError("token is required")
+	}
+
+	inactive := &dto.OAuthIntrospectionResponse{Active: false}
+	claims, tokenErr := utils.ExtractClaimsFromToken(req.Token, s.keys)
+	if tokenErr != nil {
+		return inactive, nil
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return inactive, nil
+	}
+	if challenge, _ := claims[mfaClaim].(string); challenge != "" {
+		return inactive, nil
+	}
+
+	tokenType := "access_token"
+	if _, hasRole := claims["role"].(string); !hasRole {
+		exists, err := s.repo.VerifyRefreshToken(ctx, req.Token)
+		if err != nil {
+			return nil, oauthServerError(err)
+		}
+		if !exists {
+			return inactive, nil
+		}
+		tokenType = "refresh_token"
+	}
+
+	response := &dto.OAuthIntrospectionResponse{Active: true, TokenType: tokenType, Exp: int64(exp)}
+	response.Scope, _ = claims[oauthScopeClaim].(string)
+	response.ClientID, _ = claims[oauthClientClaim].(string)
+	response.Username, _ = claims["username"].(string)
+	response.Sub, _ = claims["sub"].(string)
+	response.Iss, _ = claims["iss"].(string)
+	response.Role, _ = claims["role"].(string)
+	response.CustomerID, _ = claims["customer_id"].(string)
+	if iat, ok := claims["iat"].(float64); ok {
+		response.Iat = int64(iat)
+	}
+
+	logger.Info("Token introspected",
+		logger.String("client_id", client.ClientID),
+		logger.String("token_type", tokenType))
+	return response, nil
+}
+
+// oauthScopesCover checks the scope of an OAuth access token against the
+// route. It is applied on top of the role permissions.
+func (s *AuthService) oauthScopesCover(ctx context.Context, claims jwt.MapClaims, routeName string) *errs.AppError {
+	if s.scopes == nil {
+		return errs.NewForbiddenError("OAuth tokens are not accepted")
+	}
+	scope, _ := claims[oauthScopeClaim].(string)
+	covered, err := s.scopes.Covers(ctx, strings.Fields(scope), routeName)
+	if err != nil {
+		return err
+	}
+	if !covered {
+		logger.Warn("Token scope does not cover route",
+			logger.String("routeName", routeName),
+			logger.String("scope", scope))
+		return errs.NewForbiddenError("insufficient_scope: token scope does not cover this route")
+	}
+	return nil
+}
+
+type oauthSubject struct {
+	Username   string
+	Role       string
+	CustomerID string
+	// IsClient is set when the client acts on its own behalf; it gets
+	// neither a refresh token (RFC 6749 4.4.3) nor an ID token.
+	IsClient bool
+	// Nonce is echoed in the ID token of the authorization_code grant.
+	Nonce string
+}
+
+func oauthSubjectForUser(user *domain.User) oauthSubject {
+	subject := oauthSubject{Username: user.Username, Role: user.Role}
+	if user.CustomerID != nil {
+		subject.CustomerID = *user.CustomerID
+	}
+	return subject
+}
+
+// issueOAuthTokens signs the access token and, when the client may use the
+// refresh_token grant, a refresh token bound to the client and scope. Users
+// who granted the openid scope also get an ID token for the client.
+func (s *AuthService) issueOAuthTokens(ctx context.Context, client *domain.OAuthClient, subject oauthSubject, scopes []string) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	now := jwt.TimeFunc()
+	ttl := config.Duration("OAUTH_ACCESS_TOKEN_TTL", time.Hour)
+	scope := strings.Join(scopes, " ")
+
+	claims := jwt.MapClaims{
+		"iss":            s.serviceURL,
+		"sub":            subject.Username,
+		"username":       subject.Username,
+		"role":           subject.Role,
+		"customer_id":    subject.CustomerID,
+		oauthClientClaim: client.ClientID,
+		oauthScopeClaim:  scope,
+		"iat":            now.Unix(),
+		"exp":            now.Add(ttl).Unix(),
+	}
+	accessToken, signErr := s.sign(claims)
+	if signErr != nil {
+		logger.Error("Failed to generate OAuth access token", logger.Any("error", signErr))
+		return nil, dto.NewOAuthError(http.StatusInternalServerError, "server_error", "Error generating token")
+	}
+
+	response := &dto.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   oauthTokenType,
+		ExpiresIn:   int64(ttl.Seconds()),
+		Scope:       scope,
+	}
+
+	if !subject.IsClient && client.AllowsGrant(domain.GrantRefreshToken) {
+		jti, genErr := randomToken()
+		if genErr != nil {
+			logger.Error("Failed to generate refresh token id", logger.Any("error", genErr))
+			return nil, dto.NewOAuthError(http.StatusInternalServerError, "server_error", "Error generating token")
+		}
+		refreshClaims := jwt.MapClaims{
+			"jti":            jti,
+			"username":       subject.Username,
+			oauthClientClaim: client.ClientID,
+			oauthScopeClaim:  scope,
+			"exp":            now.Add(config.Duration("OAUTH_REFRESH_TOKEN_TTL", 7*24*time.Hour)).Unix(),
+		}
+		refreshToken, signErr := s.sign(refreshClaims)
+		if signErr != nil {
+			logger.Error("Failed to generate OAuth refresh token", logger.Any("error", signErr))
+			return nil, dto.NewOAuthError(http.StatusInternalServerError, "server_error", "Error generating token")
+		}
+		if err := s.repo.SaveRefreshToken(ctx, subject.Username, refreshToken); err != nil {
+			return nil, oauthServerError(err)
+		}
+		response.RefreshToken = refreshToken
+	}
+
+	if !subject.IsClient && containsScope(scopes, openIDScope) {
+		idToken, err := s.signIDToken(subject.Username, client.ClientID, subject.Nonce)
+		if err != nil {
+			return nil, oauthServerError(err)
+		}
+		response.IDToken = idToken
+	}
+
+	logger.Info("OAuth token issued",
+		logger.String("client_id", client.ClientID),
+		logger.String("username", subject.Username),
+		logger.String("scope", scope))
+	return response, nil
+}
+
+// resolveScopes returns the requested scopes, or every scope of the client
+// when none were requested. OAuth tokens always carry at least one scope.
+func (s *AuthService) resolveScopes(ctx context.Context, client *domain.OAuthClient, requested string) ([]string, *dto.OAuthError) {
+	scopes := strings.Fields(requested)
+	if len(scopes) == 0 {
+		scopes = strings.Fields(client.Scopes)
+	}
+	if len(scopes) == 0 {
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "invalid_scope", "No scope requested")
+	}
+	if !client.AllowsScopes(scopes) {
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "invalid_scope", "Scope not allowed for this client")
+	}
+	known, err := s.scopes.Known(ctx, scopes)
+	if err != nil {
+		return nil, oauthServerError(err)
+	}
+	if !known {
+		return nil, dto.NewOAuthError(http.StatusBadRequest, "invalid_scope", "Unknown scope")
+	}
+	return scopes, nil
+}
+
+func (s *AuthService) authenticateClient(ctx context.Context, creds dto.OAuthClientCredentials) (*domain.OAuthClient, *dto.OAuthError) {
+	client, err := s.oauth.FindClient(ctx, creds.ClientID)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			logger.Warn("Unknown OAuth client", logger.String("client_id", creds.ClientID))
+			return nil, dto.NewInvalidClientError("Client authentication failed")
+		}
+		return nil, oauthServerError(err)
+	}
+
+	if client.IsConfidential() {
+		if creds.ClientSecret == "" ||
+			subtle.ConstantTimeCompare([]byte(*client.SecretHash), []byte(hashOAuthSecret(creds.ClientSecret))) != 1 {
+			logger.Warn("OAuth client secret mismatch", logger.String("client_id", client.ClientID))
+			return nil, dto.NewInvalidClientError("Client authentication failed")
+		}
+	} else if creds.ClientSecret != "" {
+		return nil, dto.NewInvalidClientError("Public clients have no secret")
+	}
+	return client, nil
+}
+
+func oauthNotEnabled() *dto.OAuthError {
+	return dto.NewOAuthError(http.StatusServiceUnavailable, "temporarily_unavailable", "OAuth is not enabled")
+}
+
+// oauthServerError keeps the status of a request that timed out or was
+// canceled, so it is not reported as a failure of the server.
+func oauthServerError(err *errs.AppError) *dto.OAuthError {
+	status := http.StatusInternalServerError
+	if err.Code == http.StatusGatewayTimeout || err.Code == errs.StatusClientClosedRequest {
+		status = err.Code
+	}
+	return dto.NewOAuthError(status, "server_error", err.Message)
+}
+
+// oauthErrorFrom maps an authentication failure, such as a disabled user,
+// onto an invalid_grant error. Throttled logins keep their 429 status.
+func oauthErrorFrom(err *errs.AppError) *dto.OAuthError {
+	switch {
+	case err.Code >= http.StatusInternalServerError, err.Code == errs.StatusClientClosedRequest:
+		return oauthServerError(err)
+	case err.Code == http.StatusTooManyRequests:
+		return dto.NewOAuthError(err.Code, "invalid_grant", err.Message)
+	default:
+		return dto.NewInvalidGrantError(err.Message)
+	}
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func hashOAuthSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func withQuery(rawURL string, params map[string]string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	for k, v := range params {
+		if v != "" {
+			query.Set(k, v)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+var _ ports.OAuthService = (*AuthService)(nil)
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// OAuthScopeCache maps OAuth scopes onto route permissions. The mapping is
+// reloaded from the database once it is older than OAUTH_SCOPE_CACHE_TTL.
+type OAuthScopeCache struct {
+	repo     ports.OAuthRepository
+	ttl      time.Duration
+	mu       sync.Mutex
+	routes   map[string]map[string]bool
+	loadedAt time.Time
+}
+
+func NewOAuthScopeCache(repo ports.OAuthRepository) *OAuthScopeCache {
+	return &OAuthScopeCache{
+		repo: repo,
+		ttl:  config.Duration("OAUTH_SCOPE_CACHE_TTL", time.Minute),
+	}
+}
+
+func (c *OAuthScopeCache) current(ctx context.Context) (map[string]map[string]bool, *errs.AppError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.routes != nil && time.Since(c.loadedAt) < c.ttl {
+		return c.routes, nil
+	}
+
+	scopeRoutes, err := c.repo.FindScopeRoutes(ctx)
+	if err != nil {
+		if c.routes != nil {
+			logger.Warn("Using stale OAuth scopes", logger.Any("error", err))
+			return c.routes, nil
+		}
+		return nil, err
+	}
+
+	routes := make(map[string]map[string]bool)
+	for _, sr := range scopeRoutes {
+		if routes[sr.Scope] == nil {
+			routes[sr.Scope] = make(map[string]bool)
+		}
+		if sr.PermissionName != "" {
+			routes[sr.Scope][sr.PermissionName] = true
+		}
+	}
+	c.routes = routes
+	c.loadedAt = time.Now()
+	return routes, nil
+}
+
+// Known reports whether every scope is defined.
+func (c *OAuthScopeCache) Known(ctx context.Context, scopes []string) (bool, *errs.AppError) {
+	routes, err := c.current(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, scope := range scopes {
+		if _, ok := routes[scope]; !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Covers reports whether any of the scopes grants routeName.
+func (c *OAuthScopeCache) Covers(ctx context.Context, scopes []string, routeName string) (bool, *errs.AppError) {
+	routes, err := c.current(ctx)
+	if err != nil {
+		return false, err
+	}
+	for _, scope := range scopes {
+		if routes[scope][routeName] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Names lists the defined scopes.
+func (c *OAuthScopeCache) Names(ctx context.Context) ([]string, *errs.AppError) {
+	routes, err := c.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(routes))
+	for name := range routes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
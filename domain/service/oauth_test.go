@@ -0,0 +1,206 @@
+package service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+)
+
+const (
+	webClient   = "banking-web"
+	webCallback = "http://localhost:3000/callback"
+	verifier    = "verifier-of-the-banking-web-client-0123456789"
+)
+
+// addClient registers an OAuth client next to the seeded ones; a client with
+// an empty secret is public.
+func (f authFixture) addClient(t *testing.T, clientID, secret, grantTypes, redirectURIs, scopes string) {
+	t.Helper()
+	var secretHash interface{}
+	if secret != "" {
+		sum := sha256.Sum256([]byte(secret))
+		secretHash = hex.EncodeToString(sum[:])
+	}
+	_, err := f.db.Exec(`INSERT INTO oauth_clients (client_id, secret_hash, name, grant_types, redirect_uris, scopes, role)
+		VALUES (?, ?, ?, ?, ?, ?, '')`, clientID, secretHash, clientID, grantTypes, redirectURIs, scopes)
+	if err != nil {
+		t.Fatalf("adding client %s: %v", clientID, err)
+	}
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorize has user 2000 approve clientID for scope and returns the code.
+func (f authFixture) authorize(t *testing.T, clientID, redirectURI, scope string) string {
+	t.Helper()
+	redirect, oauthErr := f.auth.Authorize(context.Background(), dto.OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            clientID,
+		RedirectURI:         redirectURI,
+		Scope:               scope,
+		CodeChallenge:       pkceChallenge(verifier),
+		CodeChallengeMethod: "S256",
+		Username:            "2000",
+		Password:            seedPassword,
+	})
+	if oauthErr != nil {
+		t.Fatalf("authorizing %s: %v", clientID, oauthErr.Description)
+	}
+	parsed, err := url.Parse(redirect)
+	if err != nil {
+		t.Fatalf("parsing redirect %s: %v", redirect, err)
+	}
+	code := parsed.Query().Get("code")
+	if code == "" {
+		t.Fatalf("expected a code in %s", redirect)
+	}
+	return code
+}
+
+func (f authFixture) exchange(clientID, code, redirectURI, codeVerifier string) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	return f.auth.Token(context.Background(), dto.OAuthTokenRequest{
+		OAuthClientCredentials: dto.OAuthClientCredentials{ClientID: clientID},
+		GrantType:              "authorization_code",
+		Code:                   code,
+		RedirectURI:            redirectURI,
+		CodeVerifier:           codeVerifier,
+	})
+}
+
+func (f authFixture) webTokens(t *testing.T, scope string) *dto.OAuthTokenResponse {
+	t.Helper()
+	tokens, oauthErr := f.exchange(webClient, f.authorize(t, webClient, webCallback, scope), webCallback, verifier)
+	if oauthErr != nil {
+		t.Fatalf("exchanging the code: %v", oauthErr.Description)
+	}
+	return tokens
+}
+
+func (f authFixture) refresh(clientID, refreshToken, scope string) (*dto.OAuthTokenResponse, *dto.OAuthError) {
+	return f.auth.Token(context.Background(), dto.OAuthTokenRequest{
+		OAuthClientCredentials: dto.OAuthClientCredentials{ClientID: clientID},
+		GrantType:              "refresh_token",
+		RefreshToken:           refreshToken,
+		Scope:                  scope,
+	})
+}
+
+func expectOAuthError(t *testing.T, what string, err *dto.OAuthError, code string) {
+	t.Helper()
+	if err == nil || err.Code != code {
+		t.Errorf("expected %s to fail with %s, got %+v", what, code, err)
+	}
+}
+
+func TestOAuthCodeRequiresTheVerifierOfItsChallenge(t *testing.T) {
+	f := newAuthFixture(t)
+	code := f.authorize(t, webClient, webCallback, "customers.read")
+
+	_, err := f.exchange(webClient, code, webCallback, strings.Repeat("x", 43))
+	expectOAuthError(t, "a code with another verifier", err, "invalid_grant")
+	_, err = f.exchange(webClient, code, webCallback, verifier)
+	expectOAuthError(t, "a code after a failed verification", err, "invalid_grant")
+}
+
+func TestOAuthCodeIsSingleUse(t *testing.T) {
+	f := newAuthFixture(t)
+	code := f.authorize(t, webClient, webCallback, "customers.read")
+
+	tokens, err := f.exchange(webClient, code, webCallback, verifier)
+	if err != nil {
+		t.Fatalf("exchanging the code: %v", err.Description)
+	}
+	if tokens.AccessToken == "" || tokens.Scope != "customers.read" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	_, err = f.exchange(webClient, code, webCallback, verifier)
+	expectOAuthError(t, "a reused code", err, "invalid_grant")
+}
+
+func TestOAuthCodeIsBoundToItsRedirectURI(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addClient(t, "banking-mobile", "", "authorization_code refresh_token",
+		"http://localhost:3000/callback http://localhost:3000/other", "customers.read")
+
+	_, err := f.auth.Authorize(context.Background(), dto.OAuthAuthorizeRequest{
+		ResponseType:        "code",
+		ClientID:            webClient,
+		RedirectURI:         "http://evil.test/callback",
+		CodeChallenge:       pkceChallenge(verifier),
+		CodeChallengeMethod: "S256",
+		Username:            "2000",
+		Password:            seedPassword,
+	})
+	expectOAuthError(t, "an unregistered redirect_uri", err, "invalid_request")
+
+	code := f.authorize(t, "banking-mobile", "http://localhost:3000/callback", "customers.read")
+	_, err = f.exchange("banking-mobile", code, "http://localhost:3000/other", verifier)
+	expectOAuthError(t, "a code exchanged with another registered redirect_uri", err, "invalid_grant")
+}
+
+func TestOAuthRefreshTokenIsBoundToItsClient(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addClient(t, "banking-mobile", "", "authorization_code refresh_token", webCallback, "customers.read")
+	tokens := f.webTokens(t, "customers.read")
+
+	_, err := f.refresh("banking-mobile", tokens.RefreshToken, "")
+	expectOAuthError(t, "a refresh token of another client", err, "invalid_grant")
+	if _, err := f.refresh(webClient, tokens.RefreshToken, ""); err != nil {
+		t.Errorf("expected the refresh token to stay valid for its client: %v", err.Description)
+	}
+}
+
+func TestOAuthRefreshNeverWidensTheScope(t *testing.T) {
+	f := newAuthFixture(t)
+	tokens := f.webTokens(t, "openid customers.read")
+
+	_, err := f.refresh(webClient, tokens.RefreshToken, "openid customers.read transactions.write")
+	expectOAuthError(t, "a refresh widening the scope", err, "invalid_scope")
+
+	narrowed, err := f.refresh(webClient, tokens.RefreshToken, "customers.read")
+	if err != nil {
+		t.Fatalf("narrowing the scope: %v", err.Description)
+	}
+	if narrowed.Scope != "customers.read" {
+		t.Errorf("expected the narrowed scope, got %q", narrowed.Scope)
+	}
+	_, err = f.refresh(webClient, narrowed.RefreshToken, "openid customers.read")
+	expectOAuthError(t, "a refresh widening a narrowed scope", err, "invalid_scope")
+}
+
+func TestOAuthIntrospectionNeedsAConfidentialClient(t *testing.T) {
+	f := newAuthFixture(t)
+	f.addClient(t, "gateway", "gateway-secret", "client_credentials", "", "customers.read")
+	tokens := f.webTokens(t, "customers.read")
+
+	_, err := f.auth.Introspect(context.Background(), dto.OAuthIntrospectionRequest{
+		OAuthClientCredentials: dto.OAuthClientCredentials{ClientID: webClient},
+		Token:                  tokens.AccessToken,
+	})
+	expectOAuthError(t, "an introspection by a public client", err, "invalid_client")
+	_, err = f.auth.Introspect(context.Background(), dto.OAuthIntrospectionRequest{
+		OAuthClientCredentials: dto.OAuthClientCredentials{ClientID: "gateway", ClientSecret: "wrong"},
+		Token:                  tokens.AccessToken,
+	})
+	expectOAuthError(t, "an introspection with a wrong secret", err, "invalid_client")
+
+	introspection, err := f.auth.Introspect(context.Background(), dto.OAuthIntrospectionRequest{
+		OAuthClientCredentials: dto.OAuthClientCredentials{ClientID: "gateway", ClientSecret: "gateway-secret"},
+		Token:                  tokens.AccessToken,
+	})
+	if err != nil {
+		t.Fatalf("introspecting: %v", err.Description)
+	}
+	if !introspection.Active || introspection.ClientID != webClient || introspection.Username != "2000" {
+		t.Errorf("unexpected introspection %+v", introspection)
+	}
+}
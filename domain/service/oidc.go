@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	openIDScope  = "openid"
+	profileScope = "profile"
+)
+
+// signIDToken signs an OpenID Connect ID token for username. It carries no
+// role, so it is never accepted as an access token.
+func (s *AuthService) signIDToken(username, audience, nonce string) (string, *errs.AppError) {
+	now := jwt.TimeFunc()
+	claims := jwt.MapClaims{
+		"iss":                s.serviceURL,
+		"sub":                username,
+		"aud":                audience,
+		"preferred_username": username,
+		"iat":                now.Unix(),
+		"auth_time":          now.Unix(),
+		"exp":                now.Add(config.Duration("OIDC_ID_TOKEN_TTL", time.Hour)).Unix(),
+	}
+	if nonce != "" {
+		claims["nonce"] = nonce
+	}
+
+	idToken, signErr := s.sign(claims)
+	if signErr != nil {
+		logger.Error("Failed to generate ID token", logger.Any("error", signErr))
+		return "", errs.NewUnexpectedError("Error generating ID token: " + signErr.Error())
+	}
+	return idToken, nil
+}
+
+// loginAudience is the audience of the ID tokens issued by /auth/login, which
+// is the bank's own front end rather than a registered OAuth client.
+func loginAudience() string {
+	return config.String("OIDC_LOGIN_AUDIENCE", "banking-web")
+}
+
+func (s *AuthService) OpenIDConfiguration(ctx context.Context) dto.OpenIDConfiguration {
+	algs := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, key := range s.GetJWKS().Keys {
+		if !seen[key.Algorithm] {
+			seen[key.Algorithm] = true
+			algs = append(algs, key.Algorithm)
+		}
+	}
+
+	scopes := []string{openIDScope}
+	if s.scopes != nil {
+		if names, err := s.scopes.Names(ctx); err == nil {
+			scopes = names
+		}
+	}
+
+	return dto.OpenIDConfiguration{
+		Issuer:                 s.serviceURL,
+		AuthorizationEndpoint:  s.serviceURL + "/oauth/authorize",
+		TokenEndpoint:          s.serviceURL + "/oauth/token",
+		IntrospectionEndpoint:  s.serviceURL + "/oauth/introspect",
+		UserInfoEndpoint:       s.serviceURL + "/userinfo",
+		JWKSURI:                s.serviceURL + utils.JWKSPath,
+		ScopesSupported:        scopes,
+		ResponseTypesSupported: []string{"code"},
+		GrantTypesSupported: []string{
+			"authorization_code", "refresh_token", "password", "client_credentials",
+		},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  algs,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{pkceMethodS256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "preferred_username", "role", "customer_id",
+		},
+	}
+}
+
+// UserInfo describes the user behind an access token. OAuth tokens need the
+// openid scope, and the profile scope to include the linked customer.
+func (s *AuthService) UserInfo(ctx context.Context, token string) (*dto.UserInfoResponse, *errs.AppError) {
+	claims, tokenErr := utils.ExtractClaimsFromToken(token, s.keys)
+	if tokenErr != nil {
+		return nil, errs.NewAuthenticationError("Invalid token")
+	}
+	if exp, ok := claims["exp"].(float64); !ok || time.Unix(int64(exp), 0).Before(time.Now()) {
+		return nil, errs.NewAuthenticationError("Token expired")
+	}
+	if _, hasRole := claims["role"].(string); !hasRole {
+		return nil, errs.NewAuthenticationError("An access token is required")
+	}
+
+	withProfile := true
+	if _, isOAuth := claims[oauthClientClaim]; isOAuth {
+		scope, _ := claims[oauthScopeClaim].(string)
+		scopes := strings.Fields(scope)
+		if !containsScope(scopes, openIDScope) {
+			return nil, errs.NewForbiddenError("insufficient_scope: the openid scope is required")
+		}
+		withProfile = containsScope(scopes, profileScope)
+	}
+
+	username, _ := claims["username"].(string)
+	user, err := s.repo.FindUserByUsername(ctx, username)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			return nil, errs.NewForbiddenError("Token does not belong to a user")
+		}
+		return nil, err
+	}
+
+	response := &dto.UserInfoResponse{
+		Sub:               user.Username,
+		PreferredUsername: user.Username,
+		Role:              user.Role,
+	}
+	if user.CustomerID != nil {
+		response.CustomerID = *user.CustomerID
+		if withProfile && s.customers != nil {
+			customer, err := s.customers.ByID(ctx, *user.CustomerID)
+			if err != nil && err.Code != http.StatusNotFound {
+				return nil, err
+			}
+			if customer != nil {
+				profile := customer.ToDto()
+				response.Customer = &profile
+			}
+		}
+	}
+	return response, nil
+}
+
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
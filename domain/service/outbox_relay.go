@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const outboxPurgeInterval = 10 * time.Minute
+
+// OutboxRelay publishes the events of the outbox to a sink. An event is
+// marked published only once the sink has accepted it, so an event whose
+// mark is lost, e.g. in a crash, is published again: delivery is at least
+// once. The next event of an aggregate waits until the previous one is
+// published, and a failed event is retried with exponential backoff.
+//
+// Run a single relay per outbox: two relays may publish the same event at
+// the same time and lose the per-aggregate order.
+type OutboxRelay struct {
+	repo         ports.OutboxRepository
+	sink         ports.EventSink
+	pollInterval time.Duration
+	batchSize    int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	retention    time.Duration
+	now          func() time.Time
+}
+
+func NewOutboxRelay(repo ports.OutboxRepository, sink ports.EventSink) *OutboxRelay {
+	return &OutboxRelay{
+		repo:         repo,
+		sink:         sink,
+		pollInterval: config.Duration("OUTBOX_POLL_INTERVAL", time.Second),
+		batchSize:    max(config.Int("OUTBOX_BATCH_SIZE", 100), 1),
+		backoff:      config.Duration("OUTBOX_RETRY_BACKOFF", time.Second),
+		maxBackoff:   config.Duration("OUTBOX_MAX_BACKOFF", 5*time.Minute),
+		retention:    config.Duration("OUTBOX_RETENTION", 24*time.Hour),
+		now:          time.Now,
+	}
+}
+
+// Run relays events until ctx is canceled. After a batch that published
+// something it goes on at once, otherwise it waits for the poll interval.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+	lastPurge := r.now()
+
+	for {
+		for {
+			published, err := r.RelayBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("Error relaying outbox events", logger.Any("error", err))
+			}
+			if err != nil || published == 0 {
+				break
+			}
+		}
+		if r.retention > 0 && r.now().Sub(lastPurge) >= outboxPurgeInterval {
+			r.purge(ctx)
+			lastPurge = r.now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayBatch publishes the events that are due, at most one per aggregate,
+// and returns how many the sink accepted.
+func (r *OutboxRelay) RelayBatch(ctx context.Context) (int, *errs.AppError) {
+	entries, appErr := r.repo.Pending(ctx, r.now(), r.batchSize)
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	published := 0
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if err := r.sink.Publish(ctx, entry.Event); err != nil {
+			retryIn := r.delay(entry.Attempts + 1)
+			logger.Warn("Error publishing event, retrying later",
+				logger.Any("event_id", entry.ID),
+				logger.String("event_type", entry.Type),
+				logger.String("aggregate", entry.AggregateKey()),
+				logger.Int("attempt", entry.Attempts+1),
+				logger.String("retry_in", retryIn.String()),
+				logger.Any("error", err))
+			if appErr := r.repo.MarkFailed(ctx, entry.ID, r.now().Add(retryIn), err.Error()); appErr != nil {
+				return published, appErr
+			}
+			continue
+		}
+		if appErr := r.repo.MarkPublished(ctx, entry.ID, r.now()); appErr != nil {
+			return published, appErr
+		}
+		published++
+	}
+	return published, nil
+}
+
+func (r *OutboxRelay) delay(attempt int) time.Duration {
+	return backoffDelay(r.backoff, r.maxBackoff, attempt)
+}
+
+// backoffDelay is the wait after the given failed attempt: base after the
+// first, doubled after each of the next ones, up to limit.
+func backoffDelay(base, limit time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+func (r *OutboxRelay) purge(ctx context.Context) {
+	purged, appErr := r.repo.PurgePublished(ctx, r.now().Add(-r.retention))
+	if appErr != nil {
+		logger.Error("Error purging published outbox events", logger.Any("error", appErr))
+		return
+	}
+	if purged > 0 {
+		logger.Info("Purged published outbox events", logger.Any("count", purged))
+	}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func passwordPolicyFromEnv() domain.PasswordPolicy {
+	return domain.PasswordPolicy{
+		MinLength:     config.Int("PASSWORD_MIN_LENGTH", 8),
+		MaxLength:     config.Int("PASSWORD_MAX_LENGTH", 64),
+		RequireUpper:  config.Bool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  config.Bool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  config.Bool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: config.Bool("PASSWORD_REQUIRE_SYMBOL", false),
+	}
+}
+
+// passwordHashCostFromEnv is the bcrypt cost of the password hashes.
+func passwordHashCostFromEnv() int {
+	return min(max(config.Int("PASSWORD_HASH_COST", bcrypt.DefaultCost), bcrypt.MinCost), bcrypt.MaxCost)
+}
+
+// unknownUserHash is compared with the password of a username that does not
+// exist, so that refusing it takes as long as refusing a wrong password.
+func unknownUserHash(cost int) []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("unknown user"), cost)
+	if err != nil {
+		logger.Fatal("Error hashing the unknown user password", logger.Any("error", err))
+	}
+	return hash
+}
+
+// hashPassword returns the bcrypt hash stored for password.
+func (s *AuthService) hashPassword(password string) (string, *errs.AppError) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.passwordCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", errs.NewValidationError("Password must be at most 72 bytes")
+	}
+	if err != nil {
+		logger.Error("Error hashing password", logger.Any("error", err))
+		return "", errs.NewUnexpectedError("Error hashing password")
+	}
+	return string(hash), nil
+}
+
+// matchPassword compares password with the one stored for user. A password
+// stored in plaintext, from before passwords were hashed, or hashed with
+// another cost than PASSWORD_HASH_COST is hashed again once it matched.
+func (s *AuthService) matchPassword(ctx context.Context, user *domain.User, password string) bool {
+	stored := []byte(user.Password)
+	cost, err := bcrypt.Cost(stored)
+	if err != nil {
+		if subtle.ConstantTimeCompare(stored, []byte(password)) != 1 {
+			return false
+		}
+	} else if bcrypt.CompareHashAndPassword(stored, []byte(password)) != nil {
+		return false
+	}
+	if err != nil || cost != s.passwordCost {
+		s.rehashPassword(ctx, user.Username, password)
+	}
+	return true
+}
+
+// rehashPassword stores a new hash of a password that matched. The login goes
+// on when it fails: the stored password still matches.
+func (s *AuthService) rehashPassword(ctx context.Context, username, password string) {
+	hash, err := s.hashPassword(password)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, username, hash)
+	}
+	if err != nil {
+		logger.Warn("Error rehashing password", logger.String("username", username), logger.Any("error", err))
+		return
+	}
+	logger.Info("Password rehashed", logger.String("username", username))
+}
+
+// ChangePassword replaces the password of the holder of an access token after
+// checking the current one.
+func (s *AuthService) ChangePassword(ctx context.Context, token string, req dto.PasswordChangeRequest) *errs.AppError {
+	username, challenge, err := s.mfaTokenSubject(token)
+	if err != nil {
+		return err
+	}
+	if challenge {
+		return errs.NewAuthenticationError("Invalid token")
+	}
+	if _, err := s.checkPassword(ctx, username, req.CurrentPassword, req.SourceIP); err != nil {
+		return err
+	}
+	s.throttle.Success(ctx, username)
+	if req.NewPassword == req.CurrentPassword {
+		return errs.NewValidationError("New password must differ from the current one")
+	}
+	return s.setPassword(ctx, username, req.NewPassword)
+}
+
+// ForgotPassword sends a reset token to the user. It succeeds whether or not
+// the user exists, so the endpoint cannot be used to discover usernames.
+// Every request counts as a failure in the login throttle, known user or not,
+// so the endpoint can neither flood a user with tokens nor tell users apart.
+func (s *AuthService) ForgotPassword(ctx context.Context, req dto.PasswordForgotRequest) *errs.AppError {
+	if s.passwordResets == nil || s.notifier == nil {
+		return errs.NewForbiddenError("Password reset is not enabled")
+	}
+	if err := s.throttle.Check(ctx, req.Username, req.SourceIP); err != nil {
+		return err
+	}
+	s.throttle.Failure(ctx, req.Username, req.SourceIP)
+
+	user, err := s.repo.FindUserByUsername(ctx, req.Username)
+	if err != nil {
+		if err.Code == http.StatusNotFound {
+			logger.Info("Password reset requested for unknown user", logger.String("username", req.Username))
+			return nil
+		}
+		return err
+	}
+
+	raw := make([]byte, 32)
+	if _, genErr := rand.Read(raw); genErr != nil {
+		logger.Error("Failed to generate password reset token", logger.Any("error", genErr))
+		return errs.NewUnexpectedError("Error generating reset token")
+	}
+	resetToken := hex.EncodeToString(raw)
+
+	now := time.Now()
+	ttl := config.Duration("PASSWORD_RESET_TTL", 30*time.Minute)
+	err = s.passwordResets.SaveResetToken(ctx, domain.PasswordResetToken{
+		TokenHash: hashResetToken(resetToken),
+		Username:  user.Username,
+		CreatedOn: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := "Use this token to reset your password within " + ttl.String() + ": " + resetToken
+	if resetURL := config.String("PASSWORD_RESET_URL", ""); resetURL != "" {
+		body = "Reset your password within " + ttl.String() + ": " + resetURL + "?token=" + url.QueryEscape(resetToken)
+	}
+	notifyErr := s.notifier.Notify(domain.Notification{
+		Username: user.Username,
+		Subject:  "Password reset",
+		Body:     body,
+	})
+	if notifyErr != nil {
+		logger.Error("Failed to deliver password reset", logger.String("username", user.Username), logger.Any("error", notifyErr))
+		return errs.NewUnexpectedError("Error delivering reset token")
+	}
+
+	logger.Info("Password reset token issued", logger.String("username", user.Username))
+	return nil
+}
+
+// ResetPassword sets a new password with a token issued by ForgotPassword.
+func (s *AuthService) ResetPassword(ctx context.Context, req dto.PasswordResetRequest) *errs.AppError {
+	if s.passwordResets == nil {
+		return errs.NewForbiddenError("Password reset is not enabled")
+	}
+
+	// The password is checked against the policy, username included, before
+	// the token is consumed, so a rejected password does not burn it.
+	tokenHash := hashResetToken(req.Token)
+	username, err := s.passwordResets.FindResetToken(ctx, tokenHash, time.Now())
+	if err != nil {
+		logger.Warn("Invalid password reset token")
+		return err
+	}
+	if err := s.passwordPolicy.Validate(username, req.NewPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.passwordResets.ConsumeResetToken(ctx, tokenHash, time.Now()); err != nil {
+		logger.Warn("Invalid password reset token")
+		return err
+	}
+	if err := s.setPassword(ctx, username, req.NewPassword); err != nil {
+		return err
+	}
+	s.throttle.Success(ctx, username)
+	return nil
+}
+
+// setPassword stores a password that follows the policy and revokes every
+// refresh token of the user, so other sessions have to log in again. Both
+// happen in one transaction: a new password never leaves old sessions alive.
+func (s *AuthService) setPassword(ctx context.Context, username, password string) *errs.AppError {
+	if err := s.passwordPolicy.Validate(username, password); err != nil {
+		return err
+	}
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+	var revoked int64
+	err = s.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if err := repos.Auth.UpdatePassword(ctx, username, hash); err != nil {
+			return err
+		}
+		var err *errs.AppError
+		revoked, err = repos.Auth.RevokeRefreshTokens(ctx, username)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	logger.Info("Password changed",
+		logger.Bool("audit", true),
+		logger.String("username", username),
+		logger.Int("revoked_refresh_tokens", int(revoked)))
+	return nil
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,116 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetToken asks for a reset of username and returns the token it was sent.
+func (f authFixture) resetToken(t *testing.T, username string) string {
+	t.Helper()
+	if err := f.auth.ForgotPassword(context.Background(), dto.PasswordForgotRequest{Username: username}); err != nil {
+		t.Fatalf("forgot password: %v", err)
+	}
+	if len(f.notifier.sent) == 0 {
+		t.Fatal("expected a reset notification")
+	}
+	body := f.notifier.sent[len(f.notifier.sent)-1].Body
+	return body[strings.LastIndex(body, " ")+1:]
+}
+
+func TestResetPasswordKeepsTheTokenOfARejectedPassword(t *testing.T) {
+	t.Setenv("PASSWORD_MIN_LENGTH", "4")
+	t.Setenv("PASSWORD_REQUIRE_UPPER", "false")
+	t.Setenv("PASSWORD_REQUIRE_LOWER", "false")
+	f := newAuthFixture(t)
+	token := f.resetToken(t, "2000")
+
+	err := f.auth.ResetPassword(context.Background(), dto.PasswordResetRequest{Token: token, NewPassword: "2000"})
+	if err == nil || !strings.Contains(err.Message, "username") {
+		t.Fatalf("expected a password equal to the username to be refused, got %v", err)
+	}
+
+	if err := f.auth.ResetPassword(context.Background(), dto.PasswordResetRequest{Token: token, NewPassword: "fresh-2000"}); err != nil {
+		t.Fatalf("expected the token to survive the refused password: %v", err)
+	}
+	if _, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: "fresh-2000"}); err != nil {
+		t.Errorf("expected a login with the new password: %v", err)
+	}
+
+	err = f.auth.ResetPassword(context.Background(), dto.PasswordResetRequest{Token: token, NewPassword: "other-2000"})
+	expectCode(t, "a used reset token", err, http.StatusUnauthorized)
+}
+
+func TestForgotPasswordIsThrottled(t *testing.T) {
+	t.Setenv("LOGIN_MAX_FAILURES", "3")
+	f := newAuthFixture(t)
+
+	for _, username := range []string{"2000", "nobody"} {
+		for i := 0; i < 3; i++ {
+			err := f.auth.ForgotPassword(context.Background(), dto.PasswordForgotRequest{Username: username, SourceIP: "10.0.0.1"})
+			if err != nil {
+				t.Fatalf("forgot password %d of %s: %v", i+1, username, err)
+			}
+		}
+		err := f.auth.ForgotPassword(context.Background(), dto.PasswordForgotRequest{Username: username, SourceIP: "10.0.0.2"})
+		expectCode(t, "a reset request of "+username+" past the limit", err, http.StatusTooManyRequests)
+	}
+	if len(f.notifier.sent) != 3 {
+		t.Errorf("expected 3 reset notifications, got %d", len(f.notifier.sent))
+	}
+}
+
+func (f authFixture) storedPassword(t *testing.T, username string) string {
+	t.Helper()
+	var password string
+	if err := f.db.Get(&password, `SELECT password FROM users WHERE username = ?`, username); err != nil {
+		t.Fatalf("reading the password of %s: %v", username, err)
+	}
+	return password
+}
+
+func TestPasswordsAreStoredHashed(t *testing.T) {
+	f := newAuthFixture(t)
+	f.login(t, "2000")
+	if cost, err := bcrypt.Cost([]byte(f.storedPassword(t, "2000"))); err != nil || cost != 4 {
+		t.Errorf("expected the seeded hash rehashed with PASSWORD_HASH_COST, got cost %d (%v)", cost, err)
+	}
+
+	token := f.resetToken(t, "2000")
+	if err := f.auth.ResetPassword(context.Background(), dto.PasswordResetRequest{Token: token, NewPassword: "Fresh2000"}); err != nil {
+		t.Fatalf("resetting: %v", err)
+	}
+	stored := f.storedPassword(t, "2000")
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte("Fresh2000")) != nil {
+		t.Errorf("expected a bcrypt hash of the new password, got %q", stored)
+	}
+
+	_, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: stored})
+	expectCode(t, "a login with the hash as password", err, http.StatusUnauthorized)
+	_, err = f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "nobody", Password: "Fresh2000"})
+	expectCode(t, "a login of an unknown user", err, http.StatusUnauthorized)
+}
+
+func TestPlaintextPasswordsAreHashedAtLogin(t *testing.T) {
+	f := newAuthFixture(t)
+	if _, err := f.db.Exec(`UPDATE users SET password = 'legacy-2000' WHERE username = '2000'`); err != nil {
+		t.Fatalf("storing a plaintext password: %v", err)
+	}
+
+	_, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: "legacy"})
+	expectCode(t, "a wrong plaintext password", err, http.StatusUnauthorized)
+	if _, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: "legacy-2000"}); err != nil {
+		t.Fatalf("login with the plaintext password: %v", err)
+	}
+	if stored := f.storedPassword(t, "2000"); bcrypt.CompareHashAndPassword([]byte(stored), []byte("legacy-2000")) != nil {
+		t.Errorf("expected the plaintext password replaced by its hash, got %q", stored)
+	}
+	if _, err := f.auth.RemoteLogin(context.Background(), dto.LoginRequest{Username: "2000", Password: "legacy-2000"}); err != nil {
+		t.Errorf("login with the rehashed password: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain/policy"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+const testPolicyRoute = "TestPolicy"
+
+// evaluatePolicy builds the policy input from the token claims. The scope the
+// role is granted on the route by the role permissions is exposed to the
+// policy as subject.scope.
+func (s *AuthService) evaluatePolicy(claims jwt.MapClaims, routeName string, vars map[string]string, resource map[string]interface{}) policy.Decision {
+	subject := make(map[string]interface{}, len(claims)+1)
+	for k, v := range claims {
+		if k != "scope" {
+			subject[k] = v
+		}
+	}
+	return s.policy.Evaluate(s.policyInput(subject, routeName, vars, resource))
+}
+
+func (s *AuthService) policyInput(subject map[string]interface{}, routeName string, vars map[string]string, resource map[string]interface{}) policy.Input {
+	if _, ok := subject["scope"]; !ok {
+		role, _ := subject["role"].(string)
+		if scope, granted := s.permissions.Current().Scope(role, routeName); granted {
+			subject["scope"] = scope
+		}
+	}
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	if resource == nil {
+		resource = map[string]interface{}{}
+	}
+	return policy.Input{Subject: subject, Route: routeName, Vars: vars, Resource: resource}
+}
+
+// TestPolicy evaluates the policy for an arbitrary input without performing the
+// request. The caller's own token must be authorized for the TestPolicy route.
+func (s *AuthService) TestPolicy(ctx context.Context, callerToken string, req dto.PolicyTestRequest) (*dto.PolicyTestResponse, *errs.AppError) {
+	if _, err := s.Verify(ctx, callerToken, testPolicyRoute, nil, nil); err != nil {
+		return nil, err
+	}
+
+	subject := make(map[string]interface{}, len(req.Subject)+1)
+	for k, v := range req.Subject {
+		subject[k] = v
+	}
+	input := s.policyInput(subject, req.RouteName, req.Vars, req.Resource)
+	decision := s.policy.Evaluate(input)
+
+	return &dto.PolicyTestResponse{
+		Allow:    decision.Allow,
+		RuleID:   decision.RuleID,
+		Reason:   decision.Reason,
+		Subject:  input.Subject,
+		Vars:     input.Vars,
+		Resource: input.Resource,
+	}, nil
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// ReportProjector keeps the report tables up with the account events. Each
+// batch is written together with the checkpoint of its last event, so an
+// event is projected exactly once; projectors sharing the tables take turns
+// on the checkpoint instead of projecting twice.
+//
+// Sequences can have gaps, of rolled back appends or of appends that have
+// not committed yet. The projector stops at a gap until it has waited
+// gapTimeout or the event after it is older than that, then skips it: an
+// append committed later than that is only picked up by a rebuild.
+type ReportProjector struct {
+	repo         ports.ReportRepository
+	uow          ports.UnitOfWork
+	customers    ports.CustomerRepository
+	pollInterval time.Duration
+	batchSize    int
+	gapTimeout   time.Duration
+	now          func() time.Time
+
+	// gapAt is the first missing sequence of the gap the projector waits
+	// at, since gapSince; blocked tells the last batch stopped there.
+	gapAt    int64
+	gapSince time.Time
+	blocked  bool
+}
+
+func NewReportProjector(repo ports.ReportRepository, uow ports.UnitOfWork, customers ports.CustomerRepository) *ReportProjector {
+	return &ReportProjector{
+		repo:         repo,
+		uow:          uow,
+		customers:    customers,
+		pollInterval: config.Duration("PROJECTION_POLL_INTERVAL", time.Second),
+		batchSize:    max(config.Int("PROJECTION_BATCH_SIZE", 500), 1),
+		gapTimeout:   config.Duration("PROJECTION_GAP_TIMEOUT", 10*time.Second),
+		now:          time.Now,
+	}
+}
+
+// Run projects events until ctx is canceled. After a batch that projected
+// something it goes on at once, otherwise it waits for the poll interval.
+func (p *ReportProjector) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			projected, err := p.ProjectBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				p.logError(err)
+			}
+			if err != nil || projected == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rebuild empties the report tables and projects every event again. It
+// returns when the projection has caught up with the events, having waited
+// out the gaps on the way.
+func (p *ReportProjector) Rebuild(ctx context.Context) (int, *errs.AppError) {
+	if err := p.repo.Reset(ctx); err != nil {
+		return 0, err
+	}
+	p.gapAt, p.blocked = 0, false
+
+	total := 0
+	for {
+		projected, err := p.ProjectBatch(ctx)
+		if err != nil {
+			if err.Code != http.StatusConflict {
+				return total, err
+			}
+			p.logError(err)
+		}
+		total += projected
+		if err == nil && projected == 0 && !p.blocked {
+			return total, nil
+		}
+		if projected == 0 {
+			select {
+			case <-ctx.Done():
+				return total, errs.NewRequestCanceledError("Rebuild canceled")
+			case <-time.After(p.pollInterval):
+			}
+		}
+	}
+}
+
+// ProjectBatch projects the events after the checkpoint up to the first gap
+// it waits at, and returns how many it projected.
+func (p *ReportProjector) ProjectBatch(ctx context.Context) (int, *errs.AppError) {
+	from, err := p.repo.Checkpoint(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// The unit of work keeps appends that are still running out of sight.
+	var events []domain.AccountEvent
+	err = p.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		var err *errs.AppError
+		events, err = repos.AccountEvents.Since(ctx, from, p.batchSize)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	events = p.upToGap(from, events)
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	accountIDs := make([]string, 0, len(events))
+	seen := make(map[string]bool, len(events))
+	for _, e := range events {
+		if !seen[e.AccountID] {
+			seen[e.AccountID] = true
+			accountIDs = append(accountIDs, e.AccountID)
+		}
+	}
+	accounts, err := p.repo.FindAccounts(ctx, accountIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	var lookupErr *errs.AppError
+	cities := make(map[string]string)
+	city := func(customerID string) (string, error) {
+		if city, found := cities[customerID]; found {
+			return city, nil
+		}
+		customer, err := p.customers.ByID(ctx, customerID)
+		if err != nil && err.Code != http.StatusNotFound {
+			lookupErr = err
+			return "", err
+		}
+		if customer != nil {
+			cities[customerID] = customer.City
+		} else {
+			cities[customerID] = ""
+		}
+		return cities[customerID], nil
+	}
+	batch, projectErr := domain.ProjectReports(events, accounts, city)
+	if lookupErr != nil {
+		return 0, lookupErr
+	}
+	if projectErr != nil {
+		logger.Error("Error projecting account events", logger.Any("after_sequence", from), logger.Any("error", projectErr))
+		return 0, errs.NewUnexpectedError("Error projecting account events")
+	}
+	for _, e := range batch.Skipped {
+		logger.Warn("Skipping event of an account missing from the reports",
+			logger.Any("sequence", e.Sequence),
+			logger.String("account_id", e.AccountID),
+			logger.String("event_type", e.Type))
+	}
+
+	to := events[len(events)-1].Sequence
+	if err := p.repo.Apply(ctx, batch, from, to); err != nil {
+		return 0, err
+	}
+	return len(events), nil
+}
+
+// upToGap drops the events from the first gap in their sequences that the
+// projector still waits at.
+func (p *ReportProjector) upToGap(from int64, events []domain.AccountEvent) []domain.AccountEvent {
+	p.blocked = false
+	expected := from + 1
+	for i, e := range events {
+		if e.Sequence != expected {
+			if p.gapAt != expected {
+				p.gapAt, p.gapSince = expected, p.now()
+			}
+			if p.now().Sub(p.gapSince) < p.gapTimeout && p.now().Sub(e.OccurredAt) < p.gapTimeout {
+				p.blocked = true
+				return events[:i]
+			}
+			logger.Warn("Skipping missing account event sequences",
+				logger.Any("from", expected),
+				logger.Any("to", e.Sequence-1))
+		}
+		expected = e.Sequence + 1
+	}
+	return events
+}
+
+func (p *ReportProjector) logError(err *errs.AppError) {
+	if err.Code == http.StatusConflict {
+		logger.Info("Reports moved on by another projector, retrying")
+		return
+	}
+	logger.Error("Error projecting reports", logger.Any("error", err))
+}
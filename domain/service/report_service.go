@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// DefaultReportService rounds the sums it reads to cents again, as SQLite
+// adds decimals as floats.
+type DefaultReportService struct {
+	repo ports.ReportRepository
+}
+
+func NewReportService(repo ports.ReportRepository) ports.ReportService {
+	return &DefaultReportService{repo: repo}
+}
+
+func (s *DefaultReportService) CityReport(ctx context.Context) ([]dto.CityReportResponse, *errs.AppError) {
+	totals, err := s.repo.CityTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.CityReportResponse, 0, len(totals))
+	for _, t := range totals {
+		response = append(response, dto.CityReportResponse{
+			City:         t.City,
+			Customers:    t.Customers,
+			Accounts:     t.Accounts,
+			Balance:      domain.RoundCents(t.Balance),
+			Deposits:     domain.RoundCents(t.Deposits),
+			Withdrawals:  domain.RoundCents(t.Withdrawals),
+			Transactions: t.Transactions,
+		})
+	}
+	return response, nil
+}
+
+func (s *DefaultReportService) AccountTypeReport(ctx context.Context, req dto.ReportRangeRequest) ([]dto.AccountTypeReportResponse, *errs.AppError) {
+	totals, err := s.repo.AccountTypeTotals(ctx, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.AccountTypeReportResponse, 0, len(totals))
+	for _, t := range totals {
+		response = append(response, dto.AccountTypeReportResponse{
+			AccountType:     t.AccountType,
+			OpenAccounts:    t.OpenAccounts,
+			Balance:         domain.RoundCents(t.Balance),
+			AccountsOpened:  t.AccountsOpened,
+			Deposits:        domain.RoundCents(t.Deposits),
+			DepositCount:    t.DepositCount,
+			Withdrawals:     domain.RoundCents(t.Withdrawals),
+			WithdrawalCount: t.WithdrawalCount,
+		})
+	}
+	return response, nil
+}
+
+func (s *DefaultReportService) CustomerReport(ctx context.Context, customerID string) (*dto.CustomerReportResponse, *errs.AppError) {
+	t, err := s.repo.CustomerTotals(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.CustomerReportResponse{
+		CustomerID:   t.CustomerID,
+		City:         t.City,
+		Accounts:     t.Accounts,
+		Balance:      domain.RoundCents(t.Balance),
+		Deposits:     domain.RoundCents(t.Deposits),
+		Withdrawals:  domain.RoundCents(t.Withdrawals),
+		Transactions: t.Transactions,
+	}, nil
+}
+
+// AccountDailyReport tells an account the reports do not know, a 404, from
+// one without activity in the range.
+func (s *DefaultReportService) AccountDailyReport(ctx context.Context, req dto.ReportRangeRequest) ([]dto.DailyBalanceResponse, *errs.AppError) {
+	accounts, err := s.repo.FindAccounts(ctx, []string{req.AccountID})
+	if err != nil {
+		return nil, err
+	}
+	if len(accounts) == 0 {
+		return nil, errs.NewNotFoundError("No report for this account")
+	}
+
+	balances, err := s.repo.DailyBalances(ctx, req.AccountID, req.From, req.To)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.DailyBalanceResponse, 0, len(balances))
+	for _, b := range balances {
+		response = append(response, dto.DailyBalanceResponse{
+			Day:            b.Day,
+			ClosingBalance: domain.RoundCents(b.ClosingBalance),
+			Deposits:       domain.RoundCents(b.Deposits),
+			Withdrawals:    domain.RoundCents(b.Withdrawals),
+			Transactions:   b.Transactions,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// RolePermissionsCache keeps the role permissions loaded from the database.
+// It polls the permissions version and reloads only when another instance
+// changed an assignment; local changes reload immediately through Reload.
+type RolePermissionsCache struct {
+	repo    ports.RoleRepository
+	mu      sync.RWMutex
+	current *domain.RolePermissions
+	version int64
+}
+
+func NewRolePermissionsCache(repo ports.RoleRepository) *RolePermissionsCache {
+	cache := &RolePermissionsCache{
+		repo:    repo,
+		current: domain.NewRolePermissions(nil),
+	}
+	if err := cache.Reload(context.Background()); err != nil {
+		logger.Error("Initial load of role permissions failed", logger.Any("error", err))
+	}
+	return cache
+}
+
+func (c *RolePermissionsCache) Current() *domain.RolePermissions {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+func (c *RolePermissionsCache) Reload(ctx context.Context) *errs.AppError {
+	version, err := c.repo.PermissionsVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	assignments, err := c.repo.FindAllAssignments(ctx)
+	if err != nil {
+		return err
+	}
+
+	permissions := domain.NewRolePermissions(assignments)
+	c.mu.Lock()
+	c.current = permissions
+	c.version = version
+	c.mu.Unlock()
+
+	logger.Info("Loaded role permissions",
+		logger.Int("assignment_count", len(assignments)),
+		logger.Any("version", version))
+	return nil
+}
+
+// Watch reloads the permissions whenever the stored version changes. It runs
+// until stop is closed.
+func (c *RolePermissionsCache) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	ctx := context.Background()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			version, err := c.repo.PermissionsVersion(ctx)
+			if err != nil {
+				continue
+			}
+			c.mu.RLock()
+			changed := version != c.version
+			c.mu.RUnlock()
+			if changed {
+				if err := c.Reload(ctx); err != nil {
+					logger.Error("Error reloading role permissions", logger.Any("error", err))
+				}
+			}
+		}
+	}
+}
+
+var _ ports.RolePermissionsProvider = (*RolePermissionsCache)(nil)
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const adminRole = "admin"
+
+type DefaultRoleService struct {
+	repo        ports.RoleRepository
+	permissions ports.RolePermissionsProvider
+}
+
+func NewRoleService(repo ports.RoleRepository, permissions ports.RolePermissionsProvider) ports.RoleService {
+	return &DefaultRoleService{repo: repo, permissions: permissions}
+}
+
+func (s *DefaultRoleService) ListRoles(ctx context.Context) ([]dto.RoleResponse, *errs.AppError) {
+	roles, err := s.repo.FindAllRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.RoleResponse, 0, len(roles))
+	for _, r := range roles {
+		response = append(response, dto.RoleResponse{Name: r.Name, Description: r.Description, MFARequired: r.MFARequired})
+	}
+	return response, nil
+}
+
+func (s *DefaultRoleService) CreateRole(ctx context.Context, req dto.RoleRequest) (*dto.RoleResponse, *errs.AppError) {
+	role := domain.Role{Name: strings.ToLower(strings.TrimSpace(req.Name)), Description: req.Description}
+	saved, err := s.repo.SaveRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Role created", logger.String("role", saved.Name))
+	return &dto.RoleResponse{Name: saved.Name, Description: saved.Description}, nil
+}
+
+func (s *DefaultRoleService) DeleteRole(ctx context.Context, name string) *errs.AppError {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == adminRole {
+		return errs.NewBadRequestError("The admin role cannot be deleted")
+	}
+	if err := s.repo.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+	logger.Info("Role deleted", logger.String("role", name))
+	return s.reload(ctx)
+}
+
+func (s *DefaultRoleService) SetRoleMFA(ctx context.Context, req dto.RoleMFARequest) *errs.AppError {
+	name := strings.ToLower(strings.TrimSpace(req.RoleName))
+	if err := s.repo.SetMFARequired(ctx, name, *req.Required); err != nil {
+		return err
+	}
+	logger.Info("Role MFA requirement changed", logger.String("role", name), logger.Bool("mfa_required", *req.Required))
+	return nil
+}
+
+func (s *DefaultRoleService) ListPermissions(ctx context.Context) ([]dto.PermissionResponse, *errs.AppError) {
+	permissions, err := s.repo.FindAllPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.PermissionResponse, 0, len(permissions))
+	for _, p := range permissions {
+		response = append(response, dto.PermissionResponse{Name: p.Name, Description: p.Description})
+	}
+	return response, nil
+}
+
+func (s *DefaultRoleService) CreatePermission(ctx context.Context, req dto.PermissionRequest) (*dto.PermissionResponse, *errs.AppError) {
+	permission := domain.Permission{Name: strings.TrimSpace(req.Name), Description: req.Description}
+	saved, err := s.repo.SavePermission(ctx, permission)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Permission created", logger.String("permission", saved.Name))
+	return &dto.PermissionResponse{Name: saved.Name, Description: saved.Description}, nil
+}
+
+func (s *DefaultRoleService) DeletePermission(ctx context.Context, name string) *errs.AppError {
+	if err := s.repo.DeletePermission(ctx, name); err != nil {
+		return err
+	}
+	logger.Info("Permission deleted", logger.String("permission", name))
+	return s.reload(ctx)
+}
+
+func (s *DefaultRoleService) ListRolePermissions(ctx context.Context, roleName string) ([]dto.RolePermissionResponse, *errs.AppError) {
+	assignments, err := s.repo.FindAllAssignments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	roleName = strings.ToLower(strings.TrimSpace(roleName))
+	response := make([]dto.RolePermissionResponse, 0)
+	for _, a := range assignments {
+		if a.RoleName == roleName {
+			response = append(response, dto.RolePermissionResponse{Role: a.RoleName, Permission: a.PermissionName, Scope: a.Scope})
+		}
+	}
+	return response, nil
+}
+
+func (s *DefaultRoleService) AssignPermission(ctx context.Context, req dto.AssignPermissionRequest) (*dto.RolePermissionResponse, *errs.AppError) {
+	assignment := domain.RolePermission{
+		RoleName:       strings.ToLower(strings.TrimSpace(req.RoleName)),
+		PermissionName: strings.TrimSpace(req.PermissionName),
+		Scope:          req.Scope,
+	}
+	saved, err := s.repo.SaveAssignment(ctx, assignment)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Permission assigned",
+		logger.String("role", saved.RoleName),
+		logger.String("permission", saved.PermissionName),
+		logger.String("scope", saved.Scope))
+	if err := s.reload(ctx); err != nil {
+		return nil, err
+	}
+	return &dto.RolePermissionResponse{Role: saved.RoleName, Permission: saved.PermissionName, Scope: saved.Scope}, nil
+}
+
+func (s *DefaultRoleService) RevokePermission(ctx context.Context, roleName, permissionName string) *errs.AppError {
+	roleName = strings.ToLower(strings.TrimSpace(roleName))
+	if err := s.repo.DeleteAssignment(ctx, roleName, permissionName); err != nil {
+		return err
+	}
+	logger.Info("Permission revoked",
+		logger.String("role", roleName),
+		logger.String("permission", permissionName))
+	return s.reload(ctx)
+}
+
+func (s *DefaultRoleService) reload(ctx context.Context) *errs.AppError {
+	if err := s.permissions.Reload(ctx); err != nil {
+		logger.Error("Role data changed but permissions could not be reloaded", logger.Any("error", err))
+		return err
+	}
+	return nil
+}
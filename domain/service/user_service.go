@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	defaultUserPageSize = 50
+	maxUserPageSize     = 200
+)
+
+type DefaultUserService struct {
+	users     ports.UserRepository
+	auth      ports.AuthRepository
+	roles     ports.RoleRepository
+	customers ports.CustomerRepository
+	uow       ports.UnitOfWork
+}
+
+func NewUserService(users ports.UserRepository, auth ports.AuthRepository, roles ports.RoleRepository, customers ports.CustomerRepository, uow ports.UnitOfWork) ports.UserService {
+	return &DefaultUserService{users: users, auth: auth, roles: roles, customers: customers, uow: uow}
+}
+
+func (s *DefaultUserService) ListUsers(ctx context.Context, req dto.UserSearchRequest) ([]dto.User, *errs.AppError) {
+	if req.Status != "" && !domain.IsValidUserStatus(req.Status) {
+		return nil, errs.NewValidationError("Status must be 'active' or 'disabled'")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultUserPageSize
+	}
+	if limit > maxUserPageSize {
+		limit = maxUserPageSize
+	}
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	users, err := s.users.FindAll(ctx, domain.UserFilter{
+		Query:      strings.TrimSpace(req.Query),
+		Role:       req.Role,
+		Status:     req.Status,
+		CustomerID: req.CustomerID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.User, 0, len(users))
+	for _, u := range users {
+		response = append(response, u.ToDto())
+	}
+	return response, nil
+}
+
+func (s *DefaultUserService) GetUser(ctx context.Context, username string) (*dto.User, *errs.AppError) {
+	user, err := s.auth.FindUserByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	response := user.ToDto()
+	return &response, nil
+}
+
+func (s *DefaultUserService) SetUserRole(ctx context.Context, req dto.UserRoleRequest) *errs.AppError {
+	if _, err := s.auth.FindUserByUsername(ctx, req.Username); err != nil {
+		return err
+	}
+	role := strings.TrimSpace(req.Role)
+	if err := s.requireRole(ctx, role); err != nil {
+		return err
+	}
+	if err := s.users.UpdateRole(ctx, req.Username, role); err != nil {
+		return err
+	}
+	logger.Info("User role changed",
+		logger.Bool("audit", true),
+		logger.String("username", req.Username),
+		logger.String("role", role))
+	return nil
+}
+
+func (s *DefaultUserService) LinkCustomer(ctx context.Context, req dto.UserCustomerRequest) *errs.AppError {
+	if _, err := s.auth.FindUserByUsername(ctx, req.Username); err != nil {
+		return err
+	}
+	customerID := strings.TrimSpace(req.CustomerID)
+	if _, err := s.customers.ByID(ctx, customerID); err != nil {
+		if err.Code == http.StatusNotFound {
+			return errs.NewValidationError("Customer " + customerID + " does not exist")
+		}
+		return err
+	}
+	if err := s.users.UpdateCustomerID(ctx, req.Username, &customerID); err != nil {
+		return err
+	}
+	logger.Info("User linked to customer",
+		logger.Bool("audit", true),
+		logger.String("username", req.Username),
+		logger.String("customer_id", customerID))
+	return nil
+}
+
+func (s *DefaultUserService) UnlinkCustomer(ctx context.Context, username string) *errs.AppError {
+	if _, err := s.auth.FindUserByUsername(ctx, username); err != nil {
+		return err
+	}
+	if err := s.users.UpdateCustomerID(ctx, username, nil); err != nil {
+		return err
+	}
+	logger.Info("User unlinked from customer", logger.Bool("audit", true), logger.String("username", username))
+	return nil
+}
+
+// SetUserStatus enables or disables a user. Disabling also revokes the user's
+// refresh tokens in the same transaction; access tokens already issued stay
+// valid until they expire.
+func (s *DefaultUserService) SetUserStatus(ctx context.Context, req dto.UserStatusRequest) *errs.AppError {
+	err := s.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if _, err := repos.Auth.FindUserByUsername(ctx, req.Username); err != nil {
+			return err
+		}
+		if err := repos.Users.UpdateStatus(ctx, req.Username, req.Status); err != nil {
+			return err
+		}
+		if req.Status == domain.UserStatusDisabled {
+			if _, err := repos.Auth.RevokeRefreshTokens(ctx, req.Username); err != nil {
+				return err
+			}
+		}
+		return recordEvent(ctx, repos.Outbox, domain.AggregateUser, req.Username, domain.EventUserStatusChanged, domain.UserStatusChangedPayload{
+			Username: req.Username,
+			Status:   req.Status,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	logger.Warn("User status changed",
+		logger.Bool("audit", true),
+		logger.String("username", req.Username),
+		logger.String("status", req.Status))
+	return nil
+}
+
+// ForceLogout revokes every refresh token of the user.
+func (s *DefaultUserService) ForceLogout(ctx context.Context, username string) (*dto.ForceLogoutResponse, *errs.AppError) {
+	if _, err := s.auth.FindUserByUsername(ctx, username); err != nil {
+		return nil, err
+	}
+	revoked, err := s.auth.RevokeRefreshTokens(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	logger.Warn("User logged out by admin",
+		logger.Bool("audit", true),
+		logger.String("username", username),
+		logger.Any("revoked_refresh_tokens", revoked))
+	return &dto.ForceLogoutResponse{Username: username, RevokedRefreshTokens: revoked}, nil
+}
+
+func (s *DefaultUserService) requireRole(ctx context.Context, name string) *errs.AppError {
+	roles, err := s.roles.FindAllRoles(ctx)
+	if err != nil {
+		return err
+	}
+	for _, role := range roles {
+		if role.Name == name {
+			return nil
+		}
+	}
+	return errs.NewValidationError("Role " + name + " does not exist")
+}
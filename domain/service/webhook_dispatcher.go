@@ -0,0 +1,256 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// WebhookDispatcher delivers domain events to webhook subscriptions. As an
+// event sink of the outbox relay it queues one delivery per matching
+// subscription; Run then posts the queued deliveries, signed with the
+// subscription's secret. A delivery that fails is retried with exponential
+// backoff, and after the last attempt it is dead until it is redelivered.
+//
+// Queuing is idempotent per subscription and event, so an event the relay
+// publishes twice is delivered once; a delivery whose outcome is lost is
+// posted again, and receivers drop it by its X-Webhook-Delivery header.
+type WebhookDispatcher struct {
+	repo         ports.WebhookRepository
+	client       *http.Client
+	pollInterval time.Duration
+	batchSize    int
+	concurrency  int
+	maxAttempts  int
+	backoff      time.Duration
+	maxBackoff   time.Duration
+	now          func() time.Time
+}
+
+// NewWebhookDispatcher only connects to public addresses, checked on the
+// address dialed, unless WEBHOOK_ALLOW_PRIVATE is set; deliveries never go
+// through the proxy of the environment, which would dial in their place.
+func NewWebhookDispatcher(repo ports.WebhookRepository) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !config.Bool("WEBHOOK_ALLOW_PRIVATE", false) {
+		dialer.Control = utils.PublicDialControl
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &WebhookDispatcher{
+		repo: repo,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Duration("WEBHOOK_TIMEOUT", 10*time.Second),
+			// A redirect is reported as a failure rather than followed, so
+			// the signed body is only ever sent to the registered URL.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		pollInterval: config.Duration("WEBHOOK_POLL_INTERVAL", time.Second),
+		batchSize:    max(config.Int("WEBHOOK_BATCH_SIZE", 50), 1),
+		concurrency:  max(config.Int("WEBHOOK_CONCURRENCY", 4), 1),
+		maxAttempts:  max(config.Int("WEBHOOK_MAX_ATTEMPTS", 8), 1),
+		backoff:      config.Duration("WEBHOOK_RETRY_BACKOFF", 30*time.Second),
+		maxBackoff:   config.Duration("WEBHOOK_MAX_BACKOFF", time.Hour),
+		now:          time.Now,
+	}
+}
+
+// Publish queues the event for the subscriptions it matches. The customer of
+// an event is the customer_id of its payload; events without one only go to
+// the subscriptions of no customer.
+func (d *WebhookDispatcher) Publish(ctx context.Context, e domain.Event) error {
+	subscriptions, appErr := d.repo.FindSubscriptions(ctx, "")
+	if appErr != nil {
+		return appErr
+	}
+	var subject struct {
+		CustomerID string `json:"customer_id"`
+	}
+	_ = json.Unmarshal(e.Payload, &subject)
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	for _, subscription := range subscriptions {
+		if !subscription.Matches(e.Type, subject.CustomerID) {
+			continue
+		}
+		appErr := d.repo.EnqueueDelivery(ctx, domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        string(body),
+			Status:         domain.WebhookDeliveryPending,
+			NextAttemptAt:  d.now(),
+			CreatedOn:      d.now(),
+		})
+		if appErr != nil {
+			return appErr
+		}
+	}
+	return nil
+}
+
+// Run posts due deliveries until ctx is canceled, waiting for the poll
+// interval whenever nothing is due.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := d.DeliverBatch(ctx)
+			if err != nil && ctx.Err() == nil {
+				logger.Error("Error delivering webhooks", logger.Any("error", err))
+			}
+			if err != nil || sent == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverBatch posts the deliveries that are due and returns how many it
+// attempted, successful or not.
+func (d *WebhookDispatcher) DeliverBatch(ctx context.Context) (int, *errs.AppError) {
+	deliveries, appErr := d.repo.DueDeliveries(ctx, d.now(), d.batchSize)
+	if appErr != nil {
+		return 0, appErr
+	}
+
+	subscriptions := make(map[string]*domain.WebhookSubscription)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr *errs.AppError
+	)
+	slots := make(chan struct{}, d.concurrency)
+	attempted := 0
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			break
+		}
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			found, appErr := d.repo.FindSubscription(ctx, delivery.SubscriptionID)
+			if appErr != nil {
+				if appErr.Code == http.StatusNotFound {
+					// Deleted since the batch was read, with its deliveries.
+					continue
+				}
+				return attempted, appErr
+			}
+			subscription = found
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		attempted++
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(delivery domain.WebhookDelivery) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if appErr := d.deliver(ctx, *subscription, delivery); appErr != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = appErr
+				}
+				mu.Unlock()
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return attempted, firstErr
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, subscription domain.WebhookSubscription, delivery domain.WebhookDelivery) *errs.AppError {
+	statusCode, err := d.post(ctx, subscription, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down: the delivery stays due and is posted on restart.
+		return nil
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.LastStatusCode = statusCode
+	fields := []logger.Field{
+		logger.String("webhook_id", subscription.ID),
+		logger.String("delivery_id", delivery.ID),
+		logger.String("event_type", delivery.EventType),
+		logger.Int("attempt", delivery.Attempts),
+		logger.Int("status_code", statusCode),
+	}
+	switch {
+	case err == nil:
+		delivery.Status = domain.WebhookDeliveryDelivered
+		delivery.LastError = ""
+		delivery.DeliveredOn = &now
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = domain.WebhookDeliveryDead
+		delivery.LastError = err.Error()
+		logger.Warn("Webhook delivery failed, giving up", append(fields, logger.Any("error", err))...)
+	default:
+		retryIn := backoffDelay(d.backoff, d.maxBackoff, delivery.Attempts)
+		delivery.NextAttemptAt = now.Add(retryIn)
+		delivery.LastError = err.Error()
+		logger.Warn("Webhook delivery failed, retrying later",
+			append(fields, logger.String("retry_in", retryIn.String()), logger.Any("error", err))...)
+	}
+	return d.repo.UpdateDelivery(ctx, delivery)
+}
+
+// post sends the delivery and returns the status code of the response, 0
+// when there was none. Only a 2xx response is a success.
+func (d *WebhookDispatcher) post(ctx context.Context, subscription domain.WebhookSubscription, delivery domain.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "banking-webhooks/1")
+	req.Header.Set(domain.WebhookSignatureHeader, domain.SignWebhook(subscription.Secret, d.now(), body))
+	req.Header.Set("X-Webhook-ID", subscription.ID)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Event-ID", strconv.FormatInt(delivery.EventID, 10))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("posting to %s: %w", subscription.URL, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	webhookSecretPrefix        = "whsec_"
+	defaultWebhookDeliveryPage = 50
+	maxWebhookDeliveryPage     = 500
+)
+
+type DefaultWebhookService struct {
+	repo         ports.WebhookRepository
+	customers    ports.CustomerRepository
+	allowHTTP    bool
+	allowPrivate bool
+}
+
+// NewWebhookService only accepts https URLs of public hosts unless
+// WEBHOOK_ALLOW_HTTP and WEBHOOK_ALLOW_PRIVATE are set, which is meant for
+// local receivers.
+func NewWebhookService(repo ports.WebhookRepository, customers ports.CustomerRepository) ports.WebhookService {
+	return &DefaultWebhookService{
+		repo:         repo,
+		customers:    customers,
+		allowHTTP:    config.Bool("WEBHOOK_ALLOW_HTTP", false),
+		allowPrivate: config.Bool("WEBHOOK_ALLOW_PRIVATE", false),
+	}
+}
+
+func (s *DefaultWebhookService) CreateWebhook(ctx context.Context, req dto.WebhookRequest) (*dto.WebhookCreatedResponse, *errs.AppError) {
+	endpoint := strings.TrimSpace(req.URL)
+	u, _ := url.Parse(endpoint)
+	if u.Scheme != "https" && !s.allowHTTP {
+		return nil, errs.NewValidationError("URL must use https")
+	}
+	if !s.allowPrivate {
+		if err := utils.CheckPublicHost(ctx, u.Hostname()); err != nil {
+			logger.Warn("Webhook URL refused", logger.String("url", endpoint), logger.Any("error", err))
+			return nil, errs.NewValidationError("URL must point to a public host")
+		}
+	}
+	if req.CustomerID != "" {
+		if _, err := s.customers.ByID(ctx, req.CustomerID); err != nil {
+			if err.Code == http.StatusNotFound {
+				return nil, errs.NewValidationError("Customer " + req.CustomerID + " does not exist")
+			}
+			return nil, err
+		}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		generated, err := newWebhookSecret()
+		if err != nil {
+			logger.Error("Failed to generate webhook secret", logger.Any("error", err))
+			return nil, errs.NewUnexpectedError("Error generating webhook secret")
+		}
+		secret = generated
+	}
+
+	eventTypes := make([]string, 0, len(req.EventTypes))
+	for _, eventType := range req.EventTypes {
+		eventTypes = append(eventTypes, strings.TrimSpace(eventType))
+	}
+	subscription := domain.WebhookSubscription{
+		URL:        endpoint,
+		Secret:     secret,
+		EventTypes: strings.Join(eventTypes, ","),
+		CreatedBy:  req.CreatedBy,
+		CreatedOn:  time.Now(),
+	}
+	if req.CustomerID != "" {
+		subscription.CustomerID = &req.CustomerID
+	}
+
+	saved, err := s.repo.SaveSubscription(ctx, subscription)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("Webhook created",
+		logger.Bool("audit", true),
+		logger.String("webhook_id", saved.ID),
+		logger.String("url", saved.URL),
+		logger.String("customer_id", req.CustomerID),
+		logger.String("created_by", saved.CreatedBy))
+	return &dto.WebhookCreatedResponse{WebhookResponse: toWebhookResponse(*saved), Secret: secret}, nil
+}
+
+func (s *DefaultWebhookService) ListWebhooks(ctx context.Context, customerID string) ([]dto.WebhookResponse, *errs.AppError) {
+	subscriptions, err := s.repo.FindSubscriptions(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.WebhookResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		response = append(response, toWebhookResponse(subscription))
+	}
+	return response, nil
+}
+
+func (s *DefaultWebhookService) DeleteWebhook(ctx context.Context, customerID, webhookID string) *errs.AppError {
+	if _, err := s.subscription(ctx, customerID, webhookID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteSubscription(ctx, webhookID); err != nil {
+		return err
+	}
+	logger.Info("Webhook deleted", logger.Bool("audit", true), logger.String("webhook_id", webhookID))
+	return nil
+}
+
+func (s *DefaultWebhookService) ListDeliveries(ctx context.Context, req dto.WebhookDeliveryQueryRequest) ([]dto.WebhookDeliveryResponse, *errs.AppError) {
+	if _, err := s.subscription(ctx, req.CustomerID, req.WebhookID); err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultWebhookDeliveryPage
+	}
+	deliveries, err := s.repo.FindDeliveries(ctx, domain.WebhookDeliveryFilter{
+		SubscriptionID: req.WebhookID,
+		Status:         req.Status,
+		Limit:          min(limit, maxWebhookDeliveryPage),
+		Offset:         max(req.Offset, 0),
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response = append(response, toWebhookDeliveryResponse(delivery))
+	}
+	return response, nil
+}
+
+// Redeliver keeps the outcome of the last attempt for reference; the count
+// of attempts starts over.
+func (s *DefaultWebhookService) Redeliver(ctx context.Context, customerID, webhookID, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError) {
+	if _, err := s.subscription(ctx, customerID, webhookID); err != nil {
+		return nil, err
+	}
+	delivery, err := s.repo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.SubscriptionID != webhookID {
+		return nil, errs.NewNotFoundError("Webhook delivery not found")
+	}
+
+	delivery.Status = domain.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredOn = nil
+	if err := s.repo.UpdateDelivery(ctx, *delivery); err != nil {
+		return nil, err
+	}
+	logger.Info("Webhook delivery requeued",
+		logger.Bool("audit", true),
+		logger.String("webhook_id", webhookID),
+		logger.String("delivery_id", deliveryID))
+	response := toWebhookDeliveryResponse(*delivery)
+	return &response, nil
+}
+
+// subscription finds a subscription the customer of the route may manage;
+// another customer's subscription is reported as missing.
+func (s *DefaultWebhookService) subscription(ctx context.Context, customerID, webhookID string) (*domain.WebhookSubscription, *errs.AppError) {
+	subscription, err := s.repo.FindSubscription(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	if customerID != "" && (subscription.CustomerID == nil || *subscription.CustomerID != customerID) {
+		return nil, errs.NewNotFoundError("Webhook not found")
+	}
+	return subscription, nil
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + hex.EncodeToString(secret), nil
+}
+
+func toWebhookResponse(s domain.WebhookSubscription) dto.WebhookResponse {
+	customerID := ""
+	if s.CustomerID != nil {
+		customerID = *s.CustomerID
+	}
+	return dto.WebhookResponse{
+		ID:         s.ID,
+		URL:        s.URL,
+		EventTypes: s.EventTypeList(),
+		CustomerID: customerID,
+		CreatedBy:  s.CreatedBy,
+		CreatedOn:  s.CreatedOn,
+	}
+}
+
+func toWebhookDeliveryResponse(d domain.WebhookDelivery) dto.WebhookDeliveryResponse {
+	response := dto.WebhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastAttemptAt:  d.LastAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedOn:      d.CreatedOn,
+		DeliveredOn:    d.DeliveredOn,
+	}
+	if d.Status == domain.WebhookDeliveryPending {
+		next := d.NextAttemptAt
+		response.NextAttemptAt = &next
+	}
+	return response
+}
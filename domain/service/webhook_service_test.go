@@ -0,0 +1,73 @@
+package service_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/repository"
+)
+
+func TestCreateWebhookRefusesInternalHosts(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_HTTP", "true")
+	db := migratedDB(t)
+	webhooks := service.NewWebhookService(repository.NewWebhookRepositoryDb(db), repository.NewCustomerRepositoryDb(db))
+
+	for _, endpoint := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.10/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+	} {
+		_, err := webhooks.CreateWebhook(context.Background(), dto.WebhookRequest{URL: endpoint})
+		expectCode(t, "a webhook to "+endpoint, err, http.StatusUnprocessableEntity)
+	}
+
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+	webhooks = service.NewWebhookService(repository.NewWebhookRepositoryDb(db), repository.NewCustomerRepositoryDb(db))
+	if _, err := webhooks.CreateWebhook(context.Background(), dto.WebhookRequest{URL: "http://localhost/hook"}); err != nil {
+		t.Errorf("expected WEBHOOK_ALLOW_PRIVATE to allow a local receiver: %v", err)
+	}
+}
+
+func TestWebhookDispatcherRefusesInternalAddresses(t *testing.T) {
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { received = true }))
+	defer receiver.Close()
+
+	repo := repository.NewWebhookRepositoryDb(migratedDB(t))
+	// A subscription stored before the URL checks, or a name that resolves
+	// elsewhere once checked, is caught when the dispatcher dials.
+	subscription, err := repo.SaveSubscription(context.Background(), domain.WebhookSubscription{
+		URL: receiver.URL, Secret: "0123456789abcdef", EventTypes: domain.EventTransactionPosted, CreatedOn: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("saving subscription: %v", err)
+	}
+	dispatcher := service.NewWebhookDispatcher(repo)
+	if err := dispatcher.Publish(context.Background(), domain.Event{ID: 1, Type: domain.EventTransactionPosted, Payload: []byte(`{}`)}); err != nil {
+		t.Fatalf("publishing: %v", err)
+	}
+	if _, err := dispatcher.DeliverBatch(context.Background()); err != nil {
+		t.Fatalf("delivering: %v", err)
+	}
+
+	if received {
+		t.Error("expected the delivery not to reach a loopback receiver")
+	}
+	deliveries, err := repo.FindDeliveries(context.Background(), domain.WebhookDeliveryFilter{SubscriptionID: subscription.ID, Limit: 10})
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("expected one delivery, got %v (%v)", deliveries, err)
+	}
+	if deliveries[0].Status == domain.WebhookDeliveryDelivered || deliveries[0].Attempts != 1 {
+		t.Errorf("expected a failed attempt, got %+v", deliveries[0])
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+// VerifiedToken holds the claims of an access token that passed verification.
+type VerifiedToken struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	CustomerID string `json:"customer_id"`
+	// BreakGlassGrantID is set when the token comes from an active break-glass
+	// grant, which authorizes every route regardless of role permissions.
+	BreakGlassGrantID string `json:"break_glass_grant_id,omitempty"`
+	// APIKeyID is set when the request was authorized with an API key.
+	APIKeyID string `json:"api_key_id,omitempty"`
+	// Actor is the admin behind an impersonation token, whose other claims
+	// are those of the impersonated user.
+	Actor string `json:"actor,omitempty"`
+}
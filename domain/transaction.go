@@ -10,11 +10,11 @@ const (
 )
 
 type Transaction struct {
-	TransactionID   string  `json:"transaction_id"`
-	AccountID       string  `json:"account_id"`
-	Amount          float64 `json:"amount"`
-	TransactionType string  `json:"transaction_type"`
-	TransactionDate string  `json:"transaction_date"`
+	TransactionID   string  `db:"transaction_id" json:"transaction_id"`
+	AccountID       string  `db:"account_id" json:"account_id"`
+	Amount          float64 `db:"amount" json:"amount"`
+	TransactionType string  `db:"transaction_type" json:"transaction_type"`
+	TransactionDate string  `db:"transaction_date" json:"transaction_date"`
 }
 
 func (t Transaction) IsWithdrawal() bool {
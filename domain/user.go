@@ -1,6 +1,19 @@
 package domain
 
-import "time"
+import (
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+)
+
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+)
+
+// RoleUser is the role of self-registered users. Admins grant other roles
+// and link customers through the user management endpoints.
+const RoleUser = "user"
 
 type User struct {
 	ID         string    `db:"id" json:"id,omitempty"`
@@ -8,5 +21,40 @@ type User struct {
 	Password   string    `db:"password" json:"password"`
 	Role       string    `db:"role" json:"role"`
 	CustomerID *string   `db:"customer_id" json:"customer_id,omitempty"`
+	Status     string    `db:"status" json:"status"`
 	CreatedOn  time.Time `db:"created_on" json:"created_on"`
 }
+
+// IsActive reports whether the user may log in and refresh tokens.
+func (u User) IsActive() bool {
+	return u.Status != UserStatusDisabled
+}
+
+func (u User) ToDto() dto.User {
+	customerID := ""
+	if u.CustomerID != nil {
+		customerID = *u.CustomerID
+	}
+	return dto.User{
+		Username:   u.Username,
+		Role:       u.Role,
+		CustomerID: customerID,
+		Status:     u.Status,
+		CreatedOn:  u.CreatedOn.Format(time.RFC3339),
+	}
+}
+
+func IsValidUserStatus(status string) bool {
+	return status == UserStatusActive || status == UserStatusDisabled
+}
+
+// UserFilter selects users in the admin listing. Query matches part of the
+// username; empty fields do not filter.
+type UserFilter struct {
+	Query      string
+	Role       string
+	Status     string
+	CustomerID string
+	Limit      int
+	Offset     int
+}
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryDead      = "dead"
+)
+
+// WebhookSignatureHeader carries "t=<unix seconds>,v1=<hex HMAC-SHA256>",
+// the HMAC being over "<t>.<body>" with the subscription's secret. Signing
+// the timestamp lets receivers reject replays of old deliveries.
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// WebhookSubscription sends the events matching EventTypes to URL. A
+// subscription with a CustomerID only receives the events of that customer;
+// one without receives the events of every customer.
+type WebhookSubscription struct {
+	ID         string    `db:"subscription_id"`
+	URL        string    `db:"url"`
+	Secret     string    `db:"secret"`
+	EventTypes string    `db:"event_types"`
+	CustomerID *string   `db:"customer_id"`
+	CreatedBy  string    `db:"created_by"`
+	CreatedOn  time.Time `db:"created_on"`
+}
+
+// EventTypeList returns the event types of the filter; empty means all.
+func (s WebhookSubscription) EventTypeList() []string {
+	types := make([]string, 0)
+	for _, eventType := range strings.Split(s.EventTypes, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			types = append(types, eventType)
+		}
+	}
+	return types
+}
+
+// Matches reports whether an event of eventType concerning customerID, empty
+// when the event concerns no customer, goes to the subscription.
+func (s WebhookSubscription) Matches(eventType, customerID string) bool {
+	if s.CustomerID != nil && *s.CustomerID != customerID {
+		return false
+	}
+	types := s.EventTypeList()
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one event to send to one subscription, and the outcome
+// of the attempts so far. Payload is the request body.
+type WebhookDelivery struct {
+	ID             string     `db:"delivery_id"`
+	SubscriptionID string     `db:"subscription_id"`
+	EventID        int64      `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Payload        string     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastAttemptAt  *time.Time `db:"last_attempt_at"`
+	LastStatusCode int        `db:"last_status_code"`
+	LastError      string     `db:"last_error"`
+	CreatedOn      time.Time  `db:"created_on"`
+	DeliveredOn    *time.Time `db:"delivered_on"`
+}
+
+// WebhookDeliveryFilter selects the deliveries of a subscription; an empty
+// Status does not filter.
+type WebhookDeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Limit          int
+	Offset         int
+}
+
+// SignWebhook returns the value of the signature header for body sent at t.
+func SignWebhook(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(secret, timestamp, body)
+}
+
+// VerifyWebhookSignature checks a signature header, as a receiver would: the
+// HMAC must match and the timestamp be within tolerance of now.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return errors.New("malformed signature header")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("malformed signature timestamp")
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside tolerance")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, timestamp, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -73,4 +73,36 @@ func NewTooManyRequestsError(message string) *AppError {
 		Code:    http.StatusTooManyRequests,
 		Message: message,
 	}
-}
\ No newline at end of file
+}
+
+func NewServiceUnavailableError(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusServiceUnavailable,
+		Message: message,
+	}
+}
+
+func NewPayloadTooLargeError(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: message,
+	}
+}
+
+// StatusClientClosedRequest is the non-standard status nginx logs when the
+// client goes away before the response is written.
+const StatusClientClosedRequest = 499
+
+func NewTimeoutError(message string) *AppError {
+	return &AppError{
+		Code:    http.StatusGatewayTimeout,
+		Message: message,
+	}
+}
+
+func NewRequestCanceledError(message string) *AppError {
+	return &AppError{
+		Code:    StatusClientClosedRequest,
+		Message: message,
+	}
+}
@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+func String(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func Int(key string, fallback int) int {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logger.Warn("Invalid integer in environment, using default",
+			logger.String("key", key),
+			logger.Int("default", fallback))
+		return fallback
+	}
+	return parsed
+}
+
+func Bool(key string, fallback bool) bool {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logger.Warn("Invalid boolean in environment, using default",
+			logger.String("key", key),
+			logger.Bool("default", fallback))
+		return fallback
+	}
+	return parsed
+}
+
+func Duration(key string, fallback time.Duration) time.Duration {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Warn("Invalid duration in environment, using default",
+			logger.String("key", key),
+			logger.String("default", fallback.String()))
+		return fallback
+	}
+	return parsed
+}
@@ -1,38 +1,80 @@
 package database
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
+	_ "modernc.org/sqlite"
 )
 
-func GetClient() *sqlx.DB {
-	user := os.Getenv("MYSQL_USER")
-	password := os.Getenv("MYSQL_PASSWORD")
-	dbName := os.Getenv("MYSQL_DATABASE")
-	host := "localhost"
-	port := "3306"
+// GetClient opens the database described by LoadConfig.
+func GetClient() (*sqlx.DB, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("database config: %w", err)
+	}
+	return Open(cfg)
+}
 
-	if user == "" || password == "" || dbName == "" {
-		logger.Fatal("Missing required environment variables",
-			logger.String("variables", "MYSQL_USER, MYSQL_PASSWORD, or MYSQL_DATABASE"))
+// Open configures the pool and waits until the database answers, retrying
+// with exponential backoff so the services can start before the database.
+func Open(cfg Config) (*sqlx.DB, error) {
+	dsn, err := cfg.DSN()
+	if err != nil {
+		return nil, err
+	}
+	client, err := sqlx.Open(cfg.Driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
 	}
 
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", user, password, host, port, dbName)
+	if cfg.Driver == DriverSQLite && cfg.Path == ":memory:" {
+		// Every connection would open its own empty in-memory database.
+		cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime, cfg.ConnMaxIdleTime = 1, 1, 0, 0
+	}
+	client.SetMaxOpenConns(cfg.MaxOpenConns)
+	client.SetMaxIdleConns(cfg.MaxIdleConns)
+	client.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime))
+	client.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTime))
 
-	client, err := sqlx.Open("mysql", dsn)
-	if err != nil {
-		logger.Error("Error connecting to database", logger.Any("error", err))
-		panic(err)
+	if err := ping(client, cfg); err != nil {
+		client.Close()
+		return nil, err
 	}
+	logger.Info("Connected to database",
+		logger.String("driver", cfg.Driver),
+		logger.String("host", cfg.Host),
+		logger.String("port", cfg.Port),
+		logger.String("database", cfg.Name))
+	return client, nil
+}
 
-	client.SetConnMaxLifetime(time.Minute * 3)
-	client.SetMaxOpenConns(10)
-	client.SetMaxIdleConns(10)
+func ping(client *sqlx.DB, cfg Config) error {
+	backoff := time.Duration(cfg.ConnectBackoff)
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ConnectTimeout))
+		err := client.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempt >= cfg.ConnectRetries {
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt+1, err)
+		}
 
-	return client
+		logger.Warn("Database not reachable, retrying",
+			logger.Int("attempt", attempt+1),
+			logger.String("retry_in", backoff.String()),
+			logger.Any("error", err))
+		time.Sleep(backoff)
+		backoff *= 2
+		if maxBackoff := time.Duration(cfg.ConnectMaxBackoff); maxBackoff > 0 && backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
 }
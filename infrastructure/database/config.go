@@ -0,0 +1,260 @@
+package database
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+)
+
+const customTLSConfigName = "custom"
+
+// Config describes the database connection and its pool. LoadConfig fills it
+// from defaults, then DB_CONFIG_FILE, then the environment.
+type Config struct {
+	// Driver is mysql, postgres or sqlite. SQLite only reads Path, a file
+	// name or ":memory:".
+	Driver   string `json:"driver"`
+	Path     string `json:"path"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	// TLS is "", "true", "skip-verify" or "preferred". With TLSCAFile set,
+	// the server certificate is checked against that CA instead.
+	TLS       string            `json:"tls"`
+	TLSCAFile string            `json:"tls_ca_file"`
+	Params    map[string]string `json:"params"`
+
+	MaxOpenConns    int      `json:"max_open_conns"`
+	MaxIdleConns    int      `json:"max_idle_conns"`
+	ConnMaxLifetime Duration `json:"conn_max_lifetime"`
+	ConnMaxIdleTime Duration `json:"conn_max_idle_time"`
+
+	ConnectTimeout    Duration `json:"connect_timeout"`
+	ConnectRetries    int      `json:"connect_retries"`
+	ConnectBackoff    Duration `json:"connect_backoff"`
+	ConnectMaxBackoff Duration `json:"connect_max_backoff"`
+}
+
+// Duration reads "3m"-style strings from the config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string such as \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Driver:            DriverMySQL,
+		Host:              "localhost",
+		MaxOpenConns:      10,
+		MaxIdleConns:      10,
+		ConnMaxLifetime:   Duration(3 * time.Minute),
+		ConnectTimeout:    Duration(5 * time.Second),
+		ConnectRetries:    5,
+		ConnectBackoff:    Duration(500 * time.Millisecond),
+		ConnectMaxBackoff: Duration(10 * time.Second),
+	}
+}
+
+// LoadConfig reads DB_CONFIG_FILE when set, then lets the environment
+// override any field.
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+	if path := config.String("DB_CONFIG_FILE", ""); path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading DB_CONFIG_FILE: %w", err)
+		}
+		if err := json.Unmarshal(content, &cfg); err != nil {
+			return Config{}, fmt.Errorf("parsing DB_CONFIG_FILE %s: %w", path, err)
+		}
+	}
+
+	// DB_* names apply to every driver; the older MYSQL_* names still work.
+	cfg.Driver = config.String("DB_DRIVER", cfg.Driver)
+	cfg.Path = config.String("DB_PATH", cfg.Path)
+	cfg.Host = config.String("DB_HOST", config.String("MYSQL_HOST", cfg.Host))
+	cfg.Port = config.String("DB_PORT", config.String("MYSQL_PORT", cfg.Port))
+	cfg.User = config.String("DB_USER", config.String("MYSQL_USER", cfg.User))
+	cfg.Password = config.String("DB_PASSWORD", config.String("MYSQL_PASSWORD", cfg.Password))
+	cfg.Name = config.String("DB_NAME", config.String("MYSQL_DATABASE", cfg.Name))
+	cfg.TLS = config.String("DB_TLS", config.String("MYSQL_TLS", cfg.TLS))
+	cfg.TLSCAFile = config.String("DB_TLS_CA_FILE", config.String("MYSQL_TLS_CA_FILE", cfg.TLSCAFile))
+	if raw := config.String("DB_PARAMS", config.String("MYSQL_PARAMS", "")); raw != "" {
+		values, err := url.ParseQuery(raw)
+		if err != nil {
+			return Config{}, fmt.Errorf("parsing DB_PARAMS: %w", err)
+		}
+		if cfg.Params == nil {
+			cfg.Params = make(map[string]string)
+		}
+		for key := range values {
+			cfg.Params[key] = values.Get(key)
+		}
+	}
+
+	cfg.MaxOpenConns = config.Int("DB_MAX_OPEN_CONNS", cfg.MaxOpenConns)
+	cfg.MaxIdleConns = config.Int("DB_MAX_IDLE_CONNS", cfg.MaxIdleConns)
+	cfg.ConnMaxLifetime = Duration(config.Duration("DB_CONN_MAX_LIFETIME", time.Duration(cfg.ConnMaxLifetime)))
+	cfg.ConnMaxIdleTime = Duration(config.Duration("DB_CONN_MAX_IDLE_TIME", time.Duration(cfg.ConnMaxIdleTime)))
+	cfg.ConnectTimeout = Duration(config.Duration("DB_CONNECT_TIMEOUT", time.Duration(cfg.ConnectTimeout)))
+	cfg.ConnectRetries = config.Int("DB_CONNECT_RETRIES", cfg.ConnectRetries)
+	cfg.ConnectBackoff = Duration(config.Duration("DB_CONNECT_BACKOFF", time.Duration(cfg.ConnectBackoff)))
+	cfg.ConnectMaxBackoff = Duration(config.Duration("DB_CONNECT_MAX_BACKOFF", time.Duration(cfg.ConnectMaxBackoff)))
+
+	return cfg, cfg.Validate()
+}
+
+func (c Config) Validate() error {
+	var problems []error
+	switch c.Driver {
+	case DriverSQLite:
+		if c.Path == "" {
+			problems = append(problems, errors.New("path is required for sqlite (DB_PATH)"))
+		}
+	case DriverMySQL, DriverPostgres:
+		if c.User == "" || c.Password == "" || c.Name == "" {
+			problems = append(problems, errors.New("user, password and database name are required (DB_USER, DB_PASSWORD, DB_NAME)"))
+		}
+		if c.Host == "" {
+			problems = append(problems, errors.New("host is required"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("driver must be mysql, postgres or sqlite, got %q", c.Driver))
+	}
+	switch c.TLS {
+	case "", "false", "true", "skip-verify", "preferred":
+	default:
+		problems = append(problems, fmt.Errorf("tls must be true, false, skip-verify or preferred, got %q", c.TLS))
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 || c.ConnectRetries < 0 {
+		problems = append(problems, errors.New("pool sizes and connect retries must not be negative"))
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		problems = append(problems, errors.New("max idle connections cannot exceed max open connections"))
+	}
+	if c.ConnectTimeout <= 0 {
+		problems = append(problems, errors.New("connect timeout must be positive"))
+	}
+	return errors.Join(problems...)
+}
+
+// DSN builds the connection string of the configured driver.
+func (c Config) DSN() (string, error) {
+	switch c.Driver {
+	case DriverPostgres:
+		return c.postgresDSN(), nil
+	case DriverSQLite:
+		return c.sqliteDSN(), nil
+	default:
+		return c.mysqlDSN()
+	}
+}
+
+// mysqlDSN registers the custom TLS config when a CA file is set.
+func (c Config) mysqlDSN() (string, error) {
+	dsn := mysql.NewConfig()
+	dsn.User = c.User
+	dsn.Passwd = c.Password
+	dsn.Net = "tcp"
+	dsn.Addr = net.JoinHostPort(c.Host, c.portOr("3306"))
+	dsn.DBName = c.Name
+	dsn.ParseTime = true
+	dsn.Timeout = time.Duration(c.ConnectTimeout)
+	dsn.Params = c.Params
+
+	switch {
+	case c.TLSCAFile != "":
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return "", fmt.Errorf("reading TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("no certificate found in %s", c.TLSCAFile)
+		}
+		tlsConfig := &tls.Config{RootCAs: pool, ServerName: c.Host, MinVersion: tls.VersionTLS12}
+		if err := mysql.RegisterTLSConfig(customTLSConfigName, tlsConfig); err != nil {
+			return "", fmt.Errorf("registering TLS config: %w", err)
+		}
+		dsn.TLSConfig = customTLSConfigName
+	case c.TLS != "":
+		dsn.TLSConfig = c.TLS
+	}
+	return dsn.FormatDSN(), nil
+}
+
+func (c Config) postgresDSN() string {
+	query := url.Values{}
+	for key, value := range c.Params {
+		query.Set(key, value)
+	}
+	switch {
+	case c.TLSCAFile != "":
+		query.Set("sslmode", "verify-full")
+		query.Set("sslrootcert", c.TLSCAFile)
+	case c.TLS == "true":
+		query.Set("sslmode", "verify-full")
+	case c.TLS == "skip-verify":
+		query.Set("sslmode", "require")
+	case c.TLS == "preferred":
+		query.Set("sslmode", "prefer")
+	default:
+		query.Set("sslmode", "disable")
+	}
+	if timeout := time.Duration(c.ConnectTimeout); timeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(max(1, int(timeout.Seconds()))))
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, c.portOr("5432")),
+		Path:     "/" + c.Name,
+		RawQuery: query.Encode(),
+	}
+	return dsn.String()
+}
+
+// sqliteDSN turns on foreign keys, waits for locks instead of failing, takes
+// the write lock when a transaction starts so that reads followed by writes
+// cannot deadlock, and writes times in a format SQLite's date functions read.
+func (c Config) sqliteDSN() string {
+	query := url.Values{}
+	for key, value := range c.Params {
+		query.Set(key, value)
+	}
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", time.Duration(c.ConnectTimeout).Milliseconds()))
+	query.Set("_txlock", "immediate")
+	query.Set("_time_format", "sqlite")
+	return "file:" + c.Path + "?" + query.Encode()
+}
+
+func (c Config) portOr(fallback string) string {
+	if c.Port == "" {
+		return fallback
+	}
+	return c.Port
+}
@@ -0,0 +1,159 @@
+package database
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func mysqlConfig() Config {
+	cfg := DefaultConfig()
+	cfg.User = "banking"
+	cfg.Password = "p@ss:word/1"
+	cfg.Name = "banking"
+	return cfg
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	if err := mysqlConfig().Validate(); err != nil {
+		t.Fatalf("expected a complete config to validate, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		change func(*Config)
+		want   []string
+	}{
+		{"unknown driver", func(c *Config) { c.Driver = "oracle" }, []string{"driver must be mysql, postgres or sqlite"}},
+		{"sqlite without path", func(c *Config) { c.Driver = DriverSQLite }, []string{"path is required"}},
+		{"missing credentials", func(c *Config) { c.Password = ""; c.Host = "" }, []string{"user, password and database name", "host is required"}},
+		{"unknown tls", func(c *Config) { c.TLS = "always" }, []string{"tls must be"}},
+		{"negative pool", func(c *Config) { c.MaxOpenConns = -1 }, []string{"must not be negative"}},
+		{"idle over open", func(c *Config) { c.MaxIdleConns = c.MaxOpenConns + 1 }, []string{"max idle connections"}},
+		{"no connect timeout", func(c *Config) { c.ConnectTimeout = 0 }, []string{"connect timeout must be positive"}},
+	} {
+		cfg := mysqlConfig()
+		tt.change(&cfg)
+		err := cfg.Validate()
+		if err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("%s: expected %q in %q", tt.name, want, err)
+			}
+		}
+	}
+}
+
+func TestMySQLDSN(t *testing.T) {
+	cfg := mysqlConfig()
+	cfg.Port = "3307"
+	cfg.TLS = "skip-verify"
+	cfg.Params = map[string]string{"charset": "utf8mb4"}
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		t.Fatalf("DSN: %v", err)
+	}
+	parsed, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", dsn, err)
+	}
+	if parsed.User != "banking" || parsed.Passwd != "p@ss:word/1" || parsed.Addr != "localhost:3307" || parsed.DBName != "banking" {
+		t.Errorf("unexpected connection in %s", dsn)
+	}
+	if !parsed.ParseTime || parsed.TLSConfig != "skip-verify" || parsed.Timeout != 5*time.Second || !strings.Contains(dsn, "charset=utf8mb4") {
+		t.Errorf("unexpected options in %s", dsn)
+	}
+}
+
+func TestMySQLDSNReportsABadCAFile(t *testing.T) {
+	cfg := mysqlConfig()
+	cfg.TLSCAFile = filepath.Join(t.TempDir(), "missing.pem")
+	if _, err := cfg.DSN(); err == nil || !strings.Contains(err.Error(), "reading TLS CA file") {
+		t.Errorf("expected a missing CA file to fail, got %v", err)
+	}
+
+	cfg.TLSCAFile = filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(cfg.TLSCAFile, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("writing CA file: %v", err)
+	}
+	if _, err := cfg.DSN(); err == nil || !strings.Contains(err.Error(), "no certificate found") {
+		t.Errorf("expected a CA file without certificates to fail, got %v", err)
+	}
+}
+
+func TestPostgresDSN(t *testing.T) {
+	for _, tt := range []struct {
+		tls, caFile string
+		sslmode     string
+	}{
+		{"", "", "disable"},
+		{"true", "", "verify-full"},
+		{"skip-verify", "", "require"},
+		{"preferred", "", "prefer"},
+		{"", "/etc/ca.pem", "verify-full"},
+	} {
+		cfg := mysqlConfig()
+		cfg.Driver = DriverPostgres
+		cfg.TLS = tt.tls
+		cfg.TLSCAFile = tt.caFile
+		cfg.ConnectTimeout = Duration(500 * time.Millisecond)
+
+		dsn, err := cfg.DSN()
+		if err != nil {
+			t.Fatalf("DSN: %v", err)
+		}
+		parsed, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("parsing %s: %v", dsn, err)
+		}
+		password, _ := parsed.User.Password()
+		if parsed.Host != "localhost:5432" || parsed.Path != "/banking" || password != "p@ss:word/1" {
+			t.Errorf("unexpected connection in %s", dsn)
+		}
+		query := parsed.Query()
+		if query.Get("sslmode") != tt.sslmode {
+			t.Errorf("tls %q: expected sslmode %s, got %s", tt.tls, tt.sslmode, query.Get("sslmode"))
+		}
+		if query.Get("sslrootcert") != tt.caFile {
+			t.Errorf("tls %q: expected sslrootcert %q, got %q", tt.tls, tt.caFile, query.Get("sslrootcert"))
+		}
+		if query.Get("connect_timeout") != "1" {
+			t.Errorf("expected the timeout rounded up to a second, got %s", query.Get("connect_timeout"))
+		}
+	}
+}
+
+func TestSQLiteDSN(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Driver = DriverSQLite
+	cfg.Path = "banking.db"
+
+	dsn, err := cfg.DSN()
+	if err != nil {
+		t.Fatalf("DSN: %v", err)
+	}
+	path, rawQuery, _ := strings.Cut(dsn, "?")
+	if path != "file:banking.db" {
+		t.Errorf("unexpected path in %s", dsn)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", dsn, err)
+	}
+	pragmas := strings.Join(query["_pragma"], ",")
+	if !strings.Contains(pragmas, "foreign_keys(1)") || !strings.Contains(pragmas, "busy_timeout(5000)") {
+		t.Errorf("unexpected pragmas %s", pragmas)
+	}
+	if query.Get("_txlock") != "immediate" {
+		t.Errorf("expected immediate transactions, got %q", query.Get("_txlock"))
+	}
+}
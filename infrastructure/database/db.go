@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Conn is a DB or a Tx. Repositories query through a Conn, so the same
+// repository runs on its own or inside a unit of work. Beginning a
+// transaction on a Tx opens a savepoint in it.
+type Conn interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Insert(query, idColumn string, args ...interface{}) (int64, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	InsertContext(ctx context.Context, query, idColumn string, args ...interface{}) (int64, error)
+	Beginx() (*Tx, error)
+	BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error)
+	Dialect() Dialect
+}
+
+var (
+	_ Conn = (*DB)(nil)
+	_ Conn = (*Tx)(nil)
+)
+
+// DB is the handle the repositories query through. It rebinds "?"
+// placeholders and converts arguments for the dialect of the driver.
+type DB struct {
+	*sqlx.DB
+	dialect Dialect
+}
+
+// Wrap returns the dialect-aware handle of client, or nil for a nil client.
+func Wrap(client *sqlx.DB) *DB {
+	if client == nil {
+		return nil
+	}
+	return &DB{DB: client, dialect: DialectFor(client.DriverName())}
+}
+
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+func (db *DB) Get(dest interface{}, query string, args ...interface{}) error {
+	return db.DB.Get(dest, db.rebind(query), db.args(args)...)
+}
+
+func (db *DB) Select(dest interface{}, query string, args ...interface{}) error {
+	return db.DB.Select(dest, db.rebind(query), db.args(args)...)
+}
+
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.rebind(query), db.args(args)...)
+}
+
+// Insert runs an INSERT and returns the value generated for idColumn.
+func (db *DB) Insert(query, idColumn string, args ...interface{}) (int64, error) {
+	return insert(db.dialect, db.DB, query, idColumn, args)
+}
+
+func (db *DB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.DB.GetContext(ctx, dest, db.rebind(query), db.args(args)...)
+}
+
+func (db *DB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return db.DB.SelectContext(ctx, dest, db.rebind(query), db.args(args)...)
+}
+
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.ExecContext(ctx, db.rebind(query), db.args(args)...)
+}
+
+func (db *DB) InsertContext(ctx context.Context, query, idColumn string, args ...interface{}) (int64, error) {
+	return insertContext(ctx, db.dialect, db.DB, query, idColumn, args)
+}
+
+func (db *DB) Beginx() (*Tx, error) {
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.dialect, state: &txState{}}, nil
+}
+
+// BeginTxx starts a transaction that is rolled back if ctx is done before
+// it commits.
+func (db *DB) BeginTxx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := db.DB.BeginTxx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{Tx: tx, dialect: db.dialect, state: &txState{}}, nil
+}
+
+func (db *DB) rebind(query string) string {
+	return sqlx.Rebind(db.dialect.BindType(), query)
+}
+
+func (db *DB) args(args []interface{}) []interface{} {
+	return convertArgs(db.dialect, args)
+}
+
+// Tx is a transaction started from DB, with the same rebinding. A Tx begun
+// on another Tx is a savepoint of it: Commit releases the savepoint and
+// Rollback rolls back to it, leaving the enclosing transaction open.
+type Tx struct {
+	*sqlx.Tx
+	dialect   Dialect
+	state     *txState
+	savepoint string
+}
+
+// txState is shared by a transaction and its savepoints.
+type txState struct {
+	savepoints int
+	conflict   bool
+}
+
+func (tx *Tx) Get(dest interface{}, query string, args ...interface{}) error {
+	return tx.observe(tx.Tx.Get(dest, sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...))
+}
+
+func (tx *Tx) Select(dest interface{}, query string, args ...interface{}) error {
+	return tx.observe(tx.Tx.Select(dest, sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...))
+}
+
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	result, err := tx.Tx.Exec(sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...)
+	return result, tx.observe(err)
+}
+
+func (tx *Tx) Insert(query, idColumn string, args ...interface{}) (int64, error) {
+	id, err := insert(tx.dialect, tx.Tx, query, idColumn, args)
+	return id, tx.observe(err)
+}
+
+func (tx *Tx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.observe(tx.Tx.GetContext(ctx, dest, sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...))
+}
+
+func (tx *Tx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	return tx.observe(tx.Tx.SelectContext(ctx, dest, sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...))
+}
+
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := tx.Tx.ExecContext(ctx, sqlx.Rebind(tx.dialect.BindType(), query), convertArgs(tx.dialect, args)...)
+	return result, tx.observe(err)
+}
+
+func (tx *Tx) InsertContext(ctx context.Context, query, idColumn string, args ...interface{}) (int64, error) {
+	id, err := insertContext(ctx, tx.dialect, tx.Tx, query, idColumn, args)
+	return id, tx.observe(err)
+}
+
+func (tx *Tx) Beginx() (*Tx, error) {
+	return tx.BeginTxx(context.Background(), nil)
+}
+
+// BeginTxx opens a savepoint; opts cannot change a transaction already
+// running and are ignored.
+func (tx *Tx) BeginTxx(ctx context.Context, _ *sql.TxOptions) (*Tx, error) {
+	tx.state.savepoints++
+	name := "sp_" + strconv.Itoa(tx.state.savepoints)
+	if _, err := tx.Tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, tx.observe(err)
+	}
+	return &Tx{Tx: tx.Tx, dialect: tx.dialect, state: tx.state, savepoint: name}, nil
+}
+
+func (tx *Tx) Commit() error {
+	if tx.savepoint == "" {
+		return tx.observe(tx.Tx.Commit())
+	}
+	_, err := tx.Tx.Exec("RELEASE SAVEPOINT " + tx.savepoint)
+	return tx.observe(err)
+}
+
+func (tx *Tx) Rollback() error {
+	if tx.savepoint == "" {
+		return tx.Tx.Rollback()
+	}
+	_, err := tx.Tx.Exec("ROLLBACK TO SAVEPOINT " + tx.savepoint)
+	return err
+}
+
+// Conflicted reports whether a statement of the transaction, or of one of
+// its savepoints, failed on a deadlock or serialization conflict. The
+// database has then aborted the whole transaction, which is worth retrying.
+func (tx *Tx) Conflicted() bool {
+	return tx.state.conflict
+}
+
+func (tx *Tx) Dialect() Dialect {
+	return tx.dialect
+}
+
+func (tx *Tx) observe(err error) error {
+	if err != nil && IsRetryable(err) {
+		tx.state.conflict = true
+	}
+	return err
+}
+
+type queryer interface {
+	sqlx.Queryer
+	sqlx.Execer
+}
+
+func insert(dialect Dialect, q queryer, query, idColumn string, args []interface{}) (int64, error) {
+	args = convertArgs(dialect, args)
+	if dialect.Returning() {
+		var id int64
+		err := sqlx.Get(q, &id, sqlx.Rebind(dialect.BindType(), query+" RETURNING "+idColumn), args...)
+		return id, err
+	}
+	result, err := q.Exec(sqlx.Rebind(dialect.BindType(), query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+type queryerContext interface {
+	sqlx.QueryerContext
+	sqlx.ExecerContext
+}
+
+func insertContext(ctx context.Context, dialect Dialect, q queryerContext, query, idColumn string, args []interface{}) (int64, error) {
+	args = convertArgs(dialect, args)
+	if dialect.Returning() {
+		var id int64
+		err := sqlx.GetContext(ctx, q, &id, sqlx.Rebind(dialect.BindType(), query+" RETURNING "+idColumn), args...)
+		return id, err
+	}
+	result, err := q.ExecContext(ctx, sqlx.Rebind(dialect.BindType(), query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func convertArgs(dialect Dialect, args []interface{}) []interface{} {
+	for i, arg := range args {
+		args[i] = dialect.Arg(arg)
+	}
+	return args
+}
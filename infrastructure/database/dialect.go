@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+func init() {
+	sqlx.BindDriver(DriverSQLite, sqlx.QUESTION)
+}
+
+// Dialect hides the SQL differences between the supported databases.
+// Repositories write MySQL-style "?" placeholders; DB rebinds them.
+type Dialect interface {
+	Name() string
+	BindType() int
+	// Returning reports whether inserts return generated ids with RETURNING
+	// instead of LastInsertId.
+	Returning() bool
+	// ForUpdate is the row-locking suffix of a SELECT, empty when the
+	// database locks the whole file instead.
+	ForUpdate() string
+	// Upsert starts the clause that updates the row an INSERT collides with
+	// on the given columns; Excluded names the value the INSERT proposed.
+	Upsert(conflictColumns ...string) string
+	Excluded(column string) string
+	// Arg converts a query argument to what the driver stores faithfully.
+	Arg(arg interface{}) interface{}
+
+	// ExecScript runs a migration; LockMigrations serializes migrators.
+	// TransactionalDDL reports whether schema changes roll back with their
+	// transaction, so a migration can run in one with its record.
+	ExecScript(ctx context.Context, conn sqlx.ExecerContext, script string) error
+	LockMigrations(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (unlock func(), err error)
+	TransactionalDDL() bool
+	// TryLock takes the named lock on a connection of its own if no other
+	// session holds it, and reports false otherwise.
+	TryLock(ctx context.Context, client *sqlx.DB, name string) (Lock, bool, error)
+}
+
+// DialectFor returns the dialect of a driver name, MySQL when unknown.
+func DialectFor(driverName string) Dialect {
+	switch driverName {
+	case DriverPostgres:
+		return postgresDialect{}
+	case DriverSQLite:
+		return sqliteDialect{}
+	default:
+		return mysqlDialect{}
+	}
+}
+
+// IsDuplicateKey reports a primary key or unique constraint violation.
+func IsDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var pqErr *pq.Error
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1062
+	case errors.As(err, &pqErr):
+		return pqErr.Code == "23505"
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY || sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
+
+// IsRetryable reports a deadlock or serialization failure: the transaction
+// was aborted by a conflict with another one and can be run again.
+func IsRetryable(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var pqErr *pq.Error
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	case errors.As(err, &pqErr):
+		return pqErr.Code == "40001" || pqErr.Code == "40P01"
+	case errors.As(err, &sqliteErr):
+		code := sqliteErr.Code() & 0xff
+		return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+	}
+	return false
+}
+
+// IsForeignKeyViolation reports a row referencing a missing parent row.
+func IsForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	var pqErr *pq.Error
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.As(err, &mysqlErr):
+		return mysqlErr.Number == 1452
+	case errors.As(err, &pqErr):
+		return pqErr.Code == "23503"
+	case errors.As(err, &sqliteErr):
+		return sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY
+	}
+	return false
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                    { return DriverMySQL }
+func (mysqlDialect) BindType() int                   { return sqlx.QUESTION }
+func (mysqlDialect) Returning() bool                 { return false }
+func (mysqlDialect) ForUpdate() string               { return " FOR UPDATE" }
+func (mysqlDialect) Upsert(...string) string         { return " ON DUPLICATE KEY UPDATE " }
+func (mysqlDialect) Excluded(column string) string   { return "VALUES(" + column + ")" }
+func (mysqlDialect) Arg(arg interface{}) interface{} { return arg }
+func (mysqlDialect) TransactionalDDL() bool          { return false }
+
+// ExecScript runs the statements one by one: the driver only accepts
+// several statements per call with multiStatements, which the services
+// should not run with.
+func (mysqlDialect) ExecScript(ctx context.Context, conn sqlx.ExecerContext, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LockMigrations takes a named lock, which belongs to the session and is
+// released if the process dies.
+func (mysqlDialect) LockMigrations(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (func(), error) {
+	var acquired *int64
+	if err := conn.GetContext(ctx, &acquired, "SELECT GET_LOCK(?, ?)", migrationLockName, int(timeout.Seconds())); err != nil {
+		return nil, err
+	}
+	if acquired == nil || *acquired != 1 {
+		return nil, errMigrationLocked(timeout)
+	}
+	return func() { releaseLock(conn, "SELECT RELEASE_LOCK(?)", migrationLockName) }, nil
+}
+
+func (mysqlDialect) TryLock(ctx context.Context, client *sqlx.DB, name string) (Lock, bool, error) {
+	conn, err := client.Connx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var acquired *int64
+	if err := conn.GetContext(ctx, &acquired, "SELECT GET_LOCK(?, 0)", name); err != nil || acquired == nil || *acquired != 1 {
+		conn.Close()
+		return nil, false, err
+	}
+	return sessionLock{conn: conn, release: "SELECT RELEASE_LOCK(?)", name: name}, true, nil
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                  { return DriverPostgres }
+func (postgresDialect) BindType() int                 { return sqlx.DOLLAR }
+func (postgresDialect) Returning() bool               { return true }
+func (postgresDialect) ForUpdate() string             { return " FOR UPDATE" }
+func (postgresDialect) Excluded(column string) string { return "EXCLUDED." + column }
+func (postgresDialect) TransactionalDDL() bool        { return true }
+
+// Arg stores times in UTC, as the MySQL driver does: timestamp columns
+// drop the offset instead of converting.
+func (postgresDialect) Arg(arg interface{}) interface{} { return utc(arg) }
+
+func (postgresDialect) Upsert(conflictColumns ...string) string {
+	return " ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ") DO UPDATE SET "
+}
+
+// ExecScript sends the whole migration at once; without arguments the
+// driver uses the simple query protocol, which runs several statements,
+// including function bodies quoted with $$.
+func (postgresDialect) ExecScript(ctx context.Context, conn sqlx.ExecerContext, script string) error {
+	_, err := conn.ExecContext(ctx, script)
+	return err
+}
+
+// LockMigrations polls a session-level advisory lock until the timeout.
+func (postgresDialect) LockMigrations(ctx context.Context, conn *sqlx.Conn, timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		var acquired bool
+		if err := conn.GetContext(ctx, &acquired, "SELECT pg_try_advisory_lock(hashtext($1))", migrationLockName); err != nil {
+			return nil, err
+		}
+		if acquired {
+			return func() { releaseLock(conn, "SELECT pg_advisory_unlock(hashtext($1))", migrationLockName) }, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, errMigrationLocked(timeout)
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func (postgresDialect) TryLock(ctx context.Context, client *sqlx.DB, name string) (Lock, bool, error) {
+	conn, err := client.Connx(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var acquired bool
+	if err := conn.GetContext(ctx, &acquired, "SELECT pg_try_advisory_lock(hashtext($1))", name); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+	return sessionLock{conn: conn, release: "SELECT pg_advisory_unlock(hashtext($1))", name: name}, true, nil
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                  { return DriverSQLite }
+func (sqliteDialect) BindType() int                 { return sqlx.QUESTION }
+func (sqliteDialect) Returning() bool               { return true }
+func (sqliteDialect) ForUpdate() string             { return "" }
+func (sqliteDialect) Excluded(column string) string { return "excluded." + column }
+func (sqliteDialect) TransactionalDDL() bool        { return true }
+
+func (sqliteDialect) Upsert(conflictColumns ...string) string {
+	return " ON CONFLICT (" + strings.Join(conflictColumns, ", ") + ") DO UPDATE SET "
+}
+
+// Arg stores times in UTC: SQLite compares them as text, which only orders
+// correctly within a single zone.
+func (sqliteDialect) Arg(arg interface{}) interface{} { return utc(arg) }
+
+func utc(arg interface{}) interface{} {
+	switch t := arg.(type) {
+	case time.Time:
+		return t.UTC()
+	case *time.Time:
+		if t != nil {
+			return t.UTC()
+		}
+	}
+	return arg
+}
+
+// ExecScript sends the whole migration at once; the driver runs every
+// statement, including trigger bodies with BEGIN ... END.
+func (sqliteDialect) ExecScript(ctx context.Context, conn sqlx.ExecerContext, script string) error {
+	_, err := conn.ExecContext(ctx, script)
+	return err
+}
+
+// LockMigrations takes no lock: SQLite has none that outlives a transaction.
+// Each migration runs in its own transaction instead, begun with BEGIN
+// IMMEDIATE (the DSN sets _txlock=immediate), which takes the write lock of
+// the file; the migrator checks within it that the version is still pending,
+// so an instance that waited skips what another one applied meanwhile.
+func (sqliteDialect) LockMigrations(context.Context, *sqlx.Conn, time.Duration) (func(), error) {
+	return func() {}, nil
+}
+
+// TryLock always grants the lock: SQLite has no session locks, and a
+// database file is served by a single instance.
+func (sqliteDialect) TryLock(context.Context, *sqlx.DB, string) (Lock, bool, error) {
+	return noLock{}, true, nil
+}
+
+func errMigrationLocked(timeout time.Duration) error {
+	return fmt.Errorf("another instance is migrating; lock not acquired within %s", timeout)
+}
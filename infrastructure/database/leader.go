@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// Lock is a named lock taken with Dialect.TryLock.
+type Lock interface {
+	// Alive fails once the lock may have been lost with its connection.
+	Alive(ctx context.Context) error
+	Release()
+}
+
+// RunAsLeader runs fn while this instance holds the named lock, so that of
+// the instances sharing a database only one runs it at a time. Instances
+// without the lock try again every interval and one of them takes over when
+// the leader stops. The context of fn is canceled when ctx is, or when the
+// connection holding the lock fails; RunAsLeader returns once ctx is
+// canceled and fn has returned.
+func RunAsLeader(ctx context.Context, client *sqlx.DB, name string, interval time.Duration, fn func(ctx context.Context)) {
+	dialect := DialectFor(client.DriverName())
+	runAsLeader(ctx, func(ctx context.Context) (Lock, bool, error) {
+		return dialect.TryLock(ctx, client, name)
+	}, name, interval, fn)
+}
+
+func runAsLeader(ctx context.Context, tryLock func(ctx context.Context) (Lock, bool, error), name string, interval time.Duration, fn func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := lead(ctx, tryLock, name, interval, fn); err != nil && ctx.Err() == nil {
+			logger.Error("Error leading", logger.String("lock", name), logger.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs fn if the lock is free, checking every interval that it is
+// still held, and releases it once fn returns.
+func lead(ctx context.Context, tryLock func(ctx context.Context) (Lock, bool, error), name string, interval time.Duration, fn func(ctx context.Context)) error {
+	lock, acquired, err := tryLock(ctx)
+	if err != nil || !acquired {
+		return err
+	}
+	defer lock.Release()
+	logger.Info("Leader lock acquired", logger.String("lock", name))
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn(leaderCtx)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-ticker.C:
+			if err := lock.Alive(ctx); err != nil && ctx.Err() == nil {
+				cancel()
+				<-done
+				return fmt.Errorf("leader lock lost: %w", err)
+			}
+		}
+	}
+}
+
+// sessionLock is a lock of a database session, held on its own connection
+// and released with the release query or when the connection closes.
+type sessionLock struct {
+	conn    *sqlx.Conn
+	release string
+	name    string
+}
+
+func (l sessionLock) Alive(ctx context.Context) error {
+	return l.conn.PingContext(ctx)
+}
+
+func (l sessionLock) Release() {
+	releaseLock(l.conn, l.release, l.name)
+	l.conn.Close()
+}
+
+// noLock stands for a lock on a database without session locks.
+type noLock struct{}
+
+func (noLock) Alive(context.Context) error { return nil }
+func (noLock) Release()                    {}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeLock is held until lost is set; released counts its releases.
+type fakeLock struct {
+	lost     atomic.Bool
+	released atomic.Int32
+}
+
+func (l *fakeLock) Alive(context.Context) error {
+	if l.lost.Load() {
+		return errors.New("connection reset")
+	}
+	return nil
+}
+
+func (l *fakeLock) Release() { l.released.Add(1) }
+
+func TestRunAsLeaderWaitsForTheLockAndStopsWhenItIsLost(t *testing.T) {
+	lock := &fakeLock{}
+	var attempts atomic.Int32
+	tryLock := func(context.Context) (Lock, bool, error) {
+		// Another instance leads during the first attempt.
+		return lock, attempts.Add(1) > 1, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	leading := make(chan struct{}, 2)
+	stopped := make(chan struct{}, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		runAsLeader(ctx, tryLock, "test", 10*time.Millisecond, func(ctx context.Context) {
+			runs.Add(1)
+			leading <- struct{}{}
+			<-ctx.Done()
+			stopped <- struct{}{}
+		})
+	}()
+
+	waitFor(t, leading, "the lock to be taken once free")
+	if attempts.Load() < 2 {
+		t.Errorf("expected to lead only after the lock was free, after %d attempts", attempts.Load())
+	}
+
+	lock.lost.Store(true)
+	waitFor(t, stopped, "the leader to stop with the lock lost")
+	lock.lost.Store(false)
+	waitFor(t, leading, "the lock to be taken again")
+
+	cancel()
+	waitFor(t, stopped, "the leader to stop with the context")
+	wg.Wait()
+	if runs.Load() != 2 || lock.released.Load() != 2 {
+		t.Errorf("expected 2 runs and 2 releases, got %d and %d", runs.Load(), lock.released.Load())
+	}
+}
+
+func TestRunAsLeaderOnSQLite(t *testing.T) {
+	db := openSQLite(t, ":memory:")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan struct{}, 1)
+	go RunAsLeader(ctx, db, "test", time.Minute, func(ctx context.Context) {
+		ran <- struct{}{}
+		<-ctx.Done()
+	})
+	waitFor(t, ran, "SQLite to grant the lock")
+}
+
+func waitFor(t *testing.T, ch <-chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
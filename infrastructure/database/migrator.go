@@ -0,0 +1,256 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/db"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const migrationLockName = "schema_migrations"
+
+// MigrationStatus is a known migration and when it was applied, or nil when
+// it is pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator applies migrations and records them in schema_migrations. Every
+// run holds a lock, so instances migrating on startup wait for each other
+// instead of applying the same version twice.
+type Migrator struct {
+	client      *sqlx.DB
+	dialect     Dialect
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+func NewMigrator(client *sqlx.DB, migrations []Migration, lockTimeout time.Duration) *Migrator {
+	return &Migrator{
+		client:      client,
+		dialect:     DialectFor(client.DriverName()),
+		migrations:  migrations,
+		lockTimeout: lockTimeout,
+	}
+}
+
+// NewEmbeddedMigrator returns a migrator for the migrations built into the
+// binary for the dialect of client. MIGRATE_LOCK_TIMEOUT bounds the wait for
+// another instance.
+func NewEmbeddedMigrator(client *sqlx.DB) (*Migrator, error) {
+	dir := path.Join("migrations", DialectFor(client.DriverName()).Name())
+	migrations, err := LoadMigrations(db.Migrations, dir)
+	if err != nil {
+		return nil, err
+	}
+	return NewMigrator(client, migrations, config.Duration("MIGRATE_LOCK_TIMEOUT", time.Minute)), nil
+}
+
+// Up applies up to limit pending migrations in version order, or all of them
+// when limit is 0, and returns the ones applied.
+func (m *Migrator) Up(limit int) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if limit > 0 && len(applied) == limit {
+				break
+			}
+			ran, err := m.step(conn, migration, migration.Up, false, func(ex sqlx.ExecerContext) error {
+				if _, err := ex.ExecContext(context.Background(),
+					m.rebind("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)"),
+					migration.Version, migration.Name, time.Now().UTC()); err != nil {
+					return fmt.Errorf("recording migration %d: %w", migration.Version, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if !ran {
+				continue
+			}
+			logger.Info("Migration applied", logger.Any("version", migration.Version), logger.String("name", migration.Name))
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the steps most recently applied migrations and returns them.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+			ran, err := m.step(conn, migration, migration.Down, true, func(ex sqlx.ExecerContext) error {
+				if _, err := ex.ExecContext(context.Background(),
+					m.rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+					return fmt.Errorf("unrecording migration %d: %w", migration.Version, err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if !ran {
+				continue
+			}
+			logger.Info("Migration reverted", logger.Any("version", migration.Version), logger.String("name", migration.Name))
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Status lists every known migration with the time it was applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var statuses []MigrationStatus
+	err := m.withLock(func(conn *sqlx.Conn) error {
+		done, err := m.appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			status := MigrationStatus{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+			delete(done, migration.Version)
+		}
+		for version := range done {
+			logger.Warn("Applied migration is unknown to this binary", logger.Any("version", version))
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// step runs a script of migration and records it with record. Where DDL is
+// transactional (PostgreSQL, SQLite) both happen in one transaction, which
+// first checks that the migration is still applied or pending as expected,
+// and step reports false when another instance has changed it meanwhile. A
+// failing migration then leaves nothing behind. MySQL commits DDL
+// implicitly, so there a failing migration can be left partially applied and
+// has to be repaired by hand.
+func (m *Migrator) step(conn *sqlx.Conn, migration Migration, script string, applied bool, record func(ex sqlx.ExecerContext) error) (bool, error) {
+	ctx := context.Background()
+	if !m.dialect.TransactionalDDL() {
+		if err := m.dialect.ExecScript(ctx, conn, script); err != nil {
+			return false, fmt.Errorf("migration %d_%s failed and may be partially applied: %w", migration.Version, migration.Name, err)
+		}
+		return true, record(conn)
+	}
+
+	tx, err := m.begin(ctx, conn)
+	if err != nil {
+		return false, fmt.Errorf("starting migration %d: %w", migration.Version, err)
+	}
+	defer tx.Rollback()
+
+	var recorded int
+	if err := tx.GetContext(ctx, &recorded, m.rebind("SELECT COUNT(*) FROM schema_migrations WHERE version = ?"), migration.Version); err != nil {
+		return false, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	if (recorded > 0) != applied {
+		logger.Info("Migration changed by another instance", logger.Any("version", migration.Version))
+		return false, nil
+	}
+	if err := m.dialect.ExecScript(ctx, tx, script); err != nil {
+		return false, fmt.Errorf("migration %d_%s failed and was rolled back: %w", migration.Version, migration.Name, err)
+	}
+	if err := record(tx); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("committing migration %d: %w", migration.Version, err)
+	}
+	return true, nil
+}
+
+// begin starts a migration transaction. On SQLite it takes the write lock of
+// the file, which another migrating instance may hold for longer than the
+// busy timeout, so it retries until the lock timeout.
+func (m *Migrator) begin(ctx context.Context, conn *sqlx.Conn) (*sqlx.Tx, error) {
+	deadline := time.Now().Add(m.lockTimeout)
+	for {
+		tx, err := conn.BeginTxx(ctx, nil)
+		if err == nil || !IsRetryable(err) || time.Now().After(deadline) {
+			return tx, err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+func (m *Migrator) appliedVersions(conn *sqlx.Conn) (map[int64]time.Time, error) {
+	var rows []struct {
+		Version   int64     `db:"version"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	if err := conn.SelectContext(context.Background(), &rows, "SELECT version, applied_at FROM schema_migrations"); err != nil {
+		return nil, fmt.Errorf("reading schema_migrations: %w", err)
+	}
+	done := make(map[int64]time.Time, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row.AppliedAt
+	}
+	return done, nil
+}
+
+// withLock runs fn on a single connection holding the migration lock.
+func (m *Migrator) withLock(fn func(conn *sqlx.Conn) error) error {
+	ctx := context.Background()
+	conn, err := m.client.Connx(ctx)
+	if err != nil {
+		return fmt.Errorf("opening migration connection: %w", err)
+	}
+	defer conn.Close()
+
+	unlock, err := m.dialect.LockMigrations(ctx, conn, m.lockTimeout)
+	if err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer unlock()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  applied_at timestamp NOT NULL,
+  PRIMARY KEY (version)
+)`); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+func (m *Migrator) rebind(query string) string {
+	return sqlx.Rebind(m.dialect.BindType(), query)
+}
+
+func releaseLock(conn *sqlx.Conn, query, name string) {
+	if _, err := conn.ExecContext(context.Background(), query, name); err != nil {
+		logger.Error("Error releasing lock", logger.String("lock", name), logger.Any("error", err))
+	}
+}
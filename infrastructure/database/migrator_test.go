@@ -0,0 +1,117 @@
+package database
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/db"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "accounts", Up: "CREATE TABLE accounts (id integer PRIMARY KEY);", Down: "DROP TABLE accounts;"},
+	{Version: 2, Name: "limits", Up: "CREATE TABLE limits (id integer PRIMARY KEY);\nINSERT INTO limits VALUES (1);", Down: "DROP TABLE limits;"},
+}
+
+func openSQLite(t *testing.T, path string) *sqlx.DB {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Driver = DriverSQLite
+	cfg.Path = path
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func appliedVersions(t *testing.T, db *sqlx.DB) []int64 {
+	t.Helper()
+	var versions []int64
+	if err := db.Select(&versions, "SELECT version FROM schema_migrations ORDER BY version"); err != nil {
+		t.Fatalf("reading schema_migrations: %v", err)
+	}
+	return versions
+}
+
+func TestMigratorRollsBackAFailingMigration(t *testing.T) {
+	db := openSQLite(t, filepath.Join(t.TempDir(), "banking.db"))
+	broken := append([]Migration{}, testMigrations...)
+	broken[1].Up = "CREATE TABLE limits (id integer PRIMARY KEY);\nINSERT INTO missing VALUES (1);"
+
+	if _, err := NewMigrator(db, broken, time.Second).Up(0); err == nil {
+		t.Fatal("expected the failing migration to fail the run")
+	}
+	if versions := appliedVersions(t, db); len(versions) != 1 || versions[0] != 1 {
+		t.Errorf("expected only version 1 recorded, got %v", versions)
+	}
+	var tables int
+	if err := db.Get(&tables, "SELECT COUNT(*) FROM sqlite_master WHERE name = 'limits'"); err != nil {
+		t.Fatalf("reading sqlite_master: %v", err)
+	}
+	if tables != 0 {
+		t.Error("expected the table of the failing migration rolled back")
+	}
+
+	applied, err := NewMigrator(db, testMigrations, time.Second).Up(0)
+	if err != nil || len(applied) != 1 || applied[0].Version != 2 {
+		t.Fatalf("expected the fixed migration to apply, got %v (%v)", applied, err)
+	}
+	reverted, err := NewMigrator(db, testMigrations, time.Second).Down(2)
+	if err != nil || len(reverted) != 2 {
+		t.Fatalf("expected both migrations reverted, got %v (%v)", reverted, err)
+	}
+	if versions := appliedVersions(t, db); len(versions) != 0 {
+		t.Errorf("expected no recorded version, got %v", versions)
+	}
+}
+
+func TestSQLiteMigratorsApplyEachVersionOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "banking.db")
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		db := openSQLite(t, path)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = NewMigrator(db, testMigrations, 10*time.Second).Up(0)
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("migrator %d: %v", i, err)
+		}
+	}
+	if versions := appliedVersions(t, openSQLite(t, path)); len(versions) != 2 {
+		t.Errorf("expected both versions recorded once, got %v", versions)
+	}
+}
+
+// TestEmbeddedMigrationsBumpThePermissionsVersion checks that every migration
+// changing the permissions after the initial schema also bumps
+// permissions_version, both ways, so that running instances reload their
+// cached role permissions.
+func TestEmbeddedMigrationsBumpThePermissionsVersion(t *testing.T) {
+	for _, dialect := range []string{DriverMySQL, DriverPostgres, DriverSQLite} {
+		migrations, err := LoadMigrations(db.Migrations, path.Join("migrations", dialect))
+		if err != nil {
+			t.Fatalf("loading %s migrations: %v", dialect, err)
+		}
+		for _, migration := range migrations[1:] {
+			for direction, script := range map[string]string{"up": migration.Up, "down": migration.Down} {
+				changesPermissions := strings.Contains(strings.ReplaceAll(script, "permissions_version", ""), "permissions")
+				if changesPermissions && !strings.Contains(script, "permissions_version") {
+					t.Errorf("%s %04d_%s.%s.sql changes the permissions without bumping permissions_version", dialect, migration.Version, migration.Name, direction)
+				}
+			}
+		}
+	}
+}
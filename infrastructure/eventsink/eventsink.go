@@ -0,0 +1,82 @@
+package eventsink
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	ModeLog   = "log"
+	ModeFile  = "file"
+	ModeHTTP  = "http"
+	ModeNATS  = "nats"
+	ModeKafka = "kafka"
+)
+
+// Multi publishes every event to each of its sinks. If one of them fails the
+// event is retried on all, so the others receive it again.
+type Multi []ports.EventSink
+
+func (m Multi) Publish(ctx context.Context, e domain.Event) error {
+	for _, sink := range m {
+		if err := sink.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewFromEnv returns the sinks listed in OUTBOX_SINKS, separated by commas,
+// each configured from its own variables. An unknown or misconfigured sink
+// is an error, as events published nowhere would be lost for good.
+func NewFromEnv() (ports.EventSink, error) {
+	timeout := config.Duration("OUTBOX_SINK_TIMEOUT", 10*time.Second)
+	var sinks Multi
+	for _, mode := range strings.Split(strings.ToLower(config.String("OUTBOX_SINKS", ModeLog)), ",") {
+		var sink ports.EventSink
+		switch mode = strings.TrimSpace(mode); mode {
+		case "":
+			continue
+		case ModeLog:
+			sink = LogSink{}
+		case ModeFile:
+			sink = NewFileSink(config.String("OUTBOX_FILE", "events.log"))
+		case ModeHTTP:
+			endpoint := config.String("OUTBOX_HTTP_URL", "")
+			if endpoint == "" {
+				return nil, fmt.Errorf("OUTBOX_HTTP_URL is required by the http sink")
+			}
+			sink = NewHTTPSink(endpoint, timeout)
+		case ModeNATS:
+			nats, err := NewNATSSink(config.String("OUTBOX_NATS_URL", "nats://localhost:4222"), config.String("OUTBOX_NATS_SUBJECT", "banking.events"), timeout)
+			if err != nil {
+				return nil, err
+			}
+			sink = nats
+		case ModeKafka:
+			proxyURL := config.String("OUTBOX_KAFKA_REST_URL", "")
+			if proxyURL == "" {
+				return nil, fmt.Errorf("OUTBOX_KAFKA_REST_URL is required by the kafka sink")
+			}
+			sink = NewKafkaSink(proxyURL, config.String("OUTBOX_KAFKA_TOPIC", "banking.events"), timeout)
+		default:
+			return nil, fmt.Errorf("unknown event sink %q", mode)
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return nil, fmt.Errorf("OUTBOX_SINKS lists no sink")
+	}
+	logger.Info("Event sinks configured", logger.String("sinks", config.String("OUTBOX_SINKS", ModeLog)))
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return sinks, nil
+}
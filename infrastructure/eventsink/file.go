@@ -0,0 +1,48 @@
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// FileSink appends each event as a JSON line to a file, or to standard
+// output when the path is "-".
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSink(path string) *FileSink {
+	return &FileSink{path: path}
+}
+
+func (f *FileSink) Publish(_ context.Context, e domain.Event) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var out io.Writer = os.Stdout
+	if f.path != "-" {
+		file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("opening %s: %w", f.path, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if _, err := out.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing %s: %w", f.path, err)
+	}
+	return nil
+}
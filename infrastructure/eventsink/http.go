@@ -0,0 +1,52 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// HTTPSink posts each event as JSON to a URL. Any 2xx response accepts the
+// event; the event id is sent in Idempotency-Key so the receiver can drop
+// redeliveries.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, timeout time.Duration) *HTTPSink {
+	return &HTTPSink{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *HTTPSink) Publish(ctx context.Context, e domain.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", strconv.FormatInt(e.ID, 10))
+	req.Header.Set("X-Event-Type", e.Type)
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting to %s: %w", h.url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting to %s: status %d", h.url, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// KafkaSink produces each event to a topic through a Kafka REST proxy (the
+// v2 API of the Confluent REST Proxy), keyed by its aggregate so that the
+// events of one aggregate land on the same partition, in order.
+type KafkaSink struct {
+	endpoint string
+	client   *http.Client
+}
+
+func NewKafkaSink(proxyURL, topic string, timeout time.Duration) *KafkaSink {
+	return &KafkaSink{
+		endpoint: strings.TrimRight(proxyURL, "/") + "/topics/" + topic,
+		client:   &http.Client{Timeout: timeout},
+	}
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+type kafkaRecord struct {
+	Key   string       `json:"key"`
+	Value domain.Event `json:"value"`
+}
+
+// kafkaOffsets is the proxy's answer; a record the broker rejected has an
+// error code even though the response status is 200.
+type kafkaOffsets struct {
+	Offsets []struct {
+		ErrorCode *int   `json:"error_code"`
+		Error     string `json:"error"`
+	} `json:"offsets"`
+}
+
+func (k *KafkaSink) Publish(ctx context.Context, e domain.Event) error {
+	body, err := json.Marshal(kafkaRecords{Records: []kafkaRecord{{Key: e.AggregateKey(), Value: e}}})
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("producing to %s: %w", k.endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("producing to %s: status %d", k.endpoint, resp.StatusCode)
+	}
+
+	var offsets kafkaOffsets
+	if err := json.NewDecoder(resp.Body).Decode(&offsets); err != nil {
+		return fmt.Errorf("decoding response of %s: %w", k.endpoint, err)
+	}
+	for _, offset := range offsets.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("producing to %s: error %d: %s", k.endpoint, *offset.ErrorCode, offset.Error)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package eventsink
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// LogSink writes events to the application log.
+type LogSink struct{}
+
+func (LogSink) Publish(_ context.Context, e domain.Event) error {
+	logger.Info("Event",
+		logger.Any("event_id", e.ID),
+		logger.String("event_type", e.Type),
+		logger.String("aggregate", e.AggregateKey()),
+		logger.String("payload", string(e.Payload)))
+	return nil
+}
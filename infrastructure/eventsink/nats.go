@@ -0,0 +1,139 @@
+package eventsink
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// NATSSink publishes each event on <subject>.<aggregate type>.<event type>,
+// e.g. banking.events.account.TransactionPosted. It speaks the NATS text
+// protocol itself and follows every PUB with a PING, so an event counts as
+// published once the server has answered the PONG. A failed connection is
+// dropped and dialed again on the next event.
+type NATSSink struct {
+	mu      sync.Mutex
+	address string
+	subject string
+	timeout time.Duration
+	conn    net.Conn
+	reader  *bufio.Reader
+}
+
+// NewNATSSink takes a nats://host:port URL; the port defaults to 4222.
+func NewNATSSink(serverURL, subject string, timeout time.Duration) (*NATSSink, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil || parsed.Scheme != "nats" || parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q, expected nats://host:port", serverURL)
+	}
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), "4222")
+	}
+	return &NATSSink{address: address, subject: subject, timeout: timeout}, nil
+}
+
+func (n *NATSSink) Publish(ctx context.Context, e domain.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	subject := n.subject + "." + e.AggregateType + "." + e.Type
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.publish(ctx, subject, payload); err != nil {
+		n.close()
+		return fmt.Errorf("publishing to NATS at %s: %w", n.address, err)
+	}
+	return nil
+}
+
+func (n *NATSSink) publish(ctx context.Context, subject string, payload []byte) error {
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	deadline := time.Now().Add(n.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if err := n.conn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := n.conn.Write([]byte(message)); err != nil {
+		return err
+	}
+	return n.awaitPong()
+}
+
+// connect reads the INFO the server greets with and sends CONNECT without
+// verbose acknowledgements; the PONG after each PUB confirms it instead.
+func (n *NATSSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: n.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.address)
+	if err != nil {
+		return err
+	}
+	n.conn, n.reader = conn, bufio.NewReader(conn)
+
+	if err := conn.SetDeadline(time.Now().Add(n.timeout)); err != nil {
+		return err
+	}
+	line, err := n.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	_, err = conn.Write([]byte(`CONNECT {"verbose":false,"pedantic":false,"name":"banking-outbox"}` + "\r\n"))
+	return err
+}
+
+// awaitPong answers the server's own PINGs while waiting for the PONG.
+func (n *NATSSink) awaitPong() error {
+	for {
+		line, err := n.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("server error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+func (n *NATSSink) readLine() (string, error) {
+	line, err := n.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (n *NATSSink) close() {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn, n.reader = nil, nil
+	}
+}
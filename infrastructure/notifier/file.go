@@ -0,0 +1,45 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+// FileNotifier appends each notification as a JSON line to a file.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (f *FileNotifier) Notify(n domain.Notification) error {
+	line, err := json.Marshal(struct {
+		SentAt time.Time `json:"sent_at"`
+		domain.Notification
+	}{SentAt: time.Now(), Notification: n})
+	if err != nil {
+		return fmt.Errorf("encoding notification: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening %s: %w", f.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("writing %s: %w", f.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package notifier
+
+import (
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// LogNotifier writes notifications to the application log.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(n domain.Notification) error {
+	logger.Info("Notification",
+		logger.String("username", n.Username),
+		logger.String("subject", n.Subject),
+		logger.String("body", n.Body))
+	return nil
+}
@@ -0,0 +1,29 @@
+package notifier
+
+import (
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	ModeLog  = "log"
+	ModeFile = "file"
+)
+
+// NewFromEnv returns the notifier selected by NOTIFIER. Both built-in notifiers
+// are meant for local use; production deployments plug in their own
+// ports.Notifier.
+func NewFromEnv() ports.Notifier {
+	switch mode := strings.ToLower(config.String("NOTIFIER", ModeLog)); mode {
+	case ModeFile:
+		return NewFileNotifier(config.String("NOTIFIER_FILE", "notifications.log"))
+	case ModeLog:
+		return LogNotifier{}
+	default:
+		logger.Warn("Unknown notifier, falling back to log", logger.String("notifier", mode))
+		return LogNotifier{}
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const accountEventColumns = "sequence, account_id, version, event_type, data, occurred_at"
+
+type AccountEventStoreDb struct {
+	client database.Conn
+}
+
+func NewAccountEventStoreDb(dbClient *sqlx.DB) AccountEventStoreDb {
+	return AccountEventStoreDb{client: database.Wrap(dbClient)}
+}
+
+// Append checks the version first to report the common case plainly; the
+// unique key on (account_id, version) catches a writer that appends between
+// the check and the insert.
+func (d AccountEventStoreDb) Append(ctx context.Context, accountID string, expectedVersion int64, events ...domain.AccountEvent) *errs.AppError {
+	var current int64
+	if err := d.client.GetContext(ctx, &current, "SELECT COALESCE(MAX(version), 0) FROM account_events WHERE account_id = ?", accountID); err != nil {
+		return queryError(ctx, "Error reading account version", err, logger.String("account_id", accountID))
+	}
+	if current != expectedVersion {
+		return versionConflict(accountID, expectedVersion, current)
+	}
+
+	query := "INSERT INTO account_events (account_id, version, event_type, data, occurred_at) VALUES (?, ?, ?, ?, ?)"
+	for _, e := range events {
+		if _, err := d.client.ExecContext(ctx, query, accountID, e.Version, e.Type, e.Data, e.OccurredAt); err != nil {
+			if database.IsDuplicateKey(err) {
+				return versionConflict(accountID, expectedVersion, e.Version)
+			}
+			return queryError(ctx, "Error appending account event", err, logger.String("account_id", accountID))
+		}
+	}
+	return nil
+}
+
+func (d AccountEventStoreDb) Load(ctx context.Context, accountID string, afterVersion int64) ([]domain.AccountEvent, *errs.AppError) {
+	query := "SELECT " + accountEventColumns + " FROM account_events WHERE account_id = ? AND version > ? ORDER BY version"
+	events := make([]domain.AccountEvent, 0)
+	if err := d.client.SelectContext(ctx, &events, query, accountID, afterVersion); err != nil {
+		return nil, queryError(ctx, "Error loading account events", err, logger.String("account_id", accountID))
+	}
+	return events, nil
+}
+
+func (d AccountEventStoreDb) Since(ctx context.Context, afterSequence int64, limit int) ([]domain.AccountEvent, *errs.AppError) {
+	query := "SELECT " + accountEventColumns + " FROM account_events WHERE sequence > ? ORDER BY sequence LIMIT ?"
+	events := make([]domain.AccountEvent, 0)
+	if err := d.client.SelectContext(ctx, &events, query, afterSequence, limit); err != nil {
+		return nil, queryError(ctx, "Error reading account events", err, logger.Any("after_sequence", afterSequence))
+	}
+	return events, nil
+}
+
+func (d AccountEventStoreDb) SaveSnapshot(ctx context.Context, s domain.AccountSnapshot) *errs.AppError {
+	query := "INSERT INTO account_snapshots (account_id, version, state, taken_on) VALUES (?, ?, ?, ?)"
+	if _, err := d.client.ExecContext(ctx, query, s.AccountID, s.Version, s.State, s.TakenOn); err != nil && !database.IsDuplicateKey(err) {
+		return queryError(ctx, "Error saving account snapshot", err, logger.String("account_id", s.AccountID))
+	}
+	if _, err := d.client.ExecContext(ctx, "DELETE FROM account_snapshots WHERE account_id = ? AND version < ?", s.AccountID, s.Version); err != nil {
+		return queryError(ctx, "Error pruning account snapshots", err, logger.String("account_id", s.AccountID))
+	}
+	return nil
+}
+
+func (d AccountEventStoreDb) LatestSnapshot(ctx context.Context, accountID string) (*domain.AccountSnapshot, *errs.AppError) {
+	query := "SELECT account_id, version, state, taken_on FROM account_snapshots WHERE account_id = ? ORDER BY version DESC LIMIT 1"
+	var snapshot domain.AccountSnapshot
+	if err := d.client.GetContext(ctx, &snapshot, query, accountID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, "Error loading account snapshot", err, logger.String("account_id", accountID))
+	}
+	return &snapshot, nil
+}
+
+func versionConflict(accountID string, expected, found int64) *errs.AppError {
+	logger.Warn("Account changed concurrently",
+		logger.String("account_id", accountID),
+		logger.Any("expected_version", expected),
+		logger.Any("found_version", found))
+	return errs.NewConflictError("Account was changed by another request, try again")
+}
+
+var _ ports.AccountEventStore = (*AccountEventStoreDb)(nil)
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// AccountEventStoreMemory keeps the events of every account in one slice in
+// append order, which is the order of their sequence numbers. Like the
+// other ids of the store, sequences are not reused after a rollback.
+type AccountEventStoreMemory struct {
+	store *MemoryStore
+}
+
+func NewAccountEventStoreMemory(store *MemoryStore) AccountEventStoreMemory {
+	return AccountEventStoreMemory{store: store}
+}
+
+func (r AccountEventStoreMemory) Append(ctx context.Context, accountID string, expectedVersion int64, events ...domain.AccountEvent) *errs.AppError {
+	if appErr := contextError(ctx, "Error appending account event"); appErr != nil {
+		return appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var current int64
+	for _, e := range s.accountEvents {
+		if e.AccountID == accountID {
+			current = max(current, e.Version)
+		}
+	}
+	if current != expectedVersion {
+		return versionConflict(accountID, expectedVersion, current)
+	}
+	for _, e := range events {
+		e.Sequence = s.nextSequence
+		s.nextSequence++
+		e.AccountID = accountID
+		s.accountEvents = append(s.accountEvents, e)
+	}
+	return nil
+}
+
+func (r AccountEventStoreMemory) Load(ctx context.Context, accountID string, afterVersion int64) ([]domain.AccountEvent, *errs.AppError) {
+	if appErr := contextError(ctx, "Error loading account events"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]domain.AccountEvent, 0)
+	for _, e := range s.accountEvents {
+		if e.AccountID == accountID && e.Version > afterVersion {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r AccountEventStoreMemory) Since(ctx context.Context, afterSequence int64, limit int) ([]domain.AccountEvent, *errs.AppError) {
+	if appErr := contextError(ctx, "Error reading account events"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := make([]domain.AccountEvent, 0)
+	for _, e := range s.accountEvents {
+		if len(events) == limit {
+			break
+		}
+		if e.Sequence > afterSequence {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r AccountEventStoreMemory) SaveSnapshot(ctx context.Context, snapshot domain.AccountSnapshot) *errs.AppError {
+	if appErr := contextError(ctx, "Error saving account snapshot"); appErr != nil {
+		return appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, exists := s.accountSnapshots[snapshot.AccountID]; !exists || current.Version < snapshot.Version {
+		s.accountSnapshots[snapshot.AccountID] = snapshot
+	}
+	return nil
+}
+
+func (r AccountEventStoreMemory) LatestSnapshot(ctx context.Context, accountID string) (*domain.AccountSnapshot, *errs.AppError) {
+	if appErr := contextError(ctx, "Error loading account snapshot"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot, exists := s.accountSnapshots[accountID]
+	if !exists {
+		return nil, nil
+	}
+	return &snapshot, nil
+}
+
+var _ ports.AccountEventStore = (*AccountEventStoreMemory)(nil)
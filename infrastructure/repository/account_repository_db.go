@@ -1,6 +1,7 @@
 package repository
 
 import (
+    "context"
     "database/sql"
     "strconv"
 
@@ -8,79 +9,63 @@ import (
     "github.com/titi0001/Microservices-API-in-Go/domain"
     "github.com/titi0001/Microservices-API-in-Go/domain/ports"
     "github.com/titi0001/Microservices-API-in-Go/errs"
+    "github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
     "github.com/titi0001/Microservices-API-in-Go/logger"
 )
 
 type AccountRepositoryDb struct {
-    client *sqlx.DB
+    client database.Conn
 }
 
 func NewAccountRepositoryDb(dbClient *sqlx.DB) AccountRepositoryDb {
-    return AccountRepositoryDb{client: dbClient}
+    return AccountRepositoryDb{client: database.Wrap(dbClient)}
 }
 
-func (d AccountRepositoryDb) Save(a domain.Account) (*domain.Account, *errs.AppError) {
+func (d AccountRepositoryDb) Save(ctx context.Context, a domain.Account) (*domain.Account, *errs.AppError) {
     sqlInsert := "INSERT INTO accounts (customer_id, opening_date, account_type, amount, status) VALUES (?, ?, ?, ?, ?)"
-    result, err := d.client.Exec(sqlInsert, a.CustomerID, a.OpeningDate, a.AccountType, a.Amount, a.Status)
+    id, err := d.client.InsertContext(ctx, sqlInsert, "account_id", a.CustomerID, a.OpeningDate, a.AccountType, a.Amount, a.Status)
     if err != nil {
-        logger.Error("Error creating new account", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
-    }
-
-    id, err := result.LastInsertId()
-    if err != nil {
-        logger.Error("Error getting last insert ID", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error creating new account", err)
     }
 
     a.AccountID = strconv.FormatInt(id, 10)
     return &a, nil
 }
 
-func (d AccountRepositoryDb) SaveTransaction(t domain.Transaction) (*domain.Transaction, *errs.AppError) {
-    tx, err := d.client.Begin()
+func (d AccountRepositoryDb) SaveTransaction(ctx context.Context, t domain.Transaction) (*domain.Transaction, *errs.AppError) {
+    tx, err := d.client.BeginTxx(ctx, nil)
     if err != nil {
-        logger.Error("Error starting transaction", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error starting transaction", err)
     }
 
-    result, err := tx.Exec(
+    transactionID, err := tx.InsertContext(ctx,
         "INSERT INTO transactions (account_id, amount, transaction_type, transaction_date) VALUES (?, ?, ?, ?)",
-        t.AccountID, t.Amount, t.TransactionType, t.TransactionDate,
+        "transaction_id", t.AccountID, t.Amount, t.TransactionType, t.TransactionDate,
     )
     if err != nil {
         if rollbackErr := tx.Rollback(); rollbackErr != nil {
             logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
         }
-        logger.Error("Error inserting transaction", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error inserting transaction", err)
     }
 
     updateQuery := "UPDATE accounts SET amount = amount + ? WHERE account_id = ?"
     if t.IsWithdrawal() {
         updateQuery = "UPDATE accounts SET amount = amount - ? WHERE account_id = ?"
     }
-    _, err = tx.Exec(updateQuery, t.Amount, t.AccountID)
+    _, err = tx.ExecContext(ctx, updateQuery, t.Amount, t.AccountID)
     if err != nil {
         if rollbackErr := tx.Rollback(); rollbackErr != nil {
             logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
         }
-        logger.Error("Error updating account balance", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error updating account balance", err)
     }
 
     if err = tx.Commit(); err != nil {
-        logger.Error("Error committing transaction", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error committing transaction", err)
     }
 
-    transactionID, err := result.LastInsertId()
-    if err != nil {
-        logger.Error("Error getting transaction ID", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
-    }
-
-    account, appErr := d.FindBy(t.AccountID)
+    account, appErr := d.FindBy(ctx, t.AccountID)
     if appErr != nil {
         return nil, appErr
     }
@@ -90,20 +75,26 @@ func (d AccountRepositoryDb) SaveTransaction(t domain.Transaction) (*domain.Tran
     return &t, nil
 }
 
-func (d AccountRepositoryDb) FindBy(accountID string) (*domain.Account, *errs.AppError) {
+func (d AccountRepositoryDb) FindBy(ctx context.Context, accountID string) (*domain.Account, *errs.AppError) {
     sqlGetAccount := "SELECT account_id, customer_id, opening_date, account_type, amount, status FROM accounts WHERE account_id = ?"
     var account domain.Account
-    err := d.client.Get(&account, sqlGetAccount, accountID)
+    err := d.client.GetContext(ctx, &account, sqlGetAccount, accountID)
     if err != nil {
         if err == sql.ErrNoRows {
             logger.Warn("Account not found", logger.String("account_id", accountID))
             return nil, errs.NewNotFoundError("Account not found")
         }
-        logger.Error("Error fetching account", logger.Any("error", err))
-        return nil, errs.NewUnexpectedError("Unexpected database error")
+        return nil, queryError(ctx, "Error fetching account", err)
     }
     return &account, nil
 }
 
+func (d AccountRepositoryDb) UpdateStatus(ctx context.Context, accountID, status string) *errs.AppError {
+    if _, err := d.client.ExecContext(ctx, "UPDATE accounts SET status = ? WHERE account_id = ?", status, accountID); err != nil {
+        return queryError(ctx, "Error updating account status", err)
+    }
+    return nil
+}
+
 
 var _ ports.AccountRepository = (*AccountRepositoryDb)(nil)
\ No newline at end of file
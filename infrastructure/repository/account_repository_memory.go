@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type AccountRepositoryMemory struct {
+	store *MemoryStore
+}
+
+func NewAccountRepositoryMemory(store *MemoryStore) AccountRepositoryMemory {
+	return AccountRepositoryMemory{store: store}
+}
+
+func (r AccountRepositoryMemory) Save(ctx context.Context, a domain.Account) (*domain.Account, *errs.AppError) {
+	if appErr := contextError(ctx, "Error creating new account"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The accounts table rejects unknown customers with a foreign key error.
+	if !s.customerExists(a.CustomerID) {
+		logger.Error("Error creating new account", logger.String("customer_id", a.CustomerID), logger.String("error", "unknown customer"))
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	id := s.nextAccountID
+	s.nextAccountID++
+	a.AccountID = strconv.FormatInt(id, 10)
+	s.accounts[id] = a
+	return &a, nil
+}
+
+// SaveTransaction records the transaction and moves the balance under one
+// lock, so concurrent transactions on an account are all applied.
+func (r AccountRepositoryMemory) SaveTransaction(ctx context.Context, t domain.Transaction) (*domain.Transaction, *errs.AppError) {
+	if appErr := contextError(ctx, "Error inserting transaction"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accountID, err := strconv.ParseInt(t.AccountID, 10, 64)
+	account, exists := s.accounts[accountID]
+	if err != nil || !exists {
+		logger.Error("Error inserting transaction", logger.String("account_id", t.AccountID), logger.String("error", "unknown account"))
+		return nil, errs.NewUnexpectedError("Unexpected database error")
+	}
+
+	if t.IsWithdrawal() {
+		account.Amount -= t.Amount
+	} else {
+		account.Amount += t.Amount
+	}
+	s.accounts[accountID] = account
+
+	id := s.nextTransactionID
+	s.nextTransactionID++
+	t.TransactionID = strconv.FormatInt(id, 10)
+	s.transactions[id] = t
+
+	t.Amount = account.Amount
+	return &t, nil
+}
+
+func (r AccountRepositoryMemory) FindBy(ctx context.Context, accountID string) (*domain.Account, *errs.AppError) {
+	if appErr := contextError(ctx, "Error fetching account"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, err := strconv.ParseInt(accountID, 10, 64)
+	account, exists := s.accounts[id]
+	if err != nil || !exists {
+		logger.Warn("Account not found", logger.String("account_id", accountID))
+		return nil, errs.NewNotFoundError("Account not found")
+	}
+	return &account, nil
+}
+
+func (r AccountRepositoryMemory) UpdateStatus(ctx context.Context, accountID, status string) *errs.AppError {
+	if appErr := contextError(ctx, "Error updating account status"); appErr != nil {
+		return appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := strconv.ParseInt(accountID, 10, 64)
+	account, exists := s.accounts[id]
+	if err != nil || !exists {
+		logger.Warn("Account not found", logger.String("account_id", accountID))
+		return errs.NewNotFoundError("Account not found")
+	}
+	account.Status = status
+	s.accounts[id] = account
+	return nil
+}
+
+var _ ports.AccountRepository = (*AccountRepositoryMemory)(nil)
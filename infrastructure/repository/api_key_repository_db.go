@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+const apiKeyColumns = `key_id, prefix, key_hash, name, routes, customer_id, created_by, created_on,
+              expires_at, last_used_on, revoked_on`
+
+type APIKeyRepositoryDb struct {
+	client *database.DB
+}
+
+func NewAPIKeyRepositoryDb(dbClient *sqlx.DB) APIKeyRepositoryDb {
+	return APIKeyRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d APIKeyRepositoryDb) Save(ctx context.Context, k domain.APIKey) (*domain.APIKey, *errs.AppError) {
+	query := `INSERT INTO api_keys (prefix, key_hash, name, routes, customer_id, created_by, created_on, expires_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	id, err := d.client.InsertContext(ctx, query, "key_id", k.Prefix, k.Hash, k.Name, k.Routes, k.CustomerID, k.CreatedBy, k.CreatedOn, k.ExpiresAt)
+	if err != nil {
+		return nil, queryError(ctx, "Error saving API key", err)
+	}
+
+	k.ID = strconv.FormatInt(id, 10)
+	return &k, nil
+}
+
+func (d APIKeyRepositoryDb) FindByPrefix(ctx context.Context, prefix string) (*domain.APIKey, *errs.AppError) {
+	var key domain.APIKey
+	if err := d.client.GetContext(ctx, &key, "SELECT "+apiKeyColumns+" FROM api_keys WHERE prefix = ?", prefix); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("API key not found")
+		}
+		return nil, queryError(ctx, "Error fetching API key", err)
+	}
+	return &key, nil
+}
+
+func (d APIKeyRepositoryDb) FindAll(ctx context.Context) ([]domain.APIKey, *errs.AppError) {
+	keys := make([]domain.APIKey, 0)
+	if err := d.client.SelectContext(ctx, &keys, "SELECT "+apiKeyColumns+" FROM api_keys ORDER BY created_on DESC"); err != nil {
+		return nil, queryError(ctx, "Error querying API keys", err)
+	}
+	return keys, nil
+}
+
+func (d APIKeyRepositoryDb) Revoke(ctx context.Context, keyID string, now time.Time) *errs.AppError {
+	result, err := d.client.ExecContext(ctx, "UPDATE api_keys SET revoked_on = ? WHERE key_id = ? AND revoked_on IS NULL", now, keyID)
+	if err != nil {
+		return queryError(ctx, "Error revoking API key", err)
+	}
+	revoked, appErr := rowsChanged(result)
+	if appErr != nil {
+		return appErr
+	}
+	if !revoked {
+		return errs.NewNotFoundError("Active API key not found")
+	}
+	return nil
+}
+
+func (d APIKeyRepositoryDb) TouchLastUsed(ctx context.Context, keyID string, now time.Time) *errs.AppError {
+	query := `UPDATE api_keys SET last_used_on = ?
+              WHERE key_id = ? AND (last_used_on IS NULL OR last_used_on < ?)`
+	if _, err := d.client.ExecContext(ctx, query, now, keyID, now.Add(-time.Minute)); err != nil {
+		return queryError(ctx, "Error updating API key last use", err)
+	}
+	return nil
+}
+
+var _ ports.APIKeyRepository = (*APIKeyRepositoryDb)(nil)
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const auditColumns = "event_id, occurred_at, actor, action, resource, outcome, source_ip, request_id, details, prev_hash, hash"
+
+type AuditRepositoryDb struct {
+	client *database.DB
+}
+
+func NewAuditRepositoryDb(dbClient *sqlx.DB) AuditRepositoryDb {
+	return AuditRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+// Append serializes writers on the audit_chain_head row, so concurrent events
+// from both servers still form a single chain. SQLite has no row locks; its
+// transactions take the database write lock when they begin instead.
+func (d AuditRepositoryDb) Append(ctx context.Context, e domain.AuditEvent) (*domain.AuditEvent, *errs.AppError) {
+	tx, err := d.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, queryError(ctx, "Error starting transaction", err)
+	}
+
+	err = func() error {
+		if err := tx.GetContext(ctx, &e.PrevHash, "SELECT last_hash FROM audit_chain_head WHERE id = 1"+tx.Dialect().ForUpdate()); err != nil {
+			return err
+		}
+		e.Hash = e.ComputeHash()
+
+		query := `INSERT INTO audit_log
+                    (occurred_at, actor, action, resource, outcome, source_ip, request_id, details, prev_hash, hash)
+                  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		var err error
+		if e.ID, err = tx.InsertContext(ctx, query, "event_id", e.OccurredAt, e.Actor, e.Action, e.Resource, e.Outcome, e.SourceIP, e.RequestID, e.Details, e.PrevHash, e.Hash); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE audit_chain_head SET last_hash = ? WHERE id = 1", e.Hash)
+		return err
+	}()
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		return nil, queryError(ctx, "Error appending audit event", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, "Error committing audit event", err)
+	}
+	return &e, nil
+}
+
+func (d AuditRepositoryDb) Find(ctx context.Context, filter domain.AuditFilter) ([]domain.AuditEvent, *errs.AppError) {
+	conditions := make([]string, 0, 6)
+	args := make([]interface{}, 0, 8)
+	for column, value := range map[string]string{
+		"actor":    filter.Actor,
+		"action":   filter.Action,
+		"resource": filter.Resource,
+		"outcome":  filter.Outcome,
+	} {
+		if value != "" {
+			conditions = append(conditions, column+" = ?")
+			args = append(args, value)
+		}
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "occurred_at >= ?")
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "occurred_at < ?")
+		args = append(args, *filter.To)
+	}
+
+	query := "SELECT " + auditColumns + " FROM audit_log"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY event_id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	events := make([]domain.AuditEvent, 0)
+	if err := d.client.SelectContext(ctx, &events, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying audit log", err)
+	}
+	return events, nil
+}
+
+func (d AuditRepositoryDb) FindAfter(ctx context.Context, afterID int64, limit int) ([]domain.AuditEvent, *errs.AppError) {
+	events := make([]domain.AuditEvent, 0, limit)
+	query := "SELECT " + auditColumns + " FROM audit_log WHERE event_id > ? ORDER BY event_id LIMIT ?"
+	if err := d.client.SelectContext(ctx, &events, query, afterID, limit); err != nil {
+		return nil, queryError(ctx, "Error reading audit log", err)
+	}
+	return events, nil
+}
+
+func (d AuditRepositoryDb) ChainHead(ctx context.Context) (string, *errs.AppError) {
+	var head string
+	if err := d.client.GetContext(ctx, &head, "SELECT last_hash FROM audit_chain_head WHERE id = 1"); err != nil {
+		return "", queryError(ctx, "Error reading audit chain head", err)
+	}
+	return head, nil
+}
+
+var _ ports.AuditRepository = (*AuditRepositoryDb)(nil)
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type AuthRepositoryMemory struct {
+	store *MemoryStore
+}
+
+func NewAuthRepositoryMemory(store *MemoryStore) AuthRepositoryMemory {
+	return AuthRepositoryMemory{store: store}
+}
+
+func (r AuthRepositoryMemory) FindCredentials(ctx context.Context, username string) (*domain.User, *errs.AppError) {
+	if appErr := contextError(ctx, "Error querying user"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		logger.Warn("Invalid credentials", logger.String("username", username))
+		return nil, errs.NewAuthenticationError("Invalid credentials")
+	}
+	return &user, nil
+}
+
+func (r AuthRepositoryMemory) FindUserByUsername(ctx context.Context, username string) (*domain.User, *errs.AppError) {
+	if appErr := contextError(ctx, "Error querying user"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, exists := s.users[username]
+	if !exists {
+		return nil, errs.NewNotFoundError("User not found")
+	}
+	return withoutPassword(user), nil
+}
+
+func (r AuthRepositoryMemory) SaveUser(ctx context.Context, user domain.User) (*domain.User, *errs.AppError) {
+	if appErr := contextError(ctx, "Error saving new user"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.users[user.Username]; exists {
+		logger.Error("User already exists", logger.String("username", user.Username))
+		return nil, errs.NewValidationError("User with username " + user.Username + " already exists")
+	}
+	stored := user
+	if stored.Status == "" {
+		stored.Status = domain.UserStatusActive
+	}
+	s.users[user.Username] = stored
+	return &user, nil
+}
+
+func (r AuthRepositoryMemory) UpdatePassword(ctx context.Context, username, password string) *errs.AppError {
+	if appErr := contextError(ctx, "Error updating password"); appErr != nil {
+		return appErr
+	}
+	r.store.updateUser(username, func(u *domain.User) { u.Password = password })
+	return nil
+}
+
+func (r AuthRepositoryMemory) SaveRefreshToken(ctx context.Context, username, refreshToken string) *errs.AppError {
+	if appErr := contextError(ctx, "Error saving refresh token"); appErr != nil {
+		return appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.refreshTokens[refreshToken]; exists {
+		logger.Error("Error saving refresh token", logger.String("error", "duplicate refresh token"))
+		return errs.NewUnexpectedError("Unexpected database error")
+	}
+	s.refreshTokens[refreshToken] = username
+	return nil
+}
+
+func (r AuthRepositoryMemory) VerifyRefreshToken(ctx context.Context, refreshToken string) (bool, *errs.AppError) {
+	if appErr := contextError(ctx, "Error verifying refresh token"); appErr != nil {
+		return false, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.refreshTokens[refreshToken]
+	return exists, nil
+}
+
+func (r AuthRepositoryMemory) DeleteRefreshToken(ctx context.Context, refreshToken string) (int64, *errs.AppError) {
+	if appErr := contextError(ctx, "Error deleting refresh token"); appErr != nil {
+		return 0, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.refreshTokens[refreshToken]; !exists {
+		return 0, nil
+	}
+	delete(s.refreshTokens, refreshToken)
+	return 1, nil
+}
+
+func (r AuthRepositoryMemory) RevokeRefreshTokens(ctx context.Context, username string) (int64, *errs.AppError) {
+	if appErr := contextError(ctx, "Error revoking refresh tokens"); appErr != nil {
+		return 0, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var revoked int64
+	for token, owner := range s.refreshTokens {
+		if owner == username {
+			delete(s.refreshTokens, token)
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+// withoutPassword returns the columns the SQL repository selects.
+func withoutPassword(user domain.User) *domain.User {
+	user.Password = ""
+	return &user
+}
+
+// updateUser applies fn to a stored user; like an UPDATE, it does nothing
+// when the user does not exist.
+func (s *MemoryStore) updateUser(username string, fn func(u *domain.User)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, exists := s.users[username]; exists {
+		fn(&user)
+		s.users[username] = user
+	}
+}
+
+var _ ports.AuthRepository = (*AuthRepositoryMemory)(nil)
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+type BreakGlassRepositoryDb struct {
+	client *database.DB
+}
+
+func NewBreakGlassRepositoryDb(dbClient *sqlx.DB) BreakGlassRepositoryDb {
+	return BreakGlassRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d BreakGlassRepositoryDb) SaveGrant(ctx context.Context, g domain.BreakGlassGrant) (*domain.BreakGlassGrant, *errs.AppError) {
+	query := `INSERT INTO break_glass_grants (username, reason, source_ip, created_on, expires_at)
+              VALUES (?, ?, ?, ?, ?)`
+	id, err := d.client.InsertContext(ctx, query, "grant_id", g.Username, g.Reason, g.SourceIP, g.CreatedOn, g.ExpiresAt)
+	if err != nil {
+		return nil, queryError(ctx, "Error saving break-glass grant", err)
+	}
+
+	g.ID = strconv.FormatInt(id, 10)
+	return &g, nil
+}
+
+func (d BreakGlassRepositoryDb) FindGrant(ctx context.Context, grantID string) (*domain.BreakGlassGrant, *errs.AppError) {
+	query := `SELECT grant_id, username, reason, source_ip, created_on, expires_at, revoked_on
+              FROM break_glass_grants
+              WHERE grant_id = ?`
+	var grant domain.BreakGlassGrant
+	if err := d.client.GetContext(ctx, &grant, query, grantID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("Break-glass grant not found")
+		}
+		return nil, queryError(ctx, "Error fetching break-glass grant", err)
+	}
+	return &grant, nil
+}
+
+func (d BreakGlassRepositoryDb) SaveEvent(ctx context.Context, e domain.BreakGlassEvent) *errs.AppError {
+	query := `INSERT INTO break_glass_events (grant_id, event, username, route_name, created_on)
+              VALUES (?, ?, ?, ?, ?)`
+	if _, err := d.client.ExecContext(ctx, query, e.GrantID, e.Event, e.Username, e.RouteName, e.CreatedOn); err != nil {
+		return queryError(ctx, "Error saving break-glass event", err)
+	}
+	return nil
+}
+
+var _ ports.BreakGlassRepository = (*BreakGlassRepositoryDb)(nil)
@@ -0,0 +1,638 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/db"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// backend is one implementation of the repositories that can be swapped.
+// Both start from the same seed data: the SQL one from the migrations, the
+// memory one from db/fixtures/seed.json.
+type backend struct {
+	customers     ports.CustomerRepository
+	accounts      ports.AccountRepository
+	accountEvents ports.AccountEventStore
+	auth          ports.AuthRepository
+	users         ports.UserRepository
+	outbox        ports.OutboxRepository
+	uow           ports.UnitOfWork
+}
+
+var backends = map[string]func(t *testing.T) backend{
+	"sql": func(t *testing.T) backend {
+		client := newTestDB(t)
+		return backend{
+			customers:     NewCustomerRepositoryDb(client),
+			accounts:      NewAccountRepositoryDb(client),
+			accountEvents: NewAccountEventStoreDb(client),
+			auth:          NewAuthRepositoryDb(client),
+			users:         NewUserRepositoryDb(client),
+			outbox:        NewOutboxRepositoryDb(client),
+			uow:           NewUnitOfWorkDb(client),
+		}
+	},
+	"memory": func(t *testing.T) backend {
+		fixtures, err := LoadFixtures(db.SeedFixtures)
+		if err != nil {
+			t.Fatalf("loading fixtures: %v", err)
+		}
+		store, err := NewMemoryStore(fixtures)
+		if err != nil {
+			t.Fatalf("seeding memory store: %v", err)
+		}
+		return backend{
+			customers:     NewCustomerRepositoryMemory(store),
+			accounts:      NewAccountRepositoryMemory(store),
+			accountEvents: NewAccountEventStoreMemory(store),
+			auth:          NewAuthRepositoryMemory(store),
+			users:         NewUserRepositoryMemory(store),
+			outbox:        NewOutboxRepositoryMemory(store),
+			uow:           NewUnitOfWorkMemory(store),
+		}
+	},
+}
+
+var conformanceCases = map[string]func(t *testing.T, b backend){
+	"customers":            testCustomers,
+	"accounts":             testAccounts,
+	"concurrent deposits":  testConcurrentDeposits,
+	"users":                testUsers,
+	"refresh tokens":       testRefreshTokens,
+	"user search escaping": testUserSearch,
+	"canceled context":     testCanceledContext,
+	"unit of work":         testUnitOfWork,
+	"outbox":               testOutbox,
+	"account events":       testAccountEvents,
+	"account history":      testAccountHistory,
+	"reports":              testReports,
+}
+
+func TestRepositoryConformance(t *testing.T) {
+	for backendName, open := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			for name, run := range conformanceCases {
+				t.Run(name, func(t *testing.T) { run(t, open(t)) })
+			}
+		})
+	}
+}
+
+func testCustomers(t *testing.T, b backend) {
+	ctx := context.Background()
+	all, appErr := b.customers.FindAll(ctx, "")
+	if appErr != nil {
+		t.Fatalf("FindAll: %v", appErr.Message)
+	}
+	active, appErr := b.customers.FindAll(ctx, "1")
+	if appErr != nil {
+		t.Fatalf("FindAll active: %v", appErr.Message)
+	}
+	if len(all) != 6 || len(active) != 4 {
+		t.Fatalf("customers = %d, active = %d, want 6 and 4", len(all), len(active))
+	}
+
+	customer, appErr := b.customers.ByID(ctx, "2001")
+	if appErr != nil {
+		t.Fatalf("ByID: %v", appErr.Message)
+	}
+	if customer.Name != "Arian" || customer.Status != 1 {
+		t.Fatalf("customer = %+v, want the active customer Arian", customer)
+	}
+	for _, id := range []string{"9999", "abc"} {
+		if _, appErr := b.customers.ByID(ctx, id); appErr == nil || appErr.Code != http.StatusNotFound {
+			t.Fatalf("ByID(%q) = %v, want 404", id, appErr)
+		}
+	}
+}
+
+func testAccounts(t *testing.T, b backend) {
+	ctx := context.Background()
+	account, appErr := b.accounts.Save(ctx, domain.NewAccount("2000", "saving", 6000))
+	if appErr != nil {
+		t.Fatalf("Save: %v", appErr.Message)
+	}
+	if account.AccountID != "95474" {
+		t.Fatalf("account id = %s, want the next id after the seeded accounts", account.AccountID)
+	}
+	if _, appErr := b.accounts.Save(ctx, domain.NewAccount("9999", "saving", 6000)); appErr == nil || appErr.Code != http.StatusInternalServerError {
+		t.Fatalf("Save for an unknown customer = %v, want a database error", appErr)
+	}
+
+	transaction, appErr := b.accounts.SaveTransaction(ctx, domain.Transaction{
+		AccountID:       account.AccountID,
+		Amount:          1500,
+		TransactionType: domain.Withdrawal,
+		TransactionDate: time.Now().Format("2006-01-02 15:04:05"),
+	})
+	if appErr != nil {
+		t.Fatalf("SaveTransaction: %v", appErr.Message)
+	}
+	if transaction.TransactionID != "1" || transaction.Amount != 4500 {
+		t.Fatalf("transaction = %+v, want id 1 and balance 4500", transaction)
+	}
+	if _, appErr := b.accounts.SaveTransaction(ctx, domain.Transaction{AccountID: "1", Amount: 10, TransactionType: domain.Deposit, TransactionDate: "2024-01-01 00:00:00"}); appErr == nil {
+		t.Fatal("SaveTransaction on an unknown account succeeded")
+	}
+
+	found, appErr := b.accounts.FindBy(ctx, account.AccountID)
+	if appErr != nil {
+		t.Fatalf("FindBy: %v", appErr.Message)
+	}
+	if found.CustomerID != "2000" || found.AccountType != "saving" || found.Amount != 4500 {
+		t.Fatalf("account = %+v, want customer 2000's saving account with 4500", found)
+	}
+	if _, appErr := b.accounts.FindBy(ctx, "1"); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Fatalf("FindBy of an unknown account = %v, want 404", appErr)
+	}
+}
+
+func testConcurrentDeposits(t *testing.T, b backend) {
+	ctx := context.Background()
+	const deposits = 20
+	var wg sync.WaitGroup
+	for range deposits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			transaction := domain.Transaction{AccountID: "95472", Amount: 10, TransactionType: domain.Deposit, TransactionDate: "2024-01-01 00:00:00"}
+			if _, appErr := b.accounts.SaveTransaction(ctx, transaction); appErr != nil {
+				t.Errorf("SaveTransaction: %v", appErr.Message)
+			}
+		}()
+	}
+	wg.Wait()
+
+	account, appErr := b.accounts.FindBy(ctx, "95472")
+	if appErr != nil {
+		t.Fatalf("FindBy: %v", appErr.Message)
+	}
+	if account.Amount != 7000+deposits*10 {
+		t.Fatalf("balance = %v, want every deposit applied", account.Amount)
+	}
+}
+
+func testUsers(t *testing.T, b backend) {
+	ctx := context.Background()
+	admin, appErr := b.auth.FindCredentials(ctx, "admin")
+	if appErr != nil {
+		t.Fatalf("FindCredentials of the seeded admin: %v", appErr.Message)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("abc123")); err != nil {
+		t.Fatalf("seeded admin password = %q, want a bcrypt hash of abc123", admin.Password)
+	}
+	if _, appErr := b.auth.FindCredentials(ctx, "nobody"); appErr == nil || appErr.Code != http.StatusUnauthorized {
+		t.Fatalf("FindCredentials of an unknown user = %v, want 401", appErr)
+	}
+
+	customerID := "2002"
+	user := domain.User{Username: "carol", Password: "secret", Role: "user", CustomerID: &customerID, CreatedOn: time.Now()}
+	if _, appErr := b.auth.SaveUser(ctx, user); appErr != nil {
+		t.Fatalf("SaveUser: %v", appErr.Message)
+	}
+	if _, appErr := b.auth.SaveUser(ctx, user); appErr == nil || appErr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("second SaveUser = %v, want a validation error", appErr)
+	}
+
+	found, appErr := b.auth.FindUserByUsername(ctx, "carol")
+	if appErr != nil {
+		t.Fatalf("FindUserByUsername: %v", appErr.Message)
+	}
+	if found.Role != "user" || found.Status != domain.UserStatusActive || found.CustomerID == nil || *found.CustomerID != "2002" || found.Password != "" {
+		t.Fatalf("user = %+v, want an active user of customer 2002 without the password", found)
+	}
+	if _, appErr := b.auth.FindUserByUsername(ctx, "nobody"); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Fatalf("FindUserByUsername of an unknown user = %v, want 404", appErr)
+	}
+
+	if appErr := b.auth.UpdatePassword(ctx, "carol", "changed"); appErr != nil {
+		t.Fatalf("UpdatePassword: %v", appErr.Message)
+	}
+	if appErr := b.users.UpdateStatus(ctx, "carol", domain.UserStatusDisabled); appErr != nil {
+		t.Fatalf("UpdateStatus: %v", appErr.Message)
+	}
+	carol, appErr := b.auth.FindCredentials(ctx, "carol")
+	if appErr != nil {
+		t.Fatalf("FindCredentials: %v", appErr.Message)
+	}
+	if carol.Password != "changed" || carol.Status != domain.UserStatusDisabled {
+		t.Fatalf("credentials = %+v, want the updated password of a disabled user", carol)
+	}
+
+	admins, appErr := b.users.FindAll(ctx, domain.UserFilter{Role: "admin", Limit: 10})
+	if appErr != nil {
+		t.Fatalf("FindAll: %v", appErr.Message)
+	}
+	if len(admins) != 1 || admins[0].Username != "admin" {
+		t.Fatalf("admins = %+v, want only admin", admins)
+	}
+	page, appErr := b.users.FindAll(ctx, domain.UserFilter{Limit: 2, Offset: 1})
+	if appErr != nil {
+		t.Fatalf("FindAll: %v", appErr.Message)
+	}
+	if len(page) != 2 || page[0].Username != "2001" || page[1].Username != "admin" {
+		t.Fatalf("page = %+v, want 2001 and admin", page)
+	}
+}
+
+func testRefreshTokens(t *testing.T, b backend) {
+	ctx := context.Background()
+	for _, token := range []string{"token-1", "token-2"} {
+		if appErr := b.auth.SaveRefreshToken(ctx, "2000", token); appErr != nil {
+			t.Fatalf("SaveRefreshToken: %v", appErr.Message)
+		}
+	}
+	if appErr := b.auth.SaveRefreshToken(ctx, "2000", "token-1"); appErr == nil {
+		t.Fatal("saving the same refresh token twice succeeded")
+	}
+	if ok, appErr := b.auth.VerifyRefreshToken(ctx, "token-1"); appErr != nil || !ok {
+		t.Fatalf("VerifyRefreshToken = %v, %v, want true", ok, appErr)
+	}
+	if deleted, appErr := b.auth.DeleteRefreshToken(ctx, "token-1"); appErr != nil || deleted != 1 {
+		t.Fatalf("DeleteRefreshToken = %d, %v, want 1", deleted, appErr)
+	}
+	if deleted, appErr := b.auth.DeleteRefreshToken(ctx, "token-1"); appErr != nil || deleted != 0 {
+		t.Fatalf("second DeleteRefreshToken = %d, %v, want 0", deleted, appErr)
+	}
+	if revoked, appErr := b.auth.RevokeRefreshTokens(ctx, "2000"); appErr != nil || revoked != 1 {
+		t.Fatalf("RevokeRefreshTokens = %d, %v, want 1", revoked, appErr)
+	}
+	if ok, _ := b.auth.VerifyRefreshToken(ctx, "token-2"); ok {
+		t.Fatal("a revoked refresh token still verifies")
+	}
+}
+
+func testUserSearch(t *testing.T, b backend) {
+	ctx := context.Background()
+	for _, username := range []string{"ab_c", "abxc"} {
+		if _, appErr := b.auth.SaveUser(ctx, domain.User{Username: username, Password: "secret", Role: "user", CreatedOn: time.Now()}); appErr != nil {
+			t.Fatalf("SaveUser: %v", appErr.Message)
+		}
+	}
+
+	users, appErr := b.users.FindAll(ctx, domain.UserFilter{Query: "b_c", Limit: 10})
+	if appErr != nil {
+		t.Fatalf("FindAll: %v", appErr.Message)
+	}
+	if len(users) != 1 || users[0].Username != "ab_c" {
+		t.Fatalf("users = %+v, want only ab_c", users)
+	}
+}
+
+func testCanceledContext(t *testing.T, b backend) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, appErr := b.accounts.FindBy(canceled, "95470"); appErr == nil || appErr.Code != errs.StatusClientClosedRequest {
+		t.Fatalf("FindBy with a canceled context = %v, want 499", appErr)
+	}
+	transaction := domain.Transaction{AccountID: "95470", Amount: 10, TransactionType: domain.Deposit, TransactionDate: "2024-01-01 00:00:00"}
+	if _, appErr := b.accounts.SaveTransaction(canceled, transaction); appErr == nil || appErr.Code != errs.StatusClientClosedRequest {
+		t.Fatalf("SaveTransaction with a canceled context = %v, want 499", appErr)
+	}
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, appErr := b.customers.FindAll(expired, ""); appErr == nil || appErr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("FindAll past the deadline = %v, want 504", appErr)
+	}
+	if _, appErr := b.auth.FindUserByUsername(expired, "admin"); appErr == nil || appErr.Code != http.StatusGatewayTimeout {
+		t.Fatalf("FindUserByUsername past the deadline = %v, want 504", appErr)
+	}
+
+	account, appErr := b.accounts.FindBy(context.Background(), "95470")
+	if appErr != nil {
+		t.Fatalf("FindBy: %v", appErr.Message)
+	}
+	if account.Amount != 6823.23 {
+		t.Fatalf("balance = %v, want the canceled deposit not applied", account.Amount)
+	}
+}
+
+func testUnitOfWork(t *testing.T, b backend) {
+	ctx := context.Background()
+	deposit := func(accountID string, amount float64) domain.Transaction {
+		return domain.Transaction{AccountID: accountID, Amount: amount, TransactionType: domain.Deposit, TransactionDate: "2024-01-01 00:00:00"}
+	}
+	balance := func(accountID string) float64 {
+		t.Helper()
+		account, appErr := b.accounts.FindBy(ctx, accountID)
+		if appErr != nil {
+			t.Fatalf("FindBy: %v", appErr.Message)
+		}
+		return account.Amount
+	}
+	untouched := balance("95471")
+
+	appErr := b.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if _, appErr := repos.Accounts.SaveTransaction(ctx, deposit("95470", 100)); appErr != nil {
+			return appErr
+		}
+		return errs.NewValidationError("abort")
+	})
+	if appErr == nil || appErr.Message != "abort" {
+		t.Fatalf("Do = %v, want the error returned by fn", appErr)
+	}
+	if got := balance("95470"); got != 6823.23 {
+		t.Fatalf("balance = %v, want the deposit rolled back", got)
+	}
+
+	appErr = b.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if _, appErr := repos.Accounts.SaveTransaction(ctx, deposit("95470", 100)); appErr != nil {
+			return appErr
+		}
+		nested := b.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+			if _, appErr := repos.Accounts.SaveTransaction(ctx, deposit("95471", 100)); appErr != nil {
+				return appErr
+			}
+			return errs.NewValidationError("abort")
+		})
+		if nested == nil {
+			t.Error("nested Do succeeded, want the error returned by fn")
+		}
+		return nil
+	})
+	if appErr != nil {
+		t.Fatalf("Do: %v", appErr.Message)
+	}
+	if got := balance("95470"); got != 6923.23 {
+		t.Fatalf("balance = %v, want the outer deposit committed", got)
+	}
+	if got := balance("95471"); got != untouched {
+		t.Fatalf("balance = %v, want the nested deposit rolled back", got)
+	}
+}
+
+func testOutbox(t *testing.T, b backend) {
+	ctx := context.Background()
+	event := func(accountID string, amount float64) domain.Event {
+		t.Helper()
+		e, err := domain.NewEvent(domain.AggregateAccount, accountID, domain.EventTransactionPosted, domain.TransactionPostedPayload{AccountID: accountID, Amount: amount})
+		if err != nil {
+			t.Fatalf("NewEvent: %v", err)
+		}
+		return e
+	}
+	pending := func(now time.Time, limit int) []domain.OutboxEntry {
+		t.Helper()
+		entries, appErr := b.outbox.Pending(ctx, now, limit)
+		if appErr != nil {
+			t.Fatalf("Pending: %v", appErr.Message)
+		}
+		return entries
+	}
+	assertPending := func(entries []domain.OutboxEntry, want ...float64) {
+		t.Helper()
+		got := make([]float64, 0, len(entries))
+		for _, entry := range entries {
+			var payload domain.TransactionPostedPayload
+			if err := json.Unmarshal(entry.Payload, &payload); err != nil {
+				t.Fatalf("payload %s: %v", entry.Payload, err)
+			}
+			got = append(got, payload.Amount)
+		}
+		if !slices.Equal(got, want) {
+			t.Fatalf("pending amounts = %v, want %v", got, want)
+		}
+	}
+
+	appErr := b.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		if appErr := repos.Outbox.Append(ctx, event("95470", 1)); appErr != nil {
+			return appErr
+		}
+		return errs.NewValidationError("abort")
+	})
+	if appErr == nil {
+		t.Fatal("Do succeeded, want the error returned by fn")
+	}
+	assertPending(pending(time.Now(), 10))
+
+	appErr = b.uow.Do(ctx, func(ctx context.Context, repos ports.TxRepositories) *errs.AppError {
+		return repos.Outbox.Append(ctx, event("95470", 2), event("95471", 3), event("95470", 4))
+	})
+	if appErr != nil {
+		t.Fatalf("Append: %v", appErr.Message)
+	}
+	now := time.Now()
+	entries := pending(now, 10)
+	assertPending(entries, 2, 3)
+	assertPending(pending(now, 1), 2)
+	if entries[0].Type != domain.EventTransactionPosted || entries[0].AggregateKey() != "account/95470" || entries[0].Attempts != 0 {
+		t.Fatalf("entry = %+v, want the first TransactionPosted of account 95470", entries[0])
+	}
+
+	if appErr := b.outbox.MarkFailed(ctx, entries[0].ID, now.Add(time.Minute), "sink down"); appErr != nil {
+		t.Fatalf("MarkFailed: %v", appErr.Message)
+	}
+	assertPending(pending(now, 10), 3)
+	retried := pending(now.Add(2*time.Minute), 10)
+	assertPending(retried, 2, 3)
+	if retried[0].Attempts != 1 || retried[0].LastError != "sink down" {
+		t.Fatalf("entry = %+v, want one failed attempt", retried[0])
+	}
+
+	if appErr := b.outbox.MarkPublished(ctx, entries[0].ID, now); appErr != nil {
+		t.Fatalf("MarkPublished: %v", appErr.Message)
+	}
+	assertPending(pending(now, 10), 3, 4)
+
+	purged, appErr := b.outbox.PurgePublished(ctx, now.Add(time.Second))
+	if appErr != nil {
+		t.Fatalf("PurgePublished: %v", appErr.Message)
+	}
+	if purged != 1 {
+		t.Fatalf("purged = %d, want the published event", purged)
+	}
+	assertPending(pending(now, 10), 3, 4)
+}
+
+func testAccountEvents(t *testing.T, b backend) {
+	ctx := context.Background()
+	event := func(version int64, eventType string) domain.AccountEvent {
+		return domain.AccountEvent{AccountID: "95470", Version: version, Type: eventType, Data: "{}", OccurredAt: time.Now().UTC()}
+	}
+
+	if appErr := b.accountEvents.Append(ctx, "95470", 0, event(1, domain.AccountEventOpened), event(2, domain.AccountEventDeposited)); appErr != nil {
+		t.Fatalf("Append: %v", appErr.Message)
+	}
+	// A writer that loaded version 1 lost the race to the one above.
+	if appErr := b.accountEvents.Append(ctx, "95470", 1, event(2, domain.AccountEventWithdrawn)); appErr == nil || appErr.Code != http.StatusConflict {
+		t.Fatalf("Append at a stale version = %v, want a conflict", appErr)
+	}
+	if appErr := b.accountEvents.Append(ctx, "95470", 2, event(3, domain.AccountEventFrozen)); appErr != nil {
+		t.Fatalf("Append: %v", appErr.Message)
+	}
+
+	events, appErr := b.accountEvents.Load(ctx, "95470", 1)
+	if appErr != nil {
+		t.Fatalf("Load: %v", appErr.Message)
+	}
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	if !slices.Equal(types, []string{domain.AccountEventDeposited, domain.AccountEventFrozen}) || events[0].AccountID != "95470" || events[0].Sequence >= events[1].Sequence {
+		t.Fatalf("events after version 1 = %+v, want Deposited then Frozen in sequence", events)
+	}
+
+	if snapshot, appErr := b.accountEvents.LatestSnapshot(ctx, "95470"); appErr != nil || snapshot != nil {
+		t.Fatalf("LatestSnapshot = %v, %v, want none", snapshot, appErr)
+	}
+	for _, version := range []int64{2, 3} {
+		if appErr := b.accountEvents.SaveSnapshot(ctx, domain.AccountSnapshot{AccountID: "95470", Version: version, State: "{}", TakenOn: time.Now().UTC()}); appErr != nil {
+			t.Fatalf("SaveSnapshot: %v", appErr.Message)
+		}
+	}
+	if snapshot, appErr := b.accountEvents.LatestSnapshot(ctx, "95470"); appErr != nil || snapshot == nil || snapshot.Version != 3 {
+		t.Fatalf("LatestSnapshot = %+v, %v, want version 3", snapshot, appErr)
+	}
+}
+
+// testAccountHistory runs the account service on the backend: the history
+// of an account, replayed through snapshots, decides each change, and the
+// accounts table follows it.
+func testAccountHistory(t *testing.T, b backend) {
+	t.Setenv("ACCOUNT_SNAPSHOT_EVERY", "2")
+	ctx := context.Background()
+	accounts := service.NewAccountService(b.accounts, b.uow, nil)
+	post := func(transactionType string, amount float64) (*dto.TransactionResponse, *errs.AppError) {
+		return accounts.MakeTransaction(ctx, dto.TransactionRequest{
+			AccountID: "95470", CustomerID: "2000", Amount: amount, TransactionType: transactionType, TransactionDate: "2024-01-01 00:00:00",
+		})
+	}
+
+	// 95470 predates the event store: its first change imports it with its
+	// balance of 6823.23.
+	if _, appErr := post(dto.Deposit, 100); appErr != nil {
+		t.Fatalf("deposit: %v", appErr.Message)
+	}
+	if _, appErr := post(dto.Withdrawal, 10000); appErr == nil {
+		t.Fatal("withdrawal over the balance succeeded")
+	}
+	response, appErr := post(dto.Withdrawal, 6923.23)
+	if appErr != nil {
+		t.Fatalf("withdrawal: %v", appErr.Message)
+	}
+	if response.NewBalance != 0 {
+		t.Fatalf("balance = %v, want 0", response.NewBalance)
+	}
+
+	if _, appErr := accounts.FreezeAccount(ctx, dto.AccountStatusRequest{AccountID: "95470", Reason: "review"}); appErr != nil {
+		t.Fatalf("FreezeAccount: %v", appErr.Message)
+	}
+	if _, appErr := post(dto.Deposit, 100); appErr == nil || appErr.Code == http.StatusConflict {
+		t.Fatalf("deposit on a frozen account = %v, want a validation error", appErr)
+	}
+	closed, appErr := accounts.CloseAccount(ctx, dto.AccountStatusRequest{AccountID: "95470"})
+	if appErr != nil {
+		t.Fatalf("CloseAccount: %v", appErr.Message)
+	}
+	if closed.Status != "closed" || closed.Version != 5 || closed.Balance != 0 {
+		t.Fatalf("closed account = %+v, want closed at version 5 with no balance", closed)
+	}
+
+	events, appErr := accounts.ListAccountEvents(ctx, "95470")
+	if appErr != nil {
+		t.Fatalf("ListAccountEvents: %v", appErr.Message)
+	}
+	types := make([]string, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	want := []string{domain.AccountEventOpened, domain.AccountEventDeposited, domain.AccountEventWithdrawn, domain.AccountEventFrozen, domain.AccountEventClosed}
+	if !slices.Equal(types, want) {
+		t.Fatalf("history = %v, want %v", types, want)
+	}
+	if snapshot, appErr := b.accountEvents.LatestSnapshot(ctx, "95470"); appErr != nil || snapshot == nil || snapshot.Version != 4 {
+		t.Fatalf("LatestSnapshot = %+v, %v, want the one taken at version 4", snapshot, appErr)
+	}
+
+	account, appErr := b.accounts.FindBy(ctx, "95470")
+	if appErr != nil {
+		t.Fatalf("FindBy: %v", appErr.Message)
+	}
+	if account.Amount != 0 || account.Status != domain.AccountStatusClosed {
+		t.Fatalf("accounts row = %+v, want the closed account with no balance", account)
+	}
+}
+
+// testReports projects the history the account service writes on the
+// backend into the report tables, which are SQL on both backends, and
+// checks that a rebuild arrives at the same reports.
+func testReports(t *testing.T, b backend) {
+	ctx := context.Background()
+	accounts := service.NewAccountService(b.accounts, b.uow, nil)
+	reports := NewReportRepositoryDb(newTestDB(t))
+	projector := service.NewReportProjector(reports, b.uow, b.customers)
+
+	if _, appErr := accounts.NewAccount(ctx, dto.NewAccountRequest{CustomerID: "2001", AccountType: "checking", Amount: 5000}); appErr != nil {
+		t.Fatalf("NewAccount: %v", appErr.Message)
+	}
+	for _, req := range []dto.TransactionRequest{
+		{AccountID: "95470", CustomerID: "2000", Amount: 100, TransactionType: dto.Deposit},
+		{AccountID: "95470", CustomerID: "2000", Amount: 23.23, TransactionType: dto.Withdrawal},
+	} {
+		req.TransactionDate = "2024-01-01 00:00:00"
+		if _, appErr := accounts.MakeTransaction(ctx, req); appErr != nil {
+			t.Fatalf("MakeTransaction: %v", appErr.Message)
+		}
+	}
+
+	// Opened and Opened, Deposited, Withdrawn of the imported 95470.
+	if projected, appErr := projector.ProjectBatch(ctx); appErr != nil || projected != 4 {
+		t.Fatalf("ProjectBatch = %d, %v, want 4 events", projected, appErr)
+	}
+	if projected, appErr := projector.ProjectBatch(ctx); appErr != nil || projected != 0 {
+		t.Fatalf("second ProjectBatch = %d, %v, want nothing left", projected, appErr)
+	}
+
+	wantCities := []domain.CityTotals{
+		{City: "Delhi", Customers: 1, Accounts: 1, Balance: 6900, Deposits: 100, Withdrawals: 23.23, Transactions: 2},
+		{City: "Newburgh, NY", Customers: 1, Accounts: 1, Balance: 5000},
+	}
+	checkCities := func(when string) {
+		t.Helper()
+		cities, appErr := reports.CityTotals(ctx)
+		if appErr != nil {
+			t.Fatalf("CityTotals %s: %v", when, appErr.Message)
+		}
+		for i := range cities {
+			cities[i].Balance = domain.RoundCents(cities[i].Balance)
+		}
+		if !slices.Equal(cities, wantCities) {
+			t.Fatalf("cities %s = %+v, want %+v", when, cities, wantCities)
+		}
+	}
+	checkCities("after projecting")
+
+	daily, appErr := reports.DailyBalances(ctx, "95470", "", "")
+	if appErr != nil {
+		t.Fatalf("DailyBalances: %v", appErr.Message)
+	}
+	if len(daily) != 1 || domain.RoundCents(daily[0].ClosingBalance) != 6900 || daily[0].Transactions != 2 {
+		t.Fatalf("daily balances = %+v, want one day closing at 6900", daily)
+	}
+	types, appErr := reports.AccountTypeTotals(ctx, "", "")
+	if appErr != nil {
+		t.Fatalf("AccountTypeTotals: %v", appErr.Message)
+	}
+	if len(types) != 2 || types[0].AccountType != "checking" || types[1].OpenAccounts != 1 || types[1].DepositCount != 1 {
+		t.Fatalf("account types = %+v, want checking and saving with one deposit", types)
+	}
+
+	if projected, appErr := projector.Rebuild(ctx); appErr != nil || projected != 4 {
+		t.Fatalf("Rebuild = %d, %v, want 4 events", projected, appErr)
+	}
+	checkCities("after rebuilding")
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type CustomerRepositoryMemory struct {
+	store *MemoryStore
+}
+
+func NewCustomerRepositoryMemory(store *MemoryStore) CustomerRepositoryMemory {
+	return CustomerRepositoryMemory{store: store}
+}
+
+func (r CustomerRepositoryMemory) FindAll(ctx context.Context, status string) ([]domain.Customer, *errs.AppError) {
+	if appErr := contextError(ctx, "Error querying customers"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	customers := make([]domain.Customer, 0, len(s.customers))
+	for _, c := range s.customers {
+		if status == "" || strconv.Itoa(c.Status) == status {
+			customers = append(customers, c)
+		}
+	}
+	sort.Slice(customers, func(i, j int) bool { return customers[i].ID < customers[j].ID })
+	return customers, nil
+}
+
+func (r CustomerRepositoryMemory) ByID(ctx context.Context, id string) (*domain.Customer, *errs.AppError) {
+	if appErr := contextError(ctx, "Error fetching customer"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	customerID, err := strconv.Atoi(id)
+	c, exists := s.customers[customerID]
+	if err != nil || !exists {
+		logger.Warn("Customer not found", logger.String("customer_id", id))
+		return nil, errs.NewNotFoundError("Customer not found")
+	}
+	return &c, nil
+}
+
+var _ ports.CustomerRepository = (*CustomerRepositoryMemory)(nil)
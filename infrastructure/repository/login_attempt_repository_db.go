@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+type LoginAttemptRepositoryDb struct {
+	client *database.DB
+}
+
+func NewLoginAttemptRepositoryDb(dbClient *sqlx.DB) LoginAttemptRepositoryDb {
+	return LoginAttemptRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d LoginAttemptRepositoryDb) Find(ctx context.Context, scope, subject string) (*domain.LoginAttempt, *errs.AppError) {
+	query := `SELECT scope, subject, failures, first_failure, last_failure, locked_until
+              FROM login_attempts
+              WHERE scope = ? AND subject = ?`
+	attempt := domain.LoginAttempt{Scope: scope, Subject: subject}
+	if err := d.client.GetContext(ctx, &attempt, query, scope, subject); err != nil && err != sql.ErrNoRows {
+		return nil, queryError(ctx, "Error fetching login attempts", err)
+	}
+	return &attempt, nil
+}
+
+// RecordFailure increments the counter in a single statement so concurrent
+// failures are all counted. MySQL applies the assignments from left to right
+// while the other databases read the old row; no assignment reads a column
+// set before it, so both give the same result.
+func (d LoginAttemptRepositoryDb) RecordFailure(ctx context.Context, scope, subject string, now, windowStart time.Time) (*domain.LoginAttempt, *errs.AppError) {
+	dialect := d.client.Dialect()
+	query := `INSERT INTO login_attempts (scope, subject, failures, first_failure, last_failure)
+              VALUES (?, ?, 1, ?, ?)` + dialect.Upsert("scope", "subject") + `
+                failures = CASE WHEN login_attempts.first_failure < ? OR login_attempts.locked_until <= ?
+                  THEN 1 ELSE login_attempts.failures + 1 END,
+                first_failure = CASE WHEN login_attempts.first_failure < ? OR login_attempts.locked_until <= ?
+                  THEN ` + dialect.Excluded("first_failure") + ` ELSE login_attempts.first_failure END,
+                locked_until = CASE WHEN login_attempts.locked_until <= ? THEN NULL ELSE login_attempts.locked_until END,
+                last_failure = ` + dialect.Excluded("last_failure")
+	_, err := d.client.ExecContext(ctx, query, scope, subject, now, now, windowStart, now, windowStart, now, now)
+	if err != nil {
+		return nil, queryError(ctx, "Error recording login failure", err)
+	}
+	return d.Find(ctx, scope, subject)
+}
+
+func (d LoginAttemptRepositoryDb) Lock(ctx context.Context, scope, subject string, until time.Time) *errs.AppError {
+	query := "UPDATE login_attempts SET locked_until = ? WHERE scope = ? AND subject = ?"
+	if _, err := d.client.ExecContext(ctx, query, until, scope, subject); err != nil {
+		return queryError(ctx, "Error locking login", err)
+	}
+	return nil
+}
+
+func (d LoginAttemptRepositoryDb) Reset(ctx context.Context, scope, subject string) (bool, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx, "DELETE FROM login_attempts WHERE scope = ? AND subject = ?", scope, subject)
+	if err != nil {
+		return false, queryError(ctx, "Error resetting login attempts", err)
+	}
+	return rowsChanged(result)
+}
+
+func (d LoginAttemptRepositoryDb) FindLocked(ctx context.Context, now time.Time) ([]domain.LoginAttempt, *errs.AppError) {
+	query := `SELECT scope, subject, failures, first_failure, last_failure, locked_until
+              FROM login_attempts
+              WHERE locked_until > ?
+              ORDER BY locked_until DESC`
+	attempts := make([]domain.LoginAttempt, 0)
+	if err := d.client.SelectContext(ctx, &attempts, query, now); err != nil {
+		return nil, queryError(ctx, "Error querying lockouts", err)
+	}
+	return attempts, nil
+}
+
+var _ ports.LoginAttemptRepository = (*LoginAttemptRepositoryDb)(nil)
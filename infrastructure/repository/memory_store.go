@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// Fixtures are the rows a MemoryStore starts with. db/fixtures/seed.json
+// mirrors the rows of the initial migration.
+type Fixtures struct {
+	Customers []domain.Customer `json:"customers"`
+	Accounts  []domain.Account  `json:"accounts"`
+	Users     []domain.User     `json:"users"`
+}
+
+func LoadFixtures(data []byte) (Fixtures, error) {
+	var fixtures Fixtures
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixtures); err != nil {
+		return Fixtures{}, fmt.Errorf("parsing fixtures: %w", err)
+	}
+	return fixtures, nil
+}
+
+// MemoryStore keeps customers, accounts, account events, users and the
+// outbox in memory for tests and demos. Like a database handle, one store is
+// shared by the repositories built on it; every repository call holds its
+// lock, so each is atomic.
+type MemoryStore struct {
+	mu sync.RWMutex
+	// txMu serializes units of work; see UnitOfWorkMemory.
+	txMu              sync.Mutex
+	customers         map[int]domain.Customer
+	accounts          map[int64]domain.Account
+	transactions      map[int64]domain.Transaction
+	users             map[string]domain.User
+	refreshTokens     map[string]string
+	outbox            []domain.OutboxEntry
+	accountEvents     []domain.AccountEvent
+	accountSnapshots  map[string]domain.AccountSnapshot
+	nextAccountID     int64
+	nextTransactionID int64
+	nextEventID       int64
+	nextSequence      int64
+}
+
+// NewMemoryStore loads the fixtures, rejecting the rows the database would:
+// duplicate keys and accounts of unknown customers. New ids continue after
+// the highest loaded one, as AUTO_INCREMENT does.
+func NewMemoryStore(fixtures Fixtures) (*MemoryStore, error) {
+	s := &MemoryStore{
+		customers:         make(map[int]domain.Customer),
+		accounts:          make(map[int64]domain.Account),
+		transactions:      make(map[int64]domain.Transaction),
+		users:             make(map[string]domain.User),
+		refreshTokens:     make(map[string]string),
+		accountSnapshots:  make(map[string]domain.AccountSnapshot),
+		nextAccountID:     1,
+		nextTransactionID: 1,
+		nextEventID:       1,
+		nextSequence:      1,
+	}
+
+	for _, c := range fixtures.Customers {
+		if _, exists := s.customers[c.ID]; exists {
+			return nil, fmt.Errorf("duplicate customer %d", c.ID)
+		}
+		s.customers[c.ID] = c
+	}
+	for _, a := range fixtures.Accounts {
+		id, err := strconv.ParseInt(a.AccountID, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("account id %q is not a number", a.AccountID)
+		}
+		if _, exists := s.accounts[id]; exists {
+			return nil, fmt.Errorf("duplicate account %d", id)
+		}
+		if !s.customerExists(a.CustomerID) {
+			return nil, fmt.Errorf("account %d belongs to unknown customer %s", id, a.CustomerID)
+		}
+		s.accounts[id] = a
+		s.nextAccountID = max(s.nextAccountID, id+1)
+	}
+	for _, u := range fixtures.Users {
+		if _, exists := s.users[u.Username]; exists {
+			return nil, fmt.Errorf("duplicate user %s", u.Username)
+		}
+		if u.Status == "" {
+			u.Status = domain.UserStatusActive
+		}
+		s.users[u.Username] = u
+	}
+	return s, nil
+}
+
+// customerExists parses the id as the database would compare it with the
+// integer key. The caller holds the lock.
+func (s *MemoryStore) customerExists(customerID string) bool {
+	id, err := strconv.Atoi(customerID)
+	if err != nil {
+		return false
+	}
+	_, exists := s.customers[id]
+	return exists
+}
+
+// contextError reports a request that ended before the store was touched,
+// the way the SQL repositories report a query cut short by its context.
+func contextError(ctx context.Context, message string) *errs.AppError {
+	if err := ctx.Err(); err != nil {
+		return queryError(ctx, message, err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type MFARepositoryDb struct {
+	client *database.DB
+}
+
+func NewMFARepositoryDb(dbClient *sqlx.DB) MFARepositoryDb {
+	return MFARepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d MFARepositoryDb) FindEnrollment(ctx context.Context, username string) (*domain.MFAEnrollment, *errs.AppError) {
+	query := `SELECT username, secret, enabled, last_used_step, created_on, confirmed_on
+              FROM user_mfa
+              WHERE username = ?`
+	var enrollment domain.MFAEnrollment
+	if err := d.client.GetContext(ctx, &enrollment, query, username); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("MFA is not enrolled")
+		}
+		return nil, queryError(ctx, "Error fetching MFA enrollment", err)
+	}
+	return &enrollment, nil
+}
+
+func (d MFARepositoryDb) SaveEnrollment(ctx context.Context, e domain.MFAEnrollment, recoveryCodeHashes []string) *errs.AppError {
+	tx, err := d.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, "Error starting transaction", err)
+	}
+
+	err = func() error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM mfa_recovery_codes WHERE username = ?", e.Username); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM user_mfa WHERE username = ? AND enabled = FALSE", e.Username); err != nil {
+			return err
+		}
+		query := `INSERT INTO user_mfa (username, secret, enabled, last_used_step, created_on)
+                  VALUES (?, ?, FALSE, 0, ?)`
+		if _, err := tx.ExecContext(ctx, query, e.Username, e.Secret, e.CreatedOn); err != nil {
+			return err
+		}
+		for _, hash := range recoveryCodeHashes {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO mfa_recovery_codes (username, code_hash) VALUES (?, ?)", e.Username, hash); err != nil {
+				return err
+			}
+		}
+		return nil
+	}()
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		if database.IsDuplicateKey(err) {
+			return errs.NewConflictError("MFA is already enabled")
+		}
+		return queryError(ctx, "Error saving MFA enrollment", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, "Error committing MFA enrollment", err)
+	}
+	return nil
+}
+
+func (d MFARepositoryDb) EnableEnrollment(ctx context.Context, username string, step int64) *errs.AppError {
+	query := `UPDATE user_mfa SET enabled = TRUE, last_used_step = ?, confirmed_on = ?
+              WHERE username = ? AND enabled = FALSE`
+	if _, err := d.client.ExecContext(ctx, query, step, time.Now(), username); err != nil {
+		return queryError(ctx, "Error enabling MFA", err)
+	}
+	return nil
+}
+
+func (d MFARepositoryDb) UseStep(ctx context.Context, username string, step int64) (bool, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx,
+		"UPDATE user_mfa SET last_used_step = ? WHERE username = ? AND last_used_step < ?",
+		step, username, step,
+	)
+	if err != nil {
+		return false, queryError(ctx, "Error recording MFA code use", err)
+	}
+	return rowsChanged(result)
+}
+
+func (d MFARepositoryDb) UseRecoveryCode(ctx context.Context, username, codeHash string) (bool, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx,
+		"UPDATE mfa_recovery_codes SET used_on = ? WHERE username = ? AND code_hash = ? AND used_on IS NULL",
+		time.Now(), username, codeHash,
+	)
+	if err != nil {
+		return false, queryError(ctx, "Error using recovery code", err)
+	}
+	return rowsChanged(result)
+}
+
+func (d MFARepositoryDb) RoleRequiresMFA(ctx context.Context, role string) (bool, *errs.AppError) {
+	var required bool
+	err := d.client.GetContext(ctx, &required, "SELECT mfa_required FROM roles WHERE name = ?", role)
+	if err != nil && err != sql.ErrNoRows {
+		return false, queryError(ctx, "Error reading MFA requirement of role", err)
+	}
+	return required, nil
+}
+
+func rowsChanged(result sql.Result) (bool, *errs.AppError) {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		logger.Error("Error getting rows affected", logger.Any("error", err))
+		return false, errs.NewUnexpectedError("Unexpected database error")
+	}
+	return rowsAffected > 0, nil
+}
+
+var _ ports.MFARepository = (*MFARepositoryDb)(nil)
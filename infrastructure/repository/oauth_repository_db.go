@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+type OAuthRepositoryDb struct {
+	client *database.DB
+}
+
+func NewOAuthRepositoryDb(dbClient *sqlx.DB) OAuthRepositoryDb {
+	return OAuthRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d OAuthRepositoryDb) FindClient(ctx context.Context, clientID string) (*domain.OAuthClient, *errs.AppError) {
+	query := `SELECT client_id, secret_hash, name, grant_types, redirect_uris, scopes, role, customer_id, created_on
+              FROM oauth_clients
+              WHERE client_id = ?`
+	var client domain.OAuthClient
+	if err := d.client.GetContext(ctx, &client, query, clientID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("OAuth client not found")
+		}
+		return nil, queryError(ctx, "Error fetching OAuth client", err)
+	}
+	return &client, nil
+}
+
+func (d OAuthRepositoryDb) FindScopeRoutes(ctx context.Context) ([]domain.OAuthScopeRoute, *errs.AppError) {
+	routes := make([]domain.OAuthScopeRoute, 0)
+	query := `SELECT s.name AS scope_name, COALESCE(p.permission_name, '') AS permission_name
+              FROM oauth_scopes s
+              LEFT JOIN oauth_scope_permissions p ON p.scope_name = s.name
+              ORDER BY s.name, p.permission_name`
+	if err := d.client.SelectContext(ctx, &routes, query); err != nil {
+		return nil, queryError(ctx, "Error querying OAuth scopes", err)
+	}
+	return routes, nil
+}
+
+func (d OAuthRepositoryDb) SaveAuthorizationCode(ctx context.Context, c domain.AuthorizationCode) *errs.AppError {
+	query := `INSERT INTO oauth_authorization_codes
+                (code_hash, client_id, username, redirect_uri, scope, code_challenge, nonce, created_on, expires_at)
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := d.client.ExecContext(ctx, query, c.CodeHash, c.ClientID, c.Username, c.RedirectURI, c.Scope, c.CodeChallenge, c.Nonce, c.CreatedOn, c.ExpiresAt)
+	if err != nil {
+		return queryError(ctx, "Error saving authorization code", err)
+	}
+	return nil
+}
+
+func (d OAuthRepositoryDb) ConsumeAuthorizationCode(ctx context.Context, codeHash string, now time.Time) (*domain.AuthorizationCode, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx,
+		"UPDATE oauth_authorization_codes SET used_on = ? WHERE code_hash = ? AND used_on IS NULL AND expires_at > ?",
+		now, codeHash, now,
+	)
+	if err != nil {
+		return nil, queryError(ctx, "Error consuming authorization code", err)
+	}
+	consumed, appErr := rowsChanged(result)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if !consumed {
+		return nil, errs.NewNotFoundError("Invalid or expired authorization code")
+	}
+
+	query := `SELECT code_hash, client_id, username, redirect_uri, scope, code_challenge, nonce, created_on, expires_at, used_on
+              FROM oauth_authorization_codes
+              WHERE code_hash = ?`
+	var code domain.AuthorizationCode
+	if err := d.client.GetContext(ctx, &code, query, codeHash); err != nil {
+		return nil, queryError(ctx, "Error reading authorization code", err)
+	}
+	return &code, nil
+}
+
+var _ ports.OAuthRepository = (*OAuthRepositoryDb)(nil)
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const outboxColumns = "event_id, aggregate_type, aggregate_id, event_type, payload AS payload_text, occurred_at, attempts, next_attempt_at, last_error, published_at"
+
+// outboxRow reads the payload as text: drivers return text columns as
+// strings, which do not scan into a json.RawMessage.
+type outboxRow struct {
+	domain.OutboxEntry
+	PayloadText string `db:"payload_text"`
+}
+
+type OutboxRepositoryDb struct {
+	client database.Conn
+}
+
+func NewOutboxRepositoryDb(dbClient *sqlx.DB) OutboxRepositoryDb {
+	return OutboxRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+// Append makes each event due at once. The payload is stored as text, which
+// every driver accepts for a text column.
+func (d OutboxRepositoryDb) Append(ctx context.Context, events ...domain.Event) *errs.AppError {
+	query := `INSERT INTO outbox_events (aggregate_type, aggregate_id, event_type, payload, occurred_at, next_attempt_at)
+              VALUES (?, ?, ?, ?, ?, ?)`
+	for _, e := range events {
+		if _, err := d.client.ExecContext(ctx, query, e.AggregateType, e.AggregateID, e.Type, string(e.Payload), e.OccurredAt, e.OccurredAt); err != nil {
+			return queryError(ctx, "Error appending outbox event", err, logger.String("event_type", e.Type))
+		}
+	}
+	return nil
+}
+
+func (d OutboxRepositoryDb) Pending(ctx context.Context, now time.Time, limit int) ([]domain.OutboxEntry, *errs.AppError) {
+	query := "SELECT " + outboxColumns + ` FROM outbox_events o
+              WHERE published_at IS NULL AND next_attempt_at <= ?
+                AND NOT EXISTS (
+                  SELECT 1 FROM outbox_events earlier
+                  WHERE earlier.aggregate_type = o.aggregate_type AND earlier.aggregate_id = o.aggregate_id
+                    AND earlier.published_at IS NULL AND earlier.event_id < o.event_id)
+              ORDER BY event_id LIMIT ?`
+	rows := make([]outboxRow, 0)
+	if err := d.client.SelectContext(ctx, &rows, query, now, limit); err != nil {
+		return nil, queryError(ctx, "Error querying outbox", err)
+	}
+	entries := make([]domain.OutboxEntry, 0, len(rows))
+	for _, row := range rows {
+		row.Payload = json.RawMessage(row.PayloadText)
+		entries = append(entries, row.OutboxEntry)
+	}
+	return entries, nil
+}
+
+func (d OutboxRepositoryDb) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) *errs.AppError {
+	if _, err := d.client.ExecContext(ctx, "UPDATE outbox_events SET published_at = ? WHERE event_id = ?", publishedAt, id); err != nil {
+		return queryError(ctx, "Error marking outbox event published", err, logger.Any("event_id", id))
+	}
+	return nil
+}
+
+func (d OutboxRepositoryDb) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) *errs.AppError {
+	query := "UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = ?, last_error = ? WHERE event_id = ?"
+	if _, err := d.client.ExecContext(ctx, query, nextAttemptAt, truncate(lastError, 1000), id); err != nil {
+		return queryError(ctx, "Error recording outbox failure", err, logger.Any("event_id", id))
+	}
+	return nil
+}
+
+func (d OutboxRepositoryDb) PurgePublished(ctx context.Context, before time.Time) (int64, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx, "DELETE FROM outbox_events WHERE published_at < ?", before)
+	if err != nil {
+		return 0, queryError(ctx, "Error purging outbox", err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, queryError(ctx, "Error purging outbox", err)
+	}
+	return purged, nil
+}
+
+// truncate cuts s to fit a varchar(n) column without splitting a character.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+var _ ports.OutboxRepository = (*OutboxRepositoryDb)(nil)
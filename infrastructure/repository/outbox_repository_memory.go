@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// OutboxRepositoryMemory keeps the outbox in the store of the other
+// in-memory repositories, so a memory unit of work rolls its events back
+// with the rest of its writes.
+type OutboxRepositoryMemory struct {
+	store *MemoryStore
+}
+
+func NewOutboxRepositoryMemory(store *MemoryStore) OutboxRepositoryMemory {
+	return OutboxRepositoryMemory{store: store}
+}
+
+func (r OutboxRepositoryMemory) Append(ctx context.Context, events ...domain.Event) *errs.AppError {
+	if appErr := contextError(ctx, "Error appending outbox event"); appErr != nil {
+		return appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range events {
+		e.ID = s.nextEventID
+		s.nextEventID++
+		s.outbox = append(s.outbox, domain.OutboxEntry{Event: e, NextAttemptAt: e.OccurredAt})
+	}
+	return nil
+}
+
+// Pending walks the outbox in id order, so the first unpublished entry seen
+// for an aggregate is its oldest.
+func (r OutboxRepositoryMemory) Pending(ctx context.Context, now time.Time, limit int) ([]domain.OutboxEntry, *errs.AppError) {
+	if appErr := contextError(ctx, "Error querying outbox"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := make([]domain.OutboxEntry, 0)
+	seen := make(map[string]bool)
+	for _, entry := range s.outbox {
+		if len(entries) == limit {
+			break
+		}
+		key := entry.AggregateKey()
+		if entry.PublishedAt != nil || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !entry.NextAttemptAt.After(now) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func (r OutboxRepositoryMemory) MarkPublished(ctx context.Context, id int64, publishedAt time.Time) *errs.AppError {
+	if appErr := contextError(ctx, "Error marking outbox event published"); appErr != nil {
+		return appErr
+	}
+	r.store.updateOutboxEntry(id, func(entry *domain.OutboxEntry) { entry.PublishedAt = &publishedAt })
+	return nil
+}
+
+func (r OutboxRepositoryMemory) MarkFailed(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) *errs.AppError {
+	if appErr := contextError(ctx, "Error recording outbox failure"); appErr != nil {
+		return appErr
+	}
+	r.store.updateOutboxEntry(id, func(entry *domain.OutboxEntry) {
+		entry.Attempts++
+		entry.NextAttemptAt = nextAttemptAt
+		entry.LastError = lastError
+	})
+	return nil
+}
+
+func (r OutboxRepositoryMemory) PurgePublished(ctx context.Context, before time.Time) (int64, *errs.AppError) {
+	if appErr := contextError(ctx, "Error purging outbox"); appErr != nil {
+		return 0, appErr
+	}
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := make([]domain.OutboxEntry, 0, len(s.outbox))
+	for _, entry := range s.outbox {
+		if entry.PublishedAt == nil || !entry.PublishedAt.Before(before) {
+			kept = append(kept, entry)
+		}
+	}
+	purged := int64(len(s.outbox) - len(kept))
+	s.outbox = kept
+	return purged, nil
+}
+
+func (s *MemoryStore) updateOutboxEntry(id int64, fn func(entry *domain.OutboxEntry)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.outbox {
+		if s.outbox[i].ID == id {
+			fn(&s.outbox[i])
+			return
+		}
+	}
+}
+
+var _ ports.OutboxRepository = (*OutboxRepositoryMemory)(nil)
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type PasswordResetRepositoryDb struct {
+	client *database.DB
+}
+
+func NewPasswordResetRepositoryDb(dbClient *sqlx.DB) PasswordResetRepositoryDb {
+	return PasswordResetRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d PasswordResetRepositoryDb) SaveResetToken(ctx context.Context, t domain.PasswordResetToken) *errs.AppError {
+	tx, err := d.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, "Error starting transaction", err)
+	}
+
+	err = func() error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM password_reset_tokens WHERE username = ? AND used_on IS NULL", t.Username); err != nil {
+			return err
+		}
+		query := `INSERT INTO password_reset_tokens (token_hash, username, created_on, expires_at)
+                  VALUES (?, ?, ?, ?)`
+		_, err := tx.ExecContext(ctx, query, t.TokenHash, t.Username, t.CreatedOn, t.ExpiresAt)
+		return err
+	}()
+	if err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		return queryError(ctx, "Error saving password reset token", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, "Error committing password reset token", err)
+	}
+	return nil
+}
+
+func (d PasswordResetRepositoryDb) FindResetToken(ctx context.Context, tokenHash string, now time.Time) (string, *errs.AppError) {
+	var username string
+	err := d.client.GetContext(ctx, &username,
+		"SELECT username FROM password_reset_tokens WHERE token_hash = ? AND used_on IS NULL AND expires_at > ?",
+		tokenHash, now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errs.NewAuthenticationError("Invalid or expired reset token")
+		}
+		return "", queryError(ctx, "Error reading password reset token", err)
+	}
+	return username, nil
+}
+
+func (d PasswordResetRepositoryDb) ConsumeResetToken(ctx context.Context, tokenHash string, now time.Time) (string, *errs.AppError) {
+	result, err := d.client.ExecContext(ctx,
+		"UPDATE password_reset_tokens SET used_on = ? WHERE token_hash = ? AND used_on IS NULL AND expires_at > ?",
+		now, tokenHash, now,
+	)
+	if err != nil {
+		return "", queryError(ctx, "Error consuming password reset token", err)
+	}
+	consumed, appErr := rowsChanged(result)
+	if appErr != nil {
+		return "", appErr
+	}
+	if !consumed {
+		return "", errs.NewAuthenticationError("Invalid or expired reset token")
+	}
+
+	var username string
+	if err := d.client.GetContext(ctx, &username, "SELECT username FROM password_reset_tokens WHERE token_hash = ?", tokenHash); err != nil {
+		if err == sql.ErrNoRows {
+			return "", errs.NewAuthenticationError("Invalid or expired reset token")
+		}
+		return "", queryError(ctx, "Error reading password reset token", err)
+	}
+	return username, nil
+}
+
+var _ ports.PasswordResetRepository = (*PasswordResetRepositoryDb)(nil)
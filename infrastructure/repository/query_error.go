@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// queryError logs a failed query and returns the error the caller sees. A
+// query cut short because ctx expired or was canceled is reported as a
+// timeout or a cancellation rather than as an unexpected database error;
+// drivers do not all return the context's error, so ctx is checked as well.
+func queryError(ctx context.Context, message string, err error, fields ...logger.Field) *errs.AppError {
+	fields = append(fields, logger.Any("error", err))
+	switch {
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.Warn(message, fields...)
+		return errs.NewTimeoutError("Database query timed out")
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		logger.Warn(message, fields...)
+		return errs.NewRequestCanceledError("Request canceled")
+	}
+	logger.Error(message, fields...)
+	return errs.NewUnexpectedError("Unexpected database error")
+}
@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// errCheckpointMoved aborts a batch whose checkpoint was moved by another
+// writer since it was read.
+var errCheckpointMoved = errors.New("report checkpoint moved")
+
+// ReportRepositoryDb has no in-memory counterpart: with -storage=memory the
+// report tables live in the in-memory SQLite database.
+type ReportRepositoryDb struct {
+	client *database.DB
+}
+
+func NewReportRepositoryDb(dbClient *sqlx.DB) ReportRepositoryDb {
+	return ReportRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d ReportRepositoryDb) Checkpoint(ctx context.Context) (int64, *errs.AppError) {
+	var sequence int64
+	query := "SELECT last_sequence FROM report_checkpoints WHERE projection = ?"
+	if err := d.client.GetContext(ctx, &sequence, query, domain.ReportProjectionName); err != nil && err != sql.ErrNoRows {
+		return 0, queryError(ctx, "Error reading report checkpoint", err)
+	}
+	return sequence, nil
+}
+
+func (d ReportRepositoryDb) FindAccounts(ctx context.Context, accountIDs []string) (map[string]domain.ReportAccount, *errs.AppError) {
+	found := make(map[string]domain.ReportAccount, len(accountIDs))
+	if len(accountIDs) == 0 {
+		return found, nil
+	}
+	args := make([]interface{}, 0, len(accountIDs))
+	for _, id := range accountIDs {
+		args = append(args, id)
+	}
+	query := `SELECT account_id, customer_id, account_type, city, balance, status
+              FROM report_accounts WHERE account_id IN (?` + strings.Repeat(", ?", len(accountIDs)-1) + ")"
+
+	accounts := make([]domain.ReportAccount, 0, len(accountIDs))
+	if err := d.client.SelectContext(ctx, &accounts, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying report accounts", err)
+	}
+	for _, account := range accounts {
+		found[account.AccountID] = account
+	}
+	return found, nil
+}
+
+// Apply moves the checkpoint first: the update locks its row, so a second
+// projector waits for this batch and then finds the checkpoint moved.
+func (d ReportRepositoryDb) Apply(ctx context.Context, batch domain.ReportBatch, from, to int64) *errs.AppError {
+	return d.transaction(ctx, "Error applying report batch", func(tx *database.Tx) error {
+		if err := moveCheckpoint(ctx, tx, from, to); err != nil {
+			return err
+		}
+		dialect := tx.Dialect()
+
+		for _, a := range batch.Accounts {
+			query := `INSERT INTO report_accounts (account_id, customer_id, account_type, city, balance, status)
+                      VALUES (?, ?, ?, ?, ?, ?)` + dialect.Upsert("account_id") + `
+                        balance = ` + dialect.Excluded("balance") + `,
+                        status = ` + dialect.Excluded("status")
+			if _, err := tx.ExecContext(ctx, query, a.AccountID, a.CustomerID, a.AccountType, a.City, a.Balance, a.Status); err != nil {
+				return err
+			}
+		}
+		for _, b := range batch.Daily {
+			query := `INSERT INTO report_daily_balances (account_id, day, closing_balance, deposits, withdrawals, transactions)
+                      VALUES (?, ?, ?, ?, ?, ?)` + dialect.Upsert("account_id", "day") + `
+                        closing_balance = ` + dialect.Excluded("closing_balance") + `,
+                        deposits = report_daily_balances.deposits + ` + dialect.Excluded("deposits") + `,
+                        withdrawals = report_daily_balances.withdrawals + ` + dialect.Excluded("withdrawals") + `,
+                        transactions = report_daily_balances.transactions + ` + dialect.Excluded("transactions")
+			if _, err := tx.ExecContext(ctx, query, b.AccountID, b.Day, b.ClosingBalance, b.Deposits, b.Withdrawals, b.Transactions); err != nil {
+				return err
+			}
+		}
+		for _, c := range batch.Customers {
+			query := `INSERT INTO report_customer_totals (customer_id, city, accounts, balance, deposits, withdrawals, transactions)
+                      VALUES (?, ?, ?, ?, ?, ?, ?)` + dialect.Upsert("customer_id") + `
+                        accounts = report_customer_totals.accounts + ` + dialect.Excluded("accounts") + `,
+                        balance = report_customer_totals.balance + ` + dialect.Excluded("balance") + `,
+                        deposits = report_customer_totals.deposits + ` + dialect.Excluded("deposits") + `,
+                        withdrawals = report_customer_totals.withdrawals + ` + dialect.Excluded("withdrawals") + `,
+                        transactions = report_customer_totals.transactions + ` + dialect.Excluded("transactions")
+			if _, err := tx.ExecContext(ctx, query, c.CustomerID, c.City, c.Accounts, c.Balance, c.Deposits, c.Withdrawals, c.Transactions); err != nil {
+				return err
+			}
+		}
+		for _, v := range batch.Types {
+			query := `INSERT INTO report_type_volumes
+                        (account_type, day, accounts_opened, deposits, deposit_count, withdrawals, withdrawal_count)
+                      VALUES (?, ?, ?, ?, ?, ?, ?)` + dialect.Upsert("account_type", "day") + `
+                        accounts_opened = report_type_volumes.accounts_opened + ` + dialect.Excluded("accounts_opened") + `,
+                        deposits = report_type_volumes.deposits + ` + dialect.Excluded("deposits") + `,
+                        deposit_count = report_type_volumes.deposit_count + ` + dialect.Excluded("deposit_count") + `,
+                        withdrawals = report_type_volumes.withdrawals + ` + dialect.Excluded("withdrawals") + `,
+                        withdrawal_count = report_type_volumes.withdrawal_count + ` + dialect.Excluded("withdrawal_count")
+			if _, err := tx.ExecContext(ctx, query, v.AccountType, v.Day, v.AccountsOpened, v.Deposits, v.DepositCount, v.Withdrawals, v.WithdrawalCount); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d ReportRepositoryDb) Reset(ctx context.Context) *errs.AppError {
+	return d.transaction(ctx, "Error resetting reports", func(tx *database.Tx) error {
+		query := "UPDATE report_checkpoints SET last_sequence = 0 WHERE projection = ?"
+		if _, err := tx.ExecContext(ctx, query, domain.ReportProjectionName); err != nil {
+			return err
+		}
+		for _, table := range []string{"report_accounts", "report_daily_balances", "report_customer_totals", "report_type_volumes"} {
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (d ReportRepositoryDb) CityTotals(ctx context.Context) ([]domain.CityTotals, *errs.AppError) {
+	query := `SELECT city, COUNT(*) AS customers, SUM(accounts) AS accounts, SUM(balance) AS balance,
+                     SUM(deposits) AS deposits, SUM(withdrawals) AS withdrawals, SUM(transactions) AS transactions
+              FROM report_customer_totals GROUP BY city ORDER BY city`
+	totals := make([]domain.CityTotals, 0)
+	if err := d.client.SelectContext(ctx, &totals, query); err != nil {
+		return nil, queryError(ctx, "Error querying city report", err)
+	}
+	return totals, nil
+}
+
+func (d ReportRepositoryDb) AccountTypeTotals(ctx context.Context, fromDay, toDay string) ([]domain.AccountTypeTotals, *errs.AppError) {
+	where, args := dayRange(nil, nil, fromDay, toDay)
+	query := `SELECT a.account_type, a.open_accounts, a.balance,
+                     COALESCE(v.accounts_opened, 0) AS accounts_opened,
+                     COALESCE(v.deposits, 0) AS deposits, COALESCE(v.deposit_count, 0) AS deposit_count,
+                     COALESCE(v.withdrawals, 0) AS withdrawals, COALESCE(v.withdrawal_count, 0) AS withdrawal_count
+              FROM (SELECT account_type, SUM(CASE WHEN status = 0 THEN 0 ELSE 1 END) AS open_accounts, SUM(balance) AS balance
+                    FROM report_accounts GROUP BY account_type) a
+              LEFT JOIN (SELECT account_type, SUM(accounts_opened) AS accounts_opened,
+                                SUM(deposits) AS deposits, SUM(deposit_count) AS deposit_count,
+                                SUM(withdrawals) AS withdrawals, SUM(withdrawal_count) AS withdrawal_count
+                         FROM report_type_volumes` + where + ` GROUP BY account_type) v
+                ON v.account_type = a.account_type
+              ORDER BY a.account_type`
+	totals := make([]domain.AccountTypeTotals, 0)
+	if err := d.client.SelectContext(ctx, &totals, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying account type report", err)
+	}
+	return totals, nil
+}
+
+func (d ReportRepositoryDb) CustomerTotals(ctx context.Context, customerID string) (*domain.CustomerTotals, *errs.AppError) {
+	query := `SELECT customer_id, city, accounts, balance, deposits, withdrawals, transactions
+              FROM report_customer_totals WHERE customer_id = ?`
+	var totals domain.CustomerTotals
+	if err := d.client.GetContext(ctx, &totals, query, customerID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("No report for this customer")
+		}
+		return nil, queryError(ctx, "Error querying customer report", err, logger.String("customer_id", customerID))
+	}
+	return &totals, nil
+}
+
+func (d ReportRepositoryDb) DailyBalances(ctx context.Context, accountID, fromDay, toDay string) ([]domain.DailyBalance, *errs.AppError) {
+	where, args := dayRange([]string{"account_id = ?"}, []interface{}{accountID}, fromDay, toDay)
+	query := `SELECT account_id, day, closing_balance, deposits, withdrawals, transactions
+              FROM report_daily_balances` + where + ` ORDER BY day`
+	balances := make([]domain.DailyBalance, 0)
+	if err := d.client.SelectContext(ctx, &balances, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying daily balances", err, logger.String("account_id", accountID))
+	}
+	return balances, nil
+}
+
+// moveCheckpoint sets the checkpoint to to if it is still at from.
+func moveCheckpoint(ctx context.Context, tx *database.Tx, from, to int64) error {
+	query := "UPDATE report_checkpoints SET last_sequence = ? WHERE projection = ? AND last_sequence = ?"
+	result, err := tx.ExecContext(ctx, query, to, domain.ReportProjectionName, from)
+	if err != nil {
+		return err
+	}
+	moved, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if moved == 0 {
+		return errCheckpointMoved
+	}
+	return nil
+}
+
+// dayRange adds the conditions of a range of days, whose ends may be empty,
+// to conditions and returns the WHERE clause. Days are ISO dates, which
+// sort as strings.
+func dayRange(conditions []string, args []interface{}, fromDay, toDay string) (string, []interface{}) {
+	if fromDay != "" {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, fromDay)
+	}
+	if toDay != "" {
+		conditions = append(conditions, "day <= ?")
+		args = append(args, toDay)
+	}
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func (d ReportRepositoryDb) transaction(ctx context.Context, message string, fn func(tx *database.Tx) error) *errs.AppError {
+	tx, err := d.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, "Error starting transaction", err)
+	}
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		if errors.Is(err, errCheckpointMoved) {
+			return errs.NewConflictError("Reports were changed by another projector")
+		}
+		return queryError(ctx, message, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, message, err)
+	}
+	return nil
+}
+
+var _ ports.ReportRepository = (*ReportRepositoryDb)(nil)
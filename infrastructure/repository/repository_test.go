@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/service"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+// newTestDB opens an in-memory SQLite database with the embedded migrations
+// applied, so the repositories run their real SQL without a database server.
+func newTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	cfg := database.DefaultConfig()
+	cfg.Driver = database.DriverSQLite
+	cfg.Path = ":memory:"
+	client, err := database.Open(cfg)
+	if err != nil {
+		t.Fatalf("opening sqlite: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+
+	migrator, err := database.NewEmbeddedMigrator(client)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("applying migrations: %v", err)
+	}
+	return client
+}
+
+func TestMigrationsRoundTrip(t *testing.T) {
+	client := newTestDB(t)
+	migrator, err := database.NewEmbeddedMigrator(client)
+	if err != nil {
+		t.Fatalf("loading migrations: %v", err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if _, err := migrator.Down(len(statuses)); err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("up after down: %v", err)
+	}
+}
+
+func TestRoleRepositoryUpsertsAssignments(t *testing.T) {
+	ctx := context.Background()
+	roles := NewRoleRepositoryDb(newTestDB(t))
+	// The migrations that grant permissions bump the version too.
+	before, appErr := roles.PermissionsVersion(ctx)
+	if appErr != nil {
+		t.Fatalf("PermissionsVersion: %v", appErr.Message)
+	}
+
+	if _, appErr := roles.SaveRole(ctx, domain.Role{Name: "admin"}); appErr == nil || appErr.Code != http.StatusConflict {
+		t.Fatalf("SaveRole of an existing role = %v, want a conflict", appErr)
+	}
+	if _, appErr := roles.SaveAssignment(ctx, domain.RolePermission{RoleName: "user", PermissionName: "GetCustomer", Scope: domain.ScopeAll}); appErr != nil {
+		t.Fatalf("SaveAssignment: %v", appErr.Message)
+	}
+	if _, appErr := roles.SaveAssignment(ctx, domain.RolePermission{RoleName: "teller", PermissionName: "GetCustomer", Scope: domain.ScopeAll}); appErr == nil || appErr.Code != http.StatusNotFound {
+		t.Fatalf("SaveAssignment for a missing role = %v, want 404", appErr)
+	}
+
+	assignments, appErr := roles.FindAllAssignments(ctx)
+	if appErr != nil {
+		t.Fatalf("FindAllAssignments: %v", appErr.Message)
+	}
+	scope, ok := domain.NewRolePermissions(assignments).Scope("user", "GetCustomer")
+	if !ok || scope != domain.ScopeAll {
+		t.Fatalf("user GetCustomer scope = %q, want the updated scope %q", scope, domain.ScopeAll)
+	}
+	if version, _ := roles.PermissionsVersion(ctx); version != before+1 {
+		t.Fatalf("permissions version = %d, want %d after the one successful change", version, before+1)
+	}
+}
+
+func TestLoginAttemptRepositoryCountsFailures(t *testing.T) {
+	ctx := context.Background()
+	attempts := NewLoginAttemptRepositoryDb(newTestDB(t))
+	now := time.Now().Truncate(time.Second)
+	windowStart := now.Add(-15 * time.Minute)
+
+	for i := 1; i <= 2; i++ {
+		attempt, appErr := attempts.RecordFailure(ctx, domain.LoginScopeUsername, "2000", now, windowStart)
+		if appErr != nil {
+			t.Fatalf("RecordFailure: %v", appErr.Message)
+		}
+		if attempt.Failures != i {
+			t.Fatalf("failures = %d, want %d", attempt.Failures, i)
+		}
+	}
+
+	if appErr := attempts.Lock(ctx, domain.LoginScopeUsername, "2000", now.Add(time.Minute)); appErr != nil {
+		t.Fatalf("Lock: %v", appErr.Message)
+	}
+	locked, appErr := attempts.FindLocked(ctx, now)
+	if appErr != nil || len(locked) != 1 {
+		t.Fatalf("FindLocked = %v, %v, want one lockout", locked, appErr)
+	}
+
+	// A failure after the lock expired starts a new window.
+	later := now.Add(2 * time.Minute)
+	attempt, appErr := attempts.RecordFailure(ctx, domain.LoginScopeUsername, "2000", later, later.Add(-15*time.Minute))
+	if appErr != nil {
+		t.Fatalf("RecordFailure: %v", appErr.Message)
+	}
+	if attempt.Failures != 1 || attempt.LockedUntil != nil || !attempt.FirstFailure.Equal(later) {
+		t.Fatalf("attempt = %+v, want a fresh window", attempt)
+	}
+}
+
+func TestAuditRepositoryKeepsTheChain(t *testing.T) {
+	ctx := context.Background()
+	repo := NewAuditRepositoryDb(newTestDB(t))
+	audit := service.NewAuditService(repo)
+	for _, action := range []string{"auth.login", "account.create"} {
+		audit.Record(ctx, domain.AuditEvent{OccurredAt: time.Now(), Actor: "admin", Action: action, Outcome: domain.AuditOutcomeSuccess})
+	}
+
+	result, appErr := audit.VerifyAuditLog(ctx)
+	if appErr != nil {
+		t.Fatalf("VerifyAuditLog: %v", appErr.Message)
+	}
+	if !result.Valid || result.Checked != 2 {
+		t.Fatalf("verification = %+v, want a valid chain of 2", result)
+	}
+}
+
+func TestWebhookDispatcherDeliversEachEventOnce(t *testing.T) {
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE", "true")
+	const secret = "0123456789abcdef"
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := domain.VerifyWebhookSignature(secret, r.Header.Get(domain.WebhookSignatureHeader), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("signature: %v", err)
+		}
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	ctx := context.Background()
+	repo := NewWebhookRepositoryDb(newTestDB(t))
+	subscription, appErr := repo.SaveSubscription(ctx, domain.WebhookSubscription{
+		URL: receiver.URL, Secret: secret, EventTypes: domain.EventTransactionPosted, CreatedOn: time.Now(),
+	})
+	if appErr != nil {
+		t.Fatalf("SaveSubscription: %v", appErr.Message)
+	}
+
+	dispatcher := service.NewWebhookDispatcher(repo)
+	posted := domain.Event{ID: 1, Type: domain.EventTransactionPosted, Payload: []byte(`{"customer_id":"2000"}`)}
+	opened := domain.Event{ID: 2, Type: domain.EventAccountOpened, Payload: []byte(`{"customer_id":"2000"}`)}
+	// The relay publishes at least once: the second publish must not queue
+	// another delivery.
+	for _, e := range []domain.Event{posted, posted, opened} {
+		if err := dispatcher.Publish(ctx, e); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	if sent, appErr := dispatcher.DeliverBatch(ctx); appErr != nil || sent != 1 {
+		t.Fatalf("DeliverBatch = %d, %v, want 1 delivery", sent, appErr)
+	}
+	if received.Load() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", received.Load())
+	}
+
+	deliveries, appErr := repo.FindDeliveries(ctx, domain.WebhookDeliveryFilter{SubscriptionID: subscription.ID, Limit: 10})
+	if appErr != nil || len(deliveries) != 1 {
+		t.Fatalf("FindDeliveries = %v, %v, want one delivery", deliveries, appErr)
+	}
+	if d := deliveries[0]; d.Status != domain.WebhookDeliveryDelivered || d.Attempts != 1 || d.DeliveredOn == nil {
+		t.Fatalf("delivery = %+v, want delivered on the first attempt", d)
+	}
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type RoleRepositoryDb struct {
+	client *database.DB
+}
+
+func NewRoleRepositoryDb(dbClient *sqlx.DB) RoleRepositoryDb {
+	return RoleRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d RoleRepositoryDb) FindAllRoles(ctx context.Context) ([]domain.Role, *errs.AppError) {
+	roles := make([]domain.Role, 0)
+	if err := d.client.SelectContext(ctx, &roles, "SELECT name, description, mfa_required FROM roles ORDER BY name"); err != nil {
+		return nil, queryError(ctx, "Error querying roles", err)
+	}
+	return roles, nil
+}
+
+func (d RoleRepositoryDb) SaveRole(ctx context.Context, role domain.Role) (*domain.Role, *errs.AppError) {
+	err := d.mutate(ctx, func(tx *database.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO roles (name, description, mfa_required) VALUES (?, ?, ?)", role.Name, role.Description, role.MFARequired)
+		return err
+	})
+	if err != nil {
+		if database.IsDuplicateKey(err) {
+			return nil, errs.NewConflictError("Role " + role.Name + " already exists")
+		}
+		return nil, queryError(ctx, "Error saving role", err, logger.String("role", role.Name))
+	}
+	return &role, nil
+}
+
+func (d RoleRepositoryDb) DeleteRole(ctx context.Context, name string) *errs.AppError {
+	return d.deleteOne(ctx, "DELETE FROM roles WHERE name = ?", "Role not found", name)
+}
+
+func (d RoleRepositoryDb) SetMFARequired(ctx context.Context, name string, required bool) *errs.AppError {
+	result, err := d.client.ExecContext(ctx, "UPDATE roles SET mfa_required = ? WHERE name = ?", required, name)
+	if err != nil {
+		return queryError(ctx, "Error updating MFA requirement of role", err, logger.String("role", name))
+	}
+	// MySQL reports 0 affected rows when the value does not change.
+	if changed, appErr := rowsChanged(result); appErr != nil || changed {
+		return appErr
+	}
+	var exists int
+	if err := d.client.GetContext(ctx, &exists, "SELECT COUNT(*) FROM roles WHERE name = ?", name); err != nil {
+		return queryError(ctx, "Error querying role", err)
+	}
+	if exists == 0 {
+		return errs.NewNotFoundError("Role not found")
+	}
+	return nil
+}
+
+func (d RoleRepositoryDb) FindAllPermissions(ctx context.Context) ([]domain.Permission, *errs.AppError) {
+	permissions := make([]domain.Permission, 0)
+	if err := d.client.SelectContext(ctx, &permissions, "SELECT name, description FROM permissions ORDER BY name"); err != nil {
+		return nil, queryError(ctx, "Error querying permissions", err)
+	}
+	return permissions, nil
+}
+
+func (d RoleRepositoryDb) SavePermission(ctx context.Context, permission domain.Permission) (*domain.Permission, *errs.AppError) {
+	err := d.mutate(ctx, func(tx *database.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO permissions (name, description) VALUES (?, ?)", permission.Name, permission.Description)
+		return err
+	})
+	if err != nil {
+		if database.IsDuplicateKey(err) {
+			return nil, errs.NewConflictError("Permission " + permission.Name + " already exists")
+		}
+		return nil, queryError(ctx, "Error saving permission", err, logger.String("permission", permission.Name))
+	}
+	return &permission, nil
+}
+
+func (d RoleRepositoryDb) DeletePermission(ctx context.Context, name string) *errs.AppError {
+	return d.deleteOne(ctx, "DELETE FROM permissions WHERE name = ?", "Permission not found", name)
+}
+
+func (d RoleRepositoryDb) FindAllAssignments(ctx context.Context) ([]domain.RolePermission, *errs.AppError) {
+	assignments := make([]domain.RolePermission, 0)
+	query := "SELECT role_name, permission_name, scope FROM role_permissions ORDER BY role_name, permission_name"
+	if err := d.client.SelectContext(ctx, &assignments, query); err != nil {
+		return nil, queryError(ctx, "Error querying role permissions", err)
+	}
+	return assignments, nil
+}
+
+func (d RoleRepositoryDb) SaveAssignment(ctx context.Context, a domain.RolePermission) (*domain.RolePermission, *errs.AppError) {
+	err := d.mutate(ctx, func(tx *database.Tx) error {
+		dialect := tx.Dialect()
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO role_permissions (role_name, permission_name, scope) VALUES (?, ?, ?)"+
+				dialect.Upsert("role_name", "permission_name")+"scope = "+dialect.Excluded("scope"),
+			a.RoleName, a.PermissionName, a.Scope,
+		)
+		return err
+	})
+	if err != nil {
+		if database.IsForeignKeyViolation(err) {
+			return nil, errs.NewNotFoundError("Role or permission not found")
+		}
+		return nil, queryError(ctx, "Error saving role permission", err)
+	}
+	return &a, nil
+}
+
+func (d RoleRepositoryDb) DeleteAssignment(ctx context.Context, roleName, permissionName string) *errs.AppError {
+	return d.deleteOne(ctx,
+		"DELETE FROM role_permissions WHERE role_name = ? AND permission_name = ?",
+		"Role permission not found", roleName, permissionName,
+	)
+}
+
+func (d RoleRepositoryDb) PermissionsVersion(ctx context.Context) (int64, *errs.AppError) {
+	var version int64
+	err := d.client.GetContext(ctx, &version, "SELECT version FROM permissions_version WHERE id = 1")
+	if err != nil && err != sql.ErrNoRows {
+		return 0, queryError(ctx, "Error reading permissions version", err)
+	}
+	return version, nil
+}
+
+func (d RoleRepositoryDb) deleteOne(ctx context.Context, query, notFoundMessage string, args ...interface{}) *errs.AppError {
+	var rowsAffected int64
+	err := d.mutate(ctx, func(tx *database.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		rowsAffected, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return queryError(ctx, "Error deleting role data", err)
+	}
+	if rowsAffected == 0 {
+		return errs.NewNotFoundError(notFoundMessage)
+	}
+	return nil
+}
+
+// mutate runs fn and bumps the permissions version in the same transaction, so
+// every instance caching role permissions notices the change.
+func (d RoleRepositoryDb) mutate(ctx context.Context, fn func(tx *database.Tx) error) error {
+	tx, err := d.client.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE permissions_version SET version = version + 1 WHERE id = 1"); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", rollbackErr))
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+var _ ports.RoleRepository = (*RoleRepositoryDb)(nil)
@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type UnitOfWorkDb struct {
+	client      *database.DB
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewUnitOfWorkDb reads UOW_MAX_ATTEMPTS, how many times a transaction is
+// run before a conflict is reported, and UOW_RETRY_BACKOFF, the wait before
+// the first retry, which grows linearly with each attempt.
+func NewUnitOfWorkDb(dbClient *sqlx.DB) UnitOfWorkDb {
+	return UnitOfWorkDb{
+		client:      database.Wrap(dbClient),
+		maxAttempts: max(config.Int("UOW_MAX_ATTEMPTS", 3), 1),
+		backoff:     config.Duration("UOW_RETRY_BACKOFF", 50*time.Millisecond),
+	}
+}
+
+// txKey marks the context passed to fn with its transaction, so a nested Do
+// opens a savepoint in it instead of a second transaction.
+type txKey struct{}
+
+func (u UnitOfWorkDb) Do(ctx context.Context, fn func(ctx context.Context, repos ports.TxRepositories) *errs.AppError) *errs.AppError {
+	if outer, ok := ctx.Value(txKey{}).(*database.Tx); ok {
+		savepoint, err := outer.BeginTxx(ctx, nil)
+		if err != nil {
+			return queryError(ctx, "Error creating savepoint", err)
+		}
+		return u.run(ctx, savepoint, fn)
+	}
+
+	for attempt := 1; ; attempt++ {
+		tx, err := u.client.BeginTxx(ctx, nil)
+		if err != nil {
+			if !database.IsRetryable(err) || attempt == u.maxAttempts {
+				return queryError(ctx, "Error starting transaction", err)
+			}
+		} else {
+			appErr := u.run(ctx, tx, fn)
+			if appErr == nil || !tx.Conflicted() || attempt == u.maxAttempts {
+				return appErr
+			}
+		}
+
+		logger.Warn("Transaction conflict, retrying", logger.Int("attempt", attempt))
+		select {
+		case <-ctx.Done():
+			return queryError(ctx, "Transaction retry abandoned", ctx.Err())
+		case <-time.After(u.backoff * time.Duration(attempt)):
+		}
+	}
+}
+
+// run calls fn with repositories bound to tx, a transaction or a savepoint,
+// and commits or rolls it back. A panic in fn rolls back and is re-raised.
+func (u UnitOfWorkDb) run(ctx context.Context, tx *database.Tx, fn func(ctx context.Context, repos ports.TxRepositories) *errs.AppError) (appErr *errs.AppError) {
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		if err := tx.Rollback(); err != nil {
+			logger.Error("Error rolling back transaction", logger.Any("error", err))
+		}
+	}()
+
+	if appErr = fn(context.WithValue(ctx, txKey{}, tx), txRepositoriesDb(tx)); appErr != nil {
+		return appErr
+	}
+	committed = true
+	if err := tx.Commit(); err != nil {
+		return queryError(ctx, "Error committing transaction", err)
+	}
+	return nil
+}
+
+func txRepositoriesDb(conn database.Conn) ports.TxRepositories {
+	return ports.TxRepositories{
+		Accounts:      AccountRepositoryDb{client: conn},
+		AccountEvents: AccountEventStoreDb{client: conn},
+		Customers:     CustomerRepositoryDb{client: conn},
+		Auth:          AuthRepositoryDb{client: conn},
+		Users:         UserRepositoryDb{client: conn},
+		Outbox:        OutboxRepositoryDb{client: conn},
+	}
+}
+
+var _ ports.UnitOfWork = (*UnitOfWorkDb)(nil)
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// UnitOfWorkMemory runs units of work one at a time and rolls one back by
+// restoring a copy of the store taken when it began. Writes made outside a
+// unit of work while one is running are undone with it, which is fine for
+// tests and demos but is not isolation.
+type UnitOfWorkMemory struct {
+	store *MemoryStore
+}
+
+func NewUnitOfWorkMemory(store *MemoryStore) UnitOfWorkMemory {
+	return UnitOfWorkMemory{store: store}
+}
+
+// memoryTxKey marks the context of a running unit of work, whose nested
+// units take their own snapshot instead of waiting for it.
+type memoryTxKey struct{}
+
+func (u UnitOfWorkMemory) Do(ctx context.Context, fn func(ctx context.Context, repos ports.TxRepositories) *errs.AppError) *errs.AppError {
+	if appErr := contextError(ctx, "Error starting transaction"); appErr != nil {
+		return appErr
+	}
+	if ctx.Value(memoryTxKey{}) == nil {
+		u.store.txMu.Lock()
+		defer u.store.txMu.Unlock()
+		ctx = context.WithValue(ctx, memoryTxKey{}, true)
+	}
+
+	snapshot := u.store.snapshot()
+	committed := false
+	defer func() {
+		if !committed {
+			u.store.restore(snapshot)
+		}
+	}()
+
+	if appErr := fn(ctx, ports.TxRepositories{
+		Accounts:      NewAccountRepositoryMemory(u.store),
+		AccountEvents: NewAccountEventStoreMemory(u.store),
+		Customers:     NewCustomerRepositoryMemory(u.store),
+		Auth:          NewAuthRepositoryMemory(u.store),
+		Users:         NewUserRepositoryMemory(u.store),
+		Outbox:        NewOutboxRepositoryMemory(u.store),
+	}); appErr != nil {
+		return appErr
+	}
+	committed = true
+	return nil
+}
+
+type memorySnapshot struct {
+	customers        map[int]domain.Customer
+	accounts         map[int64]domain.Account
+	transactions     map[int64]domain.Transaction
+	users            map[string]domain.User
+	refreshTokens    map[string]string
+	outbox           []domain.OutboxEntry
+	accountEvents    []domain.AccountEvent
+	accountSnapshots map[string]domain.AccountSnapshot
+}
+
+func (s *MemoryStore) snapshot() memorySnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return memorySnapshot{
+		customers:        maps.Clone(s.customers),
+		accounts:         maps.Clone(s.accounts),
+		transactions:     maps.Clone(s.transactions),
+		users:            maps.Clone(s.users),
+		refreshTokens:    maps.Clone(s.refreshTokens),
+		outbox:           slices.Clone(s.outbox),
+		accountEvents:    slices.Clone(s.accountEvents),
+		accountSnapshots: maps.Clone(s.accountSnapshots),
+	}
+}
+
+// restore keeps the id counters, as a database does not reuse the ids of
+// rolled back rows.
+func (s *MemoryStore) restore(snapshot memorySnapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.customers = snapshot.customers
+	s.accounts = snapshot.accounts
+	s.transactions = snapshot.transactions
+	s.users = snapshot.users
+	s.refreshTokens = snapshot.refreshTokens
+	s.outbox = snapshot.outbox
+	s.accountEvents = snapshot.accountEvents
+	s.accountSnapshots = snapshot.accountSnapshots
+}
+
+var _ ports.UnitOfWork = (*UnitOfWorkMemory)(nil)
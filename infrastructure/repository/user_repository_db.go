@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+)
+
+type UserRepositoryDb struct {
+	client database.Conn
+}
+
+func NewUserRepositoryDb(dbClient *sqlx.DB) UserRepositoryDb {
+	return UserRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d UserRepositoryDb) FindAll(ctx context.Context, filter domain.UserFilter) ([]domain.User, *errs.AppError) {
+	conditions := make([]string, 0, 4)
+	args := make([]interface{}, 0, 6)
+	if filter.Query != "" {
+		conditions = append(conditions, "username LIKE ? ESCAPE '!'")
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+	}
+	if filter.Role != "" {
+		conditions = append(conditions, "role = ?")
+		args = append(args, filter.Role)
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.CustomerID != "" {
+		conditions = append(conditions, "customer_id = ?")
+		args = append(args, filter.CustomerID)
+	}
+
+	query := "SELECT username, role, customer_id, status, created_on FROM users"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY username LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	users := make([]domain.User, 0)
+	if err := d.client.SelectContext(ctx, &users, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying users", err)
+	}
+	return users, nil
+}
+
+func (d UserRepositoryDb) UpdateRole(ctx context.Context, username, role string) *errs.AppError {
+	return d.update(ctx, "UPDATE users SET role = ? WHERE username = ?", role, username)
+}
+
+func (d UserRepositoryDb) UpdateCustomerID(ctx context.Context, username string, customerID *string) *errs.AppError {
+	return d.update(ctx, "UPDATE users SET customer_id = ? WHERE username = ?", customerID, username)
+}
+
+func (d UserRepositoryDb) UpdateStatus(ctx context.Context, username, status string) *errs.AppError {
+	return d.update(ctx, "UPDATE users SET status = ? WHERE username = ?", status, username)
+}
+
+func (d UserRepositoryDb) update(ctx context.Context, query string, args ...interface{}) *errs.AppError {
+	if _, err := d.client.ExecContext(ctx, query, args...); err != nil {
+		return queryError(ctx, "Error updating user", err)
+	}
+	return nil
+}
+
+// escapeLike escapes with '!' rather than the backslash, which SQLite does
+// not treat as an escape and MySQL would need doubled inside the literal.
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+var _ ports.UserRepository = (*UserRepositoryDb)(nil)
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// UserRepositoryMemory serves the admin user listing from the same store as
+// AuthRepositoryMemory, so both see the same users.
+type UserRepositoryMemory struct {
+	store *MemoryStore
+}
+
+func NewUserRepositoryMemory(store *MemoryStore) UserRepositoryMemory {
+	return UserRepositoryMemory{store: store}
+}
+
+// FindAll matches Query case-insensitively, as LIKE does on the default
+// MySQL collation.
+func (r UserRepositoryMemory) FindAll(ctx context.Context, filter domain.UserFilter) ([]domain.User, *errs.AppError) {
+	if appErr := contextError(ctx, "Error querying users"); appErr != nil {
+		return nil, appErr
+	}
+	s := r.store
+	s.mu.RLock()
+	query := strings.ToLower(filter.Query)
+	users := make([]domain.User, 0)
+	for _, u := range s.users {
+		if filter.Query != "" && !strings.Contains(strings.ToLower(u.Username), query) {
+			continue
+		}
+		if filter.Role != "" && u.Role != filter.Role ||
+			filter.Status != "" && u.Status != filter.Status ||
+			filter.CustomerID != "" && (u.CustomerID == nil || *u.CustomerID != filter.CustomerID) {
+			continue
+		}
+		users = append(users, *withoutPassword(u))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(users, func(i, j int) bool { return users[i].Username < users[j].Username })
+	start := min(max(filter.Offset, 0), len(users))
+	end := min(start+max(filter.Limit, 0), len(users))
+	return users[start:end], nil
+}
+
+func (r UserRepositoryMemory) UpdateRole(ctx context.Context, username, role string) *errs.AppError {
+	if appErr := contextError(ctx, "Error updating user"); appErr != nil {
+		return appErr
+	}
+	r.store.updateUser(username, func(u *domain.User) { u.Role = role })
+	return nil
+}
+
+func (r UserRepositoryMemory) UpdateCustomerID(ctx context.Context, username string, customerID *string) *errs.AppError {
+	if appErr := contextError(ctx, "Error updating user"); appErr != nil {
+		return appErr
+	}
+	if customerID != nil {
+		id := *customerID
+		customerID = &id
+	}
+	r.store.updateUser(username, func(u *domain.User) { u.CustomerID = customerID })
+	return nil
+}
+
+func (r UserRepositoryMemory) UpdateStatus(ctx context.Context, username, status string) *errs.AppError {
+	if appErr := contextError(ctx, "Error updating user"); appErr != nil {
+		return appErr
+	}
+	r.store.updateUser(username, func(u *domain.User) { u.Status = status })
+	return nil
+}
+
+var _ ports.UserRepository = (*UserRepositoryMemory)(nil)
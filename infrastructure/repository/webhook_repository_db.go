@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/database"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	webhookSubscriptionColumns = "subscription_id, url, secret, event_types, customer_id, created_by, created_on"
+	webhookDeliveryColumns     = `delivery_id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+              last_attempt_at, last_status_code, last_error, created_on, delivered_on`
+)
+
+type WebhookRepositoryDb struct {
+	client *database.DB
+}
+
+func NewWebhookRepositoryDb(dbClient *sqlx.DB) WebhookRepositoryDb {
+	return WebhookRepositoryDb{client: database.Wrap(dbClient)}
+}
+
+func (d WebhookRepositoryDb) SaveSubscription(ctx context.Context, s domain.WebhookSubscription) (*domain.WebhookSubscription, *errs.AppError) {
+	query := `INSERT INTO webhook_subscriptions (url, secret, event_types, customer_id, created_by, created_on)
+              VALUES (?, ?, ?, ?, ?, ?)`
+	id, err := d.client.InsertContext(ctx, query, "subscription_id", s.URL, s.Secret, s.EventTypes, s.CustomerID, s.CreatedBy, s.CreatedOn)
+	if err != nil {
+		return nil, queryError(ctx, "Error saving webhook subscription", err)
+	}
+
+	s.ID = strconv.FormatInt(id, 10)
+	return &s, nil
+}
+
+func (d WebhookRepositoryDb) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, *errs.AppError) {
+	var subscription domain.WebhookSubscription
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions WHERE subscription_id = ?"
+	if err := d.client.GetContext(ctx, &subscription, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("Webhook not found")
+		}
+		return nil, queryError(ctx, "Error fetching webhook subscription", err)
+	}
+	return &subscription, nil
+}
+
+func (d WebhookRepositoryDb) FindSubscriptions(ctx context.Context, customerID string) ([]domain.WebhookSubscription, *errs.AppError) {
+	query := "SELECT " + webhookSubscriptionColumns + " FROM webhook_subscriptions"
+	args := make([]interface{}, 0, 1)
+	if customerID != "" {
+		query += " WHERE customer_id = ?"
+		args = append(args, customerID)
+	}
+	query += " ORDER BY subscription_id"
+
+	subscriptions := make([]domain.WebhookSubscription, 0)
+	if err := d.client.SelectContext(ctx, &subscriptions, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying webhook subscriptions", err)
+	}
+	return subscriptions, nil
+}
+
+func (d WebhookRepositoryDb) DeleteSubscription(ctx context.Context, id string) *errs.AppError {
+	result, err := d.client.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE subscription_id = ?", id)
+	if err != nil {
+		return queryError(ctx, "Error deleting webhook subscription", err)
+	}
+	deleted, appErr := rowsChanged(result)
+	if appErr != nil {
+		return appErr
+	}
+	if !deleted {
+		return errs.NewNotFoundError("Webhook not found")
+	}
+	return nil
+}
+
+// EnqueueDelivery relies on the unique key on (subscription_id, event_id):
+// the relay may hand the same event over more than once.
+func (d WebhookRepositoryDb) EnqueueDelivery(ctx context.Context, w domain.WebhookDelivery) *errs.AppError {
+	query := `INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at, created_on)
+              VALUES (?, ?, ?, ?, ?, ?, ?)`
+	if _, err := d.client.ExecContext(ctx, query, w.SubscriptionID, w.EventID, w.EventType, w.Payload, w.Status, w.NextAttemptAt, w.CreatedOn); err != nil {
+		if database.IsDuplicateKey(err) {
+			logger.Debug("Webhook delivery already queued",
+				logger.String("subscription_id", w.SubscriptionID),
+				logger.Any("event_id", w.EventID))
+			return nil
+		}
+		return queryError(ctx, "Error queuing webhook delivery", err)
+	}
+	return nil
+}
+
+func (d WebhookRepositoryDb) FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, *errs.AppError) {
+	var delivery domain.WebhookDelivery
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE delivery_id = ?"
+	if err := d.client.GetContext(ctx, &delivery, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NewNotFoundError("Webhook delivery not found")
+		}
+		return nil, queryError(ctx, "Error fetching webhook delivery", err)
+	}
+	return &delivery, nil
+}
+
+// FindDeliveries returns the newest deliveries first.
+func (d WebhookRepositoryDb) FindDeliveries(ctx context.Context, filter domain.WebhookDeliveryFilter) ([]domain.WebhookDelivery, *errs.AppError) {
+	query := "SELECT " + webhookDeliveryColumns + " FROM webhook_deliveries WHERE subscription_id = ?"
+	args := []interface{}{filter.SubscriptionID}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY delivery_id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	if err := d.client.SelectContext(ctx, &deliveries, query, args...); err != nil {
+		return nil, queryError(ctx, "Error querying webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (d WebhookRepositoryDb) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, *errs.AppError) {
+	query := "SELECT " + webhookDeliveryColumns + ` FROM webhook_deliveries
+              WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, delivery_id LIMIT ?`
+	deliveries := make([]domain.WebhookDelivery, 0)
+	if err := d.client.SelectContext(ctx, &deliveries, query, domain.WebhookDeliveryPending, now, limit); err != nil {
+		return nil, queryError(ctx, "Error querying due webhook deliveries", err)
+	}
+	return deliveries, nil
+}
+
+func (d WebhookRepositoryDb) UpdateDelivery(ctx context.Context, w domain.WebhookDelivery) *errs.AppError {
+	query := `UPDATE webhook_deliveries
+              SET status = ?, attempts = ?, next_attempt_at = ?, last_attempt_at = ?, last_status_code = ?, last_error = ?, delivered_on = ?
+              WHERE delivery_id = ?`
+	_, err := d.client.ExecContext(ctx, query, w.Status, w.Attempts, w.NextAttemptAt, w.LastAttemptAt, w.LastStatusCode,
+		truncate(w.LastError, 1000), w.DeliveredOn, w.ID)
+	if err != nil {
+		return queryError(ctx, "Error updating webhook delivery", err, logger.String("delivery_id", w.ID))
+	}
+	return nil
+}
+
+var _ ports.WebhookRepository = (*WebhookRepositoryDb)(nil)
@@ -3,7 +3,9 @@ package utils
 import (
 	"encoding/json"
 	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
 	"github.com/titi0001/Microservices-API-in-Go/logger"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -17,16 +19,13 @@ func WriteResponse(w http.ResponseWriter, code int, data any) {
 	}
 }
 
-func BuildVerifyURL(token string, routeName string, vars map[string]string) string {
-	u := url.URL{Host: "localhost:8181", Path: "/auth/verify", Scheme: "http"}
-	q := u.Query()
-	q.Add("token", token)
-	q.Add("routeName", routeName)
-	for k, v := range vars {
-		q.Add(k, v)
+func BuildAuthURL(authServiceURL, path string) (string, error) {
+	u, err := url.Parse(authServiceURL)
+	if err != nil {
+		return "", err
 	}
-	u.RawQuery = q.Encode()
-	return u.String()
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	return u.String(), nil
 }
 
 func ExtractQueryParams(r *http.Request, exclude []string) map[string]string {
@@ -43,31 +42,29 @@ func ExtractQueryParams(r *http.Request, exclude []string) map[string]string {
 	return vars
 }
 
-func ExtractClaimsFromToken(tokenString string, secretKey func() []byte) (jwt.MapClaims, error) {
+func ExtractClaimsFromToken(tokenString string, keys KeyResolver) (jwt.MapClaims, error) {
 	logger.Info("Attempting to extract claims from token",
 		logger.Int("token_length", len(tokenString)))
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			method := "unknown"
-			if alg, ok := token.Header["alg"].(string); ok {
-				method = alg
-			}
-			logger.Error("Unexpected signing method", logger.String("alg", method))
-			return nil, jwt.NewValidationError("unexpected signing method: "+method, jwt.ValidationErrorSignatureInvalid)
+		alg, _ := token.Header["alg"].(string)
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			logger.Error("Unexpected signing method", logger.String("alg", alg))
+			return nil, jwt.NewValidationError("unexpected signing method: "+alg, jwt.ValidationErrorSignatureInvalid)
+		}
+
+		kid, ok := token.Header["kid"].(string)
+		if !ok || kid == "" {
+			logger.Error("Token has no kid header")
+			return nil, jwt.NewValidationError("missing kid header", jwt.ValidationErrorUnverifiable)
 		}
-		return secretKey(), nil
+		return keys.ResolveKey(kid, alg)
 	})
 
 	if err != nil {
-		prefix := ""
-		if len(tokenString) > 0 {
-			endIndex := min(10, len(tokenString))
-			prefix = tokenString[:endIndex]
-		}
-		logger.Error("Failed to parse token",
-			logger.String("token_prefix", prefix),
-			logger.Any("error", err))
+		logger.Error("Failed to parse token", logger.Any("error", err))
 		return nil, err
 	}
 
@@ -85,13 +82,6 @@ func ExtractClaimsFromToken(tokenString string, secretKey func() []byte) (jwt.Ma
 	return nil, jwt.NewValidationError("invalid token claims", jwt.ValidationErrorClaimsInvalid)
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
-}
-
 
 func GetTokenFromHeader(header string) string {
 	splitToken := strings.Split(header, "Bearer")
@@ -100,3 +90,18 @@ func GetTokenFromHeader(header string) string {
 	}
 	return ""
 }
+
+// ClientIP returns the address of the caller. X-Forwarded-For can be set by
+// any client, so it is only honoured when TRUST_PROXY_HEADERS is enabled.
+func ClientIP(r *http.Request) string {
+	if config.Bool("TRUST_PROXY_HEADERS", false) {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
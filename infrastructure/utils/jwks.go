@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	defaultJWKSCacheTTL    = 10 * time.Minute
+	minJWKSRefreshInterval = 30 * time.Second
+	jwksFetchTimeout       = 3 * time.Second
+	JWKSPath               = "/.well-known/jwks.json"
+)
+
+// JSONWebKey is the public part of a signing key as defined by RFC 7517.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+func NewJSONWebKey(kid, alg string, publicKey crypto.PublicKey) (JSONWebKey, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return JSONWebKey{
+			KeyType:   "RSA",
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: alg,
+			N:         base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		return JSONWebKey{
+			KeyType:   "EC",
+			KeyID:     kid,
+			Use:       "sig",
+			Algorithm: alg,
+			Curve:     key.Curve.Params().Name,
+			X:         base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+			Y:         base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+		}, nil
+	default:
+		return JSONWebKey{}, fmt.Errorf("unsupported public key type %T", publicKey)
+	}
+}
+
+// PublicKey decodes the key material into an *rsa.PublicKey or *ecdsa.PublicKey.
+func (k JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decoding modulus of key %q: %w", k.KeyID, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decoding exponent of key %q: %w", k.KeyID, err)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		if k.Curve != elliptic.P256().Params().Name {
+			return nil, fmt.Errorf("unsupported curve %q for key %q", k.Curve, k.KeyID)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x of key %q: %w", k.KeyID, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decoding y of key %q: %w", k.KeyID, err)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q for key %q", k.KeyType, k.KeyID)
+	}
+}
+
+type cachedKey struct {
+	alg string
+	key crypto.PublicKey
+}
+
+// JWKSCache resolves verification keys from a remote JWKS endpoint. Keys are
+// refreshed after the TTL expires, or early when a token references an unknown
+// kid, which is how a freshly rotated key is picked up.
+type JWKSCache struct {
+	url         string
+	ttl         time.Duration
+	client      *http.Client
+	mu          sync.RWMutex
+	keys        map[string]cachedKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		url:    url,
+		ttl:    defaultJWKSCacheTTL,
+		client: &http.Client{Timeout: jwksFetchTimeout},
+		keys:   make(map[string]cachedKey),
+	}
+}
+
+func (c *JWKSCache) ResolveKey(kid, alg string) (interface{}, error) {
+	c.mu.RLock()
+	entry, ok := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > c.ttl
+	c.mu.RUnlock()
+
+	if !ok || stale {
+		if err := c.refresh(); err != nil {
+			if !ok {
+				return nil, err
+			}
+			logger.Warn("Using stale JWKS after refresh failure", logger.Any("error", err))
+		}
+		c.mu.RLock()
+		entry, ok = c.keys[kid]
+		c.mu.RUnlock()
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if entry.alg != alg {
+		return nil, fmt.Errorf("algorithm %q does not match key %q", alg, kid)
+	}
+	return entry.key, nil
+}
+
+func (c *JWKSCache) refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.lastAttempt) < minJWKSRefreshInterval && time.Since(c.fetchedAt) <= c.ttl {
+		return nil
+	}
+	c.lastAttempt = time.Now()
+
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]cachedKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		publicKey, err := jwk.PublicKey()
+		if err != nil {
+			logger.Warn("Skipping invalid JWKS key", logger.String("kid", jwk.KeyID), logger.Any("error", err))
+			continue
+		}
+		keys[jwk.KeyID] = cachedKey{alg: jwk.Algorithm, key: publicKey}
+	}
+
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	logger.Info("Refreshed JWKS cache", logger.Int("key_count", len(keys)))
+	return nil
+}
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNonPublicAddress refuses a connection to an address of the local host
+// or network.
+var ErrNonPublicAddress = errors.New("address is not public")
+
+var (
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+	thisNetwork        = netip.MustParsePrefix("0.0.0.0/8")
+)
+
+// IsPublicAddress reports whether addr is reachable on the internet rather
+// than on the host or its network: loopback, private (RFC 1918 and RFC 4193),
+// link-local, where cloud metadata services answer on 169.254.169.254,
+// shared (RFC 6598), unspecified and multicast addresses are not.
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() &&
+		!addr.IsLoopback() &&
+		!addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() &&
+		!addr.IsLinkLocalMulticast() &&
+		!addr.IsInterfaceLocalMulticast() &&
+		!addr.IsMulticast() &&
+		!addr.IsUnspecified() &&
+		!sharedAddressSpace.Contains(addr) &&
+		!thisNetwork.Contains(addr)
+}
+
+// CheckPublicHost resolves host, a name or an IP literal, and fails unless
+// every address it resolves to is public.
+func CheckPublicHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !IsPublicAddress(addr) {
+			return fmt.Errorf("%s resolves to %s: %w", host, addr.Unmap(), ErrNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// PublicDialControl is a net.Dialer Control function that refuses to connect
+// to addresses that are not public. It runs after name resolution, on the
+// address actually dialed, so a name that resolved to a public address when
+// it was checked and resolves to an internal one later is refused too.
+func PublicDialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !IsPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("dialing %s: %w", address, ErrNonPublicAddress)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	for address, public := range map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::":              false,
+		"224.0.0.1":       false,
+		"::ffff:10.0.0.1": false,
+	} {
+		if got := IsPublicAddress(netip.MustParseAddr(address)); got != public {
+			t.Errorf("%s: expected public %v, got %v", address, public, got)
+		}
+	}
+}
+
+func TestCheckPublicHost(t *testing.T) {
+	for _, host := range []string{"localhost", "127.0.0.1", "169.254.169.254", "::1"} {
+		if err := CheckPublicHost(context.Background(), host); !errors.Is(err, ErrNonPublicAddress) {
+			t.Errorf("%s: expected a non-public address, got %v", host, err)
+		}
+	}
+	if err := CheckPublicHost(context.Background(), "93.184.215.14"); err != nil {
+		t.Errorf("expected a public IP literal to pass: %v", err)
+	}
+}
+
+func TestPublicDialControlRefusesLocalReceivers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("expected the request not to reach the server")
+	}))
+	defer server.Close()
+
+	dialer := &net.Dialer{Timeout: time.Second, Control: PublicDialControl}
+	client := &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+	_, err := client.Get(server.URL)
+	if !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("expected the dial to be refused, got %v", err)
+	}
+}
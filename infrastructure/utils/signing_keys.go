@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+// KeyResolver returns the verification key for a token's kid and alg headers.
+type KeyResolver interface {
+	ResolveKey(kid, alg string) (interface{}, error)
+}
+
+// TokenSigner signs tokens and publishes the keys that verify them. Only the
+// auth server holds one; other services verify against its JWKS.
+type TokenSigner interface {
+	Sign(claims jwt.Claims) (string, error)
+	JWKS() JWKS
+}
+
+// ActiveKeyFile names the file in the key directory that holds the kid of the
+// signing key, so that a rotation only needs a file write and a SIGHUP.
+const ActiveKeyFile = "active_kid"
+
+// SigningKey is a private key identified by its kid.
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+}
+
+// PublicKey returns the public half of the signing key.
+func (k SigningKey) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// SigningKeySet holds every key the auth server publishes. Only the active key
+// signs new tokens; the others stay published so tokens they issued keep
+// verifying until they expire, which lets keys rotate without downtime.
+type SigningKeySet struct {
+	mu       sync.RWMutex
+	dir      string
+	activeID string
+	keys     map[string]SigningKey
+}
+
+// LoadSigningKeysFromEnv loads every *.pem file in JWT_KEYS_DIR, using the file
+// name as kid. The active_kid file of the directory selects the signing key,
+// then JWT_ACTIVE_KID; when neither is set the last kid in lexical order is
+// used, so dropping in a newer file rotates the key. When JWT_KEYS_DIR is unset
+// an ephemeral RSA key is generated for local use.
+func LoadSigningKeysFromEnv() (*SigningKeySet, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	if dir == "" {
+		logger.Warn("JWT_KEYS_DIR not set, generating an ephemeral RSA signing key")
+		return NewEphemeralSigningKeySet()
+	}
+
+	set := &SigningKeySet{dir: dir}
+	if err := set.Reload(); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// NewEphemeralSigningKeySet returns a set with a single freshly generated RSA key.
+func NewEphemeralSigningKeySet() (*SigningKeySet, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generating RSA key: %w", err)
+	}
+	return NewSigningKeySet(SigningKey{ID: "ephemeral", Method: jwt.SigningMethodRS256, PrivateKey: privateKey})
+}
+
+// NewSigningKeySet builds a set from in-memory keys; the first key is active.
+func NewSigningKeySet(keys ...SigningKey) (*SigningKeySet, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	set := &SigningKeySet{activeID: keys[0].ID, keys: make(map[string]SigningKey, len(keys))}
+	for _, key := range keys {
+		set.keys[key.ID] = key
+	}
+	return set, nil
+}
+
+// Reload re-reads the key directory, replacing the set atomically. The active
+// kid is read again too, from the active_kid file: the environment of the
+// process cannot change under it.
+func (s *SigningKeySet) Reload() error {
+	if s.dir == "" {
+		return nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return fmt.Errorf("listing signing keys: %w", err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no *.pem signing keys found in %s", s.dir)
+	}
+	sort.Strings(paths)
+
+	keys := make(map[string]SigningKey, len(paths))
+	for _, path := range paths {
+		key, err := loadSigningKey(path)
+		if err != nil {
+			return err
+		}
+		keys[key.ID] = key
+	}
+
+	activeID, err := s.readActiveID()
+	if err != nil {
+		return err
+	}
+	if activeID == "" {
+		activeID = strings.TrimSuffix(filepath.Base(paths[len(paths)-1]), ".pem")
+	}
+	if _, ok := keys[activeID]; !ok {
+		return fmt.Errorf("active signing key %q not found in %s", activeID, s.dir)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.activeID = activeID
+	s.mu.Unlock()
+
+	logger.Info("Loaded signing keys",
+		logger.Int("key_count", len(keys)),
+		logger.String("active_kid", activeID))
+	return nil
+}
+
+// readActiveID returns the kid in the active_kid file, or JWT_ACTIVE_KID when
+// the directory has no such file.
+func (s *SigningKeySet) readActiveID() (string, error) {
+	content, err := os.ReadFile(filepath.Join(s.dir, ActiveKeyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return os.Getenv("JWT_ACTIVE_KID"), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading active signing key: %w", err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Sign signs the claims with the active key and sets the kid header.
+func (s *SigningKeySet) Sign(claims jwt.Claims) (string, error) {
+	s.mu.RLock()
+	key := s.keys[s.activeID]
+	s.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// ResolveKey implements KeyResolver using the public keys of the set.
+func (s *SigningKeySet) ResolveKey(kid, alg string) (interface{}, error) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.Method.Alg() != alg {
+		return nil, fmt.Errorf("algorithm %q does not match key %q", alg, kid)
+	}
+	return key.PublicKey(), nil
+}
+
+// JWKS returns the public keys of the set as a JSON Web Key Set.
+func (s *SigningKeySet) JWKS() JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]string, 0, len(s.keys))
+	for id := range s.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	set := JWKS{Keys: make([]JSONWebKey, 0, len(ids))}
+	for _, id := range ids {
+		key := s.keys[id]
+		jwk, err := NewJSONWebKey(key.ID, key.Method.Alg(), key.PublicKey())
+		if err != nil {
+			logger.Error("Skipping unsupported key in JWKS", logger.String("kid", id), logger.Any("error", err))
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func loadSigningKey(path string) (SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("reading signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return SigningKey{}, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return SigningKey{}, fmt.Errorf("parsing signing key %s: %w", path, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return SigningKey{ID: kid, Method: jwt.SigningMethodRS256, PrivateKey: key}, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return SigningKey{}, fmt.Errorf("signing key %s: only P-256 EC keys are supported", path)
+		}
+		return SigningKey{ID: kid, Method: jwt.SigningMethodES256, PrivateKey: key}, nil
+	default:
+		return SigningKey{}, fmt.Errorf("signing key %s: unsupported key type %T", path, parsed)
+	}
+}
@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// writeRSAKey and writeECKey write a PEM private key named <kid>.pem to dir.
+func writeRSAKey(t *testing.T, dir, kid string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating RSA key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, kid+".pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+}
+
+func writeECKey(t *testing.T, dir, kid string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating EC key: %v", err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("encoding EC key: %v", err)
+	}
+	writePEM(t, filepath.Join(dir, kid+".pem"), "EC PRIVATE KEY", der)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("writing %s: %v", path, err)
+	}
+}
+
+func loadKeyDir(t *testing.T, dir string) *SigningKeySet {
+	t.Helper()
+	t.Setenv("JWT_KEYS_DIR", dir)
+	set, err := LoadSigningKeysFromEnv()
+	if err != nil {
+		t.Fatalf("loading signing keys: %v", err)
+	}
+	return set
+}
+
+// signedKid signs a token with the active key, checks it verifies against the
+// set and returns the kid and alg headers it was signed with.
+func signedKid(t *testing.T, set *SigningKeySet) (string, string) {
+	t.Helper()
+	token, err := set.Sign(jwt.MapClaims{"username": "2000"})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+	claims, err := ExtractClaimsFromToken(token, set)
+	if err != nil {
+		t.Fatalf("verifying a token of the set: %v", err)
+	}
+	if claims["username"] != "2000" {
+		t.Errorf("expected the signed claims back, got %v", claims)
+	}
+	parsed, _, err := new(jwt.Parser).ParseUnverified(token, jwt.MapClaims{})
+	if err != nil {
+		t.Fatalf("parsing headers: %v", err)
+	}
+	kid, _ := parsed.Header["kid"].(string)
+	return kid, parsed.Method.Alg()
+}
+
+func TestSigningKeySetSignsWithTheKeyType(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01")
+	writeECKey(t, dir, "2026-02")
+	t.Setenv("JWT_ACTIVE_KID", "")
+
+	for _, tt := range []struct {
+		active string
+		alg    string
+	}{
+		{"2026-01", "RS256"},
+		{"2026-02", "ES256"},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte(tt.active+"\n"), 0o600); err != nil {
+			t.Fatalf("writing active kid: %v", err)
+		}
+		kid, alg := signedKid(t, loadKeyDir(t, dir))
+		if kid != tt.active || alg != tt.alg {
+			t.Errorf("expected %s signed with %s, got %s with %s", tt.active, tt.alg, kid, alg)
+		}
+	}
+}
+
+func TestSigningKeySetSelectsTheActiveKid(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01")
+	writeRSAKey(t, dir, "2026-02")
+
+	t.Setenv("JWT_ACTIVE_KID", "")
+	if kid, _ := signedKid(t, loadKeyDir(t, dir)); kid != "2026-02" {
+		t.Errorf("expected the last kid without a selection, got %s", kid)
+	}
+
+	t.Setenv("JWT_ACTIVE_KID", "2026-01")
+	if kid, _ := signedKid(t, loadKeyDir(t, dir)); kid != "2026-01" {
+		t.Errorf("expected JWT_ACTIVE_KID without an active_kid file, got %s", kid)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2026-02"), 0o600); err != nil {
+		t.Fatalf("writing active kid: %v", err)
+	}
+	if kid, _ := signedKid(t, loadKeyDir(t, dir)); kid != "2026-02" {
+		t.Errorf("expected the active_kid file over JWT_ACTIVE_KID, got %s", kid)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2025-12"), 0o600); err != nil {
+		t.Fatalf("writing active kid: %v", err)
+	}
+	t.Setenv("JWT_KEYS_DIR", dir)
+	if _, err := LoadSigningKeysFromEnv(); err == nil {
+		t.Error("expected an active kid without a key file to be refused")
+	}
+}
+
+func TestSigningKeySetReloadRotatesTheKey(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01")
+	t.Setenv("JWT_ACTIVE_KID", "2026-01")
+	set := loadKeyDir(t, dir)
+	oldToken, err := set.Sign(jwt.MapClaims{"username": "2000"})
+	if err != nil {
+		t.Fatalf("signing: %v", err)
+	}
+
+	writeECKey(t, dir, "2026-02")
+	if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2026-02"), 0o600); err != nil {
+		t.Fatalf("writing active kid: %v", err)
+	}
+	if err := set.Reload(); err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	if kid, _ := signedKid(t, set); kid != "2026-02" {
+		t.Errorf("expected the reload to pick up the new active kid, got %s", kid)
+	}
+	if _, err := ExtractClaimsFromToken(oldToken, set); err != nil {
+		t.Errorf("expected a token of the old key to keep verifying: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "2026-03.pem"), []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("writing broken key: %v", err)
+	}
+	if err := set.Reload(); err == nil {
+		t.Fatal("expected a broken key file to fail the reload")
+	}
+	if kid, _ := signedKid(t, set); kid != "2026-02" {
+		t.Errorf("expected a failed reload to keep the keys, got %s", kid)
+	}
+}
+
+func TestSigningKeySetRejectsAMismatchedAlgorithm(t *testing.T) {
+	set, err := NewEphemeralSigningKeySet()
+	if err != nil {
+		t.Fatalf("generating signing key: %v", err)
+	}
+	if _, err := set.ResolveKey("ephemeral", "ES256"); err == nil {
+		t.Error("expected an RS256 key to be refused for ES256")
+	}
+	if _, err := set.ResolveKey("unknown", "RS256"); err == nil {
+		t.Error("expected an unknown kid to be refused")
+	}
+}
+
+func TestJWKSPublishesEveryKey(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01")
+	writeECKey(t, dir, "2026-02")
+	t.Setenv("JWT_ACTIVE_KID", "")
+	set := loadKeyDir(t, dir)
+
+	jwks := set.JWKS()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %d", len(jwks.Keys))
+	}
+	rsaKey, ecKey := jwks.Keys[0], jwks.Keys[1]
+	if rsaKey.KeyID != "2026-01" || rsaKey.KeyType != "RSA" || rsaKey.Algorithm != "RS256" || rsaKey.N == "" || rsaKey.E == "" {
+		t.Errorf("unexpected RSA key %+v", rsaKey)
+	}
+	if ecKey.KeyID != "2026-02" || ecKey.KeyType != "EC" || ecKey.Algorithm != "ES256" || ecKey.Curve != "P-256" || ecKey.X == "" || ecKey.Y == "" {
+		t.Errorf("unexpected EC key %+v", ecKey)
+	}
+
+	body, err := json.Marshal(jwks)
+	if err != nil {
+		t.Fatalf("encoding JWKS: %v", err)
+	}
+	var raw map[string][]map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		t.Fatalf("decoding JWKS: %v", err)
+	}
+	for _, key := range raw["keys"] {
+		if _, ok := key["d"]; ok {
+			t.Errorf("expected no private material in key %v", key["kid"])
+		}
+		if key["use"] != "sig" {
+			t.Errorf("expected use sig, got %v", key["use"])
+		}
+	}
+}
+
+func TestJWKSCacheVerifiesTokensOfTheAuthServer(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01")
+	t.Setenv("JWT_ACTIVE_KID", "")
+	set := loadKeyDir(t, dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(set.JWKS())
+	}))
+	defer server.Close()
+	cache := NewJWKSCache(server.URL + JWKSPath)
+
+	verify := func(kid string) {
+		t.Helper()
+		token, err := set.Sign(jwt.MapClaims{"username": "2000"})
+		if err != nil {
+			t.Fatalf("signing: %v", err)
+		}
+		if _, err := ExtractClaimsFromToken(token, cache); err != nil {
+			t.Errorf("expected a token of %s to verify against the JWKS: %v", kid, err)
+		}
+	}
+	verify("2026-01")
+
+	writeECKey(t, dir, "2026-02")
+	if err := os.WriteFile(filepath.Join(dir, ActiveKeyFile), []byte("2026-02"), 0o600); err != nil {
+		t.Fatalf("writing active kid: %v", err)
+	}
+	if err := set.Reload(); err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+	// An unknown kid refreshes the cache once the refresh interval is over.
+	cache.lastAttempt = time.Now().Add(-minJWKSRefreshInterval)
+	verify("2026-02")
+}
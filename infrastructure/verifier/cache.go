@@ -0,0 +1,121 @@
+package verifier
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+)
+
+const maxCacheEntries = 10000
+
+type cacheEntry struct {
+	token     *domain.VerifiedToken
+	expiresAt time.Time
+}
+
+// verificationCache remembers successful verifications for a short time. Keys
+// are hashed so raw tokens are never held in memory longer than the request.
+type verificationCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]cacheEntry
+}
+
+func newVerificationCache(ttl time.Duration) *verificationCache {
+	return &verificationCache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+func (c *verificationCache) Get(key string) (*domain.VerifiedToken, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.token, true
+}
+
+// Put caches token for the TTL, but never past notAfter, the expiry of the
+// token, so a cached verification cannot outlive it.
+func (c *verificationCache) Put(key string, token *domain.VerifiedToken, notAfter time.Time) {
+	now := time.Now()
+	if c.ttl <= 0 || !notAfter.After(now) {
+		return
+	}
+	expiresAt := now.Add(c.ttl)
+	if notAfter.Before(expiresAt) {
+		expiresAt = notAfter
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = make(map[string]cacheEntry)
+		}
+	}
+	c.entries[key] = cacheEntry{token: token, expiresAt: expiresAt}
+}
+
+// tokenExpiry reads the exp claim of a token the auth service has verified,
+// or returns the zero time, which is never cached, when it has none.
+func tokenExpiry(token string) time.Time {
+	claims := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
+
+func cacheKey(token, routeName string, vars map[string]string, resource map[string]interface{}) string {
+	h := sha256.New()
+	h.Write([]byte(token))
+	h.Write([]byte{0})
+	h.Write([]byte(routeName))
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte("vars." + k + "=" + vars[k]))
+	}
+
+	keys = keys[:0]
+	for k := range resource {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.Write([]byte{0})
+		h.Write([]byte(fmt.Sprintf("resource.%s=%v", k, resource[k])))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,158 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/api/dto"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/utils"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+type verifyResponse struct {
+	IsAuthorized bool   `json:"isAuthorized"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	CustomerID   string `json:"customer_id"`
+	BreakGlass   string `json:"break_glass_grant_id"`
+	Actor        string `json:"actor"`
+	Error        string `json:"error"`
+}
+
+// RemoteVerifier asks the auth service to verify each token. Successful
+// results are cached briefly and a circuit breaker stops hammering an auth
+// service that is down. When the breaker is open the verifier either fails
+// closed or, if configured, falls back to local verification.
+type RemoteVerifier struct {
+	authServiceURL string
+	client         *http.Client
+	cache          *verificationCache
+	breaker        *circuitBreaker
+	failClosed     bool
+	fallback       ports.TokenVerifier
+}
+
+func NewRemoteVerifier(authServiceURL string, cfg Config, fallback ports.TokenVerifier) *RemoteVerifier {
+	transport := &http.Transport{
+		MaxIdleConns:        cfg.MaxIdleConns,
+		MaxIdleConnsPerHost: cfg.MaxIdleConns,
+		IdleConnTimeout:     90 * time.Second,
+	}
+
+	return &RemoteVerifier{
+		authServiceURL: authServiceURL,
+		client:         &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		cache:          newVerificationCache(cfg.CacheTTL),
+		breaker:        newCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown),
+		failClosed:     cfg.FailClosed,
+		fallback:       fallback,
+	}
+}
+
+func (v *RemoteVerifier) Verify(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError) {
+	key := cacheKey(token, routeName, vars, resource)
+	if verified, ok := v.cache.Get(key); ok {
+		return verified, nil
+	}
+
+	if !v.breaker.Allow() {
+		return v.unavailable(ctx, token, routeName, vars, resource)
+	}
+
+	verified, appErr, available := v.call(ctx, token, routeName, vars, resource)
+	if !available {
+		v.breaker.Failure()
+		return v.unavailable(ctx, token, routeName, vars, resource)
+	}
+	v.breaker.Success()
+
+	if appErr != nil {
+		return nil, appErr
+	}
+	v.cache.Put(key, verified, tokenExpiry(token))
+	return verified, nil
+}
+
+// call reports available=false when the auth service could not give an answer,
+// as opposed to answering that the token is not authorized.
+func (v *RemoteVerifier) call(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError, bool) {
+	verifyURL, err := utils.BuildAuthURL(v.authServiceURL, "/auth/verify")
+	if err != nil {
+		logger.Error("Invalid auth service URL", logger.Any("error", err))
+		return nil, errs.NewUnexpectedError("Error verifying token"), true
+	}
+
+	payload, err := json.Marshal(dto.VerifyRequest{RouteName: routeName, Vars: vars, Resource: resource})
+	if err != nil {
+		logger.Error("Error encoding verify request", logger.Any("error", err))
+		return nil, errs.NewUnexpectedError("Error verifying token"), true
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, verifyURL, bytes.NewReader(payload))
+	if err != nil {
+		logger.Error("Error building verify request", logger.Any("error", err))
+		return nil, errs.NewUnexpectedError("Error verifying token"), true
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; that says nothing about the auth service.
+		return nil, contextError(ctx), true
+	}
+	if err != nil {
+		logger.Error("Error calling auth service", logger.Any("error", err))
+		return nil, nil, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		logger.Error("Auth service returned an error", logger.Int("status", resp.StatusCode))
+		return nil, nil, false
+	}
+
+	var body verifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		logger.Error("Error parsing auth service response", logger.Any("error", err))
+		return nil, nil, false
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK && body.IsAuthorized:
+		return &domain.VerifiedToken{
+			Username:          body.Username,
+			Role:              body.Role,
+			CustomerID:        body.CustomerID,
+			BreakGlassGrantID: body.BreakGlass,
+			Actor:             body.Actor,
+		}, nil, true
+	case resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusOK:
+		return nil, errs.NewForbiddenError("Unauthorized"), true
+	default:
+		logger.Warn("Token verification failed", logger.Int("status", resp.StatusCode))
+		return nil, errs.NewAuthenticationError("Unauthorized"), true
+	}
+}
+
+func (v *RemoteVerifier) unavailable(ctx context.Context, token, routeName string, vars map[string]string, resource map[string]interface{}) (*domain.VerifiedToken, *errs.AppError) {
+	if v.failClosed || v.fallback == nil {
+		logger.Warn("Auth service unavailable, rejecting request", logger.String("routeName", routeName))
+		return nil, errs.NewServiceUnavailableError("Authorization service unavailable")
+	}
+	logger.Warn("Auth service unavailable, falling back to local verification", logger.String("routeName", routeName))
+	return v.fallback.Verify(ctx, token, routeName, vars, resource)
+}
+
+func contextError(ctx context.Context) *errs.AppError {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errs.NewTimeoutError("Token verification timed out")
+	}
+	return errs.NewRequestCanceledError("Request canceled")
+}
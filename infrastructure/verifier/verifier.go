@@ -0,0 +1,53 @@
+package verifier
+
+import (
+	"time"
+
+	"github.com/titi0001/Microservices-API-in-Go/domain/ports"
+	"github.com/titi0001/Microservices-API-in-Go/infrastructure/config"
+	"github.com/titi0001/Microservices-API-in-Go/logger"
+)
+
+const (
+	ModeLocal  = "local"
+	ModeRemote = "remote"
+)
+
+type Config struct {
+	Mode             string
+	Timeout          time.Duration
+	CacheTTL         time.Duration
+	MaxIdleConns     int
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	FailClosed       bool
+}
+
+func ConfigFromEnv() Config {
+	return Config{
+		Mode:             config.String("AUTH_VERIFY_MODE", ModeLocal),
+		Timeout:          config.Duration("AUTH_VERIFY_TIMEOUT", 3*time.Second),
+		CacheTTL:         config.Duration("AUTH_VERIFY_CACHE_TTL", 5*time.Second),
+		MaxIdleConns:     config.Int("AUTH_VERIFY_MAX_IDLE_CONNS", 100),
+		BreakerThreshold: config.Int("AUTH_VERIFY_BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  config.Duration("AUTH_VERIFY_BREAKER_COOLDOWN", 30*time.Second),
+		FailClosed:       config.Bool("AUTH_VERIFY_FAIL_CLOSED", true),
+	}
+}
+
+// New returns the verifier selected by cfg.Mode. The local verifier checks
+// tokens in-process; it also serves as the fallback of the remote verifier
+// when fail-closed is disabled.
+func New(cfg Config, authServiceURL string, local ports.TokenVerifier) ports.TokenVerifier {
+	switch cfg.Mode {
+	case ModeRemote:
+		logger.Info("Using remote token verification", logger.String("auth_service_url", authServiceURL))
+		return NewRemoteVerifier(authServiceURL, cfg, local)
+	case ModeLocal:
+		logger.Info("Using local token verification")
+		return local
+	default:
+		logger.Warn("Unknown AUTH_VERIFY_MODE, using local verification", logger.String("mode", cfg.Mode))
+		return local
+	}
+}
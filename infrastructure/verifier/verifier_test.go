@@ -0,0 +1,200 @@
+package verifier
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/titi0001/Microservices-API-in-Go/domain"
+	"github.com/titi0001/Microservices-API-in-Go/errs"
+)
+
+// unsignedToken builds a token with the given exp; the remote verifier leaves
+// signatures to the auth service and only reads the expiry itself.
+func unsignedToken(t *testing.T, exp time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"username": "2000", "exp": exp.Unix()}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("building token: %v", err)
+	}
+	return token
+}
+
+// authService answers /auth/verify with status and body, counting calls.
+func authService(t *testing.T, status int, body verifyResponse) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.URL.Path != "/auth/verify" || r.Header.Get("Authorization") == "" {
+			t.Errorf("unexpected verify call %s with authorization %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func testConfig() Config {
+	return Config{
+		Timeout:          time.Second,
+		CacheTTL:         time.Minute,
+		MaxIdleConns:     1,
+		BreakerThreshold: 2,
+		BreakerCooldown:  time.Minute,
+		FailClosed:       true,
+	}
+}
+
+type staticVerifier struct {
+	calls int
+}
+
+func (v *staticVerifier) Verify(context.Context, string, string, map[string]string, map[string]interface{}) (*domain.VerifiedToken, *errs.AppError) {
+	v.calls++
+	return &domain.VerifiedToken{Username: "local"}, nil
+}
+
+func TestCacheNeverOutlivesTheToken(t *testing.T) {
+	cache := newVerificationCache(time.Hour)
+	token := &domain.VerifiedToken{Username: "2000"}
+
+	cache.Put("expiring", token, time.Now().Add(50*time.Millisecond))
+	cache.Put("expired", token, time.Now().Add(-time.Second))
+	cache.Put("no-exp", token, time.Time{})
+	if _, ok := cache.Get("expiring"); !ok {
+		t.Fatal("expected a live token to be cached")
+	}
+	for _, key := range []string{"expired", "no-exp"} {
+		if _, ok := cache.Get(key); ok {
+			t.Errorf("expected %s not to be cached", key)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := cache.Get("expiring"); ok {
+		t.Error("expected the entry to expire with the token, before the TTL")
+	}
+}
+
+func TestBreakerOpensAndProbesAfterCooldown(t *testing.T) {
+	breaker := newCircuitBreaker(2, 20*time.Millisecond)
+
+	breaker.Failure()
+	if !breaker.Allow() {
+		t.Fatal("expected the breaker to stay closed below the threshold")
+	}
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("expected the breaker to open at the threshold")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("expected a probe after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatal("expected a single probe while half-open")
+	}
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("expected a failed probe to open the breaker again")
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	breaker.Allow()
+	breaker.Success()
+	if !breaker.Allow() || !breaker.Allow() {
+		t.Error("expected a successful probe to close the breaker")
+	}
+}
+
+func TestRemoteVerifierCachesAuthorizedTokens(t *testing.T) {
+	server, calls := authService(t, http.StatusOK, verifyResponse{IsAuthorized: true, Username: "2000", Role: "user", CustomerID: "2000"})
+	v := NewRemoteVerifier(server.URL, testConfig(), nil)
+	token := unsignedToken(t, time.Now().Add(time.Hour))
+
+	for i := 0; i < 2; i++ {
+		verified, err := v.Verify(context.Background(), token, "GetCustomer", map[string]string{"customer_id": "2000"}, nil)
+		if err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+		if verified.Username != "2000" || verified.Role != "user" || verified.CustomerID != "2000" {
+			t.Errorf("unexpected verified token %+v", verified)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("expected the second verification from the cache, got %d calls", *calls)
+	}
+
+	if _, err := v.Verify(context.Background(), token, "GetCustomer", map[string]string{"customer_id": "2001"}, nil); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("expected other vars to miss the cache, got %d calls", *calls)
+	}
+}
+
+func TestRemoteVerifierMapsRefusals(t *testing.T) {
+	token := unsignedToken(t, time.Now().Add(time.Hour))
+	for _, tt := range []struct {
+		status int
+		body   verifyResponse
+		want   int
+	}{
+		{http.StatusForbidden, verifyResponse{Error: "denied"}, http.StatusForbidden},
+		{http.StatusOK, verifyResponse{IsAuthorized: false}, http.StatusForbidden},
+		{http.StatusUnauthorized, verifyResponse{Error: "Token expired"}, http.StatusUnauthorized},
+	} {
+		server, calls := authService(t, tt.status, tt.body)
+		v := NewRemoteVerifier(server.URL, testConfig(), nil)
+		for i := 0; i < 2; i++ {
+			_, err := v.Verify(context.Background(), token, "GetCustomer", nil, nil)
+			if err == nil || err.Code != tt.want {
+				t.Errorf("status %d: expected %d, got %v", tt.status, tt.want, err)
+			}
+		}
+		if *calls != 2 {
+			t.Errorf("status %d: expected refusals not to be cached, got %d calls", tt.status, *calls)
+		}
+	}
+}
+
+func TestRemoteVerifierFailsClosedWhenTheAuthServiceIsDown(t *testing.T) {
+	server, calls := authService(t, http.StatusInternalServerError, verifyResponse{})
+	v := NewRemoteVerifier(server.URL, testConfig(), &staticVerifier{})
+	token := unsignedToken(t, time.Now().Add(time.Hour))
+
+	for i := 0; i < 3; i++ {
+		_, err := v.Verify(context.Background(), token, "GetCustomer", nil, nil)
+		if err == nil || err.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected 503, got %v", err)
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("expected the open breaker to stop calls after the threshold, got %d calls", *calls)
+	}
+}
+
+func TestRemoteVerifierFallsBackWhenFailOpen(t *testing.T) {
+	server, _ := authService(t, http.StatusBadGateway, verifyResponse{})
+	cfg := testConfig()
+	cfg.FailClosed = false
+	local := &staticVerifier{}
+	v := NewRemoteVerifier(server.URL, cfg, local)
+
+	verified, err := v.Verify(context.Background(), unsignedToken(t, time.Now().Add(time.Hour)), "GetCustomer", nil, nil)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if verified.Username != "local" || local.calls != 1 {
+		t.Errorf("expected the local verifier to answer, got %+v after %d calls", verified, local.calls)
+	}
+}
@@ -20,7 +20,10 @@ func init() {
 	encoderConfig.StacktraceKey = ""
 	config.EncoderConfig = encoderConfig
 
-	log, err = config.Build(zap.AddCallerSkip(1))
+	log, err = config.Build(
+		zap.AddCallerSkip(1),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core { return redactingCore{core} }),
+	)
 
 	if err != nil {
 		panic(err)
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	sensitiveKeys = map[string]bool{
+		"token":          true,
+		"access_token":   true,
+		"refresh_token":  true,
+		"id_token":       true,
+		"authorization":  true,
+		"header":         true,
+		"password":       true,
+		"secret":         true,
+		"api_key":        true,
+		"x-api-key":      true,
+		"mfa_token":      true,
+		"recovery_codes": true,
+		"client_secret":  true,
+		"code_verifier":  true,
+	}
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)bearer\s+\S+`)
+	apiKeyPattern = regexp.MustCompile(`\b(bk_[a-z2-7]{8})_[A-Za-z0-9_-]+`)
+)
+
+// redactingCore scrubs credentials from every entry before it is encoded, so a
+// token never reaches the logs whichever call site tries to log it.
+type redactingCore struct {
+	zapcore.Core
+}
+
+func (c redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return redactingCore{c.Core.With(redactFields(fields))}
+}
+
+func (c redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = RedactString(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch {
+		case sensitiveKeys[strings.ToLower(field.Key)]:
+			out[i] = zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: redacted}
+		case field.Type == zapcore.StringType:
+			field.String = RedactString(field.String)
+			out[i] = field
+		case field.Type == zapcore.ErrorType || field.Type == zapcore.StringerType || field.Type == zapcore.ReflectType:
+			out[i] = redactValue(field)
+		default:
+			out[i] = field
+		}
+	}
+	return out
+}
+
+func redactValue(field zapcore.Field) zapcore.Field {
+	enc := zapcore.NewMapObjectEncoder()
+	field.AddTo(enc)
+	value, ok := enc.Fields[field.Key]
+	if !ok {
+		return field
+	}
+	if text, isString := value.(string); isString {
+		if cleaned := RedactString(text); cleaned != text {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: cleaned}
+		}
+	}
+	return field
+}
+
+// RedactString masks JWTs, bearer credentials, API key secrets and token query
+// parameters.
+func RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+	s = bearerPattern.ReplaceAllString(s, "Bearer "+redacted)
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = apiKeyPattern.ReplaceAllString(s, "${1}_"+redacted)
+	if strings.Contains(s, "token=") {
+		s = redactQuery(s)
+	}
+	return s
+}
+
+func redactQuery(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.RawQuery == "" {
+		return s
+	}
+	q := u.Query()
+	for key := range q {
+		if sensitiveKeys[strings.ToLower(key)] {
+			q.Set(key, redacted)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}